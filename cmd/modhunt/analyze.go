@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var analyzeCommand = &cli.Command{
+	Name:  "analyze",
+	Usage: "analyze modules and their contents downloaded from the module proxy",
+	Commands: []*cli.Command{
+		analyzeSizeCommand,
+		analyzeAPICommand,
+		analyzeMajorsCommand,
+	},
+}
+
+var analyzeSizeCommand = &cli.Command{
+	Name:      "size",
+	Usage:     "report and store the size of a module zip, its vendored code and binary files",
+	ArgsUsage: "MODULE[@VERSION]",
+	Description: "Without a version, the latest version is analyzed. The result replaces\n" +
+		"the stored size of the module.",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		module, version, err := resolveVersion(ctx, proxy, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+		data, err := proxy.Zip(ctx, module, version)
+		if err != nil {
+			return fmt.Errorf("get zip: %w", err)
+		}
+		s, err := modindex.AnalyzeZip(module, version, data)
+		if err != nil {
+			return err
+		}
+
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		if err := c.StoreSize(ctx, s); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s@%s\n\n", module, version)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(w, "Zip size:\t%s\n", formatBytes(s.ZipSize))
+		_, _ = fmt.Fprintf(w, "Uncompressed:\t%s\n", formatBytes(s.Size))
+		_, _ = fmt.Fprintf(w, "Files:\t%d\n", s.Files)
+		_, _ = fmt.Fprintf(w, "Vendored:\t%d files, %s\n", s.VendorFiles, formatBytes(s.VendorSize))
+		_, _ = fmt.Fprintf(w, "Binary:\t%d files, %s\n", s.BinaryFiles, formatBytes(s.BinarySize))
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Println("\nLargest files:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, f := range s.LargestFiles {
+			kind := "text"
+			if f.Binary {
+				kind = "binary"
+			}
+			_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\n", formatBytes(f.Size), kind, f.Name)
+		}
+		return w.Flush()
+	},
+}
+
+// resolveVersion splits MODULE[@VERSION] and looks up the latest
+// version if none is given.
+func resolveVersion(ctx context.Context, proxy *goproxy.Client, arg string) (module, version string, err error) {
+	module, version, _ = strings.Cut(arg, "@")
+	if version == "" {
+		info, err := proxy.Latest(ctx, module)
+		if err != nil {
+			return "", "", fmt.Errorf("latest version: %w", err)
+		}
+		version = info.Version
+	}
+	return module, version, nil
+}
+
+// formatBytes formats n in binary units, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+var analyzeAPICommand = &cli.Command{
+	Name:      "api",
+	Usage:     "summarize the exported API of the packages of a module",
+	ArgsUsage: "MODULE[@VERSION]",
+	Description: "The Go files of the module zip are parsed without type checking, so\n" +
+		"declarations are counted regardless of build constraints. Without a\n" +
+		"version, the latest version is analyzed.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "internal",
+			Usage: "include internal packages",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		module, version, err := resolveVersion(ctx, proxy, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+		data, err := proxy.Zip(ctx, module, version)
+		if err != nil {
+			return fmt.Errorf("get zip: %w", err)
+		}
+		apis, err := modindex.AnalyzeAPI(module, version, data)
+		if err != nil {
+			return err
+		}
+
+		var total modindex.PackageAPI
+		var packages, commands, internal int
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PACKAGE\tFILES\tTYPES\tFUNCS\tMETHODS\tCONSTS\tVARS")
+		for _, p := range apis {
+			switch {
+			case p.Name == "main":
+				commands++
+				continue
+			case p.Internal:
+				internal++
+				if !cmd.Bool("internal") {
+					continue
+				}
+			default:
+				packages++
+				total.Files += p.Files
+				total.Types += p.Types
+				total.Funcs += p.Funcs
+				total.Methods += p.Methods
+				total.Consts += p.Consts
+				total.Vars += p.Vars
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\n", p.ImportPath, p.Files, p.Types, p.Funcs, p.Methods, p.Consts, p.Vars)
+		}
+		_, _ = fmt.Fprintf(w, "TOTAL (public)\t%d\t%d\t%d\t%d\t%d\t%d\n", total.Files, total.Types, total.Funcs, total.Methods, total.Consts, total.Vars)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\n%s@%s: %d public packages with %d exported declarations, %d internal packages, %d commands\n",
+			module, version, packages, total.Exported(), internal, commands)
+		return nil
+	},
+}
+
+var analyzeMajorsCommand = &cli.Command{
+	Name:      "majors",
+	Usage:     "show when the major versions of a module appeared and how active they are",
+	ArgsUsage: "MODULE",
+	Description: "All paths of the project of MODULE are read from the index, e.g.\n" +
+		"example.com/mod, example.com/mod/v2 and example.com/mod/v3, and their\n" +
+		"versions are grouped by major version. The releases in the year before\n" +
+		"the newest version of the index tell which major version is maintained.",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		majors, err := c.Majors(ctx, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+		if len(majors) == 0 {
+			return fmt.Errorf("%s: no versions in the index", cmd.Args().First())
+		}
+
+		var active *modindex.MajorActivity
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "MAJOR\tPATH\tFIRST\tLAST\tLATEST\tRELEASES\tLAST YEAR\tPSEUDO")
+		for i, m := range majors {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\n", m.Major, m.Path,
+				m.First.Format(time.DateOnly), m.Last.Format(time.DateOnly), orDash(m.Latest), m.Releases, m.Recent, m.Pseudo)
+			if m.Recent > 0 && (active == nil || m.Recent >= active.Recent) {
+				active = &majors[i]
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if active == nil {
+			fmt.Println("\nNo major version was released in the last year.")
+			return nil
+		}
+		fmt.Printf("\nMost releases in the last year: %s (%s)\n", active.Major, active.Path)
+		return nil
+	},
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+	"github.com/ngrash/modhunt/modscore"
+)
+
+var apiCommand = &cli.Command{
+	Name:  "api",
+	Usage: "answer queries of editor plugins over JSON-RPC",
+	Description: "Reads JSON-RPC 2.0 requests from standard input, one per line, and\n" +
+		"writes a response per line to standard output, so that editors can\n" +
+		"keep one process running instead of starting one per query. The\n" +
+		"stored data is read once at the start and again on \"reload\".\n\n" +
+		"Methods and their params:\n\n" +
+		"  search        {\"query\": \"...\", \"topics\": [...], \"go\": \"1.21\", \"limit\": 20}\n" +
+		"  info          {\"module\": \"...\"}\n" +
+		"  alternatives  {\"package\": \"...\", \"limit\": 10, \"min_score\": 0}\n" +
+		"  score         {\"module\": \"...\"}\n" +
+		"  reload        {}\n\n" +
+		"For example:\n\n" +
+		"  {\"jsonrpc\": \"2.0\", \"id\": 1, \"method\": \"score\", \"params\": {\"module\": \"github.com/spf13/cobra\"}}\n\n" +
+		"Warnings are written to standard error.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "stdio",
+			Usage: "talk over standard input and output",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if !cmd.Bool("stdio") {
+			return fmt.Errorf("expected --stdio, the only transport")
+		}
+		ctx = withoutTimeout(ctx)
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		s := &apiServer{cmd: cmd, proxy: proxy}
+		if err := s.load(ctx); err != nil {
+			return err
+		}
+		return s.serve(ctx, os.Stdin, os.Stdout)
+	},
+}
+
+// JSON-RPC 2.0 error codes. Requests failing for reasons of their own,
+// e.g. naming an unknown module, get errNotFound.
+const (
+	errParse          = -32700
+	errInvalidRequest = -32600
+	errMethodNotFound = -32601
+	errInvalidParams  = -32602
+	errNotFound       = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"` // absent for notifications
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// apiServer answers the requests of 'api' from the data loaded last.
+type apiServer struct {
+	cmd   *cli.Command
+	proxy *goproxy.Client
+
+	lookup     *pkglists.Lookup
+	graph      graphInfo
+	scores     *scorer
+	pseudoOnly map[string]modindex.PseudoOnly
+	highest    map[string]string
+	loaded     time.Time
+}
+
+// load reads the curated lists and the stored data.
+func (s *apiServer) load(ctx context.Context) error {
+	lookup, err := newLookup(ctx, s.cmd)
+	if err != nil {
+		return fmt.Errorf("init lookup: %w", err)
+	}
+	s.lookup = lookup
+	s.graph = loadGraph(ctx, s.cmd)
+	s.scores = newScorer(ctx, s.cmd, lookup, s.graph)
+	s.pseudoOnly = loadPseudoOnly(ctx, s.cmd, curatedModules(lookup))
+	s.highest = highestMajors(ctx, s.cmd)
+	s.loaded = time.Now()
+	return nil
+}
+
+// serve answers the requests read from r on w until r ends.
+func (s *apiServer) serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	enc := json.NewEncoder(w)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+		var req rpcRequest
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			resp.Error = &rpcError{Code: errParse, Message: err.Error()}
+		} else {
+			if req.ID != nil {
+				resp.ID = req.ID
+			}
+			result, err := s.handle(ctx, req)
+			var rerr *rpcError
+			switch {
+			case errors.As(err, &rerr):
+				resp.Error = rerr
+			case err != nil:
+				resp.Error = &rpcError{Code: errNotFound, Message: err.Error()}
+			default:
+				resp.Result = result
+			}
+			if req.ID == nil {
+				continue // notifications are not answered
+			}
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// handle calls the method of req.
+func (s *apiServer) handle(ctx context.Context, req rpcRequest) (any, error) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &rpcError{Code: errInvalidRequest, Message: `expected "jsonrpc": "2.0" and a method`}
+	}
+	switch req.Method {
+	case "search":
+		var p struct {
+			Query  string   `json:"query"`
+			Topics []string `json:"topics"`
+			Go     string   `json:"go"`
+			Limit  int      `json:"limit"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.Query == "" && len(p.Topics) == 0 {
+			return nil, &rpcError{Code: errInvalidParams, Message: "expected a query or topics"}
+		}
+		return s.search(p.Query, p.Topics, p.Go, p.Limit), nil
+	case "info":
+		var p struct {
+			Module string `json:"module"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.Module == "" {
+			return nil, &rpcError{Code: errInvalidParams, Message: "expected a module"}
+		}
+		return s.info(ctx, p.Module), nil
+	case "alternatives":
+		var p struct {
+			Package  string `json:"package"`
+			Limit    int    `json:"limit"`
+			MinScore int    `json:"min_score"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.Package == "" {
+			return nil, &rpcError{Code: errInvalidParams, Message: "expected a package"}
+		}
+		return s.alternatives(p.Package, p.MinScore, p.Limit)
+	case "score":
+		var p struct {
+			Module string `json:"module"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.Module == "" {
+			return nil, &rpcError{Code: errInvalidParams, Message: "expected a module"}
+		}
+		if !s.scores.knows(p.Module) {
+			return nil, fmt.Errorf("nothing is known about %s", p.Module)
+		}
+		return newScoreResponse(p.Module, s.scores.score(p.Module), s.loaded), nil
+	case "reload":
+		if err := s.load(ctx); err != nil {
+			return nil, err
+		}
+		return struct {
+			Loaded time.Time `json:"loaded"`
+		}{s.loaded.UTC()}, nil
+	default:
+		return nil, &rpcError{Code: errMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+}
+
+// decodeParams decodes the params of a request into v. Missing params
+// leave v as is.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: errInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// packageResponse is a curated package in the results of 'api'.
+type packageResponse struct {
+	Package     string `json:"package"`
+	Module      string `json:"module"`
+	Description string `json:"description,omitempty"`
+	Score       *int   `json:"score"` // null if unknown
+	ImportedBy  int    `json:"imported_by"`
+	Go          string `json:"go,omitempty"`
+	Deprecated  string `json:"deprecated,omitempty"`
+	Untagged    bool   `json:"untagged,omitempty"`
+	NewerMajor  string `json:"newer_major,omitempty"`
+	Shared      int    `json:"shared,omitempty"` // categories and topics shared, for alternatives
+}
+
+// scoreValue returns the total of score, or nil if it is unknown.
+func scoreValue(score modscore.Score) *int {
+	if !score.Known() {
+		return nil
+	}
+	return &score.Total
+}
+
+func (s *apiServer) search(query string, topics []string, goVersion string, limit int) []packageResponse {
+	hits := searchPackages(s.lookup, s.graph, s.scores, s.pseudoOnly, query, goVersion, topics)
+	if limit > 0 {
+		hits = hits[:min(len(hits), limit)]
+	}
+	results := make([]packageResponse, 0, len(hits))
+	for _, h := range hits {
+		results = append(results, packageResponse{
+			Package:     h.name,
+			Module:      packageModulePath(s.lookup, h.name),
+			Description: h.description,
+			Score:       scoreValue(h.score),
+			ImportedBy:  h.importedBy,
+			Go:          h.goVersion,
+			Deprecated:  h.deprecated,
+			Untagged:    h.untagged,
+		})
+	}
+	return results
+}
+
+// infoResponse is the result of the info method, what 'info' shows.
+// Fields are omitted if the data was not collected.
+type infoResponse struct {
+	Module     string            `json:"module"`
+	Curated    []curatedResponse `json:"curated"`
+	Latest     *latestResponse   `json:"latest,omitempty"`
+	Repository *repoResponse     `json:"repository,omitempty"`
+	ImportedBy *int              `json:"imported_by,omitempty"`
+	Go         string            `json:"go,omitempty"`
+	Deprecated *string           `json:"deprecated,omitempty"`
+	NewerMajor string            `json:"newer_major,omitempty"`
+	Score      *scoreResponse    `json:"score,omitempty"`
+	Scorecard  *float64          `json:"scorecard,omitempty"`
+	Releases   *releasesResponse `json:"releases,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+}
+
+type curatedResponse struct {
+	Source      string `json:"source"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+}
+
+type latestResponse struct {
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+}
+
+type repoResponse struct {
+	Host        string    `json:"host"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Stars       int       `json:"stars"`
+	Forks       int       `json:"forks"`
+	Archived    bool      `json:"archived"`
+	PushedAt    time.Time `json:"pushed_at"`
+	License     string    `json:"license,omitempty"`
+	Topics      []string  `json:"topics,omitempty"`
+}
+
+type releasesResponse struct {
+	Count   int       `json:"count"`
+	First   time.Time `json:"first"`
+	Last    time.Time `json:"last"`
+	PerYear float64   `json:"per_year"`
+}
+
+func (s *apiServer) info(ctx context.Context, name string) infoResponse {
+	key := packageKey(s.lookup, name)
+	links := s.lookup.Packages[key]
+	mod := name
+	if len(links) > 0 {
+		mod = packageModulePath(s.lookup, key)
+	}
+	resp := infoResponse{Module: mod, Curated: []curatedResponse{}}
+	for _, l := range links {
+		resp.Curated = append(resp.Curated, curatedResponse{Source: l.Source.Name, Category: categoryPath(l.Category), Description: l.Description})
+	}
+	if latest, err := s.proxy.Latest(ctx, mod); err != nil {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("latest version: %v", err))
+	} else {
+		resp.Latest = &latestResponse{Version: latest.Version, Time: latest.Time.UTC()}
+	}
+	if r, ok := s.scores.repo(mod); ok {
+		resp.Repository = &repoResponse{
+			Host:        r.Host,
+			Name:        r.Name,
+			Description: r.Description,
+			Stars:       r.Stars,
+			Forks:       r.Forks,
+			Archived:    r.Archived,
+			PushedAt:    r.PushedAt.UTC(),
+			License:     r.License,
+			Topics:      r.Topics,
+		}
+	}
+	if cad, ok := s.scores.cadences[mod]; ok {
+		resp.Releases = &releasesResponse{Count: cad.Releases, First: cad.First.UTC(), Last: cad.Last.UTC(), PerYear: cad.PerYear}
+	}
+	if s.graph.importedBy != nil {
+		n := s.graph.importedBy[mod]
+		resp.ImportedBy = &n
+	}
+	resp.Go = s.graph.goVersions[mod]
+	if msg, ok := s.graph.deprecated[mod]; ok {
+		resp.Deprecated = &msg
+	}
+	resp.NewerMajor = newerMajor(s.highest, mod)
+	if sc, ok := s.scores.cards[mod]; ok {
+		resp.Scorecard = &sc.Score
+	}
+	if s.scores.knows(mod) {
+		score := newScoreResponse(mod, s.scores.score(mod), s.loaded)
+		resp.Score = &score
+	}
+	return resp
+}
+
+// alternativesResponse is the result of the alternatives method, ranked
+// as by 'alternatives'.
+type alternativesResponse struct {
+	Package      string            `json:"package"`
+	Categories   []curatedResponse `json:"categories"`
+	Alternatives []packageResponse `json:"alternatives"`
+}
+
+func (s *apiServer) alternatives(name string, minScore, limit int) (alternativesResponse, error) {
+	key := packageKey(s.lookup, name)
+	links, ok := s.lookup.Packages[key]
+	if !ok {
+		return alternativesResponse{}, fmt.Errorf("package %s not found", key)
+	}
+	shared := sharedCategories(links, key)
+	for other, n := range sharedTopics(s.lookup, s.graph, key) {
+		if _, ok := shared[other]; ok {
+			shared[other] += n
+		}
+	}
+	shared = sharedByProject(s.lookup, shared, key)
+
+	resp := alternativesResponse{Package: key, Alternatives: []packageResponse{}}
+	for _, l := range links {
+		resp.Categories = append(resp.Categories, curatedResponse{Source: l.Source.Name, Category: categoryPath(l.Category), Description: l.Description})
+	}
+	for _, p := range rankSimilar(s.lookup, s.scores, shared, minScore, limit) {
+		module := packageModulePath(s.lookup, p.name)
+		resp.Alternatives = append(resp.Alternatives, packageResponse{
+			Package:     p.name,
+			Module:      module,
+			Description: p.description,
+			Score:       scoreValue(p.score),
+			ImportedBy:  s.graph.importedBy[module],
+			Go:          s.graph.goVersions[module],
+			Deprecated:  s.graph.deprecated[module],
+			NewerMajor:  newerMajor(s.highest, module),
+			Shared:      p.shared,
+		})
+	}
+	return resp, nil
+}
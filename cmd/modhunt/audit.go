@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+
+	"github.com/ngrash/modhunt/internal/pkglists"
+)
+
+var auditCommand = &cli.Command{
+	Name:      "audit",
+	Usage:     "audit the dependencies of Go projects",
+	ArgsUsage: "[DIR|DIR/...]...",
+	Description: "Reads the go.mod of every project, or of every project below DIR/...,\n" +
+		"and looks its requirements up in the stored data. Dependencies are\n" +
+		"reported if a newer major version is in the index, they are deprecated\n" +
+		"or their repository is archived, or no curated list has them. For\n" +
+		"curated dependencies, the better-scored packages of each of their\n" +
+		"categories are suggested. With --go-sum, modules only go.sum lists,\n" +
+		"because a dependency needs them, are audited as well. Collect the\n" +
+		"stored data with 'enrich'.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "go-sum",
+			Usage: "also audit the modules in go.sum that go.mod does not require",
+		},
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "list dependencies without findings as well",
+		},
+		&cli.IntFlag{
+			Name:  "alternatives",
+			Usage: "maximum number of alternatives per category, 0 suggests none",
+			Value: 3,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		patterns := cmd.Args().Slice()
+		if len(patterns) == 0 {
+			patterns = []string{"."}
+		}
+		var files []string
+		for _, p := range patterns {
+			found, err := findGoMods(p)
+			if err != nil {
+				return err
+			}
+			files = append(files, found...)
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no go.mod found in %s", strings.Join(patterns, " "))
+		}
+
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		graph := loadGraph(ctx, cmd)
+		scores := newScorer(ctx, cmd, lookup, graph)
+		highest := highestMajors(ctx, cmd)
+
+		for i, name := range files {
+			deps, err := readProjectDeps(name, cmd.Bool("go-sum"))
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(name)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "MODULE\tVERSION\tSCORE\tFINDINGS")
+			var suggestions []string
+			for _, d := range deps {
+				var findings []string
+				if p := newerMajor(highest, d.path); p != "" {
+					findings = append(findings, "newer major: "+p)
+				}
+				if msg, ok := graph.deprecated[d.path]; ok {
+					findings = append(findings, "deprecated: "+orDash(msg))
+				}
+				if r, ok := scores.repo(d.path); ok && r.Archived {
+					findings = append(findings, "archived")
+				}
+				key := packageKey(lookup, d.path)
+				links := lookup.Packages[key]
+				if len(links) == 0 {
+					findings = append(findings, "not curated")
+				}
+				score, known := scores.score(d.path), scores.knows(d.path)
+				if n := int(cmd.Int("alternatives")); n > 0 && known && score.Known() {
+					for _, l := range links {
+						better := betterAlternatives(lookup, scores, l.Category, key, score.Total, n)
+						if len(better) > 0 {
+							suggestions = append(suggestions, fmt.Sprintf("  %s in %s > %s: %s", d.path, l.Source.Name, categoryPath(l.Category), strings.Join(better, ", ")))
+						}
+					}
+				}
+				if len(findings) == 0 && !cmd.Bool("all") {
+					continue
+				}
+				version := d.version
+				if d.indirect {
+					version += " (indirect)"
+				}
+				shown := "-"
+				if known {
+					shown = score.String()
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.path, version, shown, orDash(strings.Join(findings, "; ")))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if len(suggestions) > 0 {
+				fmt.Println("\nBetter-scored alternatives:")
+				for _, s := range suggestions {
+					fmt.Println(s)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// findGoMods returns the go.mod file of the project in dir or, if pattern
+// is dir/..., of every project below dir. Vendored, testdata and hidden
+// directories are skipped as the go command does.
+func findGoMods(pattern string) ([]string, error) {
+	dir, recursive := strings.CutSuffix(pattern, "/...")
+	if dir == "" {
+		dir = "."
+	}
+	if !recursive {
+		name := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(name); err != nil {
+			return nil, err
+		}
+		return []string{name}, nil
+	}
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != dir {
+			if name := d.Name(); name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+		}
+		if !d.IsDir() && d.Name() == "go.mod" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// projectDep is a module a project depends on.
+type projectDep struct {
+	path     string
+	version  string
+	indirect bool // not imported by the project itself
+}
+
+// readProjectDeps returns the requirements of the go.mod file name,
+// ordered by path. Replaced requirements are audited as their
+// replacement; those replaced by a directory are left out. With goSum,
+// modules whose code the go.sum next to name lists are added at their
+// highest version, as indirect.
+func readProjectDeps(name string, goSum bool) ([]projectDep, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.Parse(name, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	replaced := make(map[string]*modfile.Replace)
+	for _, r := range f.Replace {
+		replaced[r.Old.Path] = r
+	}
+	var deps []projectDep
+	seen := make(map[string]bool)
+	for _, r := range f.Require {
+		d := projectDep{path: r.Mod.Path, version: r.Mod.Version, indirect: r.Indirect}
+		seen[d.path] = true
+		if rep, ok := replaced[d.path]; ok {
+			if rep.New.Version == "" {
+				continue
+			}
+			d.path, d.version = rep.New.Path, rep.New.Version
+		}
+		deps = append(deps, d)
+	}
+	if goSum {
+		sums, err := goSumModules(filepath.Join(filepath.Dir(name), "go.sum"))
+		if err != nil {
+			return nil, err
+		}
+		for path, version := range sums {
+			if !seen[path] && (f.Module == nil || path != f.Module.Mod.Path) {
+				deps = append(deps, projectDep{path: path, version: version, indirect: true})
+			}
+		}
+	}
+	slices.SortFunc(deps, func(a, b projectDep) int { return strings.Compare(a.path, b.path) })
+	return deps, nil
+}
+
+// goSumModules returns the highest version of every module whose code the
+// go.sum file name has a hash of. A missing go.sum lists none.
+func goSumModules(name string) (map[string]string, error) {
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		if v, ok := versions[fields[0]]; !ok || semver.Compare(fields[1], v) > 0 {
+			versions[fields[0]] = fields[1]
+		}
+	}
+	return versions, sc.Err()
+}
+
+// betterAlternatives returns the packages curated in cat that score
+// higher than score, the score of the package key, best first and at
+// most limit, with their score.
+func betterAlternatives(lookup *pkglists.Lookup, scores *scorer, cat *pkglists.Category, key string, score, limit int) []string {
+	own := packageProject(lookup, key)
+	best := make(map[string]int) // by project
+	names := make(map[string]string)
+	for _, l := range cat.Links {
+		other, err := pkglists.Key(l.URL)
+		if err != nil {
+			continue
+		}
+		project := packageProject(lookup, other)
+		if project == own {
+			continue
+		}
+		s := scores.score(packageModulePath(lookup, other))
+		if !s.Known() || s.Total <= score {
+			continue
+		}
+		if prev, ok := best[project]; !ok || s.Total > prev || (s.Total == prev && other < names[project]) {
+			best[project], names[project] = s.Total, other
+		}
+	}
+	projects := make([]string, 0, len(best))
+	for p := range best {
+		projects = append(projects, p)
+	}
+	slices.SortFunc(projects, func(a, b string) int {
+		return cmp.Or(cmp.Compare(best[b], best[a]), strings.Compare(names[a], names[b]))
+	})
+	var better []string
+	for _, p := range projects[:min(len(projects), limit)] {
+		better = append(better, fmt.Sprintf("%s (%d)", names[p], best[p]))
+	}
+	return better
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/time/rate"
+)
+
+// concurrencyFlag returns the --concurrency flag of a bulk command, which
+// processes def items concurrently by default. --workers is kept as an
+// alias.
+func concurrencyFlag(def int64) *cli.IntFlag {
+	return &cli.IntFlag{
+		Name:    "concurrency",
+		Aliases: []string{"workers"},
+		Usage:   "process `N` items concurrently",
+		Value:   def,
+	}
+}
+
+var qpsFlag = &cli.FloatFlag{
+	Name:  "qps",
+	Usage: "start at most `N` items per second (0 for no limit)",
+}
+
+// bulkLimits bounds how many items a bulk command processes concurrently
+// and how many it starts per second, as set by --concurrency and --qps.
+// Unlike --proxy-rate, which limits the requests to every host, they
+// limit the items, however many requests each takes.
+type bulkLimits struct {
+	concurrency int
+	limiter     *rate.Limiter
+}
+
+// newBulkLimits returns the limits set by the flags of cmd.
+func newBulkLimits(cmd *cli.Command) bulkLimits {
+	l := bulkLimits{
+		concurrency: max(int(cmd.Int("concurrency")), 1),
+		limiter:     rate.NewLimiter(rate.Inf, 1),
+	}
+	if qps := cmd.Float(qpsFlag.Name); qps > 0 {
+		l.limiter = rate.NewLimiter(rate.Limit(qps), 1)
+	}
+	return l
+}
+
+// forEachLimited is forEachParallel within the limits l. Once ctx is
+// done, items start without waiting, for fn to fail with its error.
+func forEachLimited[R any](ctx context.Context, items []string, l bulkLimits, fn func(string) R, done func(string, R)) {
+	forEachParallel(items, l.concurrency, func(item string) R {
+		_ = l.limiter.Wait(ctx)
+		return fn(item)
+	}, done)
+}
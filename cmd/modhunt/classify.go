@@ -0,0 +1,234 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var classifyCommand = &cli.Command{
+	Name:      "classify",
+	Usage:     "suggest curated categories for a module from the index",
+	ArgsUsage: "MODULE",
+	Description: "The description and forge topics of the repository of MODULE are\n" +
+		"compared with those of the entries of every curated category, and the\n" +
+		"categories sharing the most distinctive words are suggested. Entries\n" +
+		"are described by their links and by repositories stored by 'github\n" +
+		"sync' and 'repo sync'. The repository of MODULE is fetched from its\n" +
+		"forge and stored unless it is stored already or --offline is set.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "suggest `N` categories",
+			Value: 5,
+		},
+		&cli.BoolFlag{
+			Name:  "offline",
+			Usage: "use the stored repository metadata only",
+		},
+		githubTokenFlag, githubAPIURLFlag, verboseFlag, gitlabTokenFlag, gitlabURLFlag, sourcehutTokenFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		module := cmd.Args().First()
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		if _, err := c.Latest(ctx, module); err != nil {
+			return err
+		}
+		for name := range lookup.Packages {
+			if modname.Normalize(packageModulePath(lookup, name)) == modname.Normalize(module) {
+				_, _ = fmt.Fprintf(os.Stderr, "%s is curated as %s already\n", module, name)
+				break
+			}
+		}
+		r, err := moduleRepo(ctx, cmd, c, module)
+		if err != nil {
+			return err
+		}
+		terms := entryTerms(r.Description, r.Topics)
+		if len(terms) == 0 {
+			return fmt.Errorf("repository %s/%s has neither description nor topics", r.Host, r.Name)
+		}
+
+		stored, err := c.Repos(ctx)
+		if err != nil {
+			return err
+		}
+		repos := make(map[string]modindex.Repo, len(stored))
+		for _, r := range stored {
+			repos[repoKey(r.Host, r.Name)] = r
+		}
+		matches := newCategoryTerms(lookup, repos).rank(terms)
+		if len(matches) == 0 {
+			return fmt.Errorf("no category shares a word with %q", r.Description)
+		}
+		if n := int(cmd.Int("limit")); len(matches) > n {
+			matches = matches[:n]
+		}
+
+		fmt.Printf("%s: %s\n", module, orDash(r.Description))
+		fmt.Printf("Topics: %s\n\n", orDash(strings.Join(r.Topics, " ")))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "SCORE\tCATEGORY\tSHARED")
+		for _, m := range matches {
+			_, _ = fmt.Fprintf(w, "%.1f\t%s\t%s\n", m.score, m.path, strings.Join(m.shared, " "))
+		}
+		return w.Flush()
+	},
+}
+
+// moduleRepo returns the repository of module. Unless --offline is set,
+// repositories that were never stored are fetched and stored.
+func moduleRepo(ctx context.Context, cmd *cli.Command, c *modindex.Client, module string) (modindex.Repo, error) {
+	loc, err := forge.ParseURL("https://" + modname.RepoPath(module))
+	if err != nil {
+		return modindex.Repo{}, fmt.Errorf("repository of %s: %w", module, err)
+	}
+	r, err := c.Repo(ctx, loc.Host, loc.Name)
+	if !errors.Is(err, modindex.ErrNoRepo) || cmd.Bool("offline") {
+		return r, err
+	}
+	client, err := forgeClient(cmd, loc.Host)
+	if err != nil {
+		return r, err
+	}
+	fetched, err := client.Repo(ctx, loc.Name)
+	if err != nil {
+		return r, fmt.Errorf("get repository %s/%s: %w", loc.Host, loc.Name, err)
+	}
+	r = storedRepo(loc.Host, fetched)
+	r.FetchedAt = time.Now()
+	if err := c.StoreRepo(ctx, r); err != nil {
+		return r, fmt.Errorf("store repository %s/%s: %w", loc.Host, loc.Name, err)
+	}
+	return r, nil
+}
+
+// stopWords are words of descriptions that say nothing about a category.
+var stopWords = []string{"and", "the", "for", "with", "that", "from", "your", "you", "are", "this", "its", "into", "using", "based", "written", "simple", "library", "package", "golang"}
+
+// entryTerms returns the distinct lower-case words of description, except
+// short ones and stopWords, and topics.
+func entryTerms(description string, topics []string) []string {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var terms []string
+	for _, w := range words {
+		if len(w) >= 3 && !slices.Contains(stopWords, w) {
+			terms = append(terms, w)
+		}
+	}
+	terms = append(terms, topics...)
+	slices.Sort(terms)
+	return slices.Compact(terms)
+}
+
+// categoryTerms counts how many entries of every curated category have
+// each term of entryTerms, for suggesting categories.
+type categoryTerms struct {
+	counts  map[string]map[string]int // by category path and term
+	entries map[string]int            // by category path
+	in      map[string]int            // categories per term
+}
+
+// newCategoryTerms collects the terms of the descriptions of the curated
+// links and of the descriptions and topics of their repositories in
+// repos, which are keyed by repoKey.
+func newCategoryTerms(lookup *pkglists.Lookup, repos map[string]modindex.Repo) *categoryTerms {
+	ct := &categoryTerms{counts: make(map[string]map[string]int), entries: make(map[string]int), in: make(map[string]int)}
+	for _, s := range lookup.Sources {
+		walkCategories(s.Root, s.Name, func(cat *pkglists.Category, path string) {
+			counts := make(map[string]int)
+			for _, l := range cat.Links {
+				description := l.Description
+				var topics []string
+				if loc, err := forge.ParseURL(l.URL); err == nil {
+					r := repos[repoKey(loc.Host, loc.Name)]
+					description += " " + r.Description
+					topics = r.Topics
+				}
+				for _, t := range entryTerms(description, topics) {
+					counts[t]++
+				}
+			}
+			if len(counts) == 0 {
+				return
+			}
+			ct.counts[path] = counts
+			ct.entries[path] = len(cat.Links)
+			for t := range counts {
+				ct.in[t]++
+			}
+		})
+	}
+	return ct
+}
+
+// categoryMatch is a category suggested by categoryTerms.rank.
+type categoryMatch struct {
+	path   string
+	score  float64
+	shared []string // terms, the most distinctive first
+}
+
+// rank returns the categories sharing terms, the best match first. Every
+// shared term counts by the share of entries having it, damped so that
+// large categories are not at a disadvantage, and terms common to many
+// categories, like "go", weigh less than distinctive ones.
+func (ct *categoryTerms) rank(terms []string) []categoryMatch {
+	idf := func(t string) float64 { return math.Log(float64(len(ct.counts)) / float64(ct.in[t])) }
+	var matches []categoryMatch
+	for path, counts := range ct.counts {
+		m := categoryMatch{path: path}
+		for _, t := range terms {
+			if counts[t] > 0 {
+				m.score += float64(counts[t]) / math.Sqrt(float64(ct.entries[path])) * idf(t)
+				m.shared = append(m.shared, t)
+			}
+		}
+		if m.score <= 0 {
+			continue
+		}
+		slices.SortStableFunc(m.shared, func(a, b string) int { return cmp.Compare(idf(b), idf(a)) })
+		matches = append(matches, m)
+	}
+	slices.SortFunc(matches, func(a, b categoryMatch) int {
+		return cmp.Or(cmp.Compare(b.score, a.score), strings.Compare(a.path, b.path))
+	})
+	return matches
+}
+
+// suggest returns the best category for terms, or "" if none shares any.
+func (ct *categoryTerms) suggest(terms []string) string {
+	if matches := ct.rank(terms); len(matches) > 0 {
+		return matches[0].path
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/mod/module"
+)
+
+var compareCommand = &cli.Command{
+	Name:      "compare",
+	Usage:     "compare modules side by side",
+	ArgsUsage: "MODULE MODULE...",
+	Description: "The latest versions are fetched from the module proxy and their\n" +
+		"advisories from OSV.dev. Everything else is read from the index, so\n" +
+		"run 'github sync', 'repo sync', 'scorecard sync', 'deps sync' and\n" +
+		"download-releases first.",
+	Flags: []cli.Flag{osvURLFlag},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() < 2 {
+			return fmt.Errorf("expected at least two modules")
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		modules := cmd.Args().Slice()
+		graph := loadGraph(ctx, cmd)
+		scores := newScorer(ctx, cmd, lookup, graph)
+
+		latest := latestVersions(ctx, proxy, modules)
+		versions := make(map[string]string, len(latest))
+		for m, info := range latest {
+			versions[m] = info.Version
+		}
+		scores.vulns = vulnCounts(ctx, cmd, versions)
+		pseudoOnly := loadPseudoOnly(ctx, cmd, modules)
+
+		rows := []struct {
+			name  string
+			value func(module string) string
+		}{
+			{"latest version", func(m string) string { return orDash(versions[m]) }},
+			{"released", func(m string) string {
+				if info, ok := latest[m]; ok {
+					return formatDate(info.Time)
+				}
+				return "-"
+			}},
+			{"tagged", func(m string) string {
+				if p, ok := pseudoOnly[m]; ok {
+					return fmt.Sprintf("no, %d pseudo-versions", p.Versions)
+				}
+				// The proxy resolves latest to a pseudo-version only for
+				// modules without releases.
+				info, ok := latest[m]
+				switch {
+				case !ok:
+					return "-"
+				case module.IsPseudoVersion(info.Version):
+					return "no"
+				}
+				return "yes"
+			}},
+			{"stars", func(m string) string {
+				if r, ok := scores.repo(m); ok {
+					return strconv.Itoa(r.Stars)
+				}
+				return "-"
+			}},
+			{"importers", func(m string) string {
+				if graph.importedBy == nil {
+					return "-"
+				}
+				return strconv.Itoa(graph.importedBy[m])
+			}},
+			{"license", func(m string) string {
+				r, _ := scores.repo(m)
+				return orDash(r.License)
+			}},
+			{"go", func(m string) string { return orDash(graph.goVersions[m]) }},
+			{"vulnerabilities", func(m string) string {
+				if v, ok := scores.vulns[m]; ok {
+					return strconv.Itoa(v.count)
+				}
+				return "-"
+			}},
+			{"scorecard", scores.scorecardScore},
+			{"score", func(m string) string { return scores.score(m).String() }},
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(w, "\t%s\n", strings.Join(modules, "\t"))
+		for _, row := range rows {
+			values := make([]string, len(modules))
+			for i, m := range modules {
+				values[i] = row.value(m)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", row.name, strings.Join(values, "\t"))
+		}
+		return w.Flush()
+	},
+}
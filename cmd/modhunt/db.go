@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var dbCommand = &cli.Command{
+	Name:  "db",
+	Usage: "compare and merge modhunt databases",
+	Description: "Lets a server synchronize the index and collect data while a local copy\n" +
+		"catches up by merging the server's database, e.g. a snapshot, instead of\n" +
+		"downloading and enriching everything again. Both databases must be at\n" +
+		"the same schema version.",
+	Commands: []*cli.Command{
+		dbDiffCommand,
+		dbMergeCommand,
+	},
+}
+
+var dbDiffCommand = &cli.Command{
+	Name:      "diff",
+	Usage:     "count the rows one database has and the other lacks",
+	ArgsUsage: "A.db B.db",
+	Description: "Versions are compared by path and version, collected data by what it\n" +
+		"describes, e.g. a module or repository. Rows both have are counted as\n" +
+		"newer in the one that fetched them later.",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 2 {
+			return fmt.Errorf("expected exactly two databases")
+		}
+		a, b := cmd.Args().Get(0), cmd.Args().Get(1)
+		if _, err := os.Stat(a); err != nil {
+			return err
+		}
+		c, err := modindex.Open(ctx, modindex.WithDBPath(a))
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		diffs, err := c.Diff(ctx, b)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "TABLE\tONLY A\tONLY B\tNEWER IN A\tNEWER IN B")
+		var differ bool
+		for _, d := range diffs {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", d.Table, d.OnlyA, d.OnlyB, d.NewerA, d.NewerB)
+			differ = differ || d.OnlyA+d.OnlyB+d.NewerA+d.NewerB > 0
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if !differ {
+			fmt.Println("\nThe databases hold the same data")
+		}
+		return nil
+	},
+}
+
+var dbMergeCommand = &cli.Command{
+	Name:      "merge",
+	Usage:     "merge the rows of another database into the database",
+	ArgsUsage: "FROM.db",
+	Description: "Adds the versions FROM.db has and the database lacks, marks the time\n" +
+		"ranges FROM.db synchronized as covered, and copies the collected data\n" +
+		"the database lacks or fetched earlier than FROM.db, e.g. repositories\n" +
+		"and scores. Caches, the state of enrich runs and projects are not\n" +
+		"merged; run 'projects sync' to link new paths to projects. FROM.db is\n" +
+		"not changed.",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one database")
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		start := time.Now()
+		merges, err := c.Merge(ctx, cmd.Args().First(), func(read int) {
+			_, _ = fmt.Fprintf(os.Stderr, "\rRead %d versions", read)
+		})
+		_, _ = fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "TABLE\tADDED\tREPLACED")
+		for _, m := range merges {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%d\n", m.Table, m.Added, m.Replaced)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nMerged in %s\n", time.Since(start).Round(time.Second))
+		return nil
+	},
+}
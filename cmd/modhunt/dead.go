@@ -0,0 +1,171 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var (
+	yearsFlag = &cli.IntFlag{
+		Name:  "years",
+		Usage: "flag repositories not pushed to for `N` years",
+		Value: 2,
+	}
+	releaseYearsFlag = &cli.IntFlag{
+		Name:  "release-years",
+		Usage: "flag modules not released for `N` years",
+		Value: 3,
+	}
+)
+
+var deadCommand = &cli.Command{
+	Name:  "dead",
+	Usage: "report curated packages that look archived or abandoned",
+	Description: "Packages are flagged if their repository is archived or was not pushed\n" +
+		"to for --years, if they were not released for --release-years, or if a\n" +
+		"source lists them as dead, like the Go Wiki does. Repository metadata is\n" +
+		"fetched with \"github sync\" and \"repo sync\", release histories with\n" +
+		"download-releases; packages missing them are only checked against the\n" +
+		"sources.",
+	Flags: []cli.Flag{yearsFlag, releaseYearsFlag},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		st, err := loadStaleness(ctx, cmd, c, lookup)
+		if err != nil {
+			return err
+		}
+
+		type entry struct {
+			pkg     string
+			reasons []string
+			repo    modindex.Repo
+			cad     modindex.Cadence
+		}
+		var entries []entry
+		var unknown int
+		for key, links := range lookup.Packages {
+			e := entry{pkg: key}
+			var known bool
+			e.reasons, e.repo, e.cad, known = st.reasons(key, links)
+			if !known {
+				unknown++
+			}
+			if len(e.reasons) > 0 {
+				entries = append(entries, e)
+			}
+		}
+		// Packages with the most reasons first.
+		slices.SortFunc(entries, func(a, b entry) int {
+			return cmp.Or(cmp.Compare(len(b.reasons), len(a.reasons)), strings.Compare(a.pkg, b.pkg))
+		})
+		if unknown > 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "%d of %d packages have neither repository metadata nor releases\n", unknown, len(lookup.Packages))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PACKAGE\tSTARS\tLAST PUSH\tLAST RELEASE\tREASONS")
+		for _, e := range entries {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", e.pkg, e.repo.Stars, formatDate(e.repo.PushedAt), formatDate(e.cad.Last), strings.Join(e.reasons, ", "))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d of %d packages should be flagged or removed\n", len(entries), len(lookup.Packages))
+		return nil
+	},
+}
+
+// staleness judges whether curated packages look archived or abandoned.
+type staleness struct {
+	repos         map[string]modindex.Repo // by host and lower case name
+	cadences      map[string]modindex.Cadence
+	listedDead    map[string][]string // sources by lookup key
+	pushCutoff    time.Time
+	releaseCutoff time.Time
+}
+
+// loadStaleness loads the stored repositories and release histories and
+// the cutoffs of yearsFlag and releaseYearsFlag.
+func loadStaleness(ctx context.Context, cmd *cli.Command, c *modindex.Client, lookup *pkglists.Lookup) (*staleness, error) {
+	stored, err := c.Repos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	st := &staleness{
+		repos:         make(map[string]modindex.Repo, len(stored)),
+		cadences:      make(map[string]modindex.Cadence),
+		listedDead:    make(map[string][]string),
+		pushCutoff:    now.AddDate(-int(cmd.Int(yearsFlag.Name)), 0, 0),
+		releaseCutoff: now.AddDate(-int(cmd.Int(releaseYearsFlag.Name)), 0, 0),
+	}
+	for _, r := range stored {
+		st.repos[repoKey(r.Host, r.Name)] = r
+	}
+	cads, err := c.Cadences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, cad := range cads {
+		st.cadences[cad.Module] = cad
+	}
+	for _, s := range lookup.Sources {
+		for _, l := range s.Dead {
+			key, err := pkglists.Key(l.URL)
+			if err != nil {
+				continue
+			}
+			st.listedDead[key] = append(st.listedDead[key], s.Name)
+		}
+	}
+	return st, nil
+}
+
+// reasons returns why the package key with the curated links looks dead,
+// its repository and release history, and whether either is known.
+func (st *staleness) reasons(key string, links []pkglists.Link) (reasons []string, repo modindex.Repo, cad modindex.Cadence, known bool) {
+	if loc, err := forge.ParseURL(links[0].URL); err == nil {
+		if repo, known = st.repos[repoKey(loc.Host, loc.Name)]; known {
+			if repo.Archived {
+				reasons = append(reasons, "archived")
+			}
+			if !repo.PushedAt.IsZero() && repo.PushedAt.Before(st.pushCutoff) {
+				reasons = append(reasons, "no push since "+formatDate(repo.PushedAt))
+			}
+		}
+	}
+	if c, ok := st.cadences[linkModulePath(links[0])]; ok {
+		known = true
+		cad = c
+		switch {
+		case cad.Releases == 0:
+			reasons = append(reasons, "never released")
+		case cad.Last.Before(st.releaseCutoff):
+			reasons = append(reasons, "no release since "+formatDate(cad.Last))
+		}
+	}
+	for _, source := range st.listedDead[key] {
+		reasons = append(reasons, "dead on "+source)
+	}
+	return reasons, repo, cad, known
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	goversion "go/version"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/depsdev"
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var depsCommand = &cli.Command{
+	Name:  "deps",
+	Usage: "inspect the dependency graph built from go.mod files",
+	Commands: []*cli.Command{
+		depsSyncCommand,
+		depsListCommand,
+		depsDependentsCommand,
+		depsTopCommand,
+		depsGoVersionsCommand,
+	},
+}
+
+var depsSyncCommand = &cli.Command{
+	Name:  "sync",
+	Usage: "download the go.mod files of curated modules and store their requirements",
+	Description: "The go.mod of the latest version is read, as reported by the module proxy\n" +
+		"for curated modules and by the index database with --all. Modules whose\n" +
+		"latest version was read before are skipped.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "read the go.mod files of all paths in the index database",
+		},
+		includeGeneratedFlag,
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "read go.mod files again even if their version was read before",
+		},
+		concurrencyFlag(10),
+		qpsFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		var modules []string
+		if cmd.Bool("all") {
+			modules, err = c.Paths(ctx, cmd.Bool(includeGeneratedFlag.Name))
+			if err != nil {
+				return err
+			}
+		} else {
+			lookup, err := newLookup(ctx, cmd)
+			if err != nil {
+				return fmt.Errorf("init lookup: %w", err)
+			}
+			modules = curatedModules(lookup)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type fetched struct {
+			version string
+			gomod   *modindex.GoMod
+			err     error
+		}
+		var done, stored int
+		var storeErr error
+		forEachLimited(ctx, modules, newBulkLimits(cmd), func(m string) fetched {
+			version, gm, err := fetchGoMod(ctx, c, proxy, m, cmd.Bool("all"), cmd.Bool("force"))
+			return fetched{version, gm, err}
+		}, func(m string, f fetched) {
+			done++
+			if storeErr != nil || f.gomod == nil && f.err == nil {
+				return
+			}
+			if f.err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error fetching %q: %v\n", done, len(modules), m, f.err)
+				return
+			}
+			if err := c.StoreGoMod(ctx, m, f.version, f.gomod); err != nil {
+				storeErr = fmt.Errorf("store go.mod of %s: %w", m, err)
+				cancel()
+				return
+			}
+			stored++
+			_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s@%s requires %d modules\n", done, len(modules), m, f.version, len(f.gomod.Requires))
+			if f.gomod.Deprecated != "" {
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s is deprecated: %s\n", done, len(modules), m, f.gomod.Deprecated)
+			}
+		})
+		if storeErr != nil {
+			return storeErr
+		}
+		fmt.Printf("Stored the go.mod files of %d modules\n", stored)
+		return nil
+	},
+}
+
+// fetchGoMod returns the latest version of module and its parsed go.mod.
+// The go.mod is nil if that version was stored before and force is false.
+// The latest version comes from the index database if fromIndex is set
+// and from the proxy otherwise.
+func fetchGoMod(ctx context.Context, c *modindex.Client, proxy *goproxy.Client, module string, fromIndex, force bool) (string, *modindex.GoMod, error) {
+	var version string
+	if fromIndex {
+		latest, err := c.Latest(ctx, module)
+		if err != nil {
+			return "", nil, err
+		}
+		version = latest.Version
+	} else {
+		info, err := proxy.Latest(ctx, module)
+		if err != nil {
+			return "", nil, fmt.Errorf("latest version: %w", err)
+		}
+		version = info.Version
+	}
+	if version == "" {
+		return "", nil, nil
+	}
+
+	if !force {
+		stored, err := c.GoModVersion(ctx, module)
+		if err != nil {
+			return "", nil, err
+		}
+		if stored == version {
+			return version, nil, nil
+		}
+	}
+
+	data, err := proxy.GoMod(ctx, module, version)
+	if err != nil {
+		return "", nil, fmt.Errorf("get go.mod: %w", err)
+	}
+	gm, err := modindex.ParseGoMod(module, version, data)
+	if err != nil {
+		return "", nil, err
+	}
+	return version, gm, nil
+}
+
+var depsListCommand = &cli.Command{
+	Name:      "list",
+	Usage:     "list the requirements of a module",
+	ArgsUsage: "MODULE",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		module := cmd.Args().First()
+		version, err := c.GoModVersion(ctx, module)
+		if err != nil {
+			return err
+		}
+		if version == "" {
+			return fmt.Errorf("no go.mod stored for %s, run deps sync first", module)
+		}
+		deps, err := c.Dependencies(ctx, module)
+		if err != nil {
+			return err
+		}
+
+		deprecated, err := c.Deprecations(ctx)
+		if err != nil {
+			return err
+		}
+		if msg, ok := deprecated[module]; ok {
+			fmt.Printf("Deprecated: %s\n", msg)
+		}
+		fmt.Printf("%s@%s requires %d modules\n\n", module, version, len(deps))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "MODULE\tVERSION\tINDIRECT\tDEPRECATED")
+		for _, d := range deps {
+			_, dep := deprecated[d.Path]
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%t\t%t\n", d.Path, d.Version, d.Indirect, dep)
+		}
+		return w.Flush()
+	},
+}
+
+var depsDependentsCommand = &cli.Command{
+	Name:      "dependents",
+	Usage:     "list the modules requiring a module",
+	ArgsUsage: "MODULE",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		dependents, err := c.Dependents(ctx, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "MODULE\tVERSION\tREQUIRES\tINDIRECT")
+		for _, d := range dependents {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", d.Module, d.Version, d.Requires, d.Indirect)
+		}
+		return w.Flush()
+	},
+}
+
+var depsTopCommand = &cli.Command{
+	Name:  "top",
+	Usage: "list the most required modules or, with --bloat, the modules with the most requirements",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "n",
+			Usage: "list `N` modules",
+			Value: 20,
+		},
+		&cli.BoolFlag{
+			Name:  "bloat",
+			Usage: "list the modules with the most requirements instead",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		top := c.MostRequired
+		if cmd.Bool("bloat") {
+			top = c.MostRequiring
+		}
+		counts, err := top(ctx, int(cmd.Int("n")))
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "MODULE\tDIRECT\tINDIRECT")
+		for _, dc := range counts {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%d\n", dc.Module, dc.Direct, dc.Indirect)
+		}
+		return w.Flush()
+	},
+}
+
+var importersCommand = &cli.Command{
+	Name:      "importers",
+	Usage:     "count and list the modules requiring a module",
+	ArgsUsage: "MODULE",
+	Description: "Dependents come from the dependency graph built by 'deps sync'. If it\n" +
+		"knows none, or with --deps-dev, deps.dev is asked for the number of\n" +
+		"dependents of the latest version across the ecosystem.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "deps-dev",
+			Usage: "always ask deps.dev",
+		},
+		&cli.StringFlag{
+			Name:  "deps-dev-url",
+			Usage: "use the deps.dev API at `URL`",
+			Value: depsdev.DefaultURL,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		module := cmd.Args().First()
+
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		dependents, err := c.Dependents(ctx, module)
+		if err != nil {
+			return err
+		}
+		if len(dependents) > 0 {
+			fmt.Printf("%d modules in the dependency graph require %s\n\n", len(dependents), module)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "MODULE\tVERSION\tREQUIRES\tINDIRECT")
+			for _, d := range dependents {
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", d.Module, d.Version, d.Requires, d.Indirect)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("No module in the dependency graph requires %s\n", module)
+		}
+		if len(dependents) > 0 && !cmd.Bool("deps-dev") {
+			return nil
+		}
+
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		info, err := proxy.Latest(ctx, module)
+		if err != nil {
+			return fmt.Errorf("latest version: %w", err)
+		}
+		dd, err := depsdev.New(cmd.String("deps-dev-url"), nil).Dependents(ctx, module, info.Version)
+		if err != nil {
+			return fmt.Errorf("deps.dev: %w", err)
+		}
+		fmt.Printf("deps.dev: %d packages depend on %s@%s (%d directly, %d indirectly)\n", dd.Total, module, info.Version, dd.Direct, dd.Indirect)
+		return nil
+	},
+}
+
+var depsGoVersionsCommand = &cli.Command{
+	Name:  "go-versions",
+	Usage: "report the go directives of the stored go.mod files",
+	Description: "Without --newer-than, the number of modules per go directive is listed.\n" +
+		"go.mod files without a go directive are counted as \"-\"; the go command\n" +
+		"treats them as go 1.16.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "newer-than",
+			Usage: "list the modules requiring a Go version newer than `VERSION` (e.g. 1.22)",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		newerThan := cmd.String("newer-than")
+		if newerThan != "" && !goversion.IsValid(goLang(newerThan)) {
+			return fmt.Errorf("invalid --newer-than version %q", newerThan)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		directives, err := c.GoDirectives(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if newerThan != "" {
+			_, _ = fmt.Fprintln(w, "MODULE\tGO\tTOOLCHAIN")
+			for _, d := range directives {
+				if d.GoVersion != "" && goversion.Compare(goLang(d.GoVersion), goLang(newerThan)) > 0 {
+					_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", d.Module, d.GoVersion, orDash(d.Toolchain))
+				}
+			}
+			return w.Flush()
+		}
+
+		counts := make(map[string]int)
+		for _, d := range directives {
+			counts[d.GoVersion]++
+		}
+		versions := slices.Collect(maps.Keys(counts))
+		slices.SortFunc(versions, func(a, b string) int {
+			return -goversion.Compare(goLang(a), goLang(b))
+		})
+		_, _ = fmt.Fprintln(w, "GO\tMODULES")
+		for _, v := range versions {
+			_, _ = fmt.Fprintf(w, "%s\t%d\n", orDash(v), counts[v])
+		}
+		return w.Flush()
+	},
+}
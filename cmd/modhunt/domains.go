@@ -0,0 +1,333 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/goimport"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+// Kinds of hosting reported by domains, in the order they are listed.
+const (
+	kindGitHub     = "GitHub"
+	kindGitLab     = "GitLab"
+	kindGitea      = "Gitea"
+	kindBitbucket  = "Bitbucket"
+	kindSourcehut  = "sourcehut"
+	kindGoogleCode = "Google Code"
+	kindSelfHosted = "self-hosted" // a vanity path served from no known forge
+	kindDocs       = "docs"        // a documentation site like pkg.go.dev
+	kindWebsite    = "website"     // a host without go-import tag
+	kindUnresolved = "unresolved"  // a host whose tag is not known
+)
+
+var hostingKinds = []string{kindGitHub, kindGitLab, kindGitea, kindBitbucket, kindSourcehut, kindGoogleCode, kindSelfHosted, kindDocs, kindWebsite, kindUnresolved}
+
+// docsHosts are documentation sites linked instead of repositories.
+var docsHosts = []string{"pkg.go.dev", "godoc.org", "go.dev"}
+
+var domainsCommand = &cli.Command{
+	Name:  "domains",
+	Usage: "analyze where curated packages are hosted",
+	Description: "Every curated link is classified by the forge it is hosted on. Links\n" +
+		"to other hosts are taken as vanity import paths and resolved through\n" +
+		"their go-import meta tag to the repository they are served from, which\n" +
+		"counts for its forge, or as self-hosted if it is on none. Hosts without\n" +
+		"a tag are counted as websites. The tags are stored in the index and\n" +
+		"resolved again after --ttl; with --offline, only stored tags are used.\n\n" +
+		"Packages count for the host of their first link. With --categories, the\n" +
+		"hosting of the links of every category is broken down as well.",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Usage: "reuse go-import tags resolved within `DURATION`",
+			Value: 30 * 24 * time.Hour,
+		},
+		&cli.BoolFlag{
+			Name:  "offline",
+			Usage: "use the stored go-import tags only",
+		},
+		&cli.BoolFlag{
+			Name:  "categories",
+			Usage: "break the hosting down by category",
+		},
+		concurrencyFlag(8),
+		qpsFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		var links []pkglists.Link
+		for _, s := range lookup.Sources {
+			walkCategories(s.Root, s.Name, func(cat *pkglists.Category, _ string) {
+				links = append(links, cat.Links...)
+			})
+		}
+		vanity, err := resolveVanityHosts(ctx, cmd, c, links)
+		if err != nil {
+			return err
+		}
+
+		var packages []hosting
+		for _, links := range lookup.Packages {
+			if h, ok := classifyLink(links[0].URL, vanity); ok {
+				packages = append(packages, h)
+			}
+		}
+		printHostingKinds(packages, len(lookup.Packages))
+		if err := printHosts(packages, len(lookup.Packages)); err != nil {
+			return err
+		}
+		if cmd.Bool("categories") {
+			return printCategoryHosting(lookup, vanity)
+		}
+		return nil
+	},
+}
+
+// hosting is where a curated link lives.
+type hosting struct {
+	host   string
+	kind   string
+	target string // host of the repository of a vanity path
+}
+
+// classifyLink returns where the link is hosted, given the go-import tags
+// of vanity paths. It reports false for links without repository.
+func classifyLink(link string, vanity map[string]modindex.VanityImport) (hosting, bool) {
+	loc, err := forge.ParseURL(link)
+	if err != nil {
+		return hosting{}, false
+	}
+	loc.Host = strings.TrimPrefix(loc.Host, "www.")
+	h := hosting{host: loc.Host, kind: forgeKind(loc.Host)}
+	if h.kind != "" {
+		return h, true
+	}
+	if slices.Contains(docsHosts, loc.Host) {
+		h.kind = kindDocs
+		return h, true
+	}
+	v, ok := vanity[vanityPath(loc)]
+	switch {
+	case !ok:
+		h.kind = kindUnresolved
+	case v.RepoRoot == "":
+		h.kind = kindWebsite
+	default:
+		h.target = v.RepoRoot
+		if u, err := url.Parse(v.RepoRoot); err == nil && u.Host != "" {
+			h.target = strings.ToLower(u.Host)
+		}
+		h.kind = cmp.Or(forgeKind(h.target), kindSelfHosted)
+	}
+	return h, true
+}
+
+// forgeKind returns the kind of forge at host, or "" if it is none that
+// is known.
+func forgeKind(host string) string {
+	switch {
+	case host == "github.com":
+		return kindGitHub
+	case host == "gitlab.com" || strings.HasPrefix(host, "gitlab."):
+		return kindGitLab
+	case slices.Contains(giteaHosts, host):
+		return kindGitea
+	case host == "bitbucket.org":
+		return kindBitbucket
+	case host == "git.sr.ht":
+		return kindSourcehut
+	case host == "code.google.com":
+		return kindGoogleCode
+	}
+	return ""
+}
+
+// vanityPath returns the import path a link to a vanity host stands for.
+func vanityPath(loc forge.Location) string {
+	return loc.Host + "/" + loc.Name
+}
+
+// resolveVanityHosts returns the go-import tags of the vanity paths among
+// links by path. Tags not stored within --ttl are resolved and stored
+// unless --offline is set; paths that fail to resolve are left out.
+func resolveVanityHosts(ctx context.Context, cmd *cli.Command, c *modindex.Client, links []pkglists.Link) (map[string]modindex.VanityImport, error) {
+	stored, err := c.VanityImports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vanity := make(map[string]modindex.VanityImport, len(stored))
+	for _, v := range stored {
+		vanity[v.Path] = v
+	}
+
+	var paths []string
+	for _, l := range links {
+		loc, err := forge.ParseURL(l.URL)
+		if err != nil {
+			continue
+		}
+		loc.Host = strings.TrimPrefix(loc.Host, "www.")
+		if forgeKind(loc.Host) != "" || slices.Contains(docsHosts, loc.Host) {
+			continue
+		}
+		path := vanityPath(loc)
+		if v, ok := vanity[path]; !ok || time.Since(v.ResolvedAt) >= cmd.Duration("ttl") {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+	if cmd.Bool("offline") || len(paths) == 0 {
+		return vanity, nil
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Resolving %d vanity paths\n", len(paths))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type resolved struct {
+		imp goimport.Import
+		err error
+	}
+	imports := goimport.New(nil)
+	var done int
+	var storeErr error
+	forEachLimited(ctx, paths, newBulkLimits(cmd), func(path string) resolved {
+		imp, err := imports.Resolve(ctx, path)
+		return resolved{imp, err}
+	}, func(path string, r resolved) {
+		done++
+		if storeErr != nil {
+			return
+		}
+		if r.err != nil && !errors.Is(r.err, goimport.ErrNotFound) {
+			_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error resolving %s: %v\n", done, len(paths), path, r.err)
+			return
+		}
+		v := modindex.VanityImport{Path: path, Prefix: r.imp.Prefix, VCS: r.imp.VCS, RepoRoot: r.imp.RepoRoot, ResolvedAt: time.Now()}
+		if err := c.StoreVanityImport(ctx, v); err != nil {
+			storeErr = err
+			cancel()
+			return
+		}
+		vanity[path] = v
+	})
+	return vanity, storeErr
+}
+
+// printHostingKinds lists how many packages every kind of hosting has,
+// directly or through vanity paths.
+func printHostingKinds(packages []hosting, total int) {
+	direct := make(map[string]int)
+	viaVanity := make(map[string]int)
+	for _, h := range packages {
+		if h.target != "" {
+			viaVanity[h.kind]++
+		} else {
+			direct[h.kind]++
+		}
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "HOSTING\tDIRECT\tVANITY\tPACKAGES\tSHARE")
+	for _, kind := range hostingKinds {
+		n := direct[kind] + viaVanity[kind]
+		if n == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", kind, direct[kind], viaVanity[kind], n, share(n, total))
+	}
+	_ = w.Flush()
+	fmt.Println()
+}
+
+// printHosts lists the hosts by the number of packages on them, with the
+// hosts vanity paths are served from.
+func printHosts(packages []hosting, total int) error {
+	type host struct {
+		name     string
+		kind     string
+		packages int
+		targets  map[string]int // packages by target
+	}
+	hosts := make(map[string]*host)
+	for _, p := range packages {
+		h, ok := hosts[p.host]
+		if !ok {
+			h = &host{name: p.host, kind: p.kind, targets: make(map[string]int)}
+			hosts[p.host] = h
+		}
+		h.packages++
+		if p.target != "" {
+			h.kind = "vanity"
+			h.targets[p.target]++
+		}
+	}
+	sorted := slices.SortedFunc(maps.Values(hosts), func(a, b *host) int {
+		return cmp.Or(cmp.Compare(b.packages, a.packages), strings.Compare(a.name, b.name))
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "HOST\tHOSTING\tPACKAGES\tSHARE\tSERVED FROM")
+	for _, h := range sorted {
+		var targets []string
+		for _, t := range rankCounts(h.targets, len(h.targets)) {
+			targets = append(targets, fmt.Sprintf("%s (%d)", t, h.targets[t]))
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", h.name, h.kind, h.packages, share(h.packages, total), orDash(strings.Join(targets, ", ")))
+	}
+	return w.Flush()
+}
+
+// printCategoryHosting lists for every category how many of its links
+// every kind of hosting has.
+func printCategoryHosting(lookup *pkglists.Lookup, vanity map[string]modindex.VanityImport) error {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprint(w, "LINKS\t")
+	for _, kind := range hostingKinds {
+		_, _ = fmt.Fprintf(w, "%s\t", kind)
+	}
+	_, _ = fmt.Fprintln(w, " CATEGORY")
+	for _, s := range lookup.Sources {
+		walkCategories(s.Root, s.Name, func(cat *pkglists.Category, path string) {
+			if len(cat.Links) == 0 {
+				return
+			}
+			kinds := make(map[string]int)
+			for _, l := range cat.Links {
+				if h, ok := classifyLink(l.URL, vanity); ok {
+					kinds[h.kind]++
+				}
+			}
+			_, _ = fmt.Fprintf(w, "%d\t", len(cat.Links))
+			for _, kind := range hostingKinds {
+				_, _ = fmt.Fprintf(w, "%d\t", kinds[kind])
+			}
+			_, _ = fmt.Fprintf(w, " %s\n", path)
+		})
+	}
+	return w.Flush()
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/modindex"
+)
+
+// An enrichStage is a step of 'modhunt enrich'. Most run the command that
+// collects the same data on its own, with the flags of enrich.
+type enrichStage struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, cmd *cli.Command) error
+}
+
+// enrichStages returns the stages in the order they run, each relying on
+// the data collected by those before it.
+func enrichStages() []enrichStage {
+	return []enrichStage{
+		{"resolve", "resolve curated links to module paths (links sync)", linksSyncCommand.Action},
+		{"projects", "group curated packages into projects and link the index to them (projects sync)", projectsSyncCommand.Action},
+		{"latest", "fetch the latest versions from the module proxy (download-info)", downloadInfoCommand.Action},
+		{"releases", "fetch the version lists and release times (download-releases)", downloadReleasesCommand.Action},
+		{"gomods", "read the go.mod files of the latest versions (deps sync)", depsSyncCommand.Action},
+		{"github", "fetch the metadata of GitHub repositories (github sync)", githubSyncCommand.Action},
+		{"forges", "fetch the metadata of repositories on other forges (repo sync)", repoSyncCommand.Action},
+		{"scorecard", "fetch the OpenSSF Scorecard results of GitHub repositories (scorecard sync)", scorecardSyncCommand.Action},
+		{"scores", "compute and store the health scores of curated modules", storeScores},
+	}
+}
+
+var enrichCommand = &cli.Command{
+	Name:  "enrich",
+	Usage: "collect all data on curated packages in one run",
+	Description: "The stages run one after another, each skipping the links,\n" +
+		"modules and repositories it handled within --ttl. A stage that fails\n" +
+		"stops the run; the next run skips the stages that completed within\n" +
+		"--ttl and after the stage before them, and resumes with the failed one.\n" +
+		"With --status, the state of every stage is listed instead.\n\n" +
+		"Stages: resolve (links sync), projects (projects sync), latest\n" +
+		"(download-info), releases (download-releases), gomods (deps sync), github\n" +
+		"(github sync), forges (repo sync), scorecard (scorecard sync) and scores,\n" +
+		"which stores the health scores of curated modules.",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Usage: "skip stages, links and repositories handled within `DURATION`",
+			Value: 24 * time.Hour,
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "run all stages for all items regardless of --ttl",
+		},
+		&cli.BoolFlag{
+			Name:  "status",
+			Usage: "list the state of every stage instead of running them",
+		},
+		concurrencyFlag(8),
+		qpsFlag,
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "give up a strategy or proxy request for a link after `DURATION`",
+			Value: 30 * time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "retries",
+			Usage: "retry packages that failed transiently up to `N` times",
+			Value: 3,
+		},
+		&cli.DurationFlag{
+			Name:  "backoff",
+			Usage: "wait `DURATION` before the first retry",
+			Value: 5 * time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "batch",
+			Usage: "fetch `N` GitHub repositories per GraphQL request",
+			Value: 50,
+		},
+		lookupTTLFlag,
+		negativeLookupTTLFlag,
+		githubTokenFlag,
+		githubAPIURLFlag,
+		gitlabTokenFlag,
+		gitlabURLFlag,
+		sourcehutTokenFlag,
+		verboseFlag,
+		scorecardURLFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		states, err := c.EnrichStages(ctx)
+		if err != nil {
+			return err
+		}
+		if cmd.Bool("status") {
+			return printEnrichStatus(states)
+		}
+
+		// A stage is fresh if it completed within --ttl, after the stage
+		// before it, whose data it may have used.
+		stages := enrichStages()
+		var previous time.Time
+		for i, s := range stages {
+			state := states[s.name]
+			fresh := !state.FinishedAt.IsZero() && time.Since(state.FinishedAt) < cmd.Duration("ttl") &&
+				state.StartedAt.After(previous)
+			previous = state.FinishedAt
+			if fresh && !cmd.Bool("force") {
+				_, _ = fmt.Fprintf(os.Stderr, "Stage %d/%d %s completed %s, skipping\n", i+1, len(stages), s.name, state.FinishedAt.Format(time.DateTime))
+				continue
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "Stage %d/%d %s: %s\n", i+1, len(stages), s.name, s.usage)
+			start := time.Now()
+			if err := c.StartEnrichStage(ctx, s.name, start); err != nil {
+				return err
+			}
+			stageErr := s.run(ctx, cmd)
+			// Record the failure even if the run was interrupted.
+			if err := c.FinishEnrichStage(context.WithoutCancel(ctx), s.name, time.Now(), stageErr); err != nil {
+				return err
+			}
+			if stageErr != nil {
+				return fmt.Errorf("stage %s: %w\nRun enrich again to resume", s.name, stageErr)
+			}
+			previous = time.Now()
+		}
+		fmt.Println("All stages completed")
+		return nil
+	},
+}
+
+// printEnrichStatus lists the stages with the state of their last run.
+func printEnrichStatus(states map[string]modindex.EnrichStage) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STAGE\tSTARTED\tFINISHED\tERROR")
+	for _, s := range enrichStages() {
+		state, ok := states[s.name]
+		if !ok {
+			_, _ = fmt.Fprintf(w, "%s\tnever\t-\t-\n", s.name)
+			continue
+		}
+		finished := "-"
+		if !state.FinishedAt.IsZero() {
+			finished = state.FinishedAt.Format(time.DateTime)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.name, state.StartedAt.Format(time.DateTime), finished, orDash(state.Err))
+	}
+	return w.Flush()
+}
+
+// storeScores computes the health scores of the modules of all curated
+// packages from the stored signals and stores those with known factors.
+func storeScores(ctx context.Context, cmd *cli.Command) error {
+	lookup, err := newLookup(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("init lookup: %w", err)
+	}
+	c, err := openIndex(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var modules []string
+	for name := range lookup.Packages {
+		modules = append(modules, packageModulePath(lookup, name))
+	}
+	slices.Sort(modules)
+	modules = slices.Compact(modules)
+
+	s := newScorer(ctx, cmd, lookup, loadGraph(ctx, cmd))
+	var scores []modindex.ModuleScore
+	for _, m := range modules {
+		score := s.score(m)
+		if !score.Known() {
+			continue
+		}
+		scores = append(scores, modindex.ModuleScore{Module: m, Score: score.Total, Cap: score.Cap, ScoredAt: s.now})
+	}
+	if err := c.StoreModuleScores(ctx, scores); err != nil {
+		return err
+	}
+	fmt.Printf("Scored %d of %d modules\n", len(scores), len(modules))
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var forksCommand = &cli.Command{
+	Name:      "forks",
+	Usage:     "list probable forks of a curated module and how far they diverged",
+	ArgsUsage: "[MODULE]",
+	Description: `Forks are detected among all paths of the index database by comparing
+repository names and, for paths looked up with lookup-mods --fetch, the module
+directive of their go.mod with the module paths of curated packages.
+Detection runs with --detect and replaces earlier results.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "detect",
+			Usage: "detect forks of all curated packages before listing",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() > 1 {
+			return fmt.Errorf("expected at most one argument")
+		}
+		if !cmd.Bool("detect") && cmd.Args().Len() == 0 {
+			return fmt.Errorf("expected MODULE or --detect")
+		}
+
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		if cmd.Bool("detect") {
+			lookup, err := newLookup(ctx, cmd)
+			if err != nil {
+				return fmt.Errorf("init lookup: %w", err)
+			}
+			var upstreams []string
+			for key := range lookup.Packages {
+				upstreams = append(upstreams, strings.TrimRight(key, "/"))
+			}
+			n, err := c.DetectForks(ctx, upstreams)
+			if err != nil {
+				return fmt.Errorf("detect forks: %w", err)
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "marked %d paths as forks\n", n)
+		}
+
+		if cmd.Args().Len() == 0 {
+			return nil
+		}
+		upstream, forks, err := c.Forks(ctx, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(w, "PATH\tLATEST\tRELEASED\tVS UPSTREAM\tREASON\n")
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t\tupstream\n", cmd.Args().First(), orDash(upstream.Version), releaseDate(upstream))
+		for _, f := range forks {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Path, orDash(f.Latest.Version), releaseDate(f.Latest), divergence(f.Latest, upstream), f.Reason)
+		}
+		return w.Flush()
+	},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func releaseDate(v modindex.Version) string {
+	t, err := time.Parse(time.RFC3339Nano, v.Timestamp)
+	if err != nil {
+		return "-"
+	}
+	return t.Format(time.DateOnly)
+}
+
+// divergence describes how the latest release of a fork relates to the
+// latest release of its upstream.
+func divergence(fork, upstream modindex.Version) string {
+	if fork.Version == "" || upstream.Version == "" {
+		return "-"
+	}
+	ft, err1 := time.Parse(time.RFC3339Nano, fork.Timestamp)
+	ut, err2 := time.Parse(time.RFC3339Nano, upstream.Timestamp)
+	if err1 != nil || err2 != nil {
+		return "-"
+	}
+	var rel string
+	switch c := modindex.CompareVersions(fork.Version, upstream.Version); {
+	case c < 0:
+		rel = "older version"
+	case c > 0:
+		rel = "newer version"
+	default:
+		rel = "same version"
+	}
+	days := int(ft.Sub(ut).Hours() / 24)
+	switch {
+	case days < 0:
+		return fmt.Sprintf("%s, %dd before", rel, -days)
+	case days > 0:
+		return fmt.Sprintf("%s, %dd after", rel, days)
+	default:
+		return rel
+	}
+}
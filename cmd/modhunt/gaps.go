@@ -0,0 +1,194 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var gapsCommand = &cli.Command{
+	Name:  "gaps",
+	Usage: "report what curated lists are missing",
+	Description: "Lists two kinds of gaps for list maintainers: categories whose every\n" +
+		"entry looks archived or abandoned, judged like 'dead' does, and popular\n" +
+		"modules no source curates. Modules are popular if their repository has\n" +
+		"--min-stars or they are among the --popular modules required most in\n" +
+		"the dependency graph. Their category is suggested like 'classify' does,\n" +
+		"by the words and forge topics they share with curated categories.\n\n" +
+		"Run 'github sync --popular N' to fetch the repositories of popular\n" +
+		"modules.",
+	Flags: []cli.Flag{
+		yearsFlag,
+		releaseYearsFlag,
+		&cli.IntFlag{
+			Name:  "min-stars",
+			Usage: "report uncurated repositories with at least `N` stars",
+			Value: 1000,
+		},
+		&cli.IntFlag{
+			Name:  "popular",
+			Usage: "report the uncurated modules among the `N` required most",
+			Value: 100,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		st, err := loadStaleness(ctx, cmd, c, lookup)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Categories without a live entry:")
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ENTRIES\tCATEGORY")
+		var stale int
+		for _, s := range lookup.Sources {
+			walkCategories(s.Root, s.Name, func(cat *pkglists.Category, path string) {
+				if len(cat.Links) == 0 {
+					return
+				}
+				for _, l := range cat.Links {
+					key, err := pkglists.Key(l.URL)
+					if err != nil {
+						return
+					}
+					if reasons, _, _, _ := st.reasons(key, []pkglists.Link{l}); len(reasons) == 0 {
+						return
+					}
+				}
+				stale++
+				_, _ = fmt.Fprintf(w, "%d\t%s\n", len(cat.Links), path)
+			})
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d categories without a live entry\n\n", stale)
+
+		missing, err := uncuratedModules(ctx, c, lookup, st, int(cmd.Int("min-stars")), int(cmd.Int("popular")))
+		if err != nil {
+			return err
+		}
+		fmt.Println("Popular modules no source curates:")
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "STARS\tREQUIRED BY\tMODULE\tSUGGESTED CATEGORY")
+		for _, m := range missing {
+			stars := "-"
+			if m.repo != nil {
+				stars = fmt.Sprint(m.repo.Stars)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", stars, m.requiredBy, m.module, orDash(m.category))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d popular modules are not curated\n", len(missing))
+		return nil
+	},
+}
+
+// walkCategories calls fn for cat and its subcategories, with their path
+// below the source.
+func walkCategories(cat *pkglists.Category, path string, fn func(cat *pkglists.Category, path string)) {
+	if cat.Level > 0 {
+		path += " > " + cat.Name
+		fn(cat, path)
+	}
+	for _, sub := range cat.Categories {
+		walkCategories(sub, path, fn)
+	}
+}
+
+// uncuratedModule is a popular module no source curates.
+type uncuratedModule struct {
+	module     string
+	repo       *modindex.Repo // nil if not stored
+	requiredBy int
+	category   string // suggested, empty if unknown
+}
+
+// uncuratedModules returns the modules whose stored repository has at
+// least minStars or that are among the n modules required most, unless
+// a source curates their repository or module path. The most popular
+// modules come first.
+func uncuratedModules(ctx context.Context, c *modindex.Client, lookup *pkglists.Lookup, st *staleness, minStars, n int) ([]uncuratedModule, error) {
+	curated := make(map[string]bool) // repositories and module paths
+	for key, links := range lookup.Packages {
+		curated[modname.Normalize(packageModulePath(lookup, key))] = true
+		for _, l := range links {
+			if loc, err := forge.ParseURL(l.URL); err == nil {
+				curated[repoKey(loc.Host, loc.Name)] = true
+			}
+		}
+	}
+	importedBy, err := c.ImportedBy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := c.MostRequired(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]*uncuratedModule) // by repository or module path
+	for _, dc := range counts {
+		id := dc.Module
+		m := &uncuratedModule{module: dc.Module, requiredBy: importedBy[dc.Module]}
+		if loc, err := forge.ParseURL("https://" + modname.RepoPath(dc.Module)); err == nil {
+			id = repoKey(loc.Host, loc.Name)
+			if r, ok := st.repos[id]; ok {
+				m.repo = &r
+			}
+		}
+		if !curated[id] && !curated[modname.Normalize(dc.Module)] && found[id] == nil {
+			found[id] = m
+		}
+	}
+	for id, r := range st.repos {
+		if r.Stars < minStars || curated[id] || found[id] != nil {
+			continue
+		}
+		module := r.Host + "/" + r.Name
+		found[id] = &uncuratedModule{module: module, repo: &r, requiredBy: importedBy[module]}
+	}
+
+	categories := newCategoryTerms(lookup, st.repos)
+	var missing []uncuratedModule
+	for _, m := range found {
+		if m.repo != nil {
+			m.category = categories.suggest(entryTerms(m.repo.Description, m.repo.Topics))
+		}
+		missing = append(missing, *m)
+	}
+	stars := func(m uncuratedModule) int {
+		if m.repo == nil {
+			return 0
+		}
+		return m.repo.Stars
+	}
+	slices.SortFunc(missing, func(a, b uncuratedModule) int {
+		return cmp.Or(cmp.Compare(b.requiredBy, a.requiredBy), cmp.Compare(stars(b), stars(a)), strings.Compare(a.module, b.module))
+	})
+	return missing, nil
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/time/rate"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/ghclient"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+	"github.com/ngrash/modhunt/modscore"
+)
+
+var githubTokenFlag = &cli.StringFlag{
+	Name:  "github-token",
+	Usage: "authenticate to the GitHub API with `TOKEN` (default: $GITHUB_TOKEN, $GH_TOKEN or the github-token file in the modhunt config directory)",
+	Sources: cli.NewValueSourceChain(
+		cli.EnvVar("GITHUB_TOKEN"),
+		cli.EnvVar("GH_TOKEN"),
+		cli.File(githubTokenFile()),
+	),
+}
+
+// githubTokenFile returns the path of the file the GitHub token may be
+// stored in, e.g. ~/.config/modhunt/github-token.
+func githubTokenFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "modhunt", "github-token")
+}
+
+var githubAPIURLFlag = &cli.StringFlag{
+	Name:    "github-api-url",
+	Usage:   "talk to the GitHub API at `URL`, e.g. of GitHub Enterprise",
+	Value:   "https://api.github.com",
+	Sources: cli.EnvVars("GITHUB_API_URL"),
+}
+
+var verboseFlag = &cli.BoolFlag{
+	Name:    "verbose",
+	Aliases: []string{"v"},
+	Usage:   "print the remaining API quota after every request",
+}
+
+// newGitHubClient returns a GitHub client configured by the
+// --github-token, --github-api-url and --verbose flags.
+func newGitHubClient(cmd *cli.Command) (*ghclient.Client, error) {
+	opts := []ghclient.Option{ghclient.WithBaseURL(cmd.String(githubAPIURLFlag.Name))}
+	if cmd.Bool(verboseFlag.Name) {
+		opts = append(opts, ghclient.WithLog(os.Stderr))
+	}
+	token := strings.TrimSpace(cmd.String(githubTokenFlag.Name))
+	if token == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "no GitHub token, limited to 60 requests per hour (see --github-token)")
+	}
+	return ghclient.New(token, opts...)
+}
+
+var githubCommand = &cli.Command{
+	Name:      "github",
+	Usage:     "show the GitHub repository of a curated package",
+	ArgsUsage: "PACKAGE",
+	Flags:     []cli.Flag{githubTokenFlag, githubAPIURLFlag, verboseFlag},
+	Commands: []*cli.Command{
+		githubSyncCommand,
+		githubIssuesCommand,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+
+		name := packageKey(lookup, cmd.Args().First())
+		links, ok := lookup.Packages[name]
+		if !ok {
+			return fmt.Errorf("package %s not found", name)
+		}
+		loc, err := forge.ParseURL(links[0].URL)
+		if err != nil {
+			return err
+		}
+		if loc.Host != "github.com" {
+			return fmt.Errorf("expected github.com URL, got %s (see modhunt repo)", loc.Host)
+		}
+
+		client, err := newGitHubClient(cmd)
+		if err != nil {
+			return err
+		}
+		owner, repoName, _ := strings.Cut(loc.Name, "/")
+		repo, _, err := client.Repositories.Get(ctx, owner, repoName)
+		if err != nil {
+			return fmt.Errorf("get repository: %w", err)
+		}
+		fmt.Println("Repo:", repo.GetFullName())
+		if loc.Dir != "" {
+			fmt.Printf("Directory: %s (on %s), imported as %s\n", loc.Dir, orDash(loc.Ref), loc.Path())
+		}
+		fmt.Println("Updated at:", repo.GetUpdatedAt())
+		fmt.Println("Watchers:", repo.GetWatchers())
+		fmt.Println("Stargazers:", repo.GetStargazersCount())
+		fmt.Println("Forks:", repo.GetForksCount())
+		fmt.Println("Open Issues:", repo.GetOpenIssuesCount())
+		fmt.Println("Description:", repo.GetDescription())
+		fmt.Println("Topics:", repo.Topics)
+
+		return nil
+	},
+}
+
+var githubSyncCommand = &cli.Command{
+	Name:  "sync",
+	Usage: "fetch and store the repository metadata of all curated packages hosted on GitHub",
+	Description: "Repositories fetched within --ttl are skipped, so a sync stopped by an\n" +
+		"exhausted rate limit resumes where it stopped when run again.\n\n" +
+		"With a token, repositories are fetched in batches of --batch with the\n" +
+		"GraphQL API, which also returns their latest release. Without one, they\n" +
+		"are fetched one by one with the REST API and refreshed with conditional\n" +
+		"requests, which cost no rate limit if the repository did not change.\n" +
+		"--qps limits the GraphQL requests, not the repositories in them.\n\n" +
+		"With --popular, the repositories of popular modules that no source\n" +
+		"curates are fetched as well, for 'gaps' to report.",
+	Flags: []cli.Flag{
+		concurrencyFlag(4),
+		qpsFlag,
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Usage: "skip repositories fetched within `DURATION`",
+			Value: 24 * time.Hour,
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "fetch all repositories regardless of --ttl",
+		},
+		&cli.IntFlag{
+			Name:  "batch",
+			Usage: fmt.Sprintf("fetch `N` repositories per GraphQL request (at most %d)", ghclient.MaxBatch),
+			Value: 50,
+		},
+		&cli.IntFlag{
+			Name:  "popular",
+			Usage: "also fetch the repositories of the `N` modules required most in the dependency graph",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		client, err := newGitHubClient(cmd)
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		stored, err := c.Repos(ctx)
+		if err != nil {
+			return err
+		}
+		fresh := make(map[string]bool)
+		etags := make(map[string]string)
+		for _, r := range stored {
+			if r.Host != "github.com" {
+				continue
+			}
+			etags[r.Name] = r.ETag
+			if time.Since(r.FetchedAt) < cmd.Duration("ttl") {
+				fresh[r.Name] = true
+			}
+		}
+		names := curatedGitHubRepos(lookup)
+		if n := int(cmd.Int("popular")); n > 0 {
+			popular, err := popularGitHubRepos(ctx, c, n)
+			if err != nil {
+				return err
+			}
+			names = append(names, popular...)
+			slices.Sort(names)
+			names = slices.Compact(names)
+		}
+		var repos []string
+		for _, name := range names {
+			if cmd.Bool("force") || !fresh[name] {
+				repos = append(repos, name)
+			}
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Fetching %d repositories, %d are fresh\n", len(repos), len(fresh))
+
+		if strings.TrimSpace(cmd.String(githubTokenFlag.Name)) != "" {
+			batch := min(max(int(cmd.Int("batch")), 1), ghclient.MaxBatch)
+			return syncGitHubBatches(ctx, c, client, lookup, repos, batch, newBulkLimits(cmd).limiter)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type fetched struct {
+			repo      modindex.Repo
+			unchanged bool
+			err       error
+		}
+		var done, n, unchanged int
+		var stopErr error
+		forEachLimited(ctx, repos, newBulkLimits(cmd), func(name string) fetched {
+			repo, ok, err := fetchGitHubRepo(ctx, client, name, etags[name])
+			return fetched{repo, ok, err}
+		}, func(name string, f fetched) {
+			done++
+			if stopErr != nil {
+				return
+			}
+			if isRateLimited(f.err) {
+				stopErr = fmt.Errorf("%w\nStored %d repositories, run sync again to resume", f.err, n)
+				cancel()
+				return
+			}
+			if f.err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error fetching %q: %v\n", done, len(repos), name, f.err)
+				return
+			}
+			if f.unchanged {
+				if err := c.TouchRepo(ctx, "github.com", name, time.Now()); err != nil {
+					stopErr = fmt.Errorf("store repository %s: %w", name, err)
+					cancel()
+					return
+				}
+				unchanged++
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: unchanged\n", done, len(repos), name)
+				return
+			}
+			if err := c.StoreRepo(ctx, f.repo); err != nil {
+				stopErr = fmt.Errorf("store repository %s: %w", name, err)
+				cancel()
+				return
+			}
+			n++
+			_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: %d stars\n", done, len(repos), name, f.repo.Stars)
+		})
+		if stopErr != nil {
+			return stopErr
+		}
+		if err := storeModuleTopics(ctx, c, lookup); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %d repositories, %d were unchanged\n", n, unchanged)
+		return nil
+	},
+}
+
+// syncGitHubBatches fetches repos with GraphQL requests of batch
+// repositories each, sent as fast as limiter allows, and stores them.
+// GraphQL requires authentication.
+func syncGitHubBatches(ctx context.Context, c *modindex.Client, client *ghclient.Client, lookup *pkglists.Lookup, repos []string, batch int, limiter *rate.Limiter) error {
+	var n, missing int
+	for start := 0; start < len(repos); start += batch {
+		end := min(start+batch, len(repos))
+		names := repos[start:end]
+		if err := limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("%w\nStored %d repositories, run sync again to resume", err, n)
+		}
+		fetched, err := client.BatchRepositories(ctx, names)
+		if isRateLimited(err) {
+			return fmt.Errorf("%w\nStored %d repositories, run sync again to resume", err, n)
+		}
+		if err != nil {
+			return fmt.Errorf("fetch repositories: %w", err)
+		}
+		now := time.Now()
+		for _, name := range names {
+			r, ok := fetched[name]
+			if !ok {
+				missing++
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: not found\n", end, len(repos), name)
+				continue
+			}
+			repo := modindex.Repo{
+				Host:        "github.com",
+				Name:        name,
+				Description: r.Description,
+				Stars:       r.Stars,
+				Forks:       r.Forks,
+				Archived:    r.Archived,
+				PushedAt:    r.PushedAt,
+				License:     r.License,
+				Topics:      r.Topics,
+				BusFactor:   modscore.BusFactor(r.CommitAuthors),
+				FetchedAt:   now,
+			}
+			if latest := cmp.Or(r.LatestRelease, r.LatestTag); latest != nil {
+				repo.LatestRelease, repo.LatestReleaseAt = latest.Tag, latest.PublishedAt
+			}
+			if err := c.StoreRepo(ctx, repo); err != nil {
+				return fmt.Errorf("store repository %s: %w", name, err)
+			}
+			n++
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Stored %d repositories\n", end, len(repos), n)
+	}
+	if err := storeModuleTopics(ctx, c, lookup); err != nil {
+		return err
+	}
+	fmt.Printf("Stored %d repositories, %d were not found\n", n, missing)
+	return nil
+}
+
+// curatedGitHubRepos returns the "owner/name" of the GitHub repositories
+// of curated packages, sorted and lower-cased. Packages in subdirectories
+// count for their repository.
+func curatedGitHubRepos(lookup *pkglists.Lookup) []string {
+	var repos []string
+	for _, links := range lookup.Packages {
+		for _, link := range links {
+			loc, err := forge.ParseURL(link.URL)
+			if err != nil || loc.Host != "github.com" {
+				continue
+			}
+			repos = append(repos, strings.ToLower(loc.Name))
+		}
+	}
+	slices.Sort(repos)
+	return slices.Compact(repos)
+}
+
+// popularGitHubRepos returns the "owner/name" of the GitHub repositories
+// of the n modules required most in the dependency graph, lower-cased.
+func popularGitHubRepos(ctx context.Context, c *modindex.Client, n int) ([]string, error) {
+	counts, err := c.MostRequired(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	var repos []string
+	for _, dc := range counts {
+		loc, err := forge.ParseURL("https://" + modname.RepoPath(dc.Module))
+		if err != nil || loc.Host != "github.com" {
+			continue
+		}
+		repos = append(repos, strings.ToLower(loc.Name))
+	}
+	return repos, nil
+}
+
+// fetchGitHubRepo fetches the metadata of the repository "owner/name".
+// If etag is not empty, the request is conditional and unchanged is true
+// if the repository did not change since the response with that ETag.
+func fetchGitHubRepo(ctx context.Context, client *ghclient.Client, name, etag string) (r modindex.Repo, unchanged bool, err error) {
+	req, err := client.NewRequest(http.MethodGet, "repos/"+name, nil)
+	if err != nil {
+		return r, false, fmt.Errorf("new request: %w", err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	var repo github.Repository
+	resp, err := client.Do(ctx, req, &repo)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return r, true, nil
+	}
+	if err != nil {
+		return r, false, err
+	}
+	return modindex.Repo{
+		Host:        "github.com",
+		Name:        name,
+		Description: repo.GetDescription(),
+		Stars:       repo.GetStargazersCount(),
+		Forks:       repo.GetForksCount(),
+		Archived:    repo.GetArchived(),
+		PushedAt:    repo.GetPushedAt().Time,
+		License:     repo.GetLicense().GetSPDXID(),
+		Topics:      repo.Topics,
+		ETag:        resp.Header.Get("ETag"),
+		FetchedAt:   time.Now(),
+	}, false, nil
+}
+
+// isRateLimited reports whether err is GitHub rejecting requests for
+// exceeding a rate limit.
+func isRateLimited(err error) bool {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	return errors.As(err, &rateErr) || errors.As(err, &abuseErr) || errors.Is(err, ghclient.ErrRateLimited)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/internal/modhunter"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var lookupTTLFlag = &cli.DurationFlag{
+	Name:  "lookup-ttl",
+	Usage: "reuse the latest versions of modules the proxy reported within `DURATION`",
+	Value: 7 * 24 * time.Hour,
+}
+
+var negativeLookupTTLFlag = &cli.DurationFlag{
+	Name:  "negative-lookup-ttl",
+	Usage: "reuse that the proxy did not know a path within `DURATION`",
+	Value: 24 * time.Hour,
+}
+
+// loadLookupCache returns the answers of the proxy stored in the index,
+// which modhunter uses with the TTLs set by the lookup flags.
+func loadLookupCache(ctx context.Context, cmd *cli.Command, c *modindex.Client) (*modhunter.MemoryCache, modhunter.Option, error) {
+	stored, err := c.ProxyLookups(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	cache := modhunter.NewMemoryCache()
+	for _, l := range stored {
+		e := modhunter.CacheEntry{CheckedAt: l.CheckedAt}
+		if l.Version != "" {
+			e.Info = &goproxy.Info{Version: l.Version, Time: l.Time}
+			e.Info.Origin.VCS = l.OriginVCS
+			e.Info.Origin.URL = l.OriginURL
+		}
+		cache.Load(l.Path, e)
+	}
+	return cache, modhunter.WithCache(cache, cmd.Duration(lookupTTLFlag.Name), cmd.Duration(negativeLookupTTLFlag.Name)), nil
+}
+
+// storeLookupCache stores the answers of the proxy added to cache.
+func storeLookupCache(ctx context.Context, c *modindex.Client, cache *modhunter.MemoryCache) error {
+	var lookups []modindex.ProxyLookup
+	for path, e := range cache.Added() {
+		l := modindex.ProxyLookup{Path: path, CheckedAt: e.CheckedAt}
+		if e.Info != nil {
+			l.Version = e.Info.Version
+			l.Time = e.Info.Time
+			l.OriginVCS = e.Info.Origin.VCS
+			l.OriginURL = e.Info.Origin.URL
+		}
+		lookups = append(lookups, l)
+	}
+	return c.StoreProxyLookups(ctx, lookups)
+}
+
+// repoFilesOption lets modhunter list repositories with the forge clients
+// configured by the flags of cmd. Forges without a client or a way to list
+// files are not listed.
+func repoFilesOption(cmd *cli.Command) modhunter.Option {
+	var mu sync.Mutex
+	listers := make(map[string]forge.FileLister)
+	return modhunter.WithRepoFiles(func(ctx context.Context, host string) (forge.FileLister, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		l, ok := listers[host]
+		if !ok {
+			c, _ := forgeClient(cmd, host)
+			l, _ = c.(forge.FileLister)
+			listers[host] = l
+		}
+		return l, l != nil
+	})
+}
+
+// linkOrigins returns the origins of the stored link resolutions by module
+// and by the path of the link, for modhunter.WithOrigins.
+func linkOrigins(links []modindex.LinkModule) map[string]string {
+	origins := make(map[string]string)
+	for _, l := range links {
+		if l.Origin == "" {
+			continue
+		}
+		if l.Module != "" {
+			origins[l.Module] = l.Origin
+		}
+		if path, err := modname.FromRepoURL(l.URL); err == nil {
+			origins[path] = l.Origin
+		}
+	}
+	return origins
+}
@@ -0,0 +1,926 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/parquet-go/parquet-go"
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var indexStatsCommand = &cli.Command{
+	Name:  "stats",
+	Usage: "print statistics about the module index database",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "days",
+			Usage: "number of days in the versions per day histogram",
+			Value: 30,
+		},
+		&cli.IntFlag{
+			Name:  "top",
+			Usage: "number of paths in the release count ranking",
+			Value: 10,
+		},
+		includeGeneratedFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		stats, err := c.Stats(ctx, int(cmd.Int("days")), int(cmd.Int("top")), cmd.Bool("include-generated"))
+		if err != nil {
+			return fmt.Errorf("read stats: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		_, _ = fmt.Fprintf(w, "Paths\t%d\n", stats.Paths)
+		_, _ = fmt.Fprintf(w, "Generated\t%d\n", stats.Generated)
+		_, _ = fmt.Fprintf(w, "Case variants\t%d\n", stats.Variants)
+		_, _ = fmt.Fprintf(w, "Versions\t%d\n", stats.Versions)
+		_, _ = fmt.Fprintf(w, "Oldest\t%s\n", formatTime(stats.Oldest))
+		_, _ = fmt.Fprintf(w, "Newest\t%s\n", formatTime(stats.Newest))
+		_, _ = fmt.Fprintf(w, "Size\t%s\n", humanize.IBytes(uint64(stats.SizeBytes)))
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("flush: %w", err)
+		}
+
+		if len(stats.PerDay) > 0 {
+			fmt.Println("\nVersions per day")
+			var peak int64
+			for _, d := range stats.PerDay {
+				peak = max(peak, d.Count)
+			}
+			for _, d := range stats.PerDay {
+				bar := strings.Repeat("#", int(d.Count*50/peak))
+				_, _ = fmt.Fprintf(w, "%s\t%d\t%s\n", d.Day, d.Count, bar)
+			}
+			if err := w.Flush(); err != nil {
+				return fmt.Errorf("flush: %w", err)
+			}
+		}
+
+		if len(stats.Top) > 0 {
+			fmt.Println("\nTop paths by release count")
+			for _, p := range stats.Top {
+				_, _ = fmt.Fprintf(w, "%d\t%s\n", p.Count, p.Path)
+			}
+			if err := w.Flush(); err != nil {
+				return fmt.Errorf("flush: %w", err)
+			}
+		}
+
+		if len(stats.Hosts) > 0 {
+			fmt.Println("\nTop hosts by path count")
+			for _, h := range stats.Hosts {
+				_, _ = fmt.Fprintf(w, "%d\t%s\t%d versions\n", h.Paths, h.Host, h.Releases)
+			}
+			if err := w.Flush(); err != nil {
+				return fmt.Errorf("flush: %w", err)
+			}
+		}
+
+		return nil
+	},
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.DateOnly)
+}
+
+var indexMigrateCommand = &cli.Command{
+	Name:  "migrate",
+	Usage: "apply pending schema migrations to the module index database",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "check",
+			Usage: "only report whether the database needs migration",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Bool("check") {
+			c, err := openIndex(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			fmt.Println("Database is up-to-date")
+			return nil
+		}
+
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		version, err := modindex.SchemaVersion(ctx, c.DB())
+		if err != nil {
+			return err
+		}
+		fmt.Println("Database is at schema version", version)
+		return nil
+	},
+}
+
+var indexExportCommand = &cli.Command{
+	Name:  "export",
+	Usage: "export versions from the module index database as JSONL or Parquet",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format: jsonl or parquet",
+			Value: "jsonl",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "only export versions published since `TIME` (RFC 3339, YYYY-MM-DD or an age like 30d)",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write to `FILE` instead of stdout",
+		},
+		includeGeneratedFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) (err error) {
+		var since time.Time
+		if s := cmd.String("since"); s != "" {
+			since, err = parseSince(s, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		var out io.Writer = os.Stdout
+		if name := cmd.String("output"); name != "" {
+			f, err := os.Create(name)
+			if err != nil {
+				return fmt.Errorf("create output: %w", err)
+			}
+			defer func() {
+				if closeErr := f.Close(); closeErr != nil {
+					err = errors.Join(err, closeErr)
+				}
+			}()
+			out = f
+		}
+
+		switch format := cmd.String("format"); format {
+		case "jsonl":
+			bw := bufio.NewWriter(out)
+			enc := json.NewEncoder(bw)
+			if err := c.Export(ctx, since, cmd.Bool("include-generated"), func(r modindex.Record) error {
+				return enc.Encode(r)
+			}); err != nil {
+				return fmt.Errorf("export: %w", err)
+			}
+			return bw.Flush()
+		case "parquet":
+			pw := parquet.NewGenericWriter[modindex.Record](out)
+			batch := make([]modindex.Record, 0, 10000)
+			flush := func() error {
+				if _, err := pw.Write(batch); err != nil {
+					return fmt.Errorf("write parquet: %w", err)
+				}
+				batch = batch[:0]
+				return nil
+			}
+			if err := c.Export(ctx, since, cmd.Bool("include-generated"), func(r modindex.Record) error {
+				batch = append(batch, r)
+				if len(batch) == cap(batch) {
+					return flush()
+				}
+				return nil
+			}); err != nil {
+				return fmt.Errorf("export: %w", err)
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			return pw.Close()
+		default:
+			return fmt.Errorf("unknown format %q", format)
+		}
+	},
+}
+
+// parseSince parses an absolute time (RFC 3339 or YYYY-MM-DD) or an age
+// relative to now (see parseAge).
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected RFC 3339, YYYY-MM-DD or an age like 7d", s)
+	}
+	return now.Add(-age), nil
+}
+
+// parseAge extends time.ParseDuration with the units d (days),
+// w (weeks) and y (365 days), e.g. 7d or 3y.
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid age %q: %w", s, err)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+var indexImportCommand = &cli.Command{
+	Name:      "import",
+	Usage:     "bulk-load a JSONL dump of module versions into the module index database",
+	ArgsUsage: "FILE",
+	Description: "FILE contains one JSON object per line with the fields Path, Version\n" +
+		"and Timestamp, as served by index.golang.org or written by 'index export'.\n" +
+		"Use - to read from stdin. Versions already in the database are skipped.",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one FILE argument")
+		}
+
+		var in io.Reader = os.Stdin
+		if name := cmd.Args().First(); name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				return fmt.Errorf("open dump: %w", err)
+			}
+			defer f.Close()
+			in = f
+		}
+
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		start := time.Now()
+		inserted, err := c.Import(ctx, bufio.NewReaderSize(in, 1<<20), func(read int) {
+			rate := float64(read) / time.Since(start).Seconds()
+			_, _ = fmt.Fprintf(os.Stderr, "read %d versions (%.0f/s)\n", read, rate)
+		})
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		fmt.Printf("Imported %d versions in %s\n", inserted, time.Since(start).Round(time.Second))
+		return nil
+	},
+}
+
+var indexVerifyCommand = &cli.Command{
+	Name:  "verify",
+	Usage: "check the module index database for orphans, bad timestamps and gaps",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "gap",
+			Usage: "report periods without any version longer than this as gaps",
+			Value: 6 * time.Hour,
+		},
+		&cli.BoolFlag{
+			Name:  "repair",
+			Usage: "remove orphans and backfill gaps from the index",
+		},
+		indexURLFlag,
+		indexHeaderFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		opts, err := indexOptions(cmd)
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd, opts...)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		report, err := c.Verify(ctx, cmd.Duration("gap"), cmd.Bool("repair"))
+		if err != nil {
+			return fmt.Errorf("verify: %w", err)
+		}
+
+		fmt.Println("Orphaned versions:", report.Orphans)
+		fmt.Println("Unparsable timestamps:", report.BadTimestamps)
+		for _, ts := range report.BadTimestampExamples {
+			fmt.Printf("  %q\n", ts)
+		}
+		fmt.Println("Gaps:", len(report.Gaps))
+		for _, g := range report.Gaps {
+			fmt.Println(" ", g)
+		}
+		for _, r := range report.Repaired {
+			fmt.Println("Repaired:", r)
+		}
+
+		if !report.OK() && len(report.Repaired) == 0 {
+			return fmt.Errorf("database has problems, run with --repair to fix them")
+		}
+		return nil
+	},
+}
+
+var indexPruneCommand = &cli.Command{
+	Name:  "prune",
+	Usage: "delete versions and paths from the module index database by retention policy",
+	Description: "Pruned versions are not fetched again by 'index sync', but 'index verify'\n" +
+		"reports the resulting holes as gaps.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "keep-latest",
+			Usage: "keep only the `N` highest versions of every path",
+		},
+		&cli.StringFlag{
+			Name:  "drop-pseudo-older-than",
+			Usage: "drop pseudo-versions published more than `AGE` ago (e.g. 90d)",
+		},
+		&cli.BoolFlag{
+			Name:  "drop-pseudo-only",
+			Usage: "drop paths that have only pseudo-versions",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "report what would be deleted without deleting anything",
+		},
+		&cli.BoolFlag{
+			Name:  "vacuum",
+			Usage: "rebuild the database file afterwards to reclaim disk space",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		opts := modindex.PruneOptions{
+			KeepLatest: int(cmd.Int("keep-latest")),
+			PseudoOnly: cmd.Bool("drop-pseudo-only"),
+			DryRun:     cmd.Bool("dry-run"),
+		}
+		if s := cmd.String("drop-pseudo-older-than"); s != "" {
+			age, err := parseAge(s)
+			if err != nil {
+				return err
+			}
+			opts.PseudoBefore = time.Now().Add(-age)
+		}
+		if opts == (modindex.PruneOptions{DryRun: opts.DryRun}) {
+			return fmt.Errorf("no retention policy selected")
+		}
+
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		res, err := c.Prune(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("prune: %w", err)
+		}
+		verb := "Deleted"
+		if opts.DryRun {
+			verb = "Would delete"
+		}
+		fmt.Printf("%s %d versions and %d paths\n", verb, res.Versions, res.Paths)
+
+		if cmd.Bool("vacuum") && !opts.DryRun {
+			if err := c.Vacuum(ctx); err != nil {
+				return fmt.Errorf("vacuum: %w", err)
+			}
+		}
+		return nil
+	},
+}
+
+var keepYearsFlag = &cli.IntFlag{
+	Name:  "keep-years",
+	Usage: "archive versions older than the last `N` years, including the current one",
+}
+
+var indexArchiveCommand = &cli.Command{
+	Name:  "archive",
+	Usage: "move versions of past years into compressed archive files",
+	Description: "Every year is written to versions-YEAR.jsonl.zst in the archive directory\n" +
+		"and deleted from the database. Archived versions are still included by\n" +
+		"'index export' but no longer by other queries.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "before",
+			Usage: "archive versions published before `YEAR`",
+		},
+		keepYearsFlag,
+		&cli.BoolFlag{
+			Name:  "list",
+			Usage: "list the archived years instead",
+		},
+		&cli.BoolFlag{
+			Name:  "vacuum",
+			Usage: "rebuild the database file afterwards to reclaim disk space",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		if cmd.Bool("list") {
+			archives, err := c.Archives(ctx)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "YEAR\tVERSIONS\tFILE")
+			for _, a := range archives {
+				_, _ = fmt.Fprintf(w, "%d\t%d\t%s\n", a.Year, a.Versions, a.File)
+			}
+			return w.Flush()
+		}
+
+		var before int
+		switch {
+		case cmd.IsSet("before") && cmd.IsSet(keepYearsFlag.Name):
+			return fmt.Errorf("--before and --keep-years are mutually exclusive")
+		case cmd.IsSet("before"):
+			before = int(cmd.Int("before"))
+		case cmd.Int(keepYearsFlag.Name) > 0:
+			before = time.Now().Year() - int(cmd.Int(keepYearsFlag.Name)) + 1
+		default:
+			return fmt.Errorf("either --before or --keep-years is required")
+		}
+		if err := archiveYears(ctx, c, before); err != nil {
+			return err
+		}
+
+		if cmd.Bool("vacuum") {
+			if err := c.Vacuum(ctx); err != nil {
+				return fmt.Errorf("vacuum: %w", err)
+			}
+		}
+		return nil
+	},
+}
+
+// archiveYears archives all versions published before year and
+// reports the files written.
+func archiveYears(ctx context.Context, c *modindex.Client, year int) error {
+	archives, err := c.ArchiveBefore(ctx, year)
+	for _, a := range archives {
+		fmt.Printf("Archived %d: %d versions in %s\n", a.Year, a.Versions, a.File)
+	}
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	return nil
+}
+
+var indexLagCommand = &cli.Command{
+	Name:  "lag",
+	Usage: "report how far the module index database is behind the index",
+	Description: "The lag of a batch is the time between publishing its newest version\n" +
+		"and storing it. Batches are recorded by 'index sync' and 'index follow'.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "summarize batches ingested since `TIME` (RFC 3339, YYYY-MM-DD or an age like 7d)",
+			Value: "7d",
+		},
+		&cli.DurationFlag{
+			Name:  "bucket",
+			Usage: "summarize batches per `DURATION`",
+			Value: 24 * time.Hour,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		since, err := parseSince(cmd.String("since"), time.Now())
+		if err != nil {
+			return err
+		}
+
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		rep, err := c.Lag(ctx, since, cmd.Duration("bucket"))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Newest version:  %s\n", formatTime(rep.Newest))
+		fmt.Printf("Last ingest:     %s\n", formatTime(rep.LastIngested))
+		fmt.Printf("Current lag:     %s\n", rep.Current.Round(time.Second))
+		if len(rep.Buckets) == 0 {
+			return nil
+		}
+		fmt.Println()
+
+		var maxLag time.Duration
+		for _, b := range rep.Buckets {
+			maxLag = max(maxLag, b.MaxLag)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "TIME\tBATCHES\tVERSIONS\tAVG LAG\tMAX LAG\tMAX DELAY\t")
+		for _, b := range rep.Buckets {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\n",
+				formatTime(b.Start), b.Batches, b.Versions,
+				b.AvgLag.Round(time.Second), b.MaxLag.Round(time.Second), b.MaxDelay.Round(time.Second),
+				lagBar(b.AvgLag, maxLag, 30))
+		}
+		return w.Flush()
+	},
+}
+
+// lagBar plots d relative to max as a bar of at most width characters.
+func lagBar(d, max time.Duration, width int) string {
+	if max <= 0 {
+		return ""
+	}
+	return strings.Repeat("#", int(int64(width)*int64(d)/int64(max)))
+}
+
+var indexVersionsCommand = &cli.Command{
+	Name:      "versions",
+	Usage:     "list the versions of a module path from the module index database",
+	ArgsUsage: "PATH",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one PATH argument")
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		versions, err := c.Versions(ctx, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, v := range versions {
+			var flags []string
+			if v.Incompatible {
+				flags = append(flags, "incompatible")
+			}
+			if v.Retracted {
+				flags = append(flags, "retracted")
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", v.Version, v.Timestamp, strings.Join(flags, ","))
+		}
+		return w.Flush()
+	},
+}
+
+var indexLatestCommand = &cli.Command{
+	Name:      "latest",
+	Usage:     "print the latest version of a module path from the module index database",
+	ArgsUsage: "PATH",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one PATH argument")
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		latest, err := c.Latest(ctx, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+		if latest.Version == "" {
+			return fmt.Errorf("no eligible version")
+		}
+		fmt.Printf("%s\t%s\n", latest.Version, latest.Timestamp)
+		return nil
+	},
+}
+
+var indexNewCommand = &cli.Command{
+	Name:  "new",
+	Usage: "list module paths whose first version appeared recently, grouped by host",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "list paths first seen since `TIME` (RFC 3339, YYYY-MM-DD or an age like 7d)",
+			Value: "7d",
+		},
+		includeGeneratedFlag,
+		&cli.BoolFlag{
+			Name:  "exclude-forks",
+			Usage: "exclude paths that share their repository name with a curated package",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		since, err := parseSince(cmd.String("since"), time.Now())
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		paths, err := c.NewPaths(ctx, since, cmd.Bool("include-generated"))
+		if err != nil {
+			return err
+		}
+
+		var forks map[string][]string
+		if cmd.Bool("exclude-forks") {
+			lookup, err := newLookup(ctx, cmd)
+			if err != nil {
+				return fmt.Errorf("init lookup: %w", err)
+			}
+			forks = curatedRepoNames(lookup)
+		}
+
+		byHost := make(map[string][]modindex.NewPath)
+		for _, p := range paths {
+			if forks != nil && isCuratedFork(forks, p.Path) {
+				continue
+			}
+			host, _, _ := strings.Cut(p.Path, "/")
+			byHost[host] = append(byHost[host], p)
+		}
+
+		hosts := slices.SortedFunc(maps.Keys(byHost), func(a, b string) int {
+			return cmp.Or(len(byHost[b])-len(byHost[a]), strings.Compare(a, b))
+		})
+		for _, host := range hosts {
+			fmt.Printf("%s (%d)\n", host, len(byHost[host]))
+			for _, p := range byHost[host] {
+				if p.Class != "" {
+					fmt.Printf("  %s\t%s\t%s\n", p.Path, p.FirstSeen.Format(time.RFC3339), p.Class)
+				} else {
+					fmt.Printf("  %s\t%s\n", p.Path, p.FirstSeen.Format(time.RFC3339))
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// curatedRepoNames maps the repository names of curated packages to the
+// lower case keys of all packages having that name, e.g. "cli" to both
+// "github.com/urfave/cli" and "github.com/spf13/cli".
+func curatedRepoNames(lookup *pkglists.Lookup) map[string][]string {
+	names := make(map[string][]string)
+	for key := range lookup.Packages {
+		key = strings.ToLower(strings.TrimRight(key, "/"))
+		name := modindex.RepoName(key)
+		if !slices.Contains(names[name], key) {
+			names[name] = append(names[name], key)
+		}
+	}
+	return names
+}
+
+// isCuratedFork reports whether path shares its repository name with a
+// curated package of names, as returned by curatedRepoNames, without
+// being one of those packages or below them.
+func isCuratedFork(names map[string][]string, path string) bool {
+	upstreams, ok := names[modindex.RepoName(path)]
+	if !ok {
+		return false
+	}
+	path = strings.ToLower(path)
+	return !slices.ContainsFunc(upstreams, func(up string) bool {
+		return path == up || strings.HasPrefix(path, up+"/")
+	})
+}
+
+var indexClassifyCommand = &cli.Command{
+	Name:      "classify",
+	Usage:     "tag generated, mirrored and fan-out paths so queries can exclude them",
+	ArgsUsage: "[RULES]",
+	Description: `Without arguments, all paths are classified again with the rules stored in
+the database. With a RULES file, the stored rules are replaced first. Every
+line of the file holds a class and a regular expression matched against the
+module path, e.g.
+
+   generated ^buf\.build/gen/
+
+The first matching rule wins. Empty lines and lines starting with # are ignored.
+New paths are classified during sync.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "list",
+			Usage: "print the stored rules and exit",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		if cmd.Bool("list") {
+			rules, err := c.ClassRules(ctx)
+			if err != nil {
+				return err
+			}
+			for _, r := range rules {
+				fmt.Printf("%s %s\n", r.Class, r.Pattern)
+			}
+			return nil
+		}
+
+		var rules []modindex.ClassRule
+		switch cmd.Args().Len() {
+		case 0:
+		case 1:
+			f, err := os.Open(cmd.Args().First())
+			if err != nil {
+				return fmt.Errorf("open rules: %w", err)
+			}
+			rules, err = modindex.ParseClassRules(f)
+			_ = f.Close()
+			if err != nil {
+				return fmt.Errorf("parse rules: %w", err)
+			}
+			if rules == nil {
+				rules = []modindex.ClassRule{} // replace with no rules
+			}
+		default:
+			return fmt.Errorf("expected at most one argument")
+		}
+
+		changed, err := c.Reclassify(ctx, rules)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "reclassified %d paths\n", changed)
+		return nil
+	},
+}
+
+var indexCasesCommand = &cli.Command{
+	Name:  "cases",
+	Usage: "list paths that differ only in case, linked to their canonical spelling",
+	Description: `The proxy serves a module under every spelling of its path its host
+resolves, e.g. github.com/Sirupsen/logrus for github.com/sirupsen/logrus,
+so the index may hold several paths of one module. With --detect, paths
+that differ only in case are linked to the canonical one, replacing earlier
+results: the path the go.mod of its latest version declares, as recorded by
+lookup-mods --fetch or deps sync, or else the path released most recently.
+Statistics skip the linked variants.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "detect",
+			Usage: "link the case variants of all paths before listing",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		// Listing alone doesn't write, so it can run next to 'index follow'.
+		var opts []modindex.Option
+		if !cmd.Bool("detect") {
+			opts = append(opts, modindex.WithReadOnly())
+		}
+		c, err := openIndex(ctx, cmd, opts...)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		if cmd.Bool("detect") {
+			families, variants, err := c.DetectCaseVariants(ctx)
+			if err != nil {
+				return fmt.Errorf("detect case variants: %w", err)
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "linked %d paths to the canonical path of %d families\n", variants, families)
+		}
+
+		families, err := c.CaseFamilies(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PATH\tLATEST\tRELEASED\tROLE\tGO.MOD DECLARES")
+		for _, f := range families {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\tcanonical (%s)\t\n", f.Canonical, orDash(f.Latest.Version), releaseDate(f.Latest), f.Reason)
+			for _, v := range f.Variants {
+				_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\tvariant\t%s\n", v.Path, orDash(v.Latest.Version), releaseDate(v.Latest), orDash(v.Declared))
+			}
+		}
+		return w.Flush()
+	},
+}
+
+var indexAnomaliesCommand = &cli.Command{
+	Name:  "anomalies",
+	Usage: "list paths whose release cadence changed dramatically",
+	Description: `Two kinds of anomalies are listed. A burst is a day with at least --burst
+versions of one path, which is usually automation tagging releases and
+noise in statistics. A path is silent if it had at least --busy versions in
+the year before its last release and released nothing for --silence since,
+which suggests the project was abandoned or moved.`,
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "burst",
+			Usage: "flag days with at least `N` versions of a path",
+			Value: 50,
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "look for bursts since `TIME` (RFC 3339, YYYY-MM-DD or an age like 7d)",
+			Value: "1y",
+		},
+		&cli.IntFlag{
+			Name:  "busy",
+			Usage: "flag silent paths with at least `N` versions in the year before their last",
+			Value: 20,
+		},
+		&cli.StringFlag{
+			Name:  "silence",
+			Usage: "flag busy paths without versions for `AGE`, e.g. 540d",
+			Value: "540d",
+		},
+		includeGeneratedFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		since, err := parseSince(cmd.String("since"), time.Now())
+		if err != nil {
+			return err
+		}
+		silence, err := parseAge(cmd.String("silence"))
+		if err != nil {
+			return err
+		}
+
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		anomalies, err := c.Anomalies(ctx, modindex.AnomalyOptions{
+			Burst:            int(cmd.Int("burst")),
+			BurstSince:       since,
+			Busy:             int(cmd.Int("busy")),
+			Silence:          silence,
+			IncludeGenerated: cmd.Bool("include-generated"),
+		})
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "KIND\tPATH\tDAY\tVERSIONS\tTOTAL\tNOTE")
+		for _, a := range anomalies {
+			note := fmt.Sprintf("%d versions on one day", a.Releases)
+			if a.Kind == modindex.AnomalySilent {
+				note = fmt.Sprintf("%d versions in its last year, none for %d days", a.Releases, int(time.Since(a.Day).Hours()/24))
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", a.Kind, a.Path, a.Day.Format(time.DateOnly), a.Releases, a.Total, note)
+		}
+		return w.Flush()
+	},
+}
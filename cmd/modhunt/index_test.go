@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ngrash/modhunt/internal/pkglists"
+)
+
+func TestIsCuratedFork(t *testing.T) {
+	lookup := pkglists.NewLookup()
+	for _, key := range []string{
+		"github.com/urfave/cli",
+		"github.com/spf13/cli/",
+		"github.com/Sirupsen/logrus",
+	} {
+		lookup.Packages[key] = nil
+	}
+	names := curatedRepoNames(&lookup)
+
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		// Curated packages sharing a name are not forks of each other.
+		{"github.com/urfave/cli", false},
+		{"github.com/spf13/cli", false},
+		{"github.com/urfave/cli/v2", false},
+		{"github.com/spf13/cli/cmd", false},
+		{"github.com/sirupsen/logrus", false},
+		{"github.com/someone/cli", true},
+		{"gitlab.com/someone/cli/v3", true},
+		{"github.com/someone/logrus", true},
+		{"github.com/urfave/cli2", false},
+		{"github.com/someone/other", false},
+	} {
+		if got := isCuratedFork(names, tt.path); got != tt.want {
+			t.Errorf("isCuratedFork(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/mod/module"
+
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var infoCommand = &cli.Command{
+	Name:      "info",
+	Usage:     "show everything known about a module",
+	ArgsUsage: "MODULE",
+	Description: "Combines the curated lists, the latest version on the module proxy,\n" +
+		"the versions in the index, the stored release history, repository\n" +
+		"metadata, dependency graph and scorecard, the advisories OSV.dev knows\n" +
+		"for the latest version and the health score. MODULE may also name a\n" +
+		"curated package by its URL. Collect the stored data with 'enrich'; what\n" +
+		"was not collected is shown as \"-\".",
+	Flags: []cli.Flag{osvURLFlag},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one module")
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		key := packageKey(lookup, cmd.Args().First())
+		links := lookup.Packages[key]
+		mod := cmd.Args().First()
+		if len(links) > 0 {
+			mod = packageModulePath(lookup, key)
+		}
+
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		graph := loadGraph(ctx, cmd)
+		scores := newScorer(ctx, cmd, lookup, graph)
+		latest, err := proxy.Latest(ctx, mod)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "skipping latest version: %v\n", err)
+		} else {
+			scores.vulns = vulnCounts(ctx, cmd, map[string]string{mod: latest.Version})
+		}
+		versions := indexVersions(ctx, cmd, mod)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, mod)
+		row := func(name, value string) {
+			_, _ = fmt.Fprintf(w, "  %s\t%s\n", name, value)
+		}
+		if len(links) == 0 {
+			row("curated", "no")
+		}
+		for _, l := range links {
+			row("curated", fmt.Sprintf("%s: %s", l.Source.Name, orDash(categoryPath(l.Category))))
+		}
+
+		if latest != nil {
+			v := latest.Version
+			if module.IsPseudoVersion(v) {
+				v += " (untagged)"
+			}
+			row("latest", fmt.Sprintf("%s, %s", v, formatDate(latest.Time)))
+		} else {
+			row("latest", "-")
+		}
+		row("versions", versions)
+		if cad, ok := scores.cadences[mod]; ok {
+			row("releases", fmt.Sprintf("%d since %s, %.1f per year, last %s", cad.Releases, formatDate(cad.First), cad.PerYear, formatDate(cad.Last)))
+		} else {
+			row("releases", "-")
+		}
+
+		if r, ok := scores.repo(mod); ok {
+			repo := fmt.Sprintf("%s/%s, %d stars, %d forks, pushed %s", r.Host, r.Name, r.Stars, r.Forks, formatDate(r.PushedAt))
+			if r.Archived {
+				repo += ", archived"
+			}
+			row("repository", repo)
+			if r.Description != "" {
+				row("description", r.Description)
+			}
+			row("license", orDash(r.License))
+			if len(r.Topics) > 0 {
+				row("topics", strings.Join(r.Topics, ", "))
+			}
+		} else {
+			row("repository", "-")
+			row("license", "-")
+		}
+
+		if graph.importedBy != nil {
+			row("importers", strconv.Itoa(graph.importedBy[mod]))
+		} else {
+			row("importers", "-")
+		}
+		row("go", orDash(graph.goVersions[mod]))
+		if msg, ok := graph.deprecated[mod]; ok {
+			row("deprecated", orDash(msg))
+		}
+		if v, ok := scores.vulns[mod]; ok {
+			row("vulnerabilities", v.String())
+		} else {
+			row("vulnerabilities", "-")
+		}
+		row("scorecard", scores.scorecardScore(mod))
+		score := scores.score(mod)
+		if score.Cap != "" {
+			row("score", fmt.Sprintf("%s (capped, %s)", score, score.Cap))
+		} else {
+			row("score", score.String())
+		}
+		return w.Flush()
+	},
+}
+
+// indexVersions summarizes the versions of mod in the index: how many
+// there are, how many are tagged and when the first was published.
+// Without a usable database, it warns and returns "-".
+func indexVersions(ctx context.Context, cmd *cli.Command, mod string) string {
+	c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+	var versions []modindex.Version
+	if err == nil {
+		defer c.Close()
+		versions, err = c.Versions(ctx, mod)
+	}
+	if errors.Is(err, modindex.ErrPathNotFound) {
+		return "not in the index"
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "skipping index versions: %v\n", err)
+		return "-"
+	}
+	var tagged, retracted int
+	var first time.Time
+	for _, v := range versions {
+		if v.Retracted {
+			retracted++
+		}
+		if module.IsPseudoVersion(v.Version) {
+			continue
+		}
+		tagged++
+		if t, err := time.Parse(time.RFC3339Nano, v.Timestamp); err == nil && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+	s := fmt.Sprintf("%d, %d tagged, first %s", len(versions), tagged, formatDate(first))
+	if retracted > 0 {
+		s += fmt.Sprintf(", %d retracted", retracted)
+	}
+	return s
+}
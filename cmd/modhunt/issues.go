@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/ghclient"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var githubIssuesCommand = &cli.Command{
+	Name:  "issues",
+	Usage: "show how quickly the maintainers of curated GitHub repositories respond to issues",
+	Description: "Issues are sampled with \"github issues sync\". The response time of an\n" +
+		"issue is the time until someone other than its author commented or it\n" +
+		"was closed.",
+	Commands: []*cli.Command{
+		githubIssuesSyncCommand,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		stats, err := c.IssueStats(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "REPO\tOPEN\tCLOSED\tCLOSED %\tSAMPLED\tRESPONDED\tMEDIAN RESPONSE")
+		for _, s := range stats {
+			median := "-"
+			if s.Responded > 0 {
+				median = formatDuration(s.MedianResponse)
+			}
+			_, _ = fmt.Fprintf(w, "%s/%s\t%d\t%d\t%.0f\t%d\t%d\t%s\n", s.Host, s.Name, s.Open, s.Closed, s.ClosedRatio()*100, s.Sampled, s.Responded, median)
+		}
+		return w.Flush()
+	},
+}
+
+var githubIssuesSyncCommand = &cli.Command{
+	Name:  "sync",
+	Usage: "sample the recent issues of all curated GitHub repositories",
+	Description: "Sampling uses the GraphQL API, which requires a token. Repositories\n" +
+		"sampled within --ttl are skipped, so a sync stopped by an exhausted rate\n" +
+		"limit resumes where it stopped when run again.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "sample",
+			Usage: "sample the `N` most recent issues of every repository",
+			Value: 20,
+		},
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Usage: "skip repositories sampled within `DURATION`",
+			Value: 7 * 24 * time.Hour,
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "sample all repositories regardless of --ttl",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if strings.TrimSpace(cmd.String(githubTokenFlag.Name)) == "" {
+			return fmt.Errorf("sampling issues requires a GitHub token (see --github-token)")
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		client, err := newGitHubClient(cmd)
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		stored, err := c.IssueStats(ctx)
+		if err != nil {
+			return err
+		}
+		fresh := make(map[string]bool)
+		for _, s := range stored {
+			if s.Host == "github.com" && time.Since(s.FetchedAt) < cmd.Duration("ttl") {
+				fresh[s.Name] = true
+			}
+		}
+		var repos []string
+		for _, name := range curatedGitHubRepos(lookup) {
+			if cmd.Bool("force") || !fresh[name] {
+				repos = append(repos, name)
+			}
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Sampling %d repositories, %d are fresh\n", len(repos), len(fresh))
+
+		var n int
+		for start := 0; start < len(repos); start += ghclient.MaxIssueBatch {
+			end := min(start+ghclient.MaxIssueBatch, len(repos))
+			samples, err := client.BatchIssues(ctx, repos[start:end], int(cmd.Int("sample")))
+			if isRateLimited(err) {
+				return fmt.Errorf("%w\nStored %d repositories, run sync again to resume", err, n)
+			}
+			if err != nil {
+				return fmt.Errorf("sample issues: %w", err)
+			}
+			now := time.Now()
+			for _, name := range repos[start:end] {
+				sample, ok := samples[name]
+				if !ok {
+					continue
+				}
+				var responses []modindex.IssueResponse
+				for _, issue := range sample.Issues {
+					r := modindex.IssueResponse{Created: issue.CreatedAt, Responded: issue.FirstResponse}
+					if r.Responded.IsZero() || !issue.ClosedAt.IsZero() && issue.ClosedAt.Before(r.Responded) {
+						r.Responded = issue.ClosedAt
+					}
+					responses = append(responses, r)
+				}
+				stats := modindex.IssueStatsOf("github.com", name, sample.Open, sample.Closed, responses, now)
+				if err := c.StoreIssueStats(ctx, stats); err != nil {
+					return fmt.Errorf("store issue stats of %s: %w", name, err)
+				}
+				n++
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Stored %d repositories\n", end, len(repos), n)
+		}
+		fmt.Printf("Stored issue statistics of %d repositories\n", n)
+		return nil
+	},
+}
+
+// formatDuration formats d in the largest unit of days, hours and
+// minutes that fits, e.g. "3d".
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/modhunter"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/internal/pkgsite"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var linksCommand = &cli.Command{
+	Name:  "links",
+	Usage: "list the module paths curated links resolve to",
+	Description: "Links are resolved with 'links sync'. Until then, and for links that\n" +
+		"could not be resolved, commands guess the module path from the URL.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "unresolved",
+			Usage: "only list links that are not resolved to a module",
+		},
+	},
+	Commands: []*cli.Command{linksSyncCommand},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		links := curatedLinks(lookup)
+		var resolved, notModule int
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "URL\tMODULE")
+		for _, l := range links {
+			module := l.Module
+			switch {
+			case l.Module != "":
+				resolved++
+			case l.NotModule:
+				notModule++
+				module = "not a Go module"
+			default:
+				module = "- (guessed " + linkModulePath(l) + ")"
+			}
+			if cmd.Bool("unresolved") && l.Module != "" {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", l.URL, module)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d of %d links resolved to a module, %d to none\n", resolved, len(links), notModule)
+		return nil
+	},
+}
+
+var linksSyncCommand = &cli.Command{
+	Name:  "sync",
+	Usage: "resolve curated links to module paths",
+	Description: "Every link is mapped to the path it points to, which is searched for\n" +
+		"as spelled, lower-cased, rewritten to gopkg.in, climbing to its parent\n" +
+		"paths, following its go-import meta tag, trying its major versions and\n" +
+		"following the redirect of the repository the module was last found in\n" +
+		"or, with --pkgsite, of pkg.go.dev.\n" +
+		"The first module the module proxy knows is stored. Links for which it\n" +
+		"knows none are stored as not being a Go module; links that failed\n" +
+		"otherwise, e.g. because a host did not answer, are retried on the next\n" +
+		"sync. With --explain, the paths tried for them are printed. Answers of\n" +
+		"the proxy are stored and reused within --lookup-ttl and, for paths it\n" +
+		"does not know, --negative-lookup-ttl. With --list-repos, the repositories\n" +
+		"of links without module are listed through the API of their forge to\n" +
+		"find modules in subdirectories or tell projects predating modules.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "resolve `N` links concurrently",
+			Value: 8,
+		},
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Usage: "skip links resolved within `DURATION`",
+			Value: 30 * 24 * time.Hour,
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "resolve all links regardless of --ttl",
+		},
+		&cli.BoolFlag{
+			Name:  "pkgsite",
+			Usage: "ask pkg.go.dev for the new path of modules the proxy does not know",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "give up a strategy or proxy request for a link after `DURATION`",
+			Value: 30 * time.Second,
+		},
+		&cli.BoolFlag{
+			Name:  "explain",
+			Usage: "print what was tried for links that resolved to no module or failed",
+		},
+		pkgsiteURLFlag,
+		&cli.BoolFlag{
+			Name:  "list-repos",
+			Usage: "list the repositories of links without module for go.mod files in subdirectories",
+		},
+		lookupTTLFlag,
+		negativeLookupTTLFlag,
+		githubTokenFlag,
+		githubAPIURLFlag,
+		gitlabTokenFlag,
+		gitlabURLFlag,
+		verboseFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := curatedLists()
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		stored, err := c.LinkModules(ctx)
+		if err != nil {
+			return err
+		}
+		fresh := make(map[string]bool)
+		for _, l := range stored {
+			if time.Since(l.ResolvedAt) < cmd.Duration("ttl") {
+				fresh[l.URL] = true
+			}
+		}
+		origins := linkOrigins(stored)
+		var urls []string
+		for _, l := range curatedLinks(lookup) {
+			if cmd.Bool("force") || !fresh[l.URL] {
+				urls = append(urls, l.URL)
+			}
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Resolving %d links, %d are fresh\n", len(urls), len(fresh))
+
+		cache, cacheOpt, err := loadLookupCache(ctx, cmd, c)
+		if err != nil {
+			return err
+		}
+		opts := []modhunter.Option{
+			modhunter.WithOrigins(func(path string) string { return origins[path] }),
+			modhunter.WithTimeout(cmd.Duration("timeout")),
+			cacheOpt,
+		}
+		if cmd.Bool("list-repos") {
+			opts = append(opts, repoFilesOption(cmd))
+		}
+		if cmd.Bool("pkgsite") {
+			opts = append(opts, modhunter.WithPkgsite(pkgsite.New(cmd.String(pkgsiteURLFlag.Name), pkgsite.WithRateLimit(1))))
+		}
+		hunter := modhunter.New(proxy, opts...)
+		var done, modules, moved, none, failed int
+
+		// Links to the same path are searched for once.
+		links := make(map[string][]string)
+		var paths []string
+		for _, u := range urls {
+			path, err := modname.FromRepoURL(u)
+			if err != nil {
+				done++
+				failed++
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error resolving %s: %v\n", done, len(urls), u, err)
+				continue
+			}
+			links[path] = append(links[path], u)
+			paths = append(paths, path)
+		}
+		var storeErr error
+		hunter.SearchAll(ctx, paths, int(cmd.Int("workers")), func(_, _ int, o modhunter.Outcome) {
+			if cmd.Bool("explain") {
+				explainSearch(o.Err)
+			}
+			res, err := o.Result, o.Err
+			if errors.Is(err, modhunter.ErrNotFound) {
+				res, err = modhunter.Result{}, nil
+			}
+			for _, u := range links[o.Path] {
+				done++
+				if storeErr != nil {
+					return
+				}
+				if err != nil {
+					failed++
+					_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error resolving %s: %v\n", done, len(urls), u, err)
+					continue
+				}
+				l := modindex.LinkModule{URL: u, Module: res.Module, ResolvedAt: time.Now()}
+				if res.Info != nil {
+					l.Origin = res.Info.Origin.URL
+				}
+				if err := c.StoreLinkModule(ctx, l); err != nil {
+					storeErr = err
+					return
+				}
+				switch {
+				case res.Module == "":
+					none++
+					_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: not a Go module\n", done, len(urls), u)
+				case res.Moved():
+					modules++
+					moved++
+					_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: %s, moved from %s\n", done, len(urls), u, res.Module, res.Path)
+				default:
+					modules++
+					_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: %s\n", done, len(urls), u, res.Module)
+				}
+			}
+		})
+		if err := storeLookupCache(ctx, c, cache); err != nil {
+			return err
+		}
+		if storeErr != nil {
+			return storeErr
+		}
+		fmt.Printf("Resolved %d links to modules, %d of which moved, and %d to none, %d failed\n", modules, moved, none, failed)
+		return nil
+	},
+}
+
+// curatedLists returns the curated lists commands work on. selftest
+// replaces them with the fixtures of testhunt.
+var curatedLists = pkglists.NewTestdataLookup
+
+// newLookup returns the curated lists with the module paths their links
+// were resolved to by 'links sync'. Without a usable database, links are
+// left unresolved and module paths are guessed.
+func newLookup(ctx context.Context, cmd *cli.Command) (*pkglists.Lookup, error) {
+	lookup, err := curatedLists()
+	if err != nil {
+		return nil, err
+	}
+	c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+	if err != nil {
+		return lookup, nil
+	}
+	defer c.Close()
+	stored, err := c.LinkModules(ctx)
+	if err != nil {
+		return lookup, nil
+	}
+	paths := make(map[string]string, len(stored))
+	for _, l := range stored {
+		paths[l.URL] = l.Module
+	}
+	lookup.SetModules(paths)
+	return lookup, nil
+}
+
+// curatedLinks returns one link per distinct URL, ordered by URL.
+func curatedLinks(lookup *pkglists.Lookup) []pkglists.Link {
+	var links []pkglists.Link
+	for _, ls := range lookup.Packages {
+		links = append(links, ls...)
+	}
+	slices.SortFunc(links, func(a, b pkglists.Link) int { return strings.Compare(a.URL, b.URL) })
+	return slices.CompactFunc(links, func(a, b pkglists.Link) bool { return a.URL == b.URL })
+}
+
+// packageModulePath returns the module path of the curated package key,
+// as resolved by 'links sync' or else guessed from its URL.
+func packageModulePath(lookup *pkglists.Lookup, key string) string {
+	if links := lookup.Packages[key]; len(links) > 0 && links[0].Module != "" {
+		return links[0].Module
+	}
+	return curatedModulePath(key)
+}
+
+// packageKey returns the lookup key of the package named on the command
+// line, which may be spelled as any URL of it, e.g. go.googlesource.com/net
+// for golang.org/x/net, or as another major version of its module.
+func packageKey(lookup *pkglists.Lookup, name string) string {
+	if _, ok := lookup.Packages[name]; ok {
+		return name
+	}
+	if key, err := pkglists.Key("https://" + name); err == nil {
+		if _, ok := lookup.Packages[key]; ok {
+			return key
+		}
+		name = key
+	}
+	project := modname.Project(curatedModulePath(name))
+	var keys []string
+	for key := range lookup.Packages {
+		if packageProject(lookup, key) == project {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return name
+	}
+	return slices.Min(keys)
+}
+
+// packageProject returns the project of the curated package key, shared
+// by the major versions of its module.
+func packageProject(lookup *pkglists.Lookup, key string) string {
+	return modname.Project(packageModulePath(lookup, key))
+}
+
+// packageRepo returns the repository the curated package key is linked
+// to first, if its URL names one.
+func packageRepo(lookup *pkglists.Lookup, key string) (forge.Location, bool) {
+	links := lookup.Packages[key]
+	if len(links) == 0 {
+		return forge.Location{}, false
+	}
+	loc, err := forge.ParseURL(links[0].URL)
+	return loc, err == nil
+}
+
+// repoKey identifies the repository name on host, matching names in any
+// case as forges do.
+func repoKey(host, name string) string {
+	return host + "/" + strings.ToLower(name)
+}
+
+// highestMajors returns, by project, the module path of the highest major
+// version in the index. Without a usable database, it warns and returns nil.
+func highestMajors(ctx context.Context, cmd *cli.Command) map[string]string {
+	c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "skipping major versions: %v\n", err)
+		return nil
+	}
+	defer c.Close()
+	paths, err := c.Paths(ctx, false)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "skipping major versions: %v\n", err)
+		return nil
+	}
+	highest := make(map[string]string)
+	number := make(map[string]int)
+	for _, p := range paths {
+		project, major := modname.SplitMajor(modname.Normalize(p))
+		if n := modname.MajorNumber(major); n > number[project] {
+			highest[project], number[project] = p, n
+		}
+	}
+	return highest
+}
+
+// newerMajor returns the module path of the highest major version of the
+// project of module if it is not module itself, or "".
+func newerMajor(highest map[string]string, module string) string {
+	p, ok := highest[modname.Project(module)]
+	if !ok || modname.Normalize(p) == modname.Normalize(module) {
+		return ""
+	}
+	_, major := modname.SplitMajor(modname.Normalize(p))
+	_, current := modname.SplitMajor(modname.Normalize(module))
+	if modname.MajorNumber(major) <= modname.MajorNumber(current) {
+		return ""
+	}
+	return p
+}
+
+// explainSearch prints the attempts of the modhunter.SearchError in err,
+// if any, telling which are worth retrying.
+func explainSearch(err error) {
+	var se *modhunter.SearchError
+	if !errors.As(err, &se) {
+		return
+	}
+	for _, a := range se.Attempts {
+		note := ""
+		if a.Retryable() {
+			note = " (retryable)"
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "  %s%s\n", a, note)
+	}
+}
@@ -1,36 +1,53 @@
 package main
 
 import (
-	"bufio"
+	"cmp"
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
+	goversion "go/version"
 	"maps"
 	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"slices"
-	"sort"
 	"strings"
-	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
-	"github.com/google/go-github/v68/github"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/urfave/cli/v3"
-	"golang.org/x/mod/module"
-	"golang.org/x/mod/semver"
-	_ "modernc.org/sqlite"
+	"golang.org/x/mod/modfile"
 
-	"github.com/ngrash/modhunt/internal/modindex"
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/internal/httpcache"
+	"github.com/ngrash/modhunt/internal/modhunter"
+	"github.com/ngrash/modhunt/internal/modname"
 	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+	"github.com/ngrash/modhunt/modindex/index"
+	"github.com/ngrash/modhunt/modscore"
 )
 
 func main() {
 	cmd := &cli.Command{
 		Name:  "modhunt",
 		Usage: "a tool for exploring Go module data",
+		Flags: []cli.Flag{dbFlag, busyTimeoutFlag, archiveDirFlag, goproxyFlag, proxyCacheFlag, proxyRateFlag, verifyFlag, gosumdbFlag, httpCacheFlag, modnameRulesFlag, timeoutFlag},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			ctx, err := loadModnameRules(ctx, cmd)
+			if err != nil {
+				return ctx, err
+			}
+			return limitRun(ctx, cmd)
+		},
+		After: func(context.Context, *cli.Command) error {
+			stopTimeout()
+			return nil
+		},
 		Commands: []*cli.Command{
 			categoriesCommand,
 			commonCommand,
@@ -43,14 +60,58 @@ func main() {
 			downloadInfoCommand,
 			multiURLCommand,
 			githubCommand,
+			repoCommand,
+			scorecardCommand,
+			deadCommand,
+			gapsCommand,
+			classifyCommand,
+			proposeCommand,
+			trendingCommand,
+			orgsCommand,
+			tagCheckCommand,
+			topicsCommand,
+			linksCommand,
+			resolveCommand,
+			refreshCommand,
+			enrichCommand,
+			reportCommand,
+			snapshotCommand,
+			dbCommand,
+			validatePathsCommand,
+			projectsCommand,
+			queryCommand,
+			modnameCommand,
+			infoCommand,
+			scoreCommand,
+			compareCommand,
 			searchCommand,
+			untaggedCommand,
 			domainsCommand,
 			suggestCommand,
+			forksCommand,
+			downloadReleasesCommand,
+			releasesCommand,
+			depsCommand,
+			importersCommand,
+			vulnsCommand,
+			policyCommand,
+			auditCommand,
+			replaceWithCommand,
+			analyzeCommand,
+			pkgsiteCommand,
+			serveCommand,
+			apiCommand,
+			selftestCommand,
 		},
 	}
 
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%s\n", err)
+		if modindex.IsBusy(err) {
+			explainBusy(cmd)
+		} else if errors.Is(err, context.DeadlineExceeded) && cmd.Duration(timeoutFlag.Name) > 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "Gave up after --timeout %s.\n", cmd.Duration(timeoutFlag.Name))
+		}
 		os.Exit(1)
 	}
 }
@@ -63,21 +124,364 @@ var indexCommand = &cli.Command{
 		"become available by proxy.golang.org.\"",
 	Commands: []*cli.Command{
 		indexSyncCommand,
+		indexFollowCommand,
+		indexStatsCommand,
+		indexMigrateCommand,
+		indexExportCommand,
+		indexImportCommand,
+		indexVerifyCommand,
+		indexPruneCommand,
+		indexArchiveCommand,
+		indexLagCommand,
+		indexVersionsCommand,
+		indexLatestCommand,
+		indexNewCommand,
+		indexClassifyCommand,
+		indexCasesCommand,
+		indexAnomaliesCommand,
 	},
 }
 
 var indexSyncCommand = &cli.Command{
 	Name:  "sync",
 	Usage: "synchronize the module index database",
-	Action: func(ctx context.Context, cli *cli.Command) error {
-		return modindex.SynchronizeDatabase(ctx)
+	Flags: []cli.Flag{
+		metricsAddrFlag,
+		dsnFlag,
+		indexURLFlag,
+		indexHeaderFlag,
+		&cli.BoolFlag{
+			Name:  "heal",
+			Usage: "re-fetch windows missed by earlier, interrupted runs before catching up",
+		},
+		keepYearsFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if addr := cmd.String(metricsAddrFlag.Name); addr != "" {
+			serveMetrics(addr)
+		}
+		c, err := openSyncIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		res, err := c.Sync(ctx, modindex.SyncOptions{
+			Heal:     cmd.Bool("heal"),
+			Progress: printProgress,
+		})
+		for _, h := range res.Healed {
+			fmt.Println("Healed", h)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("Index is up-to-date")
+
+		if n := cmd.Int(keepYearsFlag.Name); n > 0 {
+			return archiveYears(ctx, c, time.Now().Year()-int(n)+1)
+		}
+		return nil
+	},
+}
+
+var indexFollowCommand = &cli.Command{
+	Name:  "follow",
+	Usage: "synchronize the module index database and keep polling for new versions",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "interval",
+			Usage: "time to wait between polls once the database is up-to-date",
+			Value: time.Minute,
+		},
+		metricsAddrFlag,
+		dsnFlag,
+		indexURLFlag,
+		indexHeaderFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		ctx = withoutTimeout(ctx)
+		if addr := cmd.String(metricsAddrFlag.Name); addr != "" {
+			serveMetrics(addr)
+		}
+		c, err := openSyncIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return c.Follow(ctx, cmd.Duration("interval"))
 	},
 }
 
+var dbFlag = &cli.StringFlag{
+	Name:    "db",
+	Usage:   "use the module index database at `PATH`",
+	Value:   modindex.DefaultDBPath,
+	Sources: cli.EnvVars("MODHUNT_DB"),
+}
+
+var busyTimeoutFlag = &cli.DurationFlag{
+	Name:    "busy-timeout",
+	Usage:   "wait up to `DURATION` for other processes writing the database, e.g. a running sync",
+	Value:   modindex.DefaultBusyTimeout,
+	Sources: cli.EnvVars("MODHUNT_BUSY_TIMEOUT"),
+}
+
+// explainBusy tells why the database selected by --db was locked for
+// longer than --busy-timeout.
+func explainBusy(cmd *cli.Command) {
+	if l, ok := modindex.SyncInProgress(cmd.String(dbFlag.Name)); ok {
+		_, _ = fmt.Fprintf(os.Stderr, "The database is being synchronized (%s).\n", l)
+	} else {
+		_, _ = fmt.Fprintln(os.Stderr, "Another process is writing the database.")
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Retry later or wait longer with --busy-timeout (currently %s).\n", cmd.Duration(busyTimeoutFlag.Name))
+}
+
+var timeoutFlag = &cli.DurationFlag{
+	Name:    "timeout",
+	Usage:   "give up after `DURATION`, e.g. if a proxy or API hangs (0 for no limit; ignored by index follow, serve and api)",
+	Sources: cli.EnvVars("MODHUNT_TIMEOUT"),
+}
+
+// stopTimeout releases the timer limitRun started.
+var stopTimeout context.CancelFunc = func() {}
+
+// limitRun cancels the context of the command after --timeout. Network
+// and database calls take the context, so they give up instead of
+// waiting forever, e.g. for a hung proxy.
+func limitRun(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	d := cmd.Duration(timeoutFlag.Name)
+	if d < 0 {
+		return ctx, fmt.Errorf("invalid --timeout %s", d)
+	}
+	if d == 0 {
+		return ctx, nil
+	}
+	ctx, stopTimeout = context.WithTimeout(ctx, d)
+	return ctx, nil
+}
+
+// withoutTimeout detaches ctx from the deadline of --timeout. Commands that
+// run until they are stopped, like 'index follow' and 'serve', use it, so
+// that a --timeout set in the environment for one-shot commands doesn't
+// stop them.
+func withoutTimeout(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+var goproxyFlag = &cli.StringFlag{
+	Name:    "goproxy",
+	Usage:   "fetch module metadata through the comma-separated `LIST` of proxies, like GOPROXY",
+	Value:   goproxy.DefaultGOPROXY,
+	Sources: cli.EnvVars("GOPROXY"),
+}
+
+var proxyCacheFlag = &cli.StringFlag{
+	Name:    "proxy-cache",
+	Usage:   "cache module proxy files in `DIR`, version files forever, @latest and @v/list for an hour; checked before --http-cache",
+	Sources: cli.EnvVars("MODHUNT_PROXY_CACHE"),
+}
+
+var proxyRateFlag = &cli.FloatFlag{
+	Name:  "proxy-rate",
+	Usage: "send at most `N` requests per second to every proxy or module host (0 for no limit)",
+	Value: 10,
+}
+
+var verifyFlag = &cli.BoolFlag{
+	Name:    "verify",
+	Usage:   "verify module metadata against the checksum database",
+	Sources: cli.EnvVars("MODHUNT_VERIFY"),
+}
+
+var gosumdbFlag = &cli.StringFlag{
+	Name:    "gosumdb",
+	Usage:   "verify with the checksum database `DB`, like GOSUMDB",
+	Value:   goproxy.DefaultGOSUMDB,
+	Sources: cli.EnvVars("GOSUMDB"),
+}
+
+var httpCacheFlag = &cli.StringFlag{
+	Name:    "http-cache",
+	Usage:   "cache responses of module proxies in `DIR` for as long as their headers allow, for files --proxy-cache misses (empty to disable); the module index is never cached",
+	Value:   defaultHTTPCache(),
+	Sources: cli.EnvVars("MODHUNT_HTTP_CACHE"),
+}
+
+// defaultHTTPCache returns the directory "modhunt/http" in the user's
+// cache directory or, without one, disables the cache.
+func defaultHTTPCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "modhunt", "http")
+}
+
+// newHTTPClient returns the client for requests to module proxies,
+// caching responses in --http-cache. The module index is not cached: a
+// page near its head served from the cache would hide new versions.
+func newHTTPClient(cmd *cli.Command) *http.Client {
+	dir := cmd.String(httpCacheFlag.Name)
+	if dir == "" {
+		return http.DefaultClient
+	}
+	return &http.Client{Transport: httpcache.New(dir, nil)}
+}
+
+// newProxyClient returns a module proxy client configured by the
+// --goproxy, --proxy-cache, --proxy-rate, --verify, --gosumdb and
+// --http-cache flags. The two caches are layered: --proxy-cache wins,
+// by its fixed expiry, and only files it misses are requested through
+// the HTTP client, which --http-cache answers while Cache-Control allows.
+// With both set, a file may be stored in each.
+func newProxyClient(cmd *cli.Command) (*goproxy.Client, error) {
+	opts := []goproxy.Option{goproxy.WithHTTPClient(newHTTPClient(cmd))}
+	if dir := cmd.String(proxyCacheFlag.Name); dir != "" {
+		opts = append(opts, goproxy.WithCache(dir))
+	}
+	if r := cmd.Float(proxyRateFlag.Name); r > 0 {
+		opts = append(opts, goproxy.WithRateLimit(r, int(max(r, 1))))
+	}
+	if cmd.Bool(verifyFlag.Name) {
+		opts = append(opts, goproxy.WithVerify(cmd.String(gosumdbFlag.Name)))
+	}
+	c, err := goproxy.New(cmd.String(goproxyFlag.Name), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --goproxy: %w", err)
+	}
+	return c, nil
+}
+
+var archiveDirFlag = &cli.StringFlag{
+	Name:    "archive-dir",
+	Usage:   "keep yearly archives of old versions in `DIR` (default: \"archive\" next to the database)",
+	Sources: cli.EnvVars("MODHUNT_ARCHIVE_DIR"),
+}
+
+// openIndex opens the module index database selected by the --db,
+// --busy-timeout and --archive-dir flags. Commands that only query the
+// database pass modindex.WithReadOnly, so they can run during a sync.
+func openIndex(ctx context.Context, cmd *cli.Command, opts ...modindex.Option) (*modindex.Client, error) {
+	base := []modindex.Option{
+		modindex.WithDBPath(cmd.String(dbFlag.Name)),
+		modindex.WithBusyTimeout(cmd.Duration(busyTimeoutFlag.Name)),
+	}
+	if dir := cmd.String(archiveDirFlag.Name); dir != "" {
+		base = append(base, modindex.WithArchiveDir(dir))
+	}
+	return modindex.Open(ctx, append(base, opts...)...)
+}
+
+// openSyncIndex opens the store selected by the --db and --dsn flags for
+// synchronization and applies pending migrations.
+func openSyncIndex(ctx context.Context, cmd *cli.Command) (*modindex.Client, error) {
+	opts, err := indexOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, modindex.WithMigrate(), modindex.WithLog(os.Stderr))
+	if dsn := cmd.String(dsnFlag.Name); dsn != "" {
+		opts = append(opts, modindex.WithPostgres(dsn))
+	}
+	return openIndex(ctx, cmd, opts...)
+}
+
+var indexURLFlag = &cli.StringFlag{
+	Name:    "index-url",
+	Usage:   "fetch versions from the module index at `URL`",
+	Value:   modindex.DefaultIndexURL,
+	Sources: cli.EnvVars("MODHUNT_INDEX_URL"),
+}
+
+var indexHeaderFlag = &cli.StringSliceFlag{
+	Name:  "index-header",
+	Usage: "send `HEADER` (e.g. \"Authorization: Bearer ...\") with every request to the module index",
+}
+
+// indexOptions returns the options selected by the --index-url and
+// --index-header flags.
+func indexOptions(cmd *cli.Command) ([]modindex.Option, error) {
+	var indexOpts []index.Option
+	for _, h := range cmd.StringSlice(indexHeaderFlag.Name) {
+		key, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q, want \"Key: Value\"", h)
+		}
+		indexOpts = append(indexOpts, index.WithHeader(strings.TrimSpace(key), strings.TrimSpace(value)))
+	}
+	return []modindex.Option{
+		modindex.WithIndexURL(cmd.String(indexURLFlag.Name)),
+		modindex.WithIndexOptions(indexOpts...),
+	}, nil
+}
+
+func printProgress(p modindex.SyncProgress) {
+	if p.Current.IsZero() {
+		return
+	}
+	fmt.Print("\033[H\033[2J") // Clear screen
+
+	target := time.Now().UTC()
+	duration := target.Sub(p.Start)
+	coveredHours := int64(p.Covered.Hours())
+	openHours := int64(target.Sub(p.Current).Hours())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Duration\t%s\n", duration.Round(time.Second))
+	_, _ = fmt.Fprintf(w, "Target\t%s\n", target.Format(time.RFC3339))
+	_, _ = fmt.Fprintf(w, "Current\t%s\n", p.Current.Format(time.RFC3339))
+	_, _ = fmt.Fprintf(w, "Hours done\t%d\n", coveredHours)
+	_, _ = fmt.Fprintf(w, "Hours open\t%d\n", openHours)
+
+	if coveredHours > 0 {
+		expectedRemainingRuntime := time.Duration(openHours * int64(duration) / coveredHours)
+		coveredHoursPerMinute := float64(coveredHours) / duration.Minutes()
+
+		_, _ = fmt.Fprintf(w, "Remaining\t%s\n", expectedRemainingRuntime.Round(time.Second))
+		_, _ = fmt.Fprintf(w, "ETL\t%s\n", target.Add(expectedRemainingRuntime).Local().Format(time.RFC3339))
+		_, _ = fmt.Fprintf(w, "Speed\t%.2f hours/minute\n", coveredHoursPerMinute)
+	}
+
+	_ = w.Flush()
+}
+
+var dsnFlag = &cli.StringFlag{
+	Name:    "dsn",
+	Usage:   "synchronize into the Postgres database at `URL` instead of the SQLite database",
+	Sources: cli.EnvVars("MODHUNT_DSN"),
+}
+
+var includeGeneratedFlag = &cli.BoolFlag{
+	Name:  "include-generated",
+	Usage: "include paths tagged as generated, mirrors or fan-out (see 'modhunt index classify')",
+}
+
+var metricsAddrFlag = &cli.StringFlag{
+	Name:  "metrics-addr",
+	Usage: "serve Prometheus metrics on `ADDR` (e.g. :9090) under /metrics",
+}
+
+// serveMetrics exposes the metrics of the default Prometheus registry on addr.
+// The server runs in the background for the lifetime of the process.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "metrics server: %s\n", err)
+		}
+	}()
+}
+
 var categoriesCommand = &cli.Command{
 	Name: "categories",
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
+		lookup, err := newLookup(ctx, cmd)
 		if err != nil {
 			return fmt.Errorf("init lookup: %w", err)
 		}
@@ -89,19 +493,73 @@ var categoriesCommand = &cli.Command{
 }
 
 var commonCommand = &cli.Command{
-	Name: "common",
+	Name:  "common",
+	Usage: "list the packages curated by several sources",
+	Description: "Packages are grouped by project, whose major versions count as one.\n" +
+		"With --reconcile, links to the same package are grouped even if their\n" +
+		"URLs are spelled differently, and packages whose URLs or descriptions\n" +
+		"disagree are listed with a canonical record: the one of the source\n" +
+		"first in --precedence, upgraded to https if any source uses it. With\n" +
+		"--patch, the changes making each source match are printed instead.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "reconcile",
+			Usage: "report conflicting URLs and descriptions",
+		},
+		&cli.StringSliceFlag{
+			Name:  "precedence",
+			Usage: "prefer the records of the `SOURCE`s in this order (default: the order sources are loaded in)",
+		},
+		&cli.BoolFlag{
+			Name:  "patch",
+			Usage: "print the changes per source with --reconcile",
+		},
+	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
+		lookup, err := newLookup(ctx, cmd)
 		if err != nil {
 			return fmt.Errorf("init lookup: %w", err)
 		}
-		for name, links := range lookup.Packages {
-			if len(links) > 1 {
-				fmt.Printf("%s (%d)\n", name, len(links))
-				for _, l := range links {
-					fmt.Printf("  %s > %s - %s\n", l.Source.Name, l.Category.Name, l.Description)
+		if cmd.Bool("reconcile") {
+			for _, name := range cmd.StringSlice("precedence") {
+				if !slices.ContainsFunc(lookup.Sources, func(s *pkglists.Source) bool { return s.Name == name }) {
+					return fmt.Errorf("unknown source %q in --precedence", name)
+				}
+			}
+			conflicts := reconcile(lookup, cmd.StringSlice("precedence"))
+			if cmd.Bool("patch") {
+				printPatches(conflicts, lookup.Sources)
+				return nil
+			}
+			for _, c := range conflicts {
+				fmt.Printf("%s\n  canonical: %s - %s\n", c.id, c.canonical.URL, c.canonical.Description)
+				for _, l := range c.links {
+					fmt.Printf("  %s > %s: %s - %s\n", l.Source.Name, categoryPath(l.Category), l.URL, l.Description)
 				}
 			}
+			fmt.Printf("\n%d packages have conflicting records\n", len(conflicts))
+			return nil
+		}
+		// Packages are grouped by project, so that lists curating
+		// different major versions of a module count as curating it.
+		projects := make(map[string][]pkglists.Link)
+		for key, links := range lookup.Packages {
+			project := packageProject(lookup, key)
+			projects[project] = append(projects[project], links...)
+		}
+		highest := highestMajors(ctx, cmd)
+		for project, links := range projects {
+			if len(links) < 2 {
+				continue
+			}
+			fmt.Printf("%s (%d)", project, len(links))
+			if p, ok := highest[project]; ok {
+				fmt.Printf(", highest major: %s", p)
+			}
+			fmt.Println()
+			for _, l := range links {
+				fmt.Printf("  %s > %s - %s\n", l.Source.Name, l.Category.Name, l.Description)
+			}
 		}
 		return nil
 	},
@@ -109,19 +567,32 @@ var commonCommand = &cli.Command{
 
 var lookupModulesCommand = &cli.Command{
 	Name: "lookup-mods",
+	Flags: []cli.Flag{
+		includeGeneratedFlag,
+		&cli.BoolFlag{
+			Name:  "fetch",
+			Usage: "fetch the go.mod of the latest version of every path to record its module and retractions",
+		},
+		concurrencyFlag(10),
+		qpsFlag,
+	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		db, err := sql.Open("sqlite", "file:index.db?_pragma=foreign_keys(1)&_time_format=sqlite")
+		c, err := openIndex(ctx, cmd)
 		if err != nil {
-			return fmt.Errorf("open database: %w", err)
+			return err
 		}
-		defer db.Close()
+		defer c.Close()
 
-		return lookupAllPaths(db, 5000)
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		return lookupAllPaths(ctx, c.DB(), proxy, newBulkLimits(cmd), 5000, cmd.Bool("include-generated"), cmd.Bool("fetch"))
 	},
 }
 
-func lookupAllPaths(db *sql.DB, batchSize int) error {
-	row := db.QueryRow("SELECT COUNT(*) FROM paths")
+func lookupAllPaths(ctx context.Context, db *sql.DB, proxy *goproxy.Client, limits bulkLimits, batchSize int, includeGenerated, fetch bool) error {
+	row := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM paths WHERE ? OR class IS NULL", includeGenerated)
 	var total int
 	err := row.Scan(&total)
 	if err != nil {
@@ -138,7 +609,7 @@ func lookupAllPaths(db *sql.DB, batchSize int) error {
 		count += batchSize
 
 		var err error
-		lastID, err = lookupBatch(db, batchSize, lastID)
+		lastID, err = lookupBatch(ctx, db, proxy, limits, batchSize, lastID, includeGenerated, fetch)
 		if err != nil {
 			return fmt.Errorf("process batch: %w", err)
 		}
@@ -151,20 +622,19 @@ func lookupAllPaths(db *sql.DB, batchSize int) error {
 	return nil
 }
 
-func lookupBatch(db *sql.DB, batchSize int, lastID int64) (int64, error) {
+func lookupBatch(ctx context.Context, db *sql.DB, proxy *goproxy.Client, limits bulkLimits, batchSize int, lastID int64, includeGenerated, fetch bool) (int64, error) {
 	type PathRow struct {
-		ID            int64
-		Path          string
-		LatestVersion string // calculated later
+		ID   int64
+		Path string
 	}
 
 	// Fetch the next batch.
-	rows, err := db.Query(`SELECT id, path
+	rows, err := db.QueryContext(ctx, `SELECT id, path
             FROM paths
-            WHERE id > ?
+            WHERE id > ? AND (? OR class IS NULL)
             ORDER BY id
             LIMIT ?`,
-		lastID, batchSize,
+		lastID, includeGenerated, batchSize,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("query failed: %w", err)
@@ -177,35 +647,6 @@ func lookupBatch(db *sql.DB, batchSize int, lastID int64) (int64, error) {
 			_ = rows.Close()
 			return 0, fmt.Errorf("scan failed: %w", err)
 		}
-
-		versionRows, err := db.Query(
-			`SELECT version FROM versions WHERE path_id = ?`,
-			r.ID,
-		)
-		if err != nil {
-			return 0, fmt.Errorf("query versions: %w", err)
-		}
-		var versions []string
-		for versionRows.Next() {
-			var version string
-			if err := versionRows.Scan(&version); err != nil {
-				_ = versionRows.Close()
-				return 0, fmt.Errorf("scan version: %w", err)
-			}
-			versions = append(versions, version)
-		}
-		_ = versionRows.Close()
-
-		sort.Slice(versions, func(i, j int) bool {
-			return goVersionLess(versions[i], versions[j])
-		})
-		if len(versions) > 0 {
-			r.LatestVersion = versions[len(versions)-1]
-		}
-		// TODO: Versions are not correctly sorted.
-
-		fmt.Println(r.Path, r.LatestVersion)
-
 		batch = append(batch, r)
 	}
 	_ = rows.Close()
@@ -218,171 +659,115 @@ func lookupBatch(db *sql.DB, batchSize int, lastID int64) (int64, error) {
 	// Advance lastID to the highest ID we’ve processed in this batch.
 	lastID = batch[len(batch)-1].ID
 
-	return lastID, nil
+	// Looking up every path on the proxy is opt-in.
+	if !fetch {
+		return lastID, nil
+	}
 
+	// The latest versions are read first, for the go.mod files to be
+	// fetched concurrently and stored on this goroutine.
+	rowsByPath := make(map[string]PathRow)
+	latest := make(map[string]string)
+	var paths []string
 	for _, pathRow := range batch {
-		version, module, err := lookupModule(pathRow.Path, pathRow.LatestVersion)
+		versions, err := modindex.PathVersions(ctx, db, pathRow.ID)
 		if err != nil {
-			return 0, fmt.Errorf("lookup module %q: %w", pathRow.Path, err)
+			return 0, fmt.Errorf("versions of %q: %w", pathRow.Path, err)
+		}
+		if v := modindex.LatestVersion(versions); v != "" {
+			rowsByPath[pathRow.Path] = pathRow
+			latest[pathRow.Path] = v
+			paths = append(paths, pathRow.Path)
 		}
-		fmt.Println(pathRow.Path, version, "=>", module)
 	}
 
-	return lastID, nil
-}
-
-func goVersionLess(a, b string) bool {
-	// Classify each version: stable, prerelease, or pseudo
-	aType := classifyVersion(a)
-	bType := classifyVersion(b)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// If type differs, stable < prerelease < pseudo in ascending order,
-	// but we want stable > prerelease > pseudo for "latest",
-	// so flip the comparison to put stable last in sort order:
-	if aType != bType {
-		return aType < bType
+	type fetched struct {
+		mod *modfile.File
+		err error
 	}
-
-	switch aType {
-	case vtStable, vtPrerelease:
-		// Use semver.Compare directly
-		return semver.Compare(a, b) < 0
-
-	case vtPseudo:
-		// Compare base, then time, then commit
-		less, err := pseudoLess(a, b)
-		return err == nil && less
+	var batchErr error
+	forEachLimited(ctx, paths, limits, func(path string) fetched {
+		mod, err := lookupModule(ctx, proxy, path, latest[path])
+		return fetched{mod, err}
+	}, func(path string, f fetched) {
+		if batchErr != nil {
+			return
+		}
+		if err := storeLookedUpModule(ctx, db, rowsByPath[path].ID, path, latest[path], f.mod, f.err); err != nil {
+			batchErr = err
+			cancel()
+		}
+	})
+	if batchErr != nil {
+		return 0, batchErr
 	}
-	return false
-}
-
-const (
-	vtStable = iota
-	vtPrerelease
-	vtPseudo
-	vtInvalid
-)
 
-func classifyVersion(v string) int {
-	if !semver.IsValid(v) {
-		return vtInvalid
-	}
-	if module.IsPseudoVersion(v) {
-		return vtPseudo
-	}
-	// If prerelease is non-empty, it's vtPrerelease
-	if prerelease := semver.Prerelease(v); prerelease != "" {
-		return vtPrerelease
-	}
-	// Otherwise it's a stable release
-	return vtStable
+	return lastID, nil
 }
 
-// pseudoLess compares two pseudo-versions by the rules:
-//
-//	base version ascending, then timestamp ascending, then revision ascending
-//
-// But since we want a < b for ascending, it keeps that logic.
-func pseudoLess(a, b string) (bool, error) {
-	baseA, err := module.PseudoVersionBase(a)
-	if err != nil {
-		return false, err
-	}
-	baseB, err := module.PseudoVersionBase(b)
-	if err != nil {
-		return false, err
-	}
-	if c := semver.Compare(baseA, baseB); c != 0 {
-		return c < 0, nil
-	}
-	timeA, err := module.PseudoVersionTime(a)
+// storeLookedUpModule records the retractions and the module declared by
+// mod, the go.mod of the latest version of path. If fetching it failed
+// with err, the path is skipped with a warning.
+func storeLookedUpModule(ctx context.Context, db *sql.DB, id int64, path, latest string, mod *modfile.File, err error) error {
 	if err != nil {
-		return false, err
+		_, _ = fmt.Fprintf(os.Stderr, "skipping %s: %v\n", path, err)
+		return nil
 	}
-	timeB, err := module.PseudoVersionTime(b)
+
+	// Retractions are declared in the go.mod of the latest version
+	// and may well retract that version itself.
+	retracted, err := modindex.MarkRetracted(ctx, db, id, mod.Retract)
 	if err != nil {
-		return false, err
-	}
-	if timeA != timeB {
-		return timeA.Before(timeB), nil
+		return fmt.Errorf("mark retracted versions of %q: %w", path, err)
 	}
-	revA, err := module.PseudoVersionRev(a)
-	if err != nil {
-		return false, err
+	if retracted > 0 {
+		versions, err := modindex.PathVersions(ctx, db, id)
+		if err != nil {
+			return fmt.Errorf("versions of %q: %w", path, err)
+		}
+		latest = modindex.LatestVersion(versions)
 	}
-	revB, err := module.PseudoVersionRev(b)
-	if err != nil {
-		return false, err
+
+	if mod.Module != nil {
+		if err := modindex.SetGoModModule(ctx, db, id, mod.Module.Mod.Path); err != nil {
+			return fmt.Errorf("record module of %q: %w", path, err)
+		}
+		fmt.Println(path, latest, "=>", mod.Module.Mod.Path)
 	}
-	return strings.Compare(revA, revB) < 0, nil
+	return nil
 }
 
-func lookupModule(path, version string) (string, string, error) {
-	path = strings.ToLower(path)
-
-	resp, err := http.Get("https://proxy.golang.org/" + path + "/@v/" + version + ".mod")
+// lookupModule fetches and parses the go.mod file of the given module version.
+func lookupModule(ctx context.Context, proxy *goproxy.Client, path, version string) (*modfile.File, error) {
+	data, err := proxy.GoMod(ctx, path, version)
 	if err != nil {
-		return "", "", fmt.Errorf("get failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var module string
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "module ") {
-			module = strings.TrimPrefix(line, "module ")
-			break
-		}
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "module ") {
-			module = strings.TrimPrefix(trimmed, "module ")
-			break
-		}
+		return nil, fmt.Errorf("get go.mod: %w", err)
 	}
-	if err := scanner.Err(); err != nil {
-		return "", "", err
+	mod, err := modfile.ParseLax(path+"@"+version+"/go.mod", data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parse go.mod: %w", err)
 	}
-	if module == "" {
-		return "", "", fmt.Errorf("module not found: %s@%s", path, version)
+	if mod.Module == nil {
+		return nil, fmt.Errorf("module not found: %s@%s", path, version)
 	}
 
-	return version, module, nil
+	return mod, nil
 }
 
 var normalizeIndexCommand = &cli.Command{
 	Name: "normalize-index",
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		db, err := sql.Open("sqlite", "file:index.db?_pragma=foreign_keys(1)&_time_format=sqlite")
+		// The modules table is created by the migrations.
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
 		if err != nil {
-			return fmt.Errorf("open database: %w", err)
+			return err
 		}
-		defer db.Close()
+		defer c.Close()
 
-		_, err = db.Exec("CREATE TABLE IF NOT EXISTS modules (id INTEGER PRIMARY KEY ASC, module TEXT NOT NULL UNIQUE);")
-		if err != nil {
-			return fmt.Errorf("create table: %w", err)
-		}
-
-		// Check if column module_id exists in paths table.
-		row := db.QueryRow("SELECT COUNT(cid) FROM pragma_table_info('paths') WHERE name = 'module_id';")
-		var count int
-		err = row.Scan(&count)
-		if err != nil {
-			return fmt.Errorf("check column: %w", err)
-		}
-		if count == 0 {
-			_, err := db.Exec("ALTER TABLE paths ADD COLUMN module_id INTEGER REFERENCES modules(id);")
-			if err != nil {
-				return fmt.Errorf("add column: %w", err)
-			}
-		}
-		_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_paths_module_id ON paths(module_id);")
-		if err != nil {
-			return fmt.Errorf("create index: %w", err)
-		}
-
-		err = processAllRecords(db, 5000)
+		err = processAllRecords(ctx, c.DB(), 5000)
 		if err != nil {
 			return fmt.Errorf("process all records: %w", err)
 		}
@@ -391,8 +776,8 @@ var normalizeIndexCommand = &cli.Command{
 	},
 }
 
-func processAllRecords(db *sql.DB, batchSize int) error {
-	row := db.QueryRow("SELECT COUNT(*) FROM paths")
+func processAllRecords(ctx context.Context, db *sql.DB, batchSize int) error {
+	row := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM paths")
 	var total int
 	err := row.Scan(&total)
 	if err != nil {
@@ -409,7 +794,7 @@ func processAllRecords(db *sql.DB, batchSize int) error {
 		count += batchSize
 
 		var err error
-		lastID, err = processBatch(db, batchSize, lastID)
+		lastID, err = processBatch(ctx, db, batchSize, lastID)
 		if err != nil {
 			return fmt.Errorf("process batch: %w", err)
 		}
@@ -422,7 +807,7 @@ func processAllRecords(db *sql.DB, batchSize int) error {
 
 	// Remove unreferenced modules.
 	fmt.Println("cleaning up modules")
-	deleted, err := db.Exec("DELETE FROM modules WHERE id NOT IN (SELECT module_id FROM paths WHERE module_id IS NOT NULL);")
+	deleted, err := db.ExecContext(ctx, "DELETE FROM modules WHERE id NOT IN (SELECT module_id FROM paths WHERE module_id IS NOT NULL);")
 	if err != nil {
 		return fmt.Errorf("delete unreferenced modules: %w", err)
 	}
@@ -435,14 +820,14 @@ func processAllRecords(db *sql.DB, batchSize int) error {
 	return nil
 }
 
-func processBatch(db *sql.DB, batchSize int, lastID int64) (int64, error) {
+func processBatch(ctx context.Context, db *sql.DB, batchSize int, lastID int64) (int64, error) {
 	type PathRow struct {
 		ID   int64
 		Path string
 	}
 
 	// Fetch the next batch.
-	rows, err := db.Query(`
+	rows, err := db.QueryContext(ctx, `
             SELECT id, path
             FROM paths
             WHERE id > ?
@@ -471,12 +856,12 @@ func processBatch(db *sql.DB, batchSize int, lastID int64) (int64, error) {
 	}
 
 	// Process and update each row.
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("begin tx failed: %w", err)
 	}
 
-	stmt, err := tx.Prepare(`
+	stmt, err := tx.PrepareContext(ctx, `
             UPDATE paths
             SET module_id = ?
             WHERE id = ?
@@ -489,12 +874,12 @@ func processBatch(db *sql.DB, batchSize int, lastID int64) (int64, error) {
 
 	for _, pathRow := range batch {
 		var moduleID int64
-		moduleName := normalizeModuleName(pathRow.Path)
-		modRow := tx.QueryRow("SELECT id FROM modules WHERE module = ?", moduleName)
+		moduleName := modname.Normalize(pathRow.Path)
+		modRow := tx.QueryRowContext(ctx, "SELECT id FROM modules WHERE module = ?", moduleName)
 		err = modRow.Scan(&moduleID)
 		if errors.Is(err, sql.ErrNoRows) {
 			// Insert a new module.
-			res, err := tx.Exec("INSERT INTO modules (module) VALUES (?)", moduleName)
+			res, err := tx.ExecContext(ctx, "INSERT INTO modules (module) VALUES (?)", moduleName)
 			if err != nil {
 				_ = tx.Rollback()
 				return 0, fmt.Errorf("insert module failed: %w", err)
@@ -506,7 +891,7 @@ func processBatch(db *sql.DB, batchSize int, lastID int64) (int64, error) {
 			}
 		}
 
-		if _, err := stmt.Exec(moduleID, pathRow.ID); err != nil {
+		if _, err := stmt.ExecContext(ctx, moduleID, pathRow.ID); err != nil {
 			_ = tx.Rollback()
 			return 0, fmt.Errorf("exec update failed: %w", err)
 		}
@@ -522,63 +907,110 @@ func processBatch(db *sql.DB, batchSize int, lastID int64) (int64, error) {
 	return lastID, nil
 }
 
-func normalizeModuleName(original string) string {
-	// Inconsistent capitalization is the most common issue.
-	name := strings.ToLower(original)
-
-	// Then there are some common prefixes that can be removed.
-	if strings.HasPrefix(name, "www.github.com/") {
-		return strings.TrimPrefix(name, "www.")
-	}
-
-	if strings.HasPrefix(original, "gopkg.in/") {
-		// TODO: Why does https://pkg.go.dev/github.com/go-yaml/yaml/v3 redirect to https://pkg.go.dev/gopkg.in/yaml.v2?
-		// From https://labix.org/gopkg.in:
-		//
-		//   The gopkg.in service provides versioned URLs that offer the proper metadata for redirecting the go tool onto well defined GitHub repositories.
-		//
-		//   gopkg.in/pkg.v3      → github.com/go-pkg/pkg (branch/tag v3, v3.N, or v3.N.M)
-		//   gopkg.in/user/pkg.v3 → github.com/user/pkg   (branch/tag v3, v3.N, or v3.N.M)
-	}
-
-	return name
-}
-
 var alternativesCommand = &cli.Command{
-	Name: "alternatives",
-	Arguments: []cli.Argument{
-		&cli.StringArg{
-			Name:      "name",
-			UsageText: "",
-			Min:       1,
-			Max:       1,
+	Name:      "alternatives",
+	Usage:     "rank the packages curated in the same categories as a package",
+	ArgsUsage: "PACKAGE",
+	Description: "Alternatives are ranked by the number of categories and forge topics\n" +
+		"they share with the package, then by their health score. Packages\n" +
+		"curated by several sources, or as several major versions, are listed\n" +
+		"once; newer major versions in the index are noted.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "vulns",
+			Usage: "annotate packages with the advisories affecting their latest version",
+		},
+		osvURLFlag,
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of alternatives, 0 lists all",
+		},
+		&cli.IntFlag{
+			Name:  "min-score",
+			Usage: "omit alternatives with a lower or unknown health score",
 		},
 	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		lookup, err := newLookup(ctx, cmd)
 		if err != nil {
 			return fmt.Errorf("init lookup: %w", err)
 		}
-		name := cmd.Args().First()
+		name := packageKey(lookup, cmd.Args().First())
 		links, ok := lookup.Packages[name]
 		if !ok {
 			return fmt.Errorf("package %s not found", name)
 		}
-		fmt.Println(name, "found")
+
+		graph := loadGraph(ctx, cmd)
+		scores := newScorer(ctx, cmd, lookup, graph)
+		shared := sharedCategories(links, name)
+		if cmd.Bool("vulns") {
+			modules := []string{packageModulePath(lookup, name)}
+			for key := range shared {
+				modules = append(modules, packageModulePath(lookup, key))
+			}
+			if err := scores.loadVulns(ctx, cmd, modules); err != nil {
+				return err
+			}
+		}
+		for key, n := range sharedTopics(lookup, graph, name) {
+			if _, ok := shared[key]; ok {
+				shared[key] += n
+			}
+		}
+		shared = sharedByProject(lookup, shared, name)
+		ranked := rankSimilar(lookup, scores, shared, int(cmd.Int("min-score")), int(cmd.Int("limit")))
+		highest := highestMajors(ctx, cmd)
+
+		notes := func(module string) string {
+			var notes []string
+			if p := newerMajor(highest, module); p != "" {
+				notes = append(notes, "newer major: "+p)
+			}
+			if msg, ok := graph.deprecated[module]; ok {
+				notes = append(notes, "deprecated: "+msg)
+			}
+			if v, ok := scores.vulns[module]; ok {
+				notes = append(notes, v.String())
+			}
+			return strings.Join(notes, "; ")
+		}
+
 		for _, l := range links {
 			fmt.Println(l.Source.Name, ">", l.Category.Name)
-			for _, other := range l.Category.Links {
-				if other != l {
-					fmt.Printf("  %s\n    %s\n", other.URL, other.Description)
-				} else {
-					fmt.Printf("=>%s\n    %s\n", l.URL, l.Description)
-				}
-			}
 		}
-		return nil
+		module := packageModulePath(lookup, name)
+		fmt.Printf("%s: score %s", name, scores.score(module))
+		if n := notes(module); n != "" {
+			fmt.Printf(", %s", n)
+		}
+		fmt.Print("\n\n")
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "SHARED\tSCORE\tPACKAGE\tNOTES\tDESCRIPTION")
+		for _, p := range ranked {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", p.shared, p.score, p.name, orDash(notes(packageModulePath(lookup, p.name))), p.description)
+		}
+		return w.Flush()
 	},
 }
 
+// linkModulePath returns the module path of a curated link, as resolved
+// by 'links sync' or else guessed from its URL.
+func linkModulePath(l pkglists.Link) string {
+	if l.Module != "" {
+		return l.Module
+	}
+	key, err := pkglists.Key(l.URL)
+	if err != nil {
+		return l.URL
+	}
+	return curatedModulePath(key)
+}
+
 var goProxyCommand = &cli.Command{
 	Name: "go-proxy",
 	Arguments: []cli.Argument{
@@ -590,25 +1022,23 @@ var goProxyCommand = &cli.Command{
 		},
 	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
+		lookup, err := newLookup(ctx, cmd)
 		if err != nil {
 			return fmt.Errorf("init lookup: %w", err)
 		}
 
-		name := cmd.Args().First()
+		name := packageKey(lookup, cmd.Args().First())
 		_, ok := lookup.Packages[name]
 		if !ok {
 			return fmt.Errorf("package %s not found", name)
 		}
-		resp, err := http.Get(fmt.Sprintf("https://proxy.golang.org/%s/@latest", name))
+		proxy, err := newProxyClient(cmd)
 		if err != nil {
-			return fmt.Errorf("get latest version info: %w", err)
+			return err
 		}
-		defer resp.Body.Close()
-
-		var info VersionInfo
-		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-			return fmt.Errorf("decode version info: %w", err)
+		info, err := proxy.Latest(ctx, name)
+		if err != nil {
+			return fmt.Errorf("get latest version info: %w", err)
 		}
 
 		fmt.Println("Version:", info.Version)
@@ -619,202 +1049,153 @@ var goProxyCommand = &cli.Command{
 	},
 }
 
-type VersionInfo struct {
-	Version string    `json:"Version"`
-	Time    time.Time `json:"Time"`
-	Origin  struct {
-		VCS  string `json:"VCS"`
-		URL  string `json:"URL"`
-		Ref  string `json:"Ref"`
-		Hash string `json:"Hash"`
-	} `json:"Origin"`
-}
-
-var strangeCommand = &cli.Command{
-	Name: "strange",
-	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
-		if err != nil {
-			return fmt.Errorf("init lookup: %w", err)
-		}
-
-		for name, links := range lookup.Packages {
-			// TODO: We should probably clean this up somewhere.
-			n := strings.TrimRight(name, "/")
-			if strings.Count(n, "/") != 2 {
-				if !strings.HasPrefix(n, "gitlab.com") {
-					var sources []string
-					for _, link := range links {
-						sources = append(sources, link.Source.Name)
-					}
-					fmt.Println(n, sources)
-				}
-			}
-		}
-
-		return nil
-	},
-}
-
-func downloadLatestVersionInfo(module string) (vi VersionInfo, err error) {
-	switch {
-	case strings.HasPrefix(module, "pkg.go.dev/"):
-		module, _ = strings.CutPrefix(module, "pkg.go.dev/")
-	case strings.HasPrefix(module, "github.com/"):
-		before, after, found := strings.Cut(module, "/tree/master")
-		if found {
-			module = before + after
-			break
-		}
-		before, after, found = strings.Cut(module, "/tree/main")
-		if found {
-			module = before + after
-			break
-		}
-	}
-
-	canonical := strings.ToLower(module) // go proxy requires lowercase
-	resp, err := http.Get(fmt.Sprintf("https://proxy.golang.org/%s/@latest", canonical))
-	if err != nil {
-		return vi, err
-	}
-	defer func() {
-		closeErr := resp.Body.Close()
-		if closeErr != nil {
-			err = errors.Join(err, closeErr)
-		}
-	}()
-	if resp.StatusCode != http.StatusOK {
-		return vi, fmt.Errorf("unexpected status: %s", resp.Status)
-	}
-	var info VersionInfo
-	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		return vi, err
+// curatedModulePath guesses the module path of a curated package from
+// the URL it is listed with.
+func curatedModulePath(module string) string {
+	if path, err := modname.FromRepoURL(module); err == nil {
+		module = path
 	}
-	return info, nil
+	// Lists often use the capitalization of the repository URL,
+	// which rarely matches the module path.
+	return strings.ToLower(module)
 }
 
-func save(root *os.Root, result dlResult) (err error) {
-	// Create the directory structure.
-	parts := strings.Split(result.module, "/")
-	for i := 1; i <= len(parts); i++ {
-		dir := strings.Join(parts[:i], "/")
-		fi, err := root.Stat(dir)
-		if err != nil {
-			if !os.IsNotExist(err) {
-				return fmt.Errorf("stat dir: %w", err)
-			}
-		}
-		if err == nil && fi.IsDir() {
-			continue
-		}
-		err = root.Mkdir(dir, 0755)
+var downloadInfoCommand = &cli.Command{
+	Name:  "download-info",
+	Usage: "fetch the latest versions of curated packages from the module proxy",
+	Description: "The answers of the proxy are stored in the index and reused within\n" +
+		"--lookup-ttl and, for paths it does not know, --negative-lookup-ttl,\n" +
+		"so only packages whose answers expired are fetched again.\n" +
+		"Packages that failed because the proxy or a host did not answer, failed\n" +
+		"or rate limited the request are retried up to --retries times, waiting\n" +
+		"--backoff before the first retry and twice as long before every other.\n" +
+		"Packages the proxy does not know or refuses to serve are recorded in\n" +
+		"the index with the reason, which --failures lists.",
+	Flags: []cli.Flag{
+		concurrencyFlag(50),
+		qpsFlag,
+		&cli.IntFlag{
+			Name:  "retries",
+			Usage: "retry packages that failed transiently up to `N` times",
+			Value: 3,
+		},
+		&cli.DurationFlag{
+			Name:  "backoff",
+			Usage: "wait `DURATION` before the first retry",
+			Value: 5 * time.Second,
+		},
+		&cli.BoolFlag{
+			Name:  "failures",
+			Usage: "list the recorded failures instead of downloading",
+		},
+		lookupTTLFlag,
+		negativeLookupTTLFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
 		if err != nil {
-			return fmt.Errorf("make dir: %w", err)
+			return err
 		}
-	}
-
-	f, err := root.Create(result.module + "/latest.json")
-	if err != nil {
-		return fmt.Errorf("create file: %w", err)
-	}
-	defer func() {
-		closeErr := f.Close()
-		if closeErr != nil {
-			err = errors.Join(err, closeErr)
+		defer c.Close()
+		if cmd.Bool("failures") {
+			return printDownloadFailures(ctx, c)
 		}
-	}()
-
-	return json.NewEncoder(f).Encode(result.latest)
-}
-
-type dlResult struct {
-	module string
-	latest VersionInfo
-	err    error
-}
-
-func downloadWorker(wg *sync.WaitGroup, modules <-chan string, results chan<- dlResult) {
-	defer wg.Done()
-	for mod := range modules {
-		info, err := downloadLatestVersionInfo(mod)
-		results <- dlResult{module: mod, latest: info, err: err}
-	}
-}
-
-var downloadInfoCommand = &cli.Command{
-	Name: "download-info",
-	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
+		lookup, err := newLookup(ctx, cmd)
 		if err != nil {
 			return fmt.Errorf("init lookup: %w", err)
 		}
-
-		err = os.MkdirAll("./cache", 0755)
+		proxy, err := newProxyClient(cmd)
 		if err != nil {
-			return fmt.Errorf("make cache dir: %w", err)
+			return err
 		}
-		root, err := os.OpenRoot("cache")
+		cache, cacheOpt, err := loadLookupCache(ctx, cmd, c)
 		if err != nil {
-			return fmt.Errorf("open root: %w", err)
+			return err
 		}
 
-		var toDownload []string
+		// Packages resolved to the same module path are searched for once.
+		keys := make(map[string][]string)
+		var paths []string
 		for module := range lookup.Packages {
-			if _, err := root.Stat(module + "/latest.json"); os.IsNotExist(err) {
-				toDownload = append(toDownload, module)
-			} else if err != nil {
-				return fmt.Errorf("stat: %w", err)
-			}
+			path := packageModulePath(lookup, module)
+			keys[path] = append(keys[path], module)
+			paths = append(paths, path)
 		}
-
-		modules := make(chan string, len(toDownload))
-		results := make(chan dlResult, len(toDownload))
-		var wg sync.WaitGroup
-		numWorkers := 50
-		wg.Add(numWorkers)
-		for range numWorkers {
-			go downloadWorker(&wg, modules, results)
-		}
-
-		total := len(toDownload)
-		remaining := total
-		saveDone := make(chan struct{})
-		go func() {
-			for result := range results {
-				remaining--
-				if result.err != nil {
-					_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error downloading %q: %v\n", total-remaining, total, result.module, result.err)
-					continue
+		limits := newBulkLimits(cmd)
+		hunter := modhunter.New(proxy, cacheOpt, modhunter.WithLimiter(limits.limiter))
+		var found []string
+		var failures []modindex.DownloadFailure
+		categories := make(map[string]int)
+		backoff := cmd.Duration("backoff")
+		for round := 0; len(paths) > 0; round++ {
+			var retry []string
+			hunter.SearchAll(ctx, paths, limits.concurrency, func(done, total int, o modhunter.Outcome) {
+				var se *modhunter.SearchError
+				if o.Err != nil && errors.As(o.Err, &se) && se.Retryable() && round < int(cmd.Int("retries")) {
+					retry = append(retry, o.Path)
+					_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %q failed (%s), will retry\n", done, total, o.Path, se.Category())
+					return
 				}
-				err := save(root, result)
-				if err != nil {
-					_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error saving %q: %v\n", total-remaining, total, result.module, err)
-					continue
+				for _, module := range keys[o.Path] {
+					if o.Err == nil {
+						found = append(found, module)
+						_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %q: %s %s\n", done, total, module, o.Result.Module, o.Result.Info.Version)
+						continue
+					}
+					category := modhunter.CategoryOther
+					if se != nil {
+						category = se.Category()
+					}
+					categories[category]++
+					_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error downloading %q (%s): %v\n", done, total, module, category, o.Err)
+					if se != nil && !se.Retryable() {
+						failures = append(failures, modindex.DownloadFailure{Package: module, Path: o.Path, Category: category, Reason: o.Err.Error(), FailedAt: time.Now()})
+					}
 				}
-				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Downloaded %q\n", total-remaining, total, result.module)
+			})
+			if len(retry) == 0 || ctx.Err() != nil {
+				break
 			}
-			close(saveDone)
-		}()
-
-		for _, name := range toDownload {
-			modules <- name
+			wait := backoff << round
+			_, _ = fmt.Fprintf(os.Stderr, "Retrying %d packages in %s\n", len(retry), wait)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+			}
+			paths = retry
+		}
+		if err := storeLookupCache(ctx, c, cache); err != nil {
+			return err
+		}
+		if err := c.StoreDownloadResults(ctx, failures, found); err != nil {
+			return err
+		}
+		fmt.Printf("Found the latest versions of %d packages, fetched %d answers\n", len(found), len(cache.Added()))
+		for _, category := range slices.Sorted(maps.Keys(categories)) {
+			fmt.Printf("%d packages failed: %s\n", categories[category], category)
 		}
-		close(modules)
-
-		wg.Wait()
-		close(results)
-
-		<-saveDone
-
 		return nil
 	},
 }
 
+// printDownloadFailures lists the failures recorded by download-info.
+func printDownloadFailures(ctx context.Context, c *modindex.Client) error {
+	failures, err := c.DownloadFailures(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PACKAGE\tCATEGORY\tFAILED\tREASON")
+	for _, f := range failures {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Package, f.Category, formatDate(f.FailedAt), f.Reason)
+	}
+	return w.Flush()
+}
+
 var multiURLCommand = &cli.Command{
 	Name: "multi-url",
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
+		lookup, err := newLookup(ctx, cmd)
 		if err != nil {
 			return fmt.Errorf("init lookup: %w", err)
 		}
@@ -835,123 +1216,247 @@ var multiURLCommand = &cli.Command{
 	},
 }
 
-var githubCommand = &cli.Command{
-	Name: "github",
-	Arguments: []cli.Argument{
-		&cli.StringArg{
-			Name: "package",
-			Min:  1,
-			Max:  1,
+var searchCommand = &cli.Command{
+	Name:      "search",
+	Usage:     "search curated packages by name, description and topics",
+	ArgsUsage: "[QUERY...]",
+	Description: "Results are ranked by the number of modules requiring them in the\n" +
+		"dependency graph built by 'deps sync'. Modules whose go.mod declares them\n" +
+		"deprecated are ranked last, preceded by modules that never tagged a\n" +
+		"release. Topics are harvested by 'github sync' and 'repo sync'.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "go",
+			Usage: "only list modules whose go directive allows Go `VERSION` (e.g. 1.21)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "topic",
+			Usage: "only list modules having `TOPIC`, may be repeated",
 		},
 	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
+		topics := cmd.StringSlice("topic")
+		if cmd.Args().Len() == 0 && len(topics) == 0 {
+			return fmt.Errorf("expected a query or --topic")
+		}
+		goVersion := cmd.String("go")
+		if goVersion != "" && !goversion.IsValid(goLang(goVersion)) {
+			return fmt.Errorf("invalid --go version %q", goVersion)
+		}
+		lookup, err := newLookup(ctx, cmd)
 		if err != nil {
 			return fmt.Errorf("init lookup: %w", err)
 		}
+		graph := loadGraph(ctx, cmd)
+		scores := newScorer(ctx, cmd, lookup, graph)
+		pseudoOnly := loadPseudoOnly(ctx, cmd, curatedModules(lookup))
+		hits := searchPackages(lookup, graph, scores, pseudoOnly, strings.Join(cmd.Args().Slice(), " "), goVersion, topics)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "IMPORTED BY\tSCORE\tGO\tPACKAGE\tDESCRIPTION")
+		for _, h := range hits {
+			switch {
+			case h.deprecated != "":
+				h.description = "DEPRECATED: " + h.deprecated
+			case h.untagged:
+				h.description = strings.TrimSpace("UNTAGGED: " + h.description)
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", h.importedBy, h.score, orDash(h.goVersion), h.name, h.description)
+		}
+		return w.Flush()
+	},
+}
 
-		name := cmd.Args().First()
-		links, ok := lookup.Packages[name]
-		if !ok {
-			return fmt.Errorf("package %s not found", name)
+// searchHit is a curated package matching a search.
+type searchHit struct {
+	name        string
+	description string
+	importedBy  int
+	deprecated  string
+	untagged    bool
+	goVersion   string
+	score       modscore.Score
+}
+
+// searchPackages returns the curated packages whose name, description or
+// topics match query, having all topics and allowing goVersion if set.
+// Hits are ordered by the number of modules requiring them, deprecated
+// and untagged ones last.
+func searchPackages(lookup *pkglists.Lookup, graph graphInfo, scores *scorer, pseudoOnly map[string]modindex.PseudoOnly, query, goVersion string, topics []string) []searchHit {
+	var hits []searchHit
+	for name, links := range lookup.Packages {
+		module := packageModulePath(lookup, name)
+		_, untagged := pseudoOnly[module]
+		h := searchHit{
+			name:       name,
+			untagged:   untagged,
+			importedBy: graph.importedBy[module],
+			deprecated: graph.deprecated[module],
+			goVersion:  graph.goVersions[module],
+			score:      scores.score(module),
 		}
-		link := links[0]
+		// Modules whose go.mod was not read yet are kept.
+		if goVersion != "" && h.goVersion != "" && goversion.Compare(goLang(h.goVersion), goLang(goVersion)) > 0 {
+			continue
+		}
+		if !hasTopics(graph.topics[module], topics) {
+			continue
+		}
+		if strings.Contains(name, query) || slices.Contains(graph.topics[module], strings.ToLower(query)) {
+			hits = append(hits, h)
+			continue
+		}
+		for _, link := range links {
+			if strings.Contains(link.Description, query) {
+				h.description = link.Description
+				hits = append(hits, h)
+				break
+			}
+		}
+	}
+	slices.SortFunc(hits, func(a, b searchHit) int {
+		isDeprecated := func(h searchHit) bool { return h.deprecated != "" }
+		return cmp.Or(
+			cmpBool(isDeprecated(a), isDeprecated(b)),
+			cmpBool(a.untagged, b.untagged),
+			b.importedBy-a.importedBy,
+			strings.Compare(a.name, b.name),
+		)
+	})
+	return hits
+}
 
-		u, err := url.Parse(link.URL)
-		if err != nil {
-			return fmt.Errorf("parse URL: %w", err)
+// graphInfo is what the dependency graph built by 'deps sync' knows
+// about modules.
+type graphInfo struct {
+	importedBy map[string]int    // number of modules requiring each module
+	deprecated map[string]string // deprecation messages
+	goVersions map[string]string // go directives
+	topics     map[string][]string
+}
+
+// loadGraph loads the dependency graph information used to rank and
+// annotate packages. Without a usable database, it warns and returns
+// no information, so rankings fall back to names.
+func loadGraph(ctx context.Context, cmd *cli.Command) graphInfo {
+	var g graphInfo
+	c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+	if err == nil {
+		defer c.Close()
+		g.importedBy, err = c.ImportedBy(ctx)
+		if err == nil {
+			g.deprecated, err = c.Deprecations(ctx)
 		}
-		if u.Host != "github.com" {
-			return fmt.Errorf("expected github.com URL, got %s", u.Host)
+		var directives []modindex.GoDirectives
+		if err == nil {
+			directives, err = c.GoDirectives(ctx)
 		}
-		parts := strings.Split(u.Path, "/")
-		if len(parts) != 3 {
-			return fmt.Errorf("expected /<owner>/<repo> URL, got %s", u.Path)
+		if err == nil {
+			g.topics, err = c.ModuleTopics(ctx)
 		}
+		if err == nil {
+			g.goVersions = make(map[string]string, len(directives))
+			for _, d := range directives {
+				g.goVersions[d.Module] = d.GoVersion
+			}
+			return g
+		}
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "ranking without dependency graph: %v\n", err)
+	return graphInfo{}
+}
 
-		client := github.NewClient(nil)
-		repo, _, err := client.Repositories.Get(context.Background(), parts[1], parts[2])
-		if err != nil {
-			return fmt.Errorf("get repository: %w", err)
+// hasTopics reports whether have contains all topics in want,
+// ignoring case.
+func hasTopics(have, want []string) bool {
+	for _, t := range want {
+		if !slices.Contains(have, strings.ToLower(t)) {
+			return false
 		}
-		fmt.Println("Repo:", repo.GetFullName())
-		fmt.Println("Updated at:", repo.GetUpdatedAt())
-		fmt.Println("Watchers:", repo.GetWatchers())
-		fmt.Println("Stargazers:", repo.GetStargazersCount())
-		fmt.Println("Forks:", repo.GetForksCount())
-		fmt.Println("Open Issues:", repo.GetOpenIssuesCount())
-		fmt.Println("Description:", repo.GetDescription())
-		fmt.Println("Topics:", repo.Topics)
+	}
+	return true
+}
 
-		return nil
-	},
+// goLang turns a go directive like "1.21" into the "go1.21" form
+// expected by go/version.
+func goLang(v string) string {
+	if strings.HasPrefix(v, "go") {
+		return v
+	}
+	return "go" + v
 }
 
-var searchCommand = &cli.Command{
-	Name: "search",
-	Arguments: []cli.Argument{
-		&cli.StringArg{
-			Name: "query",
-			Min:  1,
+// cmpBool orders false before true.
+func cmpBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+var suggestCommand = &cli.Command{
+	Name:      "suggest",
+	Usage:     "suggest healthy curated packages similar to a package",
+	ArgsUsage: "PACKAGE",
+	Description: "Packages are similar if they are curated in the same categories, share\n" +
+		"forge topics or are required by the same modules: in the dependency\n" +
+		"graph of 'deps sync', at least --min-overlap of the modules requiring\n" +
+		"either package must require both. They are ranked by the number of\n" +
+		"categories, topics and overlaps shared, then by their health score.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of suggestions",
+			Value: 10,
+		},
+		&cli.FloatFlag{
+			Name:  "min-overlap",
+			Usage: "count packages sharing at least `FRACTION` of their dependents as similar",
+			Value: 0.05,
 		},
 	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		lookup, err := newLookup(ctx, cmd)
 		if err != nil {
 			return fmt.Errorf("init lookup: %w", err)
 		}
-		query := strings.Join(cmd.Args().Slice(), " ")
-		for name, links := range lookup.Packages {
-			if strings.Contains(name, query) {
-				fmt.Println(name)
-				continue
-			}
-			for _, link := range links {
-				if strings.Contains(link.Description, query) {
-					fmt.Println(name, link.Description)
-					continue
-				}
-			}
+		name := packageKey(lookup, cmd.Args().First())
+		links, ok := lookup.Packages[name]
+		if !ok {
+			return fmt.Errorf("package %s not found", name)
 		}
-		return nil
-	},
-}
+		graph := loadGraph(ctx, cmd)
+		scores := newScorer(ctx, cmd, lookup, graph)
 
-var domainsCommand = &cli.Command{
-	Name: "domains",
-	Action: func(ctx context.Context, cmd *cli.Command) error {
-		lookup, err := pkglists.NewTestdataLookup()
-		if err != nil {
-			return fmt.Errorf("init lookup: %w", err)
+		shared := sharedCategories(links, name)
+		for key, n := range sharedTopics(lookup, graph, name) {
+			shared[key] += n
 		}
-
-		domains := make(map[string]int)
-		for _, links := range lookup.Packages {
-			for _, link := range links {
-				u, err := url.Parse(link.URL)
-				if err != nil {
-					return fmt.Errorf("parse URL: %w", err)
-				}
-				domains[u.Host]++
+		overlaps := dependentOverlap(ctx, cmd, packageModulePath(lookup, name))
+		for key := range lookup.Packages {
+			if key != name && overlaps[packageModulePath(lookup, key)] >= cmd.Float("min-overlap") {
+				shared[key]++
 			}
 		}
-		keys := slices.SortedFunc(maps.Keys(domains), func(i, j string) int {
-			return domains[i] - domains[j]
-		})
-		for _, key := range keys {
-			percentage := float64(domains[key]) / float64(len(lookup.Packages)) * 100
-			fmt.Printf("%s: %d (%.2f%%)\n", key, domains[key], percentage)
+		suggestions := rankSimilar(lookup, scores, shared, 0, int(cmd.Int("limit")))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "SHARED\tSCORE\tSCORECARD\tOVERLAP\tPACKAGE\tDESCRIPTION")
+		for _, s := range suggestions {
+			overlap := "-"
+			if o, ok := overlaps[packageModulePath(lookup, s.name)]; ok {
+				overlap = fmt.Sprintf("%.0f%%", o*100)
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", s.shared, s.score, scores.scorecardScore(packageModulePath(lookup, s.name)), overlap, s.name, s.description)
 		}
-		return nil
-	},
-}
-
-var suggestCommand = &cli.Command{
-	Name: "suggest",
-	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// Find an approved package that is similar to the given package.
-		// We can use GitHub topics to find similar packages.
-		return nil
+		return w.Flush()
 	},
 }
 
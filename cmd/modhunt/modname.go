@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/modname"
+)
+
+var modnameRulesFlag = &cli.StringFlag{
+	Name:    "modname-rules",
+	Usage:   "canonicalize module paths with the rewrite rules in `FILE` (see 'modhunt modname')",
+	Sources: cli.EnvVars("MODHUNT_MODNAME_RULES"),
+}
+
+// loadModnameRules replaces the rewrite rules of modname with those in the
+// file named by --modname-rules, if any.
+func loadModnameRules(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	name := cmd.String(modnameRulesFlag.Name)
+	if name == "" {
+		return ctx, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return ctx, fmt.Errorf("open modname rules: %w", err)
+	}
+	defer f.Close()
+	rules, err := modname.ParseRules(f)
+	if err != nil {
+		return ctx, fmt.Errorf("parse modname rules: %w", err)
+	}
+	modname.SetRules(rules)
+	return ctx, nil
+}
+
+var modnameCommand = &cli.Command{
+	Name:  "modname",
+	Usage: "show how module paths are canonicalized",
+	Description: `Module paths from lists and the index are compared by their canonical
+name. Besides the built-in mappings of gopkg.in and go.googlesource.com
+paths, rewrite rules replace the prefix of a path, e.g. hosts known under
+several names or projects that moved. The --modname-rules file replaces the
+default rules; every line holds a prefix of path elements and its
+replacement, separated by white space, e.g.
+
+   code.google.com/p/go.net golang.org/x/net
+
+A prefix without replacement is stripped. The first matching rule wins.
+Empty lines and lines starting with # are ignored.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "list",
+			Usage: "print the rules in effect",
+		},
+	},
+	Commands: []*cli.Command{modnameTestCommand},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if !cmd.Bool("list") {
+			return cli.ShowSubcommandHelp(cmd)
+		}
+		for _, r := range modname.Rules() {
+			fmt.Println(r)
+		}
+		return nil
+	},
+}
+
+var modnameTestCommand = &cli.Command{
+	Name:      "test",
+	Usage:     "print every step of canonicalizing a path or URL",
+	ArgsUsage: "PATH",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		path := cmd.Args().First()
+		module, err := modname.FromRepoURL(path)
+		if err != nil {
+			return err
+		}
+		rule := "-"
+		if r, ok := modname.MatchRule(strings.TrimPrefix(strings.TrimPrefix(path, "https://"), "http://")); ok {
+			rule = r.String()
+		} else if r, ok := modname.MatchRule(module); ok {
+			rule = r.String()
+		}
+		normalized := modname.Normalize(module)
+		project, major := modname.SplitMajor(normalized)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(w, "input:\t%s\n", path)
+		_, _ = fmt.Fprintf(w, "rule:\t%s\n", rule)
+		_, _ = fmt.Fprintf(w, "module path:\t%s\n", module)
+		if github, gopkgMajor, ok := modname.FromGopkgIn(module); ok {
+			_, _ = fmt.Fprintf(w, "gopkg.in:\t%s at %s\n", github, gopkgMajor)
+		}
+		if repo, ok := modname.ToGoogleSource(module); ok {
+			_, _ = fmt.Fprintf(w, "googlesource:\t%s\n", repo)
+		}
+		_, _ = fmt.Fprintf(w, "normalized:\t%s\n", normalized)
+		_, _ = fmt.Fprintf(w, "project:\t%s\n", project)
+		_, _ = fmt.Fprintf(w, "major:\t%s\n", orDash(major))
+		_, _ = fmt.Fprintf(w, "repository:\t%s\n", modname.RepoPath(module))
+		return w.Flush()
+	},
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var orgsCommand = &cli.Command{
+	Name:  "orgs",
+	Usage: "rank the owners of index paths by their modules and releases",
+	Description: "Paths on forges with owners, like GitHub and GitLab, are aggregated by\n" +
+		"owner, e.g. github.com/uber-go, other paths by host, e.g. go.uber.org.\n" +
+		"Prolific owners are ecosystems worth a look, or sources of noise to\n" +
+		"classify as generated with 'index classify'.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "list `N` orgs, 0 lists all",
+			Value: 30,
+		},
+		&cli.StringFlag{
+			Name:  "sort",
+			Usage: "rank by `KEY`: paths, releases or last",
+			Value: "paths",
+		},
+		includeGeneratedFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		var compare func(a, b modindex.Org) int
+		switch cmd.String("sort") {
+		case "paths":
+			compare = func(a, b modindex.Org) int { return cmp.Compare(b.Paths, a.Paths) }
+		case "releases":
+			compare = func(a, b modindex.Org) int { return cmp.Compare(b.Releases, a.Releases) }
+		case "last":
+			compare = func(a, b modindex.Org) int { return b.Last.Compare(a.Last) }
+		default:
+			return fmt.Errorf("invalid --sort %q: expected paths, releases or last", cmd.String("sort"))
+		}
+
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		orgs, err := c.Orgs(ctx, cmd.Bool("include-generated"))
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(orgs, func(a, b modindex.Org) int {
+			return cmp.Or(compare(a, b), strings.Compare(a.Name, b.Name))
+		})
+
+		var paths int
+		for _, o := range orgs {
+			paths += o.Paths
+		}
+		if n := int(cmd.Int("limit")); n > 0 && len(orgs) > n {
+			orgs = orgs[:n]
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ORG\tPATHS\tSHARE\tRELEASES\tFIRST\tLAST")
+		for _, o := range orgs {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\t%s\n", o.Name, o.Paths, share(o.Paths, paths), o.Releases, formatDate(o.First), formatDate(o.Last))
+		}
+		return w.Flush()
+	},
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/pkgsite"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var pkgsiteURLFlag = &cli.StringFlag{
+	Name:  "pkgsite-url",
+	Usage: "read module pages from `URL`",
+	Value: pkgsite.DefaultURL,
+}
+
+var pkgsiteCommand = &cli.Command{
+	Name:  "pkgsite",
+	Usage: "enrich modules with metadata from pkg.go.dev",
+	Commands: []*cli.Command{
+		pkgsiteSyncCommand,
+		pkgsiteCheckCommand,
+	},
+}
+
+var pkgsiteSyncCommand = &cli.Command{
+	Name:      "sync",
+	Usage:     "read the pkg.go.dev pages of curated or the given modules and store their metadata",
+	ArgsUsage: "[MODULE...]",
+	Flags: []cli.Flag{
+		pkgsiteURLFlag,
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "fetch `N` pages concurrently",
+			Value: 2,
+		},
+		&cli.FloatFlag{
+			Name:  "rate",
+			Usage: "fetch at most `N` pages per second",
+			Value: 1,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		modules := cmd.Args().Slice()
+		if len(modules) == 0 {
+			lookup, err := newLookup(ctx, cmd)
+			if err != nil {
+				return fmt.Errorf("init lookup: %w", err)
+			}
+			modules = curatedModules(lookup)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		var opts []pkgsite.Option
+		if r := cmd.Float("rate"); r > 0 {
+			opts = append(opts, pkgsite.WithRateLimit(r))
+		}
+		site := pkgsite.New(cmd.String(pkgsiteURLFlag.Name), opts...)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type fetched struct {
+			meta *pkgsite.Metadata
+			err  error
+		}
+		var done, stored int
+		var storeErr error
+		forEachParallel(modules, int(cmd.Int("workers")), func(m string) fetched {
+			meta, err := site.Metadata(ctx, m)
+			return fetched{meta, err}
+		}, func(m string, f fetched) {
+			done++
+			if storeErr != nil {
+				return
+			}
+			if f.err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error fetching %q: %v\n", done, len(modules), m, f.err)
+				return
+			}
+			err := c.StorePkgsite(ctx, modindex.PkgsiteInfo{
+				Module:     m,
+				Path:       f.meta.Path,
+				Version:    f.meta.Version,
+				Licenses:   f.meta.Licenses,
+				ImportedBy: f.meta.ImportedBy,
+				FetchedAt:  time.Now(),
+			})
+			if err != nil {
+				storeErr = fmt.Errorf("store pkg.go.dev metadata of %s: %w", m, err)
+				cancel()
+				return
+			}
+			stored++
+			msg := fmt.Sprintf("%s, imported by %d", orDash(f.meta.Version), f.meta.ImportedBy)
+			if f.meta.Redirected(m) {
+				msg += ", redirects to " + f.meta.Path
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: %s\n", done, len(modules), m, msg)
+		})
+		if storeErr != nil {
+			return storeErr
+		}
+		fmt.Printf("Stored the pkg.go.dev metadata of %d modules\n", stored)
+		return nil
+	},
+}
+
+var pkgsiteCheckCommand = &cli.Command{
+	Name:  "check",
+	Usage: "cross-check stored pkg.go.dev metadata with the module proxy",
+	Description: "Lists modules pkg.go.dev redirects to another path, e.g. from a GitHub\n" +
+		"repository to its gopkg.in path, modules without a detected license and\n" +
+		"modules whose version on pkg.go.dev is not the latest on the proxy.",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		infos, err := c.PkgsiteInfos(ctx)
+		if err != nil {
+			return err
+		}
+		modules := make([]string, len(infos))
+		for i, p := range infos {
+			modules[i] = p.Module
+		}
+		latest := make(map[string]string)
+		forEachParallel(modules, 10, func(m string) string {
+			info, err := proxy.Latest(ctx, m)
+			if err != nil {
+				return ""
+			}
+			return info.Version
+		}, func(m, version string) {
+			latest[m] = version
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "MODULE\tISSUES")
+		for _, p := range infos {
+			var issues []string
+			if !strings.EqualFold(p.Path, p.Module) {
+				issues = append(issues, "redirects to "+p.Path)
+			}
+			if len(p.Licenses) == 0 {
+				issues = append(issues, "no license detected")
+			}
+			if v := latest[p.Module]; v != "" && p.Version != "" && v != p.Version {
+				issues = append(issues, fmt.Sprintf("shows %s, proxy has %s", p.Version, v))
+			}
+			if len(issues) > 0 {
+				_, _ = fmt.Fprintf(w, "%s\t%s\n", p.Module, strings.Join(issues, "; "))
+			}
+		}
+		return w.Flush()
+	},
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/internal/osv"
+	"github.com/ngrash/modhunt/internal/policy"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var policyCommand = &cli.Command{
+	Name:  "policy",
+	Usage: "check modules against the dependency policy of an organization",
+	Commands: []*cli.Command{
+		policyCheckCommand,
+	},
+}
+
+var policyCheckCommand = &cli.Command{
+	Name:      "check",
+	Usage:     "check whether modules may be used as dependencies",
+	ArgsUsage: "MODULE[@VERSION]...",
+	Description: "Checks the rules of --policy, a YAML file like\n\n" +
+		"  licenses: [MIT, Apache-2.0, BSD-3-Clause]\n" +
+		"  max_severity: moderate   # none, low, moderate, high or critical\n" +
+		"  min_score: 50\n" +
+		"  max_dependencies: 40\n" +
+		"  banned_hosts: [example.com]\n\n" +
+		"against the latest version of every module or the version given. The\n" +
+		"go.mod, the advisories and their severity are fetched from the module\n" +
+		"proxy and OSV.dev; the license and the score are read from the index,\n" +
+		"so run 'enrich' first. Rules that can't be checked fail. Exits with\n" +
+		"status 1 if any module fails, e.g. to gate new dependencies in CI.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "policy",
+			Usage:   "read the rules from `FILE`",
+			Value:   "policy.yaml",
+			Sources: cli.EnvVars("MODHUNT_POLICY"),
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print a JSON object per module",
+		},
+		osvURLFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() == 0 {
+			return fmt.Errorf("expected at least one module")
+		}
+		p, err := policy.Load(cmd.String("policy"))
+		if err != nil {
+			return err
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		scores := newScorer(ctx, cmd, lookup, loadGraph(ctx, cmd))
+		vulns := osv.New(cmd.String(osvURLFlag.Name), nil)
+
+		enc := json.NewEncoder(os.Stdout)
+		var failed int
+		for i, arg := range cmd.Args().Slice() {
+			f, err := policyFacts(ctx, cmd, proxy, vulns, scores, arg)
+			if err != nil {
+				return fmt.Errorf("%s: %w", arg, err)
+			}
+			results := p.Check(f)
+			passed := policy.Passed(results)
+			if !passed {
+				failed++
+			}
+			if cmd.Bool("json") {
+				err := enc.Encode(struct {
+					Module  string          `json:"module"`
+					Version string          `json:"version"`
+					Pass    bool            `json:"pass"`
+					Results []policy.Result `json:"results"`
+				}{f.Module, f.Version, passed, results})
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			printPolicyResults(f, passed, results)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d modules fail the policy", failed, cmd.Args().Len())
+		}
+		return nil
+	},
+}
+
+func printPolicyResults(f policy.Facts, passed bool, results []policy.Result) {
+	verdict := "pass"
+	if !passed {
+		verdict = "FAIL"
+	}
+	fmt.Printf("%s@%s: %s\n", f.Module, f.Version, verdict)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		outcome := "pass"
+		if !r.Pass {
+			outcome = "FAIL"
+		}
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\n", r.Rule, outcome, r.Reason)
+	}
+	_ = w.Flush()
+}
+
+// policyFacts collects what the rules of a policy check about arg, a
+// module with an optional version. Facts that can't be collected are
+// left unknown with a warning.
+func policyFacts(ctx context.Context, cmd *cli.Command, proxy *goproxy.Client, vulns *osv.Client, scores *scorer, arg string) (policy.Facts, error) {
+	mod, version, err := resolveVersion(ctx, proxy, arg)
+	if err != nil {
+		return policy.Facts{}, err
+	}
+	f := policy.Facts{Module: mod, Version: version}
+	addHost := func(h string) {
+		if h != "" && !slices.Contains(f.Hosts, h) {
+			f.Hosts = append(f.Hosts, h)
+		}
+	}
+	host, _, _ := strings.Cut(mod, "/")
+	addHost(host)
+
+	repo, ok := scores.repo(mod)
+	if ok {
+		f.License = repo.License
+		addHost(repo.Host)
+	}
+	if info, err := proxy.Info(ctx, mod, version); err == nil && info.Origin.URL != "" {
+		if u, err := url.Parse(info.Origin.URL); err == nil {
+			addHost(u.Hostname())
+		}
+	}
+	if f.License == "" {
+		f.License = pkgsiteLicense(ctx, cmd, mod)
+	}
+
+	advisories, err := vulns.Vulns(ctx, osv.Package{Module: mod, Version: version})
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "skipping advisories of %s: %v\n", mod, err)
+	} else {
+		f.VulnsKnown = true
+		f.Vulns = policyVulns(advisories)
+	}
+
+	if f.VulnsKnown {
+		scores.vulns = map[string]moduleVulns{mod: {count: len(f.Vulns), version: version}}
+	}
+	if score := scores.score(mod); scores.knows(mod) && score.Known() {
+		f.ScoreKnown, f.Score = true, score.Total
+	}
+
+	data, err := proxy.GoMod(ctx, mod, version)
+	if err == nil {
+		var gm *modindex.GoMod
+		if gm, err = modindex.ParseGoMod(mod, version, data); err == nil {
+			f.DependenciesKnown, f.Dependencies = true, len(gm.Requires)
+		}
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "skipping dependencies of %s: %v\n", mod, err)
+	}
+	return f, nil
+}
+
+// policyVulns returns the advisories with their severity. Advisories of
+// the Go vulnerability database have no severity and are left out if an
+// alias in advisories has one, as it describes the same vulnerability.
+func policyVulns(advisories []osv.Vuln) []policy.Vuln {
+	severity := make(map[string]string, len(advisories))
+	for _, a := range advisories {
+		severity[a.ID] = a.Severity()
+	}
+	var vulns []policy.Vuln
+	for _, a := range advisories {
+		s := a.Severity()
+		if s == "" && slices.ContainsFunc(a.Aliases, func(id string) bool { return severity[id] != "" }) {
+			continue
+		}
+		vulns = append(vulns, policy.Vuln{ID: a.ID, Severity: s})
+	}
+	return vulns
+}
+
+// pkgsiteLicense returns the license pkg.go.dev showed for mod, if it
+// showed exactly one, as stored by 'pkgsite check'.
+func pkgsiteLicense(ctx context.Context, cmd *cli.Command, mod string) string {
+	c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+	if err != nil {
+		return ""
+	}
+	defer c.Close()
+	info, err := c.Pkgsite(ctx, mod)
+	if err != nil || len(info.Licenses) != 1 {
+		return ""
+	}
+	return info.Licenses[0]
+}
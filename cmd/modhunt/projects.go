@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var projectsCommand = &cli.Command{
+	Name:      "projects",
+	Usage:     "list the projects curated packages belong to, or show one",
+	ArgsUsage: "[ID|MODULE]",
+	Description: "A project is a curated module with all its major versions. It ties\n" +
+		"together the links that curate it, the paths of the index that belong\n" +
+		"to it and its repository: the forge it is linked to or, for vanity\n" +
+		"paths, the origin the module proxy reported. Projects are stored with\n" +
+		"'projects sync', which keeps their IDs across runs, and can be named by\n" +
+		"ID or by the module path of any of their major versions.",
+	Commands: []*cli.Command{projectsSyncCommand},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() > 1 {
+			return fmt.Errorf("expected at most one argument")
+		}
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		if cmd.Args().Len() == 1 {
+			p, err := c.Project(ctx, cmd.Args().First())
+			if errors.Is(err, modindex.ErrNoProject) {
+				return fmt.Errorf("%w (see modhunt projects sync)", err)
+			}
+			if err != nil {
+				return err
+			}
+			return printProject(ctx, c, p)
+		}
+
+		projects, err := c.Projects(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tPROJECT\tMODULE\tREPOSITORY\tLINKS\tPATHS")
+		for _, p := range projects {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\n", p.ID, p.Name, p.Module, orDash(p.Repo), p.Links, p.Paths)
+		}
+		return w.Flush()
+	},
+}
+
+var projectsSyncCommand = &cli.Command{
+	Name:  "sync",
+	Usage: "store the projects of all curated packages and link the index to them",
+	Description: "Curated packages are grouped by project, the module path resolved by\n" +
+		"'links sync' or else guessed from their URL without major version. Run\n" +
+		"it after 'links sync' for the modules and origins it resolved.",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		links, err := c.LinkModules(ctx)
+		if err != nil {
+			return err
+		}
+
+		projects := curatedProjects(lookup, linkOrigins(links))
+		paths, err := c.StoreProjects(ctx, projects)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Stored %d projects, linked %d paths of the index\n", len(projects), paths)
+		return nil
+	},
+}
+
+// curatedProjects groups the curated packages by project. The module of a
+// project is its highest curated major version. Its repository is the one
+// its packages are linked to, unless that is no known forge and origins
+// holds the origin of its module.
+func curatedProjects(lookup *pkglists.Lookup, origins map[string]string) []modindex.Project {
+	byName := make(map[string]*modindex.Project)
+	for _, key := range slices.Sorted(maps.Keys(lookup.Packages)) {
+		module := packageModulePath(lookup, key)
+		name := modname.Project(module)
+		p, ok := byName[name]
+		if !ok {
+			p = &modindex.Project{Name: name, Module: module}
+			byName[name] = p
+		}
+		if majorOf(module) > majorOf(p.Module) {
+			p.Module = module
+		}
+		for _, l := range lookup.Packages[key] {
+			p.Links = append(p.Links, l.URL)
+		}
+		if p.Repo != "" {
+			continue
+		}
+		loc, ok := packageRepo(lookup, key)
+		if origin, err := forge.ParseURL(origins[module]); err == nil && (!ok || forgeKind(loc.Host) == "") {
+			loc, ok = origin, true
+		}
+		if ok {
+			p.Repo = repoKey(loc.Host, loc.Name)
+		}
+	}
+	projects := make([]modindex.Project, 0, len(byName))
+	for _, name := range slices.Sorted(maps.Keys(byName)) {
+		projects = append(projects, *byName[name])
+	}
+	return projects
+}
+
+// majorOf returns the major version of a module path, e.g. 3 for
+// gopkg.in/yaml.v3.
+func majorOf(module string) int {
+	_, major := modname.SplitMajor(module)
+	return modname.MajorNumber(major)
+}
+
+// printProject prints what is known about p: its links, its paths in the
+// index with their latest versions and its stored repository.
+func printProject(ctx context.Context, c *modindex.Client, p modindex.Project) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "ID\t%d\n", p.ID)
+	_, _ = fmt.Fprintf(w, "Project\t%s\n", p.Name)
+	_, _ = fmt.Fprintf(w, "Module\t%s\n", p.Module)
+	_, _ = fmt.Fprintf(w, "Repository\t%s\n", orDash(p.Repo))
+	repos, err := c.Repos(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range repos {
+		if repoKey(r.Host, r.Name) == p.Repo {
+			_, _ = fmt.Fprintf(w, "Stars\t%d\n", r.Stars)
+			_, _ = fmt.Fprintf(w, "Archived\t%t\n", r.Archived)
+			_, _ = fmt.Fprintf(w, "License\t%s\n", orDash(r.License))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nLinks")
+	for _, l := range p.Links {
+		fmt.Println("  " + l)
+	}
+	fmt.Println("\nPaths in the index")
+	for _, path := range p.Paths {
+		versions, err := c.Versions(ctx, path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %s (%d versions, latest %s)\n", path, len(versions), orDash(modindex.LatestVersion(versions)))
+	}
+	return nil
+}
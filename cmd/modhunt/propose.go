@@ -0,0 +1,252 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/mod/module"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+// awesomeGo is the name of the awesome-go source in lookups.
+const awesomeGo = "Awesome Go"
+
+var proposeCommand = &cli.Command{
+	Name:      "propose",
+	Usage:     "draft a pull request adding a module to awesome-go",
+	ArgsUsage: "MODULE",
+	Description: "Prints the line to add to the awesome-go README, where to insert it in\n" +
+		"the alphabetical order of its category, and a pull request body with the\n" +
+		"links and quality evidence the awesome-go maintainers ask for. Without\n" +
+		"--category, the category is suggested like 'classify' does.\n\n" +
+		"Evidence comes from the index: the repository stored by 'github sync'\n" +
+		"or 'repo sync' and the releases stored by download-releases. The\n" +
+		"repository and its README are fetched unless --offline is set.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "category",
+			Usage: "add the module to `CATEGORY`, e.g. \"Web Frameworks > Routers\"",
+		},
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "link the module as `NAME` (default: the repository name)",
+		},
+		&cli.StringFlag{
+			Name:  "description",
+			Usage: "describe the module as `TEXT` (default: the repository description)",
+		},
+		&cli.BoolFlag{
+			Name:  "offline",
+			Usage: "use the stored repository metadata only",
+		},
+		githubTokenFlag, githubAPIURLFlag, verboseFlag, gitlabTokenFlag, gitlabURLFlag, sourcehutTokenFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		mod := cmd.Args().First()
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		for name, links := range lookup.Packages {
+			if modname.Normalize(packageModulePath(lookup, name)) != modname.Normalize(mod) {
+				continue
+			}
+			for _, l := range links {
+				if l.Source.Name == awesomeGo {
+					return fmt.Errorf("%s is listed in %s already", mod, categoryPath(l.Category))
+				}
+			}
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		r, err := moduleRepo(ctx, cmd, c, mod)
+		if err != nil {
+			return err
+		}
+
+		cat, err := proposedCategory(ctx, c, lookup, cmd.String("category"), entryTerms(r.Description, r.Topics))
+		if err != nil {
+			return err
+		}
+		link := pkglists.Link{
+			URL:         "https://" + r.Host + "/" + r.Name,
+			Name:        cmd.String("name"),
+			Description: cmp.Or(cmd.String("description"), r.Description),
+		}
+		if link.Name == "" {
+			link.Name = r.Name[strings.LastIndex(r.Name, "/")+1:]
+		}
+		if link.Description == "" {
+			return fmt.Errorf("repository %s/%s has no description, set --description", r.Host, r.Name)
+		}
+		if !strings.HasSuffix(link.Description, ".") {
+			link.Description += "."
+		}
+
+		fmt.Printf("Category: %s\n", categoryPath(cat))
+		if next, ok := insertBefore(cat, link.Name); ok {
+			fmt.Printf("Insert before: %s\n", awesomeLine(next))
+		} else if len(cat.Links) > 0 {
+			fmt.Printf("Insert after: %s\n", awesomeLine(cat.Links[len(cat.Links)-1]))
+		}
+		fmt.Printf("\n%s\n\n", awesomeLine(link))
+
+		var readme string
+		if !cmd.Bool("offline") {
+			readme = fetchReadme(ctx, cmd, r)
+		}
+		fmt.Println(proposalBody(ctx, c, mod, r, link, cat, readme))
+		return nil
+	},
+}
+
+// proposedCategory returns the awesome-go category named path or, if
+// path is empty, the one suggested for terms.
+func proposedCategory(ctx context.Context, c *modindex.Client, lookup *pkglists.Lookup, path string, terms []string) (*pkglists.Category, error) {
+	var source *pkglists.Source
+	for _, s := range lookup.Sources {
+		if s.Name == awesomeGo {
+			source = s
+		}
+	}
+	if source == nil {
+		return nil, fmt.Errorf("no %s source", awesomeGo)
+	}
+	path = strings.TrimPrefix(path, awesomeGo+" > ")
+	if path == "" {
+		stored, err := c.Repos(ctx)
+		if err != nil {
+			return nil, err
+		}
+		repos := make(map[string]modindex.Repo, len(stored))
+		for _, r := range stored {
+			repos[repoKey(r.Host, r.Name)] = r
+		}
+		for _, m := range newCategoryTerms(lookup, repos).rank(terms) {
+			if p, ok := strings.CutPrefix(m.path, awesomeGo+" > "); ok {
+				path = p
+				_, _ = fmt.Fprintf(os.Stderr, "Suggesting %s for the shared words %s, see 'modhunt classify'\n", p, strings.Join(m.shared, " "))
+				break
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("no category to suggest, set --category")
+		}
+	}
+	var found *pkglists.Category
+	walkCategories(source.Root, "", func(cat *pkglists.Category, p string) {
+		if strings.TrimPrefix(p, " > ") == path {
+			found = cat
+		}
+	})
+	if found == nil {
+		return nil, fmt.Errorf("no category %q in %s, see 'modhunt categories'", path, awesomeGo)
+	}
+	return found, nil
+}
+
+// insertBefore returns the link of cat that a link named name goes
+// before, in the case-insensitive order of awesome-go. It reports false
+// if the link goes last.
+func insertBefore(cat *pkglists.Category, name string) (pkglists.Link, bool) {
+	for _, l := range cat.Links {
+		if strings.ToLower(l.Name) > strings.ToLower(name) {
+			return l, true
+		}
+	}
+	return pkglists.Link{}, false
+}
+
+// awesomeLine returns the line of l in the awesome-go README.
+func awesomeLine(l pkglists.Link) string {
+	return fmt.Sprintf("- [%s](%s) - %s", l.Name, l.URL, l.Description)
+}
+
+// fetchReadme returns the README of r, or "" if its forge does not serve
+// it or fetching it failed.
+func fetchReadme(ctx context.Context, cmd *cli.Command, r modindex.Repo) string {
+	client, err := forgeClient(cmd, r.Host)
+	if err != nil {
+		return ""
+	}
+	reader, ok := client.(forge.ReadmeReader)
+	if !ok {
+		return ""
+	}
+	readme, err := reader.Readme(ctx, r.Name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Not checking README badges: %v\n", err)
+	}
+	return readme
+}
+
+var (
+	coverageBadge   = regexp.MustCompile(`https?://[^)\s"]*(codecov\.io|coveralls\.io|codeclimate\.com|coverage)[^)\s"]*`)
+	reportCardBadge = regexp.MustCompile(`https?://goreportcard\.com/[^)\s"]*`)
+)
+
+// proposalBody returns the pull request body adding link to cat, in the
+// structure of the awesome-go pull request template.
+func proposalBody(ctx context.Context, c *modindex.Client, mod string, r modindex.Repo, link pkglists.Link, cat *pkglists.Category, readme string) string {
+	coverage := "none found"
+	reportCard := "https://goreportcard.com/report/" + mod
+	if readme == "" {
+		coverage = "README not checked"
+	} else if badge := coverageBadge.FindString(readme); badge != "" {
+		coverage = badge
+	}
+
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "Add %s to %s\n\n", link.Name, categoryPath(cat))
+	_, _ = fmt.Fprintf(&b, "Please provide package links to:\n\n")
+	_, _ = fmt.Fprintf(&b, "- forge link: %s\n", link.URL)
+	_, _ = fmt.Fprintf(&b, "- pkg.go.dev: https://pkg.go.dev/%s\n", mod)
+	_, _ = fmt.Fprintf(&b, "- goreportcard.com: %s\n", reportCard)
+	_, _ = fmt.Fprintf(&b, "- coverage service link: %s\n\n", coverage)
+
+	_, _ = fmt.Fprintf(&b, "Quality evidence:\n\n")
+	_, _ = fmt.Fprintf(&b, "- Stars: %d, forks: %d\n", r.Stars, r.Forks)
+	_, _ = fmt.Fprintf(&b, "- License: %s\n", cmp.Or(r.License, "none detected"))
+	_, _ = fmt.Fprintf(&b, "- Last push: %s\n", formatDate(r.PushedAt))
+	if latest, err := c.Latest(ctx, mod); err == nil && latest.Version != "" {
+		note := ""
+		if module.IsPseudoVersion(latest.Version) {
+			note = " (no tagged release yet, awesome-go requires one)"
+		}
+		_, _ = fmt.Fprintf(&b, "- Latest version: %s, published %s%s\n", latest.Version, releaseDate(latest), note)
+	}
+	if cad, err := c.Cadence(ctx, mod); err == nil {
+		_, _ = fmt.Fprintf(&b, "- Releases: %d since %s, %.1f per year\n", cad.Releases, formatDate(cad.First), cad.PerYear)
+	} else if !errors.Is(err, modindex.ErrNoCadence) {
+		_, _ = fmt.Fprintf(os.Stderr, "Not reporting releases: %v\n", err)
+	}
+	if readme != "" {
+		_, _ = fmt.Fprintf(&b, "- README badges: coverage %s, Go Report Card %s\n", badgeMark(coverageBadge, readme), badgeMark(reportCardBadge, readme))
+	}
+	_, _ = fmt.Fprintf(&b, "- Repository data as of %s\n", r.FetchedAt.Format(time.DateOnly))
+	return b.String()
+}
+
+func badgeMark(badge *regexp.Regexp, readme string) string {
+	if badge.MatchString(readme) {
+		return "present"
+	}
+	return "missing"
+}
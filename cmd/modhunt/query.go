@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var queryCommand = &cli.Command{
+	Name:      "query",
+	Usage:     "run a read-only SQL query against the index database",
+	ArgsUsage: "SQL",
+	Description: "The query runs on a connection that refuses writes and its rows are\n" +
+		"printed as a table or, with --json, as one JSON object per row. With\n" +
+		"--schema, the tables of the database and their columns are listed\n" +
+		"instead, e.g.\n\n" +
+		"  modhunt query --schema\n" +
+		"  modhunt query \"SELECT host, name, stars FROM repos ORDER BY stars DESC LIMIT 10\"",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print JSON objects",
+		},
+		&cli.BoolFlag{
+			Name:  "schema",
+			Usage: "list the tables and their columns",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		if cmd.Bool("schema") {
+			tables, err := c.Schema(ctx)
+			if err != nil {
+				return err
+			}
+			return printSchema(tables)
+		}
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected one SQL query or --schema")
+		}
+		res, err := c.Query(ctx, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+		if cmd.Bool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			for _, row := range res.Rows {
+				obj := make(map[string]any, len(row))
+				for i, v := range row {
+					obj[res.Columns[i]] = v
+				}
+				if err := enc.Encode(obj); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, strings.ToUpper(strings.Join(res.Columns, "\t")))
+		for _, row := range res.Rows {
+			cells := make([]string, len(row))
+			for i, v := range row {
+				cells[i] = "NULL"
+				if v != nil {
+					cells[i] = strings.ReplaceAll(fmt.Sprint(v), "\n", " ")
+				}
+			}
+			_, _ = fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "%d rows\n", len(res.Rows))
+		return nil
+	},
+}
+
+// printSchema lists the tables with their columns and types.
+func printSchema(tables []modindex.TableSchema) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, t := range tables {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = fmt.Fprintln(w, t.Name)
+		for _, col := range t.Columns {
+			var notes []string
+			if col.PrimaryKey {
+				notes = append(notes, "primary key")
+			}
+			if col.NotNull {
+				notes = append(notes, "not null")
+			}
+			_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\n", col.Name, orDash(col.Type), strings.Join(notes, ", "))
+		}
+	}
+	return w.Flush()
+}
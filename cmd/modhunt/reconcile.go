@@ -0,0 +1,130 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/pkglists"
+)
+
+// packageIdentity returns what identifies the package a curated URL
+// points to regardless of how it is spelled: the lower-cased repository
+// and directory for forge URLs, otherwise the lookup key without a
+// trailing slash. URLs with a query, which may select the repository as
+// on code.google.com, count as spelled.
+func packageIdentity(rawurl string) string {
+	if loc, err := forge.ParseURL(rawurl); err == nil && !strings.Contains(rawurl, "?") {
+		id := repoKey(loc.Host, loc.Name)
+		if loc.Dir != "" {
+			id += "/" + loc.Dir
+		}
+		return id
+	}
+	key, err := pkglists.Key(rawurl)
+	if err != nil {
+		return rawurl
+	}
+	return strings.ToLower(strings.TrimSuffix(key, "/"))
+}
+
+// sourceRank returns the rank of sources of lookup by precedence: sources
+// in precedence come first in its order, the others after in the order of
+// the lookup.
+func sourceRank(lookup *pkglists.Lookup, precedence []string) func(*pkglists.Source) int {
+	return func(s *pkglists.Source) int {
+		if i := slices.Index(precedence, s.Name); i >= 0 {
+			return i
+		}
+		return len(precedence) + slices.Index(lookup.Sources, s)
+	}
+}
+
+// conflict is a package that sources curate with different URLs or
+// descriptions.
+type conflict struct {
+	id        string
+	links     []pkglists.Link // ordered by source precedence
+	canonical pkglists.Link
+}
+
+// reconcile groups the curated links of lookup by package identity and
+// returns the packages whose links disagree, with a canonical link each.
+// Sources earlier in precedence win; sources missing from it come after
+// in the order of the lookup. The canonical URL uses https if any source
+// does.
+func reconcile(lookup *pkglists.Lookup, precedence []string) []conflict {
+	rank := sourceRank(lookup, precedence)
+	groups := make(map[string][]pkglists.Link)
+	for _, links := range lookup.Packages {
+		for _, l := range links {
+			id := packageIdentity(l.URL)
+			groups[id] = append(groups[id], l)
+		}
+	}
+
+	var conflicts []conflict
+	for id, links := range groups {
+		if len(links) < 2 {
+			continue
+		}
+		slices.SortStableFunc(links, func(a, b pkglists.Link) int {
+			return cmp.Compare(rank(a.Source), rank(b.Source))
+		})
+		canonical := links[0]
+		var https, differ bool
+		for _, l := range links {
+			https = https || strings.HasPrefix(l.URL, "https://")
+			differ = differ || l.URL != canonical.URL || l.Description != canonical.Description
+		}
+		if !differ {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(canonical.URL, "http://"); ok && https {
+			canonical.URL = "https://" + rest
+		}
+		conflicts = append(conflicts, conflict{id: id, links: links, canonical: canonical})
+	}
+	slices.SortFunc(conflicts, func(a, b conflict) int { return strings.Compare(a.id, b.id) })
+	return conflicts
+}
+
+// printPatches prints, per source, the changes that make its links match
+// the canonical ones, in the style of a unified diff.
+func printPatches(conflicts []conflict, sources []*pkglists.Source) {
+	for _, s := range sources {
+		var lines []string
+		for _, c := range conflicts {
+			for _, l := range c.links {
+				if l.Source != s || l.URL == c.canonical.URL && l.Description == c.canonical.Description {
+					continue
+				}
+				lines = append(lines,
+					"@@ "+categoryPath(l.Category)+" @@",
+					"- "+l.URL+" - "+l.Description,
+					"+ "+c.canonical.URL+" - "+c.canonical.Description)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Printf("--- %s (%s)\n", s.Name, s.URL)
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+}
+
+// categoryPath returns the names of cat and its parents below the root,
+// e.g. "Databases > MySQL".
+func categoryPath(cat *pkglists.Category) string {
+	var names []string
+	for c := cat; c != nil && c.Parent != nil; c = c.Parent {
+		names = append(names, c.Name)
+	}
+	slices.Reverse(names)
+	return strings.Join(names, " > ")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var refreshCommand = &cli.Command{
+	Name:  "refresh",
+	Usage: "fetch stale answers of the module proxy stored in the index again",
+	Description: "The latest versions reported by the module proxy, and the paths it did\n" +
+		"not know, are stored by 'download-info', 'links sync' and 'resolve'.\n" +
+		"Answers older than --older-than are fetched again, as are those of paths\n" +
+		"the index saw a new version of since, which 'index sync' keeps current.\n" +
+		"Answers that cannot be fetched are kept as they are.",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "older-than",
+			Usage: "fetch answers older than `DURATION` again",
+			Value: 7 * 24 * time.Hour,
+		},
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "fetch `N` answers concurrently",
+			Value: 8,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		stale, err := c.StaleProxyLookups(ctx, time.Now().Add(-cmd.Duration("older-than")))
+		if err != nil {
+			return err
+		}
+		stored, err := c.ProxyLookups(ctx)
+		if err != nil {
+			return err
+		}
+		previous := make(map[string]string) // version by path
+		for _, l := range stored {
+			previous[l.Path] = l.Version
+		}
+		var paths []string
+		var expired, superseded int
+		for _, s := range stale {
+			paths = append(paths, s.Path)
+			if s.Expired {
+				expired++
+			}
+			if s.Superseded {
+				superseded++
+			}
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Refreshing %d answers, %d expired and %d with new versions in the index\n", len(paths), expired, superseded)
+
+		type fetched struct {
+			info *goproxy.Info
+			err  error
+		}
+		var lookups []modindex.ProxyLookup
+		var done, changed, gone, failed int
+		forEachParallel(paths, int(cmd.Int("workers")), func(path string) fetched {
+			info, err := proxy.Latest(ctx, path)
+			return fetched{info, err}
+		}, func(path string, f fetched) {
+			done++
+			l := modindex.ProxyLookup{Path: path, CheckedAt: time.Now()}
+			switch {
+			case errors.Is(f.err, goproxy.ErrNotFound):
+				if previous[path] != "" {
+					gone++
+				}
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: not found\n", done, len(paths), path)
+			case f.err != nil:
+				failed++
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error fetching %s: %v\n", done, len(paths), path, f.err)
+				return
+			default:
+				l.Version, l.Time = f.info.Version, f.info.Time
+				l.OriginVCS, l.OriginURL = f.info.Origin.VCS, f.info.Origin.URL
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: %s\n", done, len(paths), path, f.info.Version)
+			}
+			if l.Version != previous[path] {
+				changed++
+			}
+			lookups = append(lookups, l)
+		})
+		if err := c.StoreProxyLookups(ctx, lookups); err != nil {
+			return err
+		}
+		fmt.Printf("Refreshed %d answers, %d changed, %d of which no longer found, %d failed\n", len(lookups), changed, gone, failed)
+		return nil
+	},
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var downloadReleasesCommand = &cli.Command{
+	Name:  "download-releases",
+	Usage: "fetch the release history of all curated packages from the module proxy",
+	Description: "For every curated package, the version list and the .info of every\n" +
+		"version are fetched and stored with the resulting release cadence,\n" +
+		"replacing earlier results.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "fetch `N` packages concurrently",
+			Value: 10,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		modules := curatedModules(lookup)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type fetched struct {
+			releases []modindex.Release
+			err      error
+		}
+		var done int
+		var storeErr error
+		forEachParallel(modules, int(cmd.Int("workers")), func(m string) fetched {
+			releases, err := fetchReleases(ctx, proxy, m)
+			return fetched{releases, err}
+		}, func(m string, f fetched) {
+			done++
+			if storeErr != nil {
+				return
+			}
+			if f.err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error fetching %q: %v\n", done, len(modules), m, f.err)
+				return
+			}
+			cad, err := c.StoreReleases(ctx, m, f.releases)
+			if err != nil {
+				storeErr = fmt.Errorf("store releases of %s: %w", m, err)
+				cancel()
+				return
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: %d releases, %.1f per year\n", done, len(modules), m, cad.Releases, cad.PerYear)
+		})
+		return storeErr
+	},
+}
+
+// fetchReleases returns all tagged versions of module with their publish times.
+func fetchReleases(ctx context.Context, proxy *goproxy.Client, module string) ([]modindex.Release, error) {
+	versions, err := proxy.List(ctx, module)
+	if err != nil {
+		return nil, fmt.Errorf("list versions: %w", err)
+	}
+	releases := make([]modindex.Release, 0, len(versions))
+	for _, v := range versions {
+		info, err := proxy.Info(ctx, module, v)
+		if err != nil {
+			return nil, fmt.Errorf("info of %s: %w", v, err)
+		}
+		releases = append(releases, modindex.Release{Version: info.Version, Time: info.Time})
+	}
+	return releases, nil
+}
+
+var releasesCommand = &cli.Command{
+	Name:      "releases",
+	Usage:     "show the release cadence of curated packages or the releases of one module",
+	ArgsUsage: "[MODULE]",
+	Description: "Release histories are fetched with download-releases. Without MODULE,\n" +
+		"the cadence of all packages is listed, the most frequently released first.",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() > 1 {
+			return fmt.Errorf("expected at most one argument")
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if cmd.Args().Len() == 0 {
+			cads, err := c.Cadences(ctx)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(w, "MODULE\tRELEASES\tPER YEAR\tFIRST\tLAST")
+			for _, cad := range cads {
+				_, _ = fmt.Fprintf(w, "%s\t%d\t%.1f\t%s\t%s\n", cad.Module, cad.Releases, cad.PerYear, formatDate(cad.First), formatDate(cad.Last))
+			}
+			return w.Flush()
+		}
+
+		module := cmd.Args().First()
+		cad, err := c.Cadence(ctx, module)
+		if err != nil {
+			return err
+		}
+		releases, err := c.Releases(ctx, module)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d releases since %s, %.1f per year\n\n", cad.Releases, formatDate(cad.First), cad.PerYear)
+		_, _ = fmt.Fprintln(w, "VERSION\tRELEASED")
+		for _, r := range releases {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", r.Version, formatDate(r.Time))
+		}
+		return w.Flush()
+	},
+}
+
+// forEachParallel calls fn for every item on n goroutines and reports
+// each result to done, which is called on the calling goroutine.
+func forEachParallel[R any](items []string, n int, fn func(string) R, done func(string, R)) {
+	type result struct {
+		item string
+		r    R
+	}
+	todo := make(chan string)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for range max(n, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range todo {
+				results <- result{item, fn(item)}
+			}
+		}()
+	}
+	go func() {
+		for _, item := range items {
+			todo <- item
+		}
+		close(todo)
+		wg.Wait()
+		close(results)
+	}()
+	for r := range results {
+		done(r.item, r.r)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var replaceWithCommand = &cli.Command{
+	Name:      "replace-with",
+	Usage:     "suggest healthier replacements for a dependency of a project",
+	ArgsUsage: "MODULE",
+	Description: "MODULE must be required by the go.mod in --dir. The packages curated\n" +
+		"in its canonical category, that of the source first in --precedence,\n" +
+		"are listed if they score higher. The migration effort is estimated\n" +
+		"from the number of files importing MODULE in the project: low up to 2,\n" +
+		"medium up to 10, high above. To compare API surfaces, the exported\n" +
+		"declarations of the packages the project imports and of the public\n" +
+		"packages of the latest version of each alternative are counted from\n" +
+		"their module zips.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "dir",
+			Usage: "look MODULE up in the project in `DIR`",
+			Value: ".",
+		},
+		&cli.StringSliceFlag{
+			Name:  "precedence",
+			Usage: "take the category of the first of the `SOURCE`s curating MODULE (default: the order sources are loaded in)",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of alternatives, 0 lists all",
+			Value: 5,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one module")
+		}
+		mod := cmd.Args().First()
+		gomod := filepath.Join(cmd.String("dir"), "go.mod")
+		deps, err := readProjectDeps(gomod, false)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(deps, func(d projectDep) bool { return d.path == mod })
+		if i < 0 {
+			return fmt.Errorf("%s is not required by %s", mod, gomod)
+		}
+		dep := deps[i]
+
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		for _, name := range cmd.StringSlice("precedence") {
+			if !slices.ContainsFunc(lookup.Sources, func(s *pkglists.Source) bool { return s.Name == name }) {
+				return fmt.Errorf("unknown source %q in --precedence", name)
+			}
+		}
+		key := packageKey(lookup, mod)
+		links := lookup.Packages[key]
+		if len(links) == 0 {
+			return fmt.Errorf("%s is not curated, so it has no category to find replacements in", mod)
+		}
+		canonical := canonicalLink(lookup, links, cmd.StringSlice("precedence"))
+
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		scores := newScorer(ctx, cmd, lookup, loadGraph(ctx, cmd))
+		score := scores.score(mod)
+		files, imported, err := projectImports(filepath.Dir(gomod), mod)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s %s in %s: score %s\n", mod, dep.version, gomod, score)
+		fmt.Printf("  category: %s > %s\n", canonical.Source.Name, categoryPath(canonical.Category))
+		used := "0"
+		if len(imported) > 0 {
+			used = apiSize(ctx, proxy, dep.path, dep.version, imported)
+		}
+		fmt.Printf("  imported as %d packages in %d files, %s exported declarations\n", len(imported), files, used)
+		fmt.Printf("  migration effort: %s\n\n", migrationEffort(files))
+
+		var alternatives []similarPackage
+		seen := make(map[string]bool) // by project
+		own := packageProject(lookup, key)
+		for _, l := range canonical.Category.Links {
+			other, err := pkglists.Key(l.URL)
+			if err != nil {
+				continue
+			}
+			project := packageProject(lookup, other)
+			if project == own || seen[project] {
+				continue
+			}
+			seen[project] = true
+			s := scores.score(packageModulePath(lookup, other))
+			if !s.Known() || (score.Known() && s.Total <= score.Total) {
+				continue
+			}
+			alternatives = append(alternatives, similarPackage{name: other, description: l.Description, score: s})
+		}
+		slices.SortFunc(alternatives, func(a, b similarPackage) int {
+			return cmp.Or(cmp.Compare(b.score.Total, a.score.Total), strings.Compare(a.name, b.name))
+		})
+		if limit := int(cmd.Int("limit")); limit > 0 {
+			alternatives = alternatives[:min(len(alternatives), limit)]
+		}
+		if len(alternatives) == 0 {
+			fmt.Println("No higher-scored packages are curated in its category.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "SCORE\tAPI\tPACKAGE\tDESCRIPTION")
+		for _, a := range alternatives {
+			module := packageModulePath(lookup, a.name)
+			api := "-"
+			if _, version, err := resolveVersion(ctx, proxy, module); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "skipping API of %s: %v\n", module, err)
+			} else {
+				api = apiSize(ctx, proxy, module, version, nil)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.score, api, a.name, a.description)
+		}
+		return w.Flush()
+	},
+}
+
+// canonicalLink returns the link of links from the source ranked first
+// by sourceRank.
+func canonicalLink(lookup *pkglists.Lookup, links []pkglists.Link, precedence []string) pkglists.Link {
+	rank := sourceRank(lookup, precedence)
+	return slices.MinFunc(links, func(a, b pkglists.Link) int {
+		return cmp.Compare(rank(a.Source), rank(b.Source))
+	})
+}
+
+// projectImports returns the number of Go files of the project in dir
+// importing packages of mod and the import paths of those packages.
+// Nested modules, vendored code, testdata and hidden directories are
+// skipped.
+func projectImports(dir, mod string) (int, []string, error) {
+	var files int
+	var paths []string
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if name := d.Name(); name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil // the go command reports broken files
+		}
+		var imports bool
+		for _, spec := range f.Imports {
+			p, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || (p != mod && !strings.HasPrefix(p, mod+"/")) {
+				continue
+			}
+			imports = true
+			if !slices.Contains(paths, p) {
+				paths = append(paths, p)
+			}
+		}
+		if imports {
+			files++
+		}
+		return nil
+	})
+	slices.Sort(paths)
+	return files, paths, err
+}
+
+// migrationEffort estimates the effort of replacing a dependency that
+// files Go files of a project import.
+func migrationEffort(files int) string {
+	switch {
+	case files == 0:
+		return "none, no file imports it"
+	case files <= 2:
+		return "low"
+	case files <= 10:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// apiSize counts the exported declarations of the packages of mod at
+// version with the import paths in packages, or of all its public
+// packages if packages is nil. It warns and returns "-" if the module
+// zip can't be analyzed.
+func apiSize(ctx context.Context, proxy *goproxy.Client, mod, version string, packages []string) string {
+	data, err := proxy.Zip(ctx, mod, version)
+	var apis []modindex.PackageAPI
+	if err == nil {
+		apis, err = modindex.AnalyzeAPI(mod, version, data)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "skipping API of %s: %v\n", mod, err)
+		return "-"
+	}
+	var n int
+	for _, p := range apis {
+		if (packages == nil && !p.Internal && p.Name != "main") || slices.Contains(packages, p.ImportPath) {
+			n += p.Exported()
+		}
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var gitlabTokenFlag = &cli.StringFlag{
+	Name:    "gitlab-token",
+	Usage:   "authenticate to the GitLab API with `TOKEN`",
+	Sources: cli.EnvVars("GITLAB_TOKEN"),
+}
+
+var gitlabURLFlag = &cli.StringFlag{
+	Name:    "gitlab-url",
+	Usage:   "talk to the GitLab instance at `URL` for gitlab.com repositories",
+	Value:   forge.GitLabURL,
+	Sources: cli.EnvVars("GITLAB_URL"),
+}
+
+var sourcehutTokenFlag = &cli.StringFlag{
+	Name:    "sourcehut-token",
+	Usage:   "authenticate to the sourcehut API with `TOKEN`, which it requires",
+	Sources: cli.EnvVars("SRHT_TOKEN"),
+}
+
+// giteaHosts are the hosts running Gitea or its fork Forgejo.
+var giteaHosts = []string{"codeberg.org", "gitea.com"}
+
+// forgeClient returns the client for the forge at host, configured by
+// the flags of the forge.
+func forgeClient(cmd *cli.Command, host string) (forge.Client, error) {
+	switch host {
+	case "github.com":
+		client, err := newGitHubClient(cmd)
+		if err != nil {
+			return nil, err
+		}
+		return forge.NewGitHub(client), nil
+	case "gitlab.com":
+		return forge.NewGitLab(cmd.String(gitlabURLFlag.Name), strings.TrimSpace(cmd.String(gitlabTokenFlag.Name)), nil), nil
+	case "git.sr.ht":
+		token := strings.TrimSpace(cmd.String(sourcehutTokenFlag.Name))
+		if token == "" {
+			return nil, fmt.Errorf("the sourcehut API requires a token (see --sourcehut-token)")
+		}
+		return forge.NewSourceHut(forge.SourceHutURL, token, nil), nil
+	case "bitbucket.org":
+		return forge.NewBitbucket(forge.BitbucketURL, nil), nil
+	}
+	if slices.Contains(giteaHosts, host) {
+		return forge.NewGitea("https://"+host, nil), nil
+	}
+	return nil, fmt.Errorf("%w: %s", errUnsupportedForge, host)
+}
+
+var errUnsupportedForge = errors.New("unsupported forge")
+
+var repoCommand = &cli.Command{
+	Name:      "repo",
+	Usage:     "show the repository metadata of a curated package on any supported forge",
+	ArgsUsage: "PACKAGE",
+	Flags:     []cli.Flag{githubTokenFlag, githubAPIURLFlag, verboseFlag, gitlabTokenFlag, gitlabURLFlag, sourcehutTokenFlag},
+	Commands: []*cli.Command{
+		repoSyncCommand,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		name := packageKey(lookup, cmd.Args().First())
+		links, ok := lookup.Packages[name]
+		if !ok {
+			return fmt.Errorf("package %s not found", name)
+		}
+		loc, err := forge.ParseURL(links[0].URL)
+		if err != nil {
+			return err
+		}
+		client, err := forgeClient(cmd, loc.Host)
+		if err != nil {
+			return err
+		}
+		r, err := client.Repo(ctx, loc.Name)
+		if err != nil {
+			return fmt.Errorf("get repository: %w", err)
+		}
+		fmt.Println("Repo:", loc.Host+"/"+r.Name)
+		if loc.Dir != "" {
+			fmt.Printf("Directory: %s (on %s), imported as %s\n", loc.Dir, orDash(loc.Ref), loc.Path())
+		}
+		fmt.Println("Description:", r.Description)
+		fmt.Println("Stars:", r.Stars)
+		fmt.Println("Forks:", r.Forks)
+		fmt.Println("Archived:", r.Archived)
+		fmt.Println("Last activity:", formatDate(r.PushedAt))
+		fmt.Println("License:", orDash(r.License))
+		fmt.Println("Topics:", strings.Join(r.Topics, " "))
+		fmt.Println("Latest release:", orDash(r.LatestRelease), formatDate(r.LatestReleaseAt))
+		return nil
+	},
+}
+
+var repoSyncCommand = &cli.Command{
+	Name:  "sync",
+	Usage: "fetch and store the repository metadata of curated packages hosted outside of GitHub",
+	Description: "GitHub repositories are synced by \"modhunt github sync\", which batches\n" +
+		"requests. Repositories fetched within --ttl are skipped.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "fetch `N` repositories concurrently",
+			Value: 4,
+		},
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Usage: "skip repositories fetched within `DURATION`",
+			Value: 24 * time.Hour,
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "fetch all repositories regardless of --ttl",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		stored, err := c.Repos(ctx)
+		if err != nil {
+			return err
+		}
+		fresh := make(map[string]bool)
+		for _, r := range stored {
+			if time.Since(r.FetchedAt) < cmd.Duration("ttl") {
+				fresh[r.Host+"/"+r.Name] = true
+			}
+		}
+		clients := make(map[string]forge.Client)
+		skipped := make(map[string]bool)
+		var repos []string // host/name
+		for _, repo := range curatedForgeRepos(lookup) {
+			host, _, _ := strings.Cut(repo, "/")
+			if host == "github.com" || !cmd.Bool("force") && fresh[repo] {
+				continue
+			}
+			if _, ok := clients[host]; !ok {
+				if skipped[host] {
+					continue
+				}
+				client, err := forgeClient(cmd, host)
+				if err != nil {
+					skipped[host] = true
+					if !errors.Is(err, errUnsupportedForge) {
+						_, _ = fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", host, err)
+					}
+					continue
+				}
+				clients[host] = client
+			}
+			repos = append(repos, repo)
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Fetching %d repositories from %d forges\n", len(repos), len(clients))
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type fetched struct {
+			repo *forge.Repo
+			err  error
+		}
+		var done, n, missing int
+		var storeErr error
+		forEachParallel(repos, int(cmd.Int("workers")), func(repo string) fetched {
+			host, name, _ := strings.Cut(repo, "/")
+			r, err := clients[host].Repo(ctx, name)
+			return fetched{r, err}
+		}, func(repo string, f fetched) {
+			host, _, _ := strings.Cut(repo, "/")
+			done++
+			if storeErr != nil {
+				return
+			}
+			if errors.Is(f.err, forge.ErrNotFound) {
+				missing++
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: not found\n", done, len(repos), repo)
+				return
+			}
+			if f.err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error fetching %q: %v\n", done, len(repos), repo, f.err)
+				return
+			}
+			if err := c.StoreRepo(ctx, storedRepo(host, f.repo)); err != nil {
+				storeErr = fmt.Errorf("store repository %s: %w", repo, err)
+				cancel()
+				return
+			}
+			n++
+			_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: %d stars\n", done, len(repos), repo, f.repo.Stars)
+		})
+		if storeErr != nil {
+			return storeErr
+		}
+		if err := storeModuleTopics(ctx, c, lookup); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %d repositories, %d were not found\n", n, missing)
+		return nil
+	},
+}
+
+// curatedForgeRepos returns the "host/name" of the repositories of all
+// curated packages, sorted.
+func curatedForgeRepos(lookup *pkglists.Lookup) []string {
+	var repos []string
+	for _, links := range lookup.Packages {
+		for _, link := range links {
+			loc, err := forge.ParseURL(link.URL)
+			if err != nil {
+				continue
+			}
+			repos = append(repos, loc.Host+"/"+loc.Name)
+		}
+	}
+	slices.Sort(repos)
+	return slices.Compact(repos)
+}
+
+// storedRepo converts r on the forge at host into the model of the index.
+func storedRepo(host string, r *forge.Repo) modindex.Repo {
+	return modindex.Repo{
+		Host:        host,
+		Name:        r.Name,
+		Description: r.Description,
+		Stars:       r.Stars,
+		Forks:       r.Forks,
+		Archived:    r.Archived,
+		PushedAt:    r.PushedAt,
+		License:     r.License,
+		Topics:      r.Topics,
+
+		LatestRelease:   r.LatestRelease,
+		LatestReleaseAt: r.LatestReleaseAt,
+
+		FetchedAt: time.Now(),
+	}
+}
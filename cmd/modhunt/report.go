@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"math"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var reportCommand = &cli.Command{
+	Name:  "report",
+	Usage: "render a report on the state of the curated Go ecosystem",
+	Description: "The report covers the sizes of categories, how long ago curated\n" +
+		"packages were last pushed to or released, the licenses of their\n" +
+		"repositories, the hosts they live on, the packages that look dead,\n" +
+		"judged like 'dead' does, and the modules trending in the index, ranked\n" +
+		"like 'trending' does. It is rendered from the data collected by\n" +
+		"'enrich' and 'index sync'; sections without data say so.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "render the report as `FORMAT`, markdown or html",
+			Value: "markdown",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write the report to `FILE` instead of standard output",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "list `N` entries in every ranking",
+			Value: 15,
+		},
+		&cli.StringFlag{
+			Name:  "window",
+			Usage: "rank the modules trending within the last `AGE` (e.g. 30d)",
+			Value: "30d",
+		},
+		yearsFlag,
+		releaseYearsFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		format := cmd.String("format")
+		if format != "markdown" && format != "html" {
+			return fmt.Errorf("invalid --format %q, want markdown or html", format)
+		}
+		window, err := parseAge(cmd.String("window"))
+		if err != nil || window <= 0 {
+			return fmt.Errorf("invalid --window %q", cmd.String("window"))
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		r := &ecosystemReport{lookup: lookup, limit: int(cmd.Int("limit")), now: time.Now()}
+		if r.st, err = loadStaleness(ctx, cmd, c, lookup); err != nil {
+			return err
+		}
+		if r.trending, err = loadTrending(ctx, c, window); err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		r.render(&buf)
+		out := buf.Bytes()
+		if format == "html" {
+			if out, err = markdownToHTML(out, "State of the curated Go ecosystem"); err != nil {
+				return err
+			}
+		}
+		if path := cmd.String("output"); path != "" {
+			return os.WriteFile(path, out, 0o644)
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+// ecosystemReport is the data a report is rendered from.
+type ecosystemReport struct {
+	lookup   *pkglists.Lookup
+	st       *staleness
+	trending []trendingModule // nil if the index is empty
+	limit    int
+	now      time.Time
+}
+
+// render writes the report as Markdown to w.
+func (r *ecosystemReport) render(w io.Writer) {
+	var categories int
+	for _, s := range r.lookup.Sources {
+		walkCategories(s.Root, s.Name, func(*pkglists.Category, string) { categories++ })
+	}
+	_, _ = fmt.Fprintf(w, "# State of the curated Go ecosystem\n\n")
+	_, _ = fmt.Fprintf(w, "As of %s, %d sources curate %d packages in %d categories.\n",
+		r.now.Format(time.DateOnly), len(r.lookup.Sources), len(r.lookup.Packages), categories)
+
+	r.renderCategories(w)
+	r.renderStaleness(w)
+	r.renderLicenses(w)
+	r.renderDomains(w)
+	r.renderDead(w)
+	r.renderTrending(w)
+}
+
+func (r *ecosystemReport) renderCategories(w io.Writer) {
+	type category struct {
+		path    string
+		entries int
+	}
+	var cats []category
+	for _, s := range r.lookup.Sources {
+		walkCategories(s.Root, s.Name, func(cat *pkglists.Category, path string) {
+			cats = append(cats, category{path, len(cat.Links)})
+		})
+	}
+	slices.SortFunc(cats, func(a, b category) int {
+		return cmp.Or(cmp.Compare(b.entries, a.entries), strings.Compare(a.path, b.path))
+	})
+	_, _ = fmt.Fprintf(w, "\n## Largest categories\n\n")
+	_, _ = fmt.Fprintf(w, "| Category | Entries |\n|---|---:|\n")
+	for _, c := range cats[:min(len(cats), r.limit)] {
+		_, _ = fmt.Fprintf(w, "| %s | %d |\n", markdownCell(c.path), c.entries)
+	}
+}
+
+// staleBucket counts the packages last active less than years ago and
+// not counted by the bucket before.
+type staleBucket struct {
+	label string
+	years float64
+}
+
+var staleBuckets = []staleBucket{
+	{"within 6 months", 0.5},
+	{"6 to 12 months ago", 1},
+	{"1 to 2 years ago", 2},
+	{"2 to 3 years ago", 3},
+	{"more than 3 years ago", math.Inf(1)},
+}
+
+func (r *ecosystemReport) renderStaleness(w io.Writer) {
+	counts := make([]int, len(staleBuckets))
+	var unknown int
+	for key, links := range r.lookup.Packages {
+		_, repo, cad, known := r.st.reasons(key, links)
+		last := repo.PushedAt
+		if cad.Last.After(last) {
+			last = cad.Last
+		}
+		if !known || last.IsZero() {
+			unknown++
+			continue
+		}
+		age := r.now.Sub(last).Hours() / (365 * 24)
+		counts[slices.IndexFunc(staleBuckets, func(b staleBucket) bool { return age < b.years })]++
+	}
+	_, _ = fmt.Fprintf(w, "\n## Last activity\n\n")
+	_, _ = fmt.Fprintf(w, "The last push to the repository or the last release, whichever is newer.\n\n")
+	_, _ = fmt.Fprintf(w, "| Last activity | Packages | Share |\n|---|---:|---:|\n")
+	for i, b := range staleBuckets {
+		_, _ = fmt.Fprintf(w, "| %s | %d | %s |\n", b.label, counts[i], share(counts[i], len(r.lookup.Packages)))
+	}
+	_, _ = fmt.Fprintf(w, "| unknown | %d | %s |\n", unknown, share(unknown, len(r.lookup.Packages)))
+}
+
+func (r *ecosystemReport) renderLicenses(w io.Writer) {
+	licenses := make(map[string]int)
+	var repos int
+	seen := make(map[string]bool)
+	for key := range r.lookup.Packages {
+		loc, ok := packageRepo(r.lookup, key)
+		if !ok {
+			continue
+		}
+		id := repoKey(loc.Host, loc.Name)
+		repo, ok := r.st.repos[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		repos++
+		licenses[cmp.Or(repo.License, "none detected")]++
+	}
+	_, _ = fmt.Fprintf(w, "\n## Licenses\n\n")
+	if repos == 0 {
+		_, _ = fmt.Fprintf(w, "No repository metadata was collected yet.\n")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "| License | Repositories | Share |\n|---|---:|---:|\n")
+	for _, l := range rankCounts(licenses, r.limit) {
+		_, _ = fmt.Fprintf(w, "| %s | %d | %s |\n", markdownCell(l), licenses[l], share(licenses[l], repos))
+	}
+}
+
+func (r *ecosystemReport) renderDomains(w io.Writer) {
+	domains := make(map[string]int)
+	for _, links := range r.lookup.Packages {
+		u, err := url.Parse(links[0].URL)
+		if err != nil {
+			continue
+		}
+		domains[strings.TrimPrefix(u.Host, "www.")]++
+	}
+	_, _ = fmt.Fprintf(w, "\n## Most popular hosts\n\n")
+	_, _ = fmt.Fprintf(w, "| Host | Packages | Share |\n|---|---:|---:|\n")
+	for _, d := range rankCounts(domains, r.limit) {
+		_, _ = fmt.Fprintf(w, "| %s | %d | %s |\n", markdownCell(d), domains[d], share(domains[d], len(r.lookup.Packages)))
+	}
+}
+
+func (r *ecosystemReport) renderDead(w io.Writer) {
+	type entry struct {
+		pkg     string
+		reasons []string
+	}
+	var entries []entry
+	for key, links := range r.lookup.Packages {
+		if reasons, _, _, _ := r.st.reasons(key, links); len(reasons) > 0 {
+			entries = append(entries, entry{key, reasons})
+		}
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Or(cmp.Compare(len(b.reasons), len(a.reasons)), strings.Compare(a.pkg, b.pkg))
+	})
+	_, _ = fmt.Fprintf(w, "\n## Dead entry candidates\n\n")
+	_, _ = fmt.Fprintf(w, "%d of %d packages look archived or abandoned", len(entries), len(r.lookup.Packages))
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintf(w, ".\n")
+		return
+	}
+	_, _ = fmt.Fprintf(w, ", those with the most reasons are:\n\n")
+	_, _ = fmt.Fprintf(w, "| Package | Reasons |\n|---|---|\n")
+	for _, e := range entries[:min(len(entries), r.limit)] {
+		_, _ = fmt.Fprintf(w, "| %s | %s |\n", markdownCell(e.pkg), markdownCell(strings.Join(e.reasons, ", ")))
+	}
+}
+
+func (r *ecosystemReport) renderTrending(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "\n## Trending modules\n\n")
+	if r.trending == nil {
+		_, _ = fmt.Fprintf(w, "The module index was not synchronized yet.\n")
+		return
+	}
+	curated := make(map[string]bool, len(r.lookup.Packages))
+	for key := range r.lookup.Packages {
+		curated[modname.Normalize(packageModulePath(r.lookup, key))] = true
+	}
+	_, _ = fmt.Fprintf(w, "Modules released more often than in the year before.\n\n")
+	_, _ = fmt.Fprintf(w, "| Module | Releases | Year before | First seen | Curated |\n|---|---:|---:|---|---|\n")
+	for _, m := range r.trending[:min(len(r.trending), r.limit)] {
+		_, _ = fmt.Fprintf(w, "| %s | %d | %d | %s | %s |\n", markdownCell(m.Path), m.Recent, m.Before, formatDate(m.FirstSeen),
+			curatedMark(curated[modname.Normalize(m.Path)]))
+	}
+}
+
+// loadTrending ranks the modules released at least twice within the
+// window ending with the newest version in the index. It returns nil if
+// the index is empty.
+func loadTrending(ctx context.Context, c *modindex.Client, window time.Duration) ([]trendingModule, error) {
+	end, err := c.Newest(ctx)
+	if err != nil || end.IsZero() {
+		return nil, err
+	}
+	activity, err := c.Activity(ctx, end.Add(-window), end)
+	if err != nil {
+		return nil, err
+	}
+	modules := []trendingModule{}
+	for _, a := range activity {
+		if a.Recent >= 2 {
+			modules = append(modules, newTrendingModule(a, window, end))
+		}
+	}
+	sortTrending(modules)
+	return modules, nil
+}
+
+// rankCounts returns the up to limit keys of counts with the highest
+// counts.
+func rankCounts(counts map[string]int, limit int) []string {
+	keys := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a, b))
+	})
+	return keys[:min(len(keys), limit)]
+}
+
+// share formats n of total as percentage.
+func share(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)/float64(total)*100)
+}
+
+// markdownCell escapes s for a cell of a Markdown table.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// markdownToHTML renders the Markdown md as standalone HTML document.
+func markdownToHTML(md []byte, title string) ([]byte, error) {
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n", html.EscapeString(title))
+	if err := goldmark.New(goldmark.WithExtensions(extension.Table)).Convert(md, &buf); err != nil {
+		return nil, fmt.Errorf("render HTML: %w", err)
+	}
+	buf.WriteString("</body>\n</html>\n")
+	return buf.Bytes(), nil
+}
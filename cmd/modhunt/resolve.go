@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/modhunter"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkgsite"
+)
+
+var resolveCommand = &cli.Command{
+	Name:      "resolve",
+	Usage:     "find the module of a repository URL, import path or package path",
+	ArgsUsage: "URL-OR-PATH...",
+	Description: "Every argument is searched for like 'links sync' does: as spelled,\n" +
+		"lower-cased, rewritten to gopkg.in, climbing to its parent paths,\n" +
+		"following its go-import meta tag, trying its major versions and following\n" +
+		"the redirect of a repository that moved. The module the module proxy\n" +
+		"knows is printed with the strategy that found it, its latest version and\n" +
+		"the repository it was built from. With --json, one JSON object is printed\n" +
+		"per argument. If the index is usable, answers of the proxy and origins\n" +
+		"stored by 'links sync' are reused.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print JSON objects",
+		},
+		&cli.BoolFlag{
+			Name:  "explain",
+			Usage: "print what was tried for arguments that resolved to no module",
+		},
+		&cli.BoolFlag{
+			Name:  "pkgsite",
+			Usage: "ask pkg.go.dev for the new path of modules the proxy does not know",
+		},
+		&cli.BoolFlag{
+			Name:  "list-repos",
+			Usage: "list the repositories of arguments without module for go.mod files in subdirectories",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "give up a strategy or proxy request after `DURATION`",
+			Value: 30 * time.Second,
+		},
+		pkgsiteURLFlag,
+		lookupTTLFlag,
+		negativeLookupTTLFlag,
+		githubTokenFlag,
+		githubAPIURLFlag,
+		gitlabTokenFlag,
+		gitlabURLFlag,
+		verboseFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() == 0 {
+			return fmt.Errorf("expected at least one argument")
+		}
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		opts := []modhunter.Option{modhunter.WithTimeout(cmd.Duration("timeout"))}
+		if cmd.Bool("pkgsite") {
+			opts = append(opts, modhunter.WithPkgsite(pkgsite.New(cmd.String(pkgsiteURLFlag.Name))))
+		}
+		if cmd.Bool("list-repos") {
+			opts = append(opts, repoFilesOption(cmd))
+		}
+		// The index only speeds up resolving, so it is not required.
+		var cache *modhunter.MemoryCache
+		c, err := openIndex(ctx, cmd)
+		if err == nil {
+			defer c.Close()
+			if links, err := c.LinkModules(ctx); err == nil {
+				origins := linkOrigins(links)
+				opts = append(opts, modhunter.WithOrigins(func(path string) string { return origins[path] }))
+			}
+			var cacheOpt modhunter.Option
+			if cache, cacheOpt, err = loadLookupCache(ctx, cmd, c); err == nil {
+				opts = append(opts, cacheOpt)
+			}
+		}
+		hunter := modhunter.New(proxy, opts...)
+
+		var failed int
+		enc := json.NewEncoder(os.Stdout)
+		for i, arg := range cmd.Args().Slice() {
+			r := resolve(ctx, hunter, arg)
+			if r.Error != "" {
+				failed++
+			}
+			if cmd.Bool("explain") {
+				explainSearch(r.err)
+			}
+			if cmd.Bool("json") {
+				if err := enc.Encode(r); err != nil {
+					return err
+				}
+				continue
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			if err := printResolution(r); err != nil {
+				return err
+			}
+		}
+		if cache != nil {
+			if err := storeLookupCache(ctx, c, cache); err != nil {
+				return err
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d arguments not resolved", failed, cmd.Args().Len())
+		}
+		return nil
+	},
+}
+
+// resolution is the outcome of resolving an argument of 'resolve'.
+type resolution struct {
+	Input     string     `json:"input"`
+	Path      string     `json:"path,omitempty"`
+	Module    string     `json:"module,omitempty"`
+	Strategy  string     `json:"strategy,omitempty"`
+	Moved     bool       `json:"moved,omitempty"`
+	Version   string     `json:"version,omitempty"`
+	Time      *time.Time `json:"time,omitempty"`
+	VCS       string     `json:"vcs,omitempty"`
+	Origin    string     `json:"origin,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Retryable bool       `json:"retryable,omitempty"`
+
+	err error
+}
+
+// resolve searches for the module of the URL or path arg.
+func resolve(ctx context.Context, hunter *modhunter.Hunter, arg string) resolution {
+	r := resolution{Input: arg}
+	path, err := modname.FromRepoURL(arg)
+	if err != nil {
+		r.Error, r.err = err.Error(), err
+		return r
+	}
+	r.Path = path
+	res, err := hunter.Search(ctx, path)
+	if err != nil {
+		r.Error, r.err = err.Error(), err
+		var se *modhunter.SearchError
+		r.Retryable = !errors.As(err, &se) || se.Retryable()
+		return r
+	}
+	r.Module, r.Strategy, r.Moved = res.Module, res.Strategy, res.Moved()
+	r.Version, r.Time = res.Info.Version, &res.Info.Time
+	r.VCS, r.Origin = res.Info.Origin.VCS, res.Info.Origin.URL
+	return r
+}
+
+func printResolution(r resolution) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "input:\t%s\n", r.Input)
+	if r.Path != "" && r.Path != r.Input {
+		_, _ = fmt.Fprintf(w, "path:\t%s\n", r.Path)
+	}
+	if r.Error != "" {
+		note := ""
+		if r.Retryable {
+			note = " (retryable)"
+		}
+		_, _ = fmt.Fprintf(w, "error:\t%s%s\n", r.Error, note)
+		return w.Flush()
+	}
+	module := r.Module
+	if r.Moved {
+		module += " (moved)"
+	}
+	_, _ = fmt.Fprintf(w, "module:\t%s\n", module)
+	_, _ = fmt.Fprintf(w, "strategy:\t%s\n", r.Strategy)
+	_, _ = fmt.Fprintf(w, "latest:\t%s (%s)\n", r.Version, formatDate(*r.Time))
+	origin := orDash(r.Origin)
+	if r.VCS != "" && r.Origin != "" {
+		origin = r.VCS + " " + r.Origin
+	}
+	_, _ = fmt.Fprintf(w, "origin:\t%s\n", origin)
+	return w.Flush()
+}
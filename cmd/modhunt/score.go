@@ -0,0 +1,352 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+	"github.com/ngrash/modhunt/modscore"
+)
+
+var scoreCommand = &cli.Command{
+	Name:      "score",
+	Usage:     "explain the health score of curated modules",
+	ArgsUsage: "MODULE...",
+	Description: "Scores combine the release history fetched with download-releases,\n" +
+		"the repository metadata of 'github sync' and 'repo sync', the issues\n" +
+		"sampled by 'github issues sync', the dependency graph of 'deps sync'\n" +
+		"and, with --vulns, advisories from OSV.dev. Signals that were not\n" +
+		"collected do not count.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "vulns",
+			Usage: "count the advisories affecting the latest versions",
+		},
+		osvURLFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() == 0 {
+			return fmt.Errorf("expected at least one module")
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		modules := cmd.Args().Slice()
+		s := newScorer(ctx, cmd, lookup, loadGraph(ctx, cmd))
+		if cmd.Bool("vulns") {
+			if err := s.loadVulns(ctx, cmd, modules); err != nil {
+				return err
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i, m := range modules {
+			if i > 0 {
+				_, _ = fmt.Fprintln(w)
+			}
+			score := s.score(m)
+			_, _ = fmt.Fprintf(w, "%s\tscore %s", m, score)
+			if score.Cap != "" {
+				_, _ = fmt.Fprintf(w, " (capped, %s)", score.Cap)
+			}
+			_, _ = fmt.Fprintln(w)
+			for _, f := range score.Factors {
+				value := "-"
+				if f.Known {
+					value = fmt.Sprintf("%.0f%%", f.Value*100)
+				}
+				_, _ = fmt.Fprintf(w, "  %s\t%.0f\t%s\t%s\n", f.Name, f.Weight, value, f.Detail)
+			}
+		}
+		return w.Flush()
+	},
+}
+
+// scorer computes the health scores of curated modules from the data
+// stored in the index.
+type scorer struct {
+	graph    graphInfo
+	repos    map[string]modindex.Repo // by module path of curated packages
+	byName   map[string]modindex.Repo // by host and lower case name
+	cadences map[string]modindex.Cadence
+	cards    map[string]modindex.Scorecard  // by module path of curated packages
+	issues   map[string]modindex.IssueStats // by module path of curated packages
+	vulns    map[string]moduleVulns         // nil unless loaded
+	now      time.Time
+}
+
+// newScorer loads the stored signals of the modules of curated packages.
+// Without a usable database, it warns and scores with what is known.
+func newScorer(ctx context.Context, cmd *cli.Command, lookup *pkglists.Lookup, graph graphInfo) *scorer {
+	s := &scorer{
+		graph:    graph,
+		repos:    make(map[string]modindex.Repo),
+		byName:   make(map[string]modindex.Repo),
+		cadences: make(map[string]modindex.Cadence),
+		cards:    make(map[string]modindex.Scorecard),
+		issues:   make(map[string]modindex.IssueStats),
+		now:      time.Now(),
+	}
+	c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "scoring without index: %v\n", err)
+		return s
+	}
+	defer c.Close()
+
+	repos, err := c.Repos(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "scoring without repositories: %v\n", err)
+	}
+	for _, r := range repos {
+		s.byName[repoKey(r.Host, r.Name)] = r
+	}
+	cards, err := c.Scorecards(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "scoring without scorecards: %v\n", err)
+	}
+	cardsByName := make(map[string]modindex.Scorecard, len(cards))
+	for _, sc := range cards {
+		cardsByName[repoKey(sc.Host, sc.Name)] = sc
+	}
+	issues, err := c.IssueStats(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "scoring without issues: %v\n", err)
+	}
+	issuesByName := make(map[string]modindex.IssueStats, len(issues))
+	for _, is := range issues {
+		issuesByName[repoKey(is.Host, is.Name)] = is
+	}
+	for name := range lookup.Packages {
+		loc, ok := packageRepo(lookup, name)
+		if !ok {
+			continue
+		}
+		if r, ok := s.byName[repoKey(loc.Host, loc.Name)]; ok {
+			s.repos[packageModulePath(lookup, name)] = r
+		}
+		if sc, ok := cardsByName[repoKey(loc.Host, loc.Name)]; ok {
+			s.cards[packageModulePath(lookup, name)] = sc
+		}
+		if is, ok := issuesByName[repoKey(loc.Host, loc.Name)]; ok {
+			s.issues[packageModulePath(lookup, name)] = is
+		}
+	}
+
+	cads, err := c.Cadences(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "scoring without releases: %v\n", err)
+	}
+	for _, cad := range cads {
+		s.cadences[cad.Module] = cad
+	}
+	return s
+}
+
+// loadVulns counts the advisories affecting the latest versions of modules.
+func (s *scorer) loadVulns(ctx context.Context, cmd *cli.Command, modules []string) error {
+	proxy, err := newProxyClient(cmd)
+	if err != nil {
+		return err
+	}
+	versions := make(map[string]string)
+	for m, info := range latestVersions(ctx, proxy, modules) {
+		versions[m] = info.Version
+	}
+	s.vulns = vulnCounts(ctx, cmd, versions)
+	return nil
+}
+
+// repo returns the stored repository of module. Modules that are not
+// curated are looked up by their path, which names the repository for
+// most modules hosted on a forge.
+func (s *scorer) repo(module string) (modindex.Repo, bool) {
+	if r, ok := s.repos[module]; ok {
+		return r, true
+	}
+	loc, err := forge.ParseURL("https://" + modname.RepoPath(module))
+	if err != nil {
+		return modindex.Repo{}, false
+	}
+	r, ok := s.byName[repoKey(loc.Host, loc.Name)]
+	return r, ok
+}
+
+// knows reports whether the release history, repository or go.mod of
+// module is stored. Scores of other modules only reflect that nothing
+// requires them.
+func (s *scorer) knows(module string) bool {
+	_, cadence := s.cadences[module]
+	_, repo := s.repo(module)
+	_, gomod := s.graph.goVersions[module]
+	return cadence || repo || gomod
+}
+
+// scorecardScore formats the OpenSSF Scorecard score of the repository
+// of the curated module, or "-" if none is stored.
+func (s *scorer) scorecardScore(module string) string {
+	if sc, ok := s.cards[module]; ok {
+		return fmt.Sprintf("%.1f/10", sc.Score)
+	}
+	return "-"
+}
+
+// score returns the health score of module.
+func (s *scorer) score(module string) modscore.Score {
+	var sig modscore.Signals
+	if cad, ok := s.cadences[module]; ok {
+		sig.HasReleases = true
+		sig.LastRelease, sig.ReleasesPerYear = cad.Last, cad.PerYear
+	}
+	if r, ok := s.repo(module); ok {
+		sig.HasRepo = true
+		sig.Stars, sig.Archived, sig.BusFactor = r.Stars, r.Archived, r.BusFactor
+	}
+	if s.graph.importedBy != nil {
+		sig.HasGraph = true
+		sig.ImportedBy = s.graph.importedBy[module]
+		_, sig.Deprecated = s.graph.deprecated[module]
+	}
+	if v, ok := s.vulns[module]; ok {
+		sig.HasVulns = true
+		sig.Vulns = v.count
+	}
+	if is, ok := s.issues[module]; ok {
+		sig.HasIssues = true
+		sig.IssuesSampled, sig.IssuesAnswered, sig.MedianResponse = is.Sampled, is.Responded, is.MedianResponse
+	}
+	return modscore.Compute(sig, s.now)
+}
+
+// sharedCategories counts the categories of links, the curated links of
+// the package name, that other packages are curated in as well.
+func sharedCategories(links []pkglists.Link, name string) map[string]int {
+	shared := make(map[string]int) // by package
+	for _, l := range links {
+		seen := make(map[string]bool) // a package linked twice in a category
+		for _, other := range l.Category.Links {
+			key, err := pkglists.Key(other.URL)
+			if err != nil || key == name || seen[key] {
+				continue
+			}
+			seen[key] = true
+			shared[key]++
+		}
+	}
+	return shared
+}
+
+// sharedByProject merges the packages in shared that are major versions
+// of the same project into the one with the smallest key, keeping the
+// highest count, and drops those of the project of the package name.
+func sharedByProject(lookup *pkglists.Lookup, shared map[string]int, name string) map[string]int {
+	own := packageProject(lookup, name)
+	keys := make(map[string]string) // by project
+	counts := make(map[string]int)  // by project
+	for key, n := range shared {
+		project := packageProject(lookup, key)
+		if project == own {
+			continue
+		}
+		if prev, ok := keys[project]; !ok || key < prev {
+			keys[project] = key
+		}
+		counts[project] = max(counts[project], n)
+	}
+	merged := make(map[string]int, len(keys))
+	for project, key := range keys {
+		merged[key] = counts[project]
+	}
+	return merged
+}
+
+// sharedTopics counts the forge topics of the package name that other
+// curated packages are tagged with as well.
+func sharedTopics(lookup *pkglists.Lookup, graph graphInfo, name string) map[string]int {
+	shared := make(map[string]int) // by package
+	topics := graph.topics[packageModulePath(lookup, name)]
+	if len(topics) == 0 {
+		return shared
+	}
+	for key := range lookup.Packages {
+		if key == name {
+			continue
+		}
+		for _, t := range graph.topics[packageModulePath(lookup, key)] {
+			if slices.Contains(topics, t) {
+				shared[key]++
+			}
+		}
+	}
+	return shared
+}
+
+// similarPackage is a curated package ranked by rankSimilar.
+type similarPackage struct {
+	name        string
+	description string
+	shared      int // categories and topics shared
+	score       modscore.Score
+}
+
+// rankSimilar orders the packages in shared by the number of categories
+// and topics they share, then by score. Packages scoring below minScore
+// are left out, unless minScore is 0; limit, if not 0, caps the result.
+func rankSimilar(lookup *pkglists.Lookup, scores *scorer, shared map[string]int, minScore, limit int) []similarPackage {
+	var ranked []similarPackage
+	for key, n := range shared {
+		p := similarPackage{name: key, shared: n, score: scores.score(packageModulePath(lookup, key))}
+		if minScore > 0 && (!p.score.Known() || p.score.Total < minScore) {
+			continue
+		}
+		if links := lookup.Packages[key]; len(links) > 0 {
+			p.description = links[0].Description
+		}
+		ranked = append(ranked, p)
+	}
+	slices.SortFunc(ranked, func(a, b similarPackage) int {
+		return cmp.Or(
+			cmp.Compare(b.shared, a.shared),
+			cmp.Compare(b.score.Total, a.score.Total),
+			strings.Compare(a.name, b.name),
+		)
+	})
+	if limit > 0 {
+		ranked = ranked[:min(len(ranked), limit)]
+	}
+	return ranked
+}
+
+// dependentOverlap returns, for every module required together with
+// module in the dependency graph, the Jaccard index of their dependents.
+// Without a usable database, it warns and returns nil.
+func dependentOverlap(ctx context.Context, cmd *cli.Command, module string) map[string]float64 {
+	c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "skipping dependent overlap: %v\n", err)
+		return nil
+	}
+	defer c.Close()
+	dependents, overlaps, err := c.DependentOverlap(ctx, module)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "skipping dependent overlap: %v\n", err)
+		return nil
+	}
+	jaccard := make(map[string]float64, len(overlaps))
+	for _, o := range overlaps {
+		jaccard[o.Module] = o.Jaccard(dependents)
+	}
+	return jaccard
+}
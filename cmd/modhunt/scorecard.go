@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/scorecard"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var scorecardURLFlag = &cli.StringFlag{
+	Name:  "scorecard-url",
+	Usage: "fetch OpenSSF Scorecard results from the API at `URL`",
+	Value: scorecard.DefaultURL,
+}
+
+var scorecardCommand = &cli.Command{
+	Name:      "scorecard",
+	Usage:     "show the stored OpenSSF Scorecard result of a curated package or module",
+	ArgsUsage: "PACKAGE|MODULE",
+	Description: "OpenSSF Scorecard rates the supply-chain security practices of GitHub\n" +
+		"repositories, e.g. whether branches are protected and dependencies\n" +
+		"pinned, from 0 to 10. Results are fetched by 'scorecard sync'.",
+	Commands: []*cli.Command{
+		scorecardSyncCommand,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		loc, ok := packageRepo(lookup, packageKey(lookup, cmd.Args().First()))
+		if !ok {
+			if loc, err = forge.ParseURL("https://" + modname.RepoPath(cmd.Args().First())); err != nil {
+				return fmt.Errorf("repository of %s: %w", cmd.Args().First(), err)
+			}
+		}
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		s, err := c.Scorecard(ctx, loc.Host, loc.Name)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s/%s: %.1f/10, scanned %s at %s\n\n", s.Host, s.Name, s.Score, s.Scanned, orDash(s.Commit))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "CHECK\tSCORE\tREASON")
+		for _, ch := range s.Checks {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", ch.Name, checkScore(ch.Score), ch.Reason)
+		}
+		return w.Flush()
+	},
+}
+
+// checkScore formats the score of a Scorecard check.
+func checkScore(score int) string {
+	if score < 0 {
+		return "n/a"
+	}
+	return fmt.Sprint(score)
+}
+
+var scorecardSyncCommand = &cli.Command{
+	Name:  "sync",
+	Usage: "fetch and store the OpenSSF Scorecard results of curated GitHub repositories",
+	Description: "Scorecard scans GitHub repositories only, so repositories on other\n" +
+		"forges are skipped, like those fetched within --ttl. Repositories\n" +
+		"Scorecard did not scan are reported and skipped as well.",
+	Flags: []cli.Flag{
+		scorecardURLFlag,
+		concurrencyFlag(4),
+		qpsFlag,
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Usage: "skip repositories fetched within `DURATION`",
+			Value: 7 * 24 * time.Hour,
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "fetch all repositories regardless of --ttl",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		stored, err := c.Scorecards(ctx)
+		if err != nil {
+			return err
+		}
+		fresh := make(map[string]bool)
+		for _, s := range stored {
+			if time.Since(s.FetchedAt) < cmd.Duration("ttl") {
+				fresh[repoKey(s.Host, s.Name)] = true
+			}
+		}
+		var repos []string // host/name
+		for _, repo := range curatedForgeRepos(lookup) {
+			host, name, _ := strings.Cut(repo, "/")
+			if host == "github.com" && (cmd.Bool("force") || !fresh[repoKey(host, name)]) {
+				repos = append(repos, repo)
+			}
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Fetching the scorecards of %d repositories\n", len(repos))
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type fetched struct {
+			result *scorecard.Result
+			err    error
+		}
+		client := scorecard.New(cmd.String(scorecardURLFlag.Name), nil)
+		var done, n, missing int
+		var storeErr error
+		forEachLimited(ctx, repos, newBulkLimits(cmd), func(repo string) fetched {
+			r, err := client.Project(ctx, repo)
+			return fetched{r, err}
+		}, func(repo string, f fetched) {
+			done++
+			if storeErr != nil {
+				return
+			}
+			if errors.Is(f.err, scorecard.ErrNotFound) {
+				missing++
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: not scanned\n", done, len(repos), repo)
+				return
+			}
+			if f.err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error fetching %q: %v\n", done, len(repos), repo, f.err)
+				return
+			}
+			host, name, _ := strings.Cut(repo, "/")
+			s := modindex.Scorecard{
+				Host:      host,
+				Name:      name,
+				Score:     f.result.Score,
+				Scanned:   f.result.Date,
+				Commit:    f.result.Repo.Commit,
+				FetchedAt: time.Now(),
+			}
+			for _, ch := range f.result.Checks {
+				s.Checks = append(s.Checks, modindex.ScorecardCheck{Name: ch.Name, Score: ch.Score, Reason: ch.Reason})
+			}
+			if err := c.StoreScorecard(ctx, s); err != nil {
+				storeErr = fmt.Errorf("store scorecard of %s: %w", repo, err)
+				cancel()
+				return
+			}
+			n++
+			_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s: %.1f\n", done, len(repos), repo, s.Score)
+		})
+		if storeErr != nil {
+			return storeErr
+		}
+		fmt.Printf("Stored %d scorecards, %d repositories were not scanned\n", n, missing)
+		return nil
+	},
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/testhunt"
+)
+
+var selftestCommand = &cli.Command{
+	Name:  "selftest",
+	Usage: "run the whole pipeline on fixtures and compare the results with golden data",
+	Description: "Serves the small ecosystem of the testhunt package through a fake module\n" +
+		"index, module proxy, GitHub API and Scorecard API on loopback addresses,\n" +
+		"then runs 'index sync', every stage of 'enrich' and 'report' against a\n" +
+		"temporary database, like a real run but without the network. The\n" +
+		"resolved links, releases, repositories, scorecards and health scores are\n" +
+		"compared with the golden data of testhunt and the time every stage took\n" +
+		"is reported, over --runs runs.\n\n" +
+		"The flags of enrich and report apply, except those selecting the\n" +
+		"database, the proxy and the APIs. With --print, the results are printed\n" +
+		"instead, e.g. to update internal/testhunt/testdata/golden.txt after an\n" +
+		"intended change of parsing or scoring.",
+	Flags: slices.Concat([]cli.Flag{
+		&cli.IntFlag{
+			Name:  "runs",
+			Usage: "run the pipeline `N` times, each on a new database",
+			Value: 1,
+		},
+		&cli.BoolFlag{
+			Name:  "print",
+			Usage: "print the results instead of comparing them",
+		},
+		indexURLFlag,
+	}, enrichCommand.Flags, reportCommand.Flags),
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		runs := int(cmd.Int("runs"))
+		if runs < 1 {
+			return fmt.Errorf("invalid --runs %d", runs)
+		}
+		curatedLists = testhunt.Lookup
+
+		// The stages report their progress on standard output, which is
+		// kept for the results.
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+
+		var got string
+		timings := make(map[string][]time.Duration)
+		var stages []string
+		for i := range runs {
+			_, _ = fmt.Fprintf(os.Stderr, "Run %d/%d\n", i+1, runs)
+			out, took, err := runSelftest(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			if i > 0 && out != got {
+				return fmt.Errorf("run %d differs from run 1:\n%s", i+1, strings.Join(testhunt.Diff(got, out), "\n"))
+			}
+			got = out
+			for _, t := range took {
+				if i == 0 {
+					stages = append(stages, t.stage)
+				}
+				timings[t.stage] = append(timings[t.stage], t.took)
+			}
+		}
+		os.Stdout = stdout
+		if cmd.Bool("print") {
+			fmt.Print(got)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "STAGE\tMIN\tMEAN\tMAX")
+		var total time.Duration
+		for _, s := range stages {
+			ts := timings[s]
+			var sum time.Duration
+			for _, t := range ts {
+				sum += t
+			}
+			total += sum
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s, slices.Min(ts).Round(time.Millisecond), (sum / time.Duration(len(ts))).Round(time.Millisecond), slices.Max(ts).Round(time.Millisecond))
+		}
+		_, _ = fmt.Fprintf(w, "total\t\t%s\t\n", (total / time.Duration(runs)).Round(time.Millisecond))
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if diff := testhunt.Diff(testhunt.Golden, got); len(diff) > 0 {
+			fmt.Printf("\nResults differ from the golden data (-want +got):\n%s\n", strings.Join(diff, "\n"))
+			return fmt.Errorf("selftest failed: %d lines differ", len(diff))
+		}
+		fmt.Printf("\nResults match the golden data (%d lines)\n", strings.Count(got, "\n"))
+		return nil
+	},
+}
+
+// stageTiming is the time a stage of selftest took.
+type stageTiming struct {
+	stage string
+	took  time.Duration
+}
+
+// runSelftest runs the pipeline on a new database against the fakes of
+// testhunt and returns the results and the time every stage took.
+func runSelftest(ctx context.Context, cmd *cli.Command) (string, []stageTiming, error) {
+	dir, err := os.MkdirTemp("", "modhunt-selftest-")
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	env := testhunt.Start(testhunt.Default(), time.Now())
+	defer env.Close()
+
+	for name, value := range map[string]string{
+		dbFlag.Name:           filepath.Join(dir, "modhunt.db"),
+		archiveDirFlag.Name:   filepath.Join(dir, "archive"),
+		goproxyFlag.Name:      env.ProxyURL(),
+		proxyCacheFlag.Name:   "",
+		httpCacheFlag.Name:    "",
+		proxyRateFlag.Name:    "0",
+		verifyFlag.Name:       "false",
+		indexURLFlag.Name:     env.IndexURL(),
+		githubTokenFlag.Name:  "", // the fake serves the REST API only
+		githubAPIURLFlag.Name: env.GitHubURL(),
+		scorecardURLFlag.Name: env.ScorecardURL(),
+		"output":              filepath.Join(dir, "report.md"),
+	} {
+		if err := cmd.Set(name, value); err != nil {
+			return "", nil, fmt.Errorf("set --%s: %w", name, err)
+		}
+	}
+
+	stages := slices.Concat(
+		[]enrichStage{{"sync", "synchronize the module index (index sync)", indexSyncCommand.Action}},
+		enrichStages(),
+		[]enrichStage{{"report", "render the ecosystem report (report)", reportCommand.Action}},
+	)
+	var took []stageTiming
+	for i, s := range stages {
+		_, _ = fmt.Fprintf(os.Stderr, "Stage %d/%d %s: %s\n", i+1, len(stages), s.name, s.usage)
+		start := time.Now()
+		if err := s.run(ctx, cmd); err != nil {
+			return "", nil, fmt.Errorf("stage %s: %w", s.name, err)
+		}
+		took = append(took, stageTiming{s.name, time.Since(start)})
+	}
+	if info, err := os.Stat(filepath.Join(dir, "report.md")); err != nil || info.Size() == 0 {
+		return "", nil, fmt.Errorf("stage report: no report rendered")
+	}
+	out, err := selftestResults(ctx, cmd)
+	return out, took, err
+}
+
+// selftestResults returns the data the pipeline stored, one sorted line
+// per result, leaving out everything that depends on the clock.
+func selftestResults(ctx context.Context, cmd *cli.Command) (string, error) {
+	lookup, err := newLookup(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("init lookup: %w", err)
+	}
+	c, err := openIndex(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	var lines []string
+	links, err := c.LinkModules(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, l := range links {
+		lines = append(lines, fmt.Sprintf("link %s %s", l.URL, orDash(l.Module)))
+	}
+	cads, err := c.Cadences(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, cad := range cads {
+		lines = append(lines, fmt.Sprintf("releases %s %d", cad.Module, cad.Releases))
+	}
+	repos, err := c.Repos(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range repos {
+		lines = append(lines, fmt.Sprintf("repo %s/%s stars=%d archived=%t license=%s topics=%s",
+			r.Host, r.Name, r.Stars, r.Archived, orDash(r.License), strings.Join(r.Topics, ",")))
+	}
+	cards, err := c.Scorecards(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, sc := range cards {
+		lines = append(lines, fmt.Sprintf("scorecard %s/%s %.1f checks=%d", sc.Host, sc.Name, sc.Score, len(sc.Checks)))
+	}
+	scores, err := c.ModuleScores(ctx)
+	if err != nil {
+		return "", err
+	}
+	s := newScorer(ctx, cmd, lookup, loadGraph(ctx, cmd))
+	for _, ms := range scores {
+		lines = append(lines, fmt.Sprintf("score %s %d %s", ms.Module, ms.Score, orDash(ms.Cap)))
+		for _, f := range s.score(ms.Module).Factors {
+			value := "-"
+			if f.Known {
+				value = fmt.Sprintf("%.0f%%", f.Value*100)
+			}
+			lines = append(lines, fmt.Sprintf("factor %s %s %s", ms.Module, f.Name, value))
+		}
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "\n") + "\n", nil
+}
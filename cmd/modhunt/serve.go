@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/modscore"
+)
+
+var serveCommand = &cli.Command{
+	Name:  "serve",
+	Usage: "serve health badges and scores of modules over HTTP",
+	Description: "Serves the health score of 'modhunt score' for embedding in READMEs,\n" +
+		"like the badges of goreportcard:\n\n" +
+		"  /badge/MODULE.svg   a badge showing the score\n" +
+		"  /api/score/MODULE   the score and its factors as JSON\n\n" +
+		"Scores are computed from the stored data, which is read again every\n" +
+		"--reload, e.g. after the next 'enrich'. Modules nothing is known about\n" +
+		"get an \"unknown\" badge and a 404 response.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "addr",
+			Usage: "listen on `ADDR`",
+			Value: ":8080",
+		},
+		&cli.DurationFlag{
+			Name:  "reload",
+			Usage: "read the stored data again every `DURATION`",
+			Value: 10 * time.Minute,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		ctx, stop := signal.NotifyContext(withoutTimeout(ctx), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		h := &healthHandler{}
+		if err := h.load(ctx, cmd); err != nil {
+			return err
+		}
+		go func() {
+			t := time.NewTicker(cmd.Duration("reload"))
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					if err := h.load(ctx, cmd); err != nil {
+						_, _ = fmt.Fprintf(os.Stderr, "%s | Reload failed: %v\n", time.Now().Format(time.RFC3339), err)
+					}
+				}
+			}
+		}()
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /badge/{module...}", h.badge)
+		mux.HandleFunc("GET /api/score/{module...}", h.score)
+		srv := &http.Server{Addr: cmd.String("addr"), Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+		_, _ = fmt.Fprintf(os.Stderr, "Serving on %s\n", srv.Addr)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+// healthHandler serves the scores of the scorer loaded last.
+type healthHandler struct {
+	mu     sync.RWMutex
+	scores *scorer
+	loaded time.Time
+}
+
+// load replaces the scorer with one reading the stored data again.
+func (h *healthHandler) load(ctx context.Context, cmd *cli.Command) error {
+	lookup, err := newLookup(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("init lookup: %w", err)
+	}
+	s := newScorer(ctx, cmd, lookup, loadGraph(ctx, cmd))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.scores, h.loaded = s, time.Now()
+	return nil
+}
+
+// lookup returns the score of module, whether any data on it is stored
+// and when the data was read.
+func (h *healthHandler) lookup(module string) (modscore.Score, bool, time.Time) {
+	h.mu.RLock()
+	s, loaded := *h.scores, h.loaded
+	h.mu.RUnlock()
+	// Scores age with the time since the last release, so they are
+	// computed as of now.
+	s.now = time.Now()
+	return s.score(module), s.knows(module), loaded
+}
+
+func (h *healthHandler) badge(w http.ResponseWriter, r *http.Request) {
+	module := strings.TrimSuffix(r.PathValue("module"), ".svg")
+	score, known, _ := h.lookup(module)
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// Let image proxies like GitHub's refresh badges every hour.
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	_ = renderBadge(w, "modhunt", score, known)
+}
+
+// scoreResponse is the JSON of /api/score/MODULE and of the score method
+// of 'api --stdio'.
+type scoreResponse struct {
+	Module  string           `json:"module"`
+	Score   int              `json:"score"`
+	Cap     string           `json:"cap,omitempty"`
+	Factors []factorResponse `json:"factors"`
+	Loaded  time.Time        `json:"loaded"` // when the stored data was read
+}
+
+type factorResponse struct {
+	Name   string   `json:"name"`
+	Weight float64  `json:"weight"`
+	Value  *float64 `json:"value"` // null if the signal is unknown
+	Detail string   `json:"detail"`
+}
+
+func (h *healthHandler) score(w http.ResponseWriter, r *http.Request) {
+	module := r.PathValue("module")
+	score, known, loaded := h.lookup(module)
+	if !known {
+		http.Error(w, fmt.Sprintf("nothing is known about %s", module), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	_ = json.NewEncoder(w).Encode(newScoreResponse(module, score, loaded))
+}
+
+// newScoreResponse returns the JSON of score, the score of module
+// computed from the data read at loaded.
+func newScoreResponse(module string, score modscore.Score, loaded time.Time) scoreResponse {
+	resp := scoreResponse{Module: module, Score: score.Total, Cap: score.Cap, Loaded: loaded.UTC()}
+	for _, f := range score.Factors {
+		fr := factorResponse{Name: f.Name, Weight: f.Weight, Detail: f.Detail}
+		if f.Known {
+			fr.Value = &f.Value
+		}
+		resp.Factors = append(resp.Factors, fr)
+	}
+	return resp
+}
+
+// badgeTemplate is a flat badge in the style of shields.io. Widths are
+// estimated from the number of characters, which is close enough for
+// short labels in Verdana at 11px.
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Value}}">
+<title>{{.Label}}: {{.Value}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.ValueWidth}}" height="20" fill="{{.Color}}"/><rect width="{{.Width}}" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{.Label}}</text><text x="{{.LabelX}}" y="14">{{.Label}}</text>
+<text x="{{.ValueX}}" y="15" fill="#010101" fill-opacity=".3">{{.Value}}</text><text x="{{.ValueX}}" y="14">{{.Value}}</text>
+</g>
+</svg>
+`))
+
+// renderBadge writes a badge showing score, or "unknown" if nothing is
+// known about the module.
+func renderBadge(w io.Writer, label string, score modscore.Score, known bool) error {
+	value, color := "unknown", "#9f9f9f"
+	if known && score.Known() {
+		value = fmt.Sprintf("%d/100", score.Total)
+		switch {
+		case score.Total >= 80:
+			color = "#4c1"
+		case score.Total >= 60:
+			color = "#97ca00"
+		case score.Total >= 40:
+			color = "#dfb317"
+		case score.Total >= 20:
+			color = "#fe7d37"
+		default:
+			color = "#e05d44"
+		}
+	}
+	textWidth := func(s string) int { return 7*len(s) + 10 }
+	lw, vw := textWidth(label), textWidth(value)
+	return badgeTemplate.Execute(w, map[string]any{
+		"Label":      html.EscapeString(label),
+		"Value":      html.EscapeString(value),
+		"Color":      color,
+		"Width":      lw + vw,
+		"LabelWidth": lw,
+		"ValueWidth": vw,
+		"LabelX":     lw / 2,
+		"ValueX":     lw + vw/2,
+	})
+}
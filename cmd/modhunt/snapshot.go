@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var snapshotCommand = &cli.Command{
+	Name:  "snapshot",
+	Usage: "publish or fetch the enriched database as a single SQLite file",
+	Description: "A snapshot is a compacted copy of the database with everything 'index\n" +
+		"sync' and 'enrich' collected, for publishing e.g. as a release artifact.\n" +
+		"Caches, the state of local runs and the yearly archives are left out.\n" +
+		"Snapshots keep the schema version of the modhunt that created them and\n" +
+		"are migrated when fetched by a later one.",
+	Commands: []*cli.Command{
+		snapshotCreateCommand,
+		snapshotFetchCommand,
+	},
+}
+
+var snapshotCreateCommand = &cli.Command{
+	Name:  "create",
+	Usage: "write a snapshot of the database and its checksum",
+	Description: "Writes the snapshot to --output and its SHA-256 checksum, in the\n" +
+		"format of sha256sum, next to it with the suffix .sha256. The database\n" +
+		"may be synchronized meanwhile.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write the snapshot to `FILE` (default: modhunt-DATE-schemaN.db)",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		out := cmd.String("output")
+		if out == "" {
+			out = fmt.Sprintf("modhunt-%s-schema%d.db", time.Now().UTC().Format("20060102"), modindex.LatestSchemaVersion())
+		}
+		info, err := c.Snapshot(ctx, out)
+		if err != nil {
+			return err
+		}
+		sum := fmt.Sprintf("%s  %s\n", info.SHA256, filepath.Base(out))
+		if err := os.WriteFile(out+".sha256", []byte(sum), 0o644); err != nil {
+			return fmt.Errorf("write checksum: %w", err)
+		}
+		if info.Archives > 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "Left out %d yearly archives, the snapshot holds the live versions only\n", info.Archives)
+		}
+		fmt.Printf("Wrote %s (%s, schema version %d, %d versions)\n", out, formatBytes(info.Size), info.SchemaVersion, info.Versions)
+		fmt.Printf("SHA-256 %s\n", info.SHA256)
+		return nil
+	},
+}
+
+var snapshotFetchCommand = &cli.Command{
+	Name:      "fetch",
+	Usage:     "download a published snapshot as the database",
+	ArgsUsage: "URL",
+	Description: "Downloads the snapshot, verifies it against --sha256 or else the\n" +
+		"checksum published at URL.sha256, migrates it to the current schema and\n" +
+		"installs it at the path of --db. An existing database is only replaced\n" +
+		"with --force.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "sha256",
+			Usage: "expect the snapshot to have the hex-encoded SHA-256 checksum `SUM`",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "replace an existing database",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one URL")
+		}
+		u := cmd.Args().First()
+		path := cmd.String(dbFlag.Name)
+		if _, err := os.Stat(path); err == nil && !cmd.Bool("force") {
+			return fmt.Errorf("database %s exists, set --force to replace it", path)
+		}
+
+		want := strings.ToLower(cmd.String("sha256"))
+		if want == "" {
+			sum, err := download(ctx, u+".sha256")
+			if err != nil {
+				return fmt.Errorf("fetch checksum (or set --sha256): %w", err)
+			}
+			fields := strings.Fields(string(sum))
+			if len(fields) == 0 {
+				return fmt.Errorf("empty checksum at %s.sha256", u)
+			}
+			want = strings.ToLower(fields[0])
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".fetch-*")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.Remove(tmp.Name()) }() // no-op after rename
+		_, _ = fmt.Fprintf(os.Stderr, "Downloading %s\n", u)
+		got, n, err := downloadTo(ctx, u, tmp)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("checksum mismatch: got %s, want %s", got, want)
+		}
+
+		c, err := modindex.Open(ctx, modindex.WithDBPath(tmp.Name()), modindex.WithMigrate())
+		if err != nil {
+			return fmt.Errorf("open snapshot: %w", err)
+		}
+		version, err := modindex.SchemaVersion(ctx, c.DB())
+		c.Close()
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return fmt.Errorf("install snapshot: %w", err)
+		}
+		fmt.Printf("Installed %s (%s, schema version %d) at %s\n", u, formatBytes(n), version, path)
+		return nil
+	},
+}
+
+// download returns the body of the response to a GET request for u.
+func download(ctx context.Context, u string) ([]byte, error) {
+	resp, err := get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return io.ReadAll(resp.Body)
+}
+
+// downloadTo writes the body of the response to a GET request for u to w
+// and returns its hex-encoded SHA-256 checksum and size.
+func downloadTo(ctx context.Context, u string, w io.Writer) (string, int64, error) {
+	resp, err := get(ctx, u)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), resp.Body)
+	if err != nil {
+		return "", n, fmt.Errorf("download %s: %w", u, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+func get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", u, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("get %s: unexpected status: %s", u, resp.Status)
+	}
+	return resp, nil
+}
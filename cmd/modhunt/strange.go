@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/linkcheck"
+	"github.com/ngrash/modhunt/internal/pkglists"
+)
+
+var strangeCommand = &cli.Command{
+	Name:  "strange",
+	Usage: "flag curated links that look wrong, with a suggested fix",
+	Description: "Every curated link is checked by rules that flag anomalies like plain\n" +
+		"HTTP links, hosts that shut down, links to documentation or deep into a\n" +
+		"repository and hosts that are no known forge. All rules apply by default;\n" +
+		"--rules reads the rules to apply from a file instead, one per line: the\n" +
+		"name of the rule followed by its arguments, e.g. the hosts it applies\n" +
+		"to, separated by white space. A rule without arguments uses its\n" +
+		"defaults. Empty lines and lines starting with # are ignored:\n\n" +
+		"  insecure\n" +
+		"  non-forge github.com gitlab.com codeberg.org go.example.org\n" +
+		"  deep-path 4\n\n" +
+		"--list prints the rules in effect and those available. With --json, one\n" +
+		"JSON object is printed per finding.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "rules",
+			Usage: "apply the rules in `FILE`",
+		},
+		&cli.BoolFlag{
+			Name:  "list",
+			Usage: "list the rules instead of checking links",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print JSON objects",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		rules := linkcheck.DefaultRules()
+		if file := cmd.String("rules"); file != "" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			rules, err = linkcheck.ParseRules(f)
+			_ = f.Close()
+			if err != nil {
+				return fmt.Errorf("parse %s: %w", file, err)
+			}
+		}
+		if cmd.Bool("list") {
+			fmt.Println("Rules in effect:")
+			for _, r := range rules {
+				fmt.Println("  " + r.String())
+			}
+			fmt.Println("\nAvailable rules:")
+			for _, u := range linkcheck.Usage() {
+				fmt.Println("  " + u)
+			}
+			return nil
+		}
+
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		var findings []strangeLink
+		for _, s := range lookup.Sources {
+			walkCategories(s.Root, s.Name, func(cat *pkglists.Category, path string) {
+				for _, l := range cat.Links {
+					for _, f := range linkcheck.Check(rules, l.URL) {
+						findings = append(findings, strangeLink{f, s.Name, path})
+					}
+				}
+			})
+		}
+
+		if cmd.Bool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			for _, f := range findings {
+				if err := enc.Encode(f); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return printStrangeLinks(findings)
+	},
+}
+
+// strangeLink is an anomaly in a curated link.
+type strangeLink struct {
+	linkcheck.Finding
+	Source   string `json:"source"`
+	Category string `json:"category"`
+}
+
+// printStrangeLinks lists the findings followed by how many every rule
+// flagged.
+func printStrangeLinks(findings []strangeLink) error {
+	counts := make(map[string]int)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RULE\tURL\tPROBLEM\tFIX\tCATEGORY")
+	for _, f := range findings {
+		counts[f.Rule]++
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Rule, f.URL, f.Problem, f.Fix, f.Category)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RULE\tFINDINGS")
+	for _, rule := range rankCounts(counts, len(counts)) {
+		_, _ = fmt.Fprintf(w, "%s\t%d\n", rule, counts[rule])
+	}
+	return w.Flush()
+}
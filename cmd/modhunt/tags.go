@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var tagCheckCommand = &cli.Command{
+	Name:  "tag-check",
+	Usage: "compare the latest forge tags of curated packages with the latest version on the module proxy",
+	Description: "Latest releases and tags are fetched with \"github sync\" and \"repo sync\".\n" +
+		"Packages are flagged if the proxy serves only pseudo-versions although\n" +
+		"the repository has tags, which is often a sign of tags that are no\n" +
+		"semantic versions or miss the major version suffix of the module path.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "query `N` modules concurrently",
+			Value: 10,
+		},
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "list consistent packages as well",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		c, err := openIndex(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		stored, err := c.Repos(ctx)
+		if err != nil {
+			return err
+		}
+		repos := make(map[string]modindex.Repo, len(stored))
+		for _, r := range stored {
+			repos[repoKey(r.Host, r.Name)] = r
+		}
+		tags := make(map[string]string) // by module path
+		for key := range lookup.Packages {
+			loc, ok := packageRepo(lookup, key)
+			if !ok {
+				continue
+			}
+			if r, ok := repos[repoKey(loc.Host, loc.Name)]; ok && r.LatestRelease != "" {
+				tags[loc.Path()] = r.LatestRelease
+			}
+		}
+		modules := slices.Sorted(maps.Keys(tags))
+		_, _ = fmt.Fprintf(os.Stderr, "Checking %d modules with tags\n", len(modules))
+
+		type checked struct {
+			latest  string
+			problem string
+			err     error
+		}
+		results := make(map[string]checked, len(modules))
+		var done int
+		forEachParallel(modules, int(cmd.Int("workers")), func(m string) checked {
+			info, err := proxy.Latest(ctx, m)
+			if errors.Is(err, goproxy.ErrNotFound) {
+				return checked{problem: "not on the proxy"}
+			}
+			if err != nil {
+				return checked{err: err}
+			}
+			return checked{latest: info.Version, problem: tagProblem(m, tags[m], info.Version)}
+		}, func(m string, r checked) {
+			done++
+			if r.err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%d/%d | Error fetching %q: %v\n", done, len(modules), m, r.err)
+			}
+			results[m] = r
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "MODULE\tFORGE TAG\tPROXY LATEST\tPROBLEM")
+		var flagged int
+		for _, m := range modules {
+			r := results[m]
+			if r.err != nil || r.problem == "" && !cmd.Bool("all") {
+				continue
+			}
+			if r.problem != "" {
+				flagged++
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m, tags[m], orDash(r.latest), orDash(r.problem))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d of %d modules with tags are flagged\n", flagged, len(modules))
+		return nil
+	},
+}
+
+// tagProblem explains why the latest tag of the repository of path does
+// not show up as the latest version on the proxy, or returns "".
+func tagProblem(path, tag, latest string) string {
+	pseudo := module.IsPseudoVersion(latest)
+	switch {
+	case !semver.IsValid(tag):
+		if pseudo {
+			return "only pseudo-versions, tag is no semantic version"
+		}
+		return "" // e.g. a release named after a date besides proper tags
+	case semver.Compare(tag, latest) <= 0:
+		return ""
+	case module.CheckPathMajor(tag, pathMajor(path)) != nil:
+		if pseudo {
+			return "only pseudo-versions, tag needs module path suffix /" + semver.Major(tag)
+		}
+		return "tag needs module path suffix /" + semver.Major(tag)
+	case pseudo:
+		return "only pseudo-versions despite tags"
+	}
+	return "tag not on the proxy yet"
+}
+
+// pathMajor returns the major version suffix of a module path, e.g.
+// "/v2", or "" if there is none.
+func pathMajor(path string) string {
+	_, major, _ := module.SplitPathVersion(path)
+	return major
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var topicsCommand = &cli.Command{
+	Name:      "topics",
+	Usage:     "list the forge topics of curated packages or the packages with a topic",
+	ArgsUsage: "[TOPIC]",
+	Description: "Topics are harvested from GitHub, GitLab and Gitea by \"github sync\"\n" +
+		"and \"repo sync\". Without TOPIC, all topics are listed, the most common\n" +
+		"first.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "min",
+			Usage: "only list topics of at least `N` modules",
+			Value: 2,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() > 1 {
+			return fmt.Errorf("expected at most one argument")
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if cmd.Args().Len() == 0 {
+			counts, err := c.TopicCounts(ctx, int(cmd.Int("min")))
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(w, "MODULES\tTOPIC")
+			for _, tc := range counts {
+				_, _ = fmt.Fprintf(w, "%d\t%s\n", tc.Modules, tc.Topic)
+			}
+			return w.Flush()
+		}
+
+		modules, err := c.ModulesWithTopic(ctx, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		descriptions := make(map[string]string)
+		for name, links := range lookup.Packages {
+			descriptions[packageModulePath(lookup, name)] = links[0].Description
+		}
+		_, _ = fmt.Fprintln(w, "MODULE\tDESCRIPTION")
+		for _, m := range modules {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", m, descriptions[m])
+		}
+		return w.Flush()
+	},
+}
+
+// storeModuleTopics maps the stored topics of repositories to the modules
+// of curated packages and replaces the stored module topics.
+func storeModuleTopics(ctx context.Context, c *modindex.Client, lookup *pkglists.Lookup) error {
+	repos, err := c.Repos(ctx)
+	if err != nil {
+		return err
+	}
+	byRepo := make(map[string][]string, len(repos))
+	for _, r := range repos {
+		byRepo[repoKey(r.Host, r.Name)] = r.Topics
+	}
+	topics := make(map[string][]string)
+	for name := range lookup.Packages {
+		loc, ok := packageRepo(lookup, name)
+		if !ok {
+			continue
+		}
+		if ts := byRepo[repoKey(loc.Host, loc.Name)]; len(ts) > 0 {
+			topics[packageModulePath(lookup, name)] = ts
+		}
+	}
+	if err := c.ReplaceModuleTopics(ctx, topics); err != nil {
+		return fmt.Errorf("store module topics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/depsdev"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var trendingCommand = &cli.Command{
+	Name:  "trending",
+	Usage: "list modules gaining traction",
+	Description: "Modules are ranked by how much more often they were released within\n" +
+		"--window than in the year before, so new modules and modules picking up\n" +
+		"pace rank first. The window ends with the newest version in the index.\n\n" +
+		"With --deps-dev, the dependents of the --top modules are counted on\n" +
+		"deps.dev and stored. Modules that gained dependents since the count\n" +
+		"stored a --window ago rank higher, so run it regularly.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "window",
+			Usage: "compare the last `AGE` (e.g. 30d) with the year before",
+			Value: "30d",
+		},
+		&cli.IntFlag{
+			Name:  "min-releases",
+			Usage: "skip modules released less than `N` times within the window",
+			Value: 2,
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "list `N` modules",
+			Value: 25,
+		},
+		&cli.BoolFlag{
+			Name:  "deps-dev",
+			Usage: "count the dependents of the top modules on deps.dev",
+		},
+		&cli.IntFlag{
+			Name:  "top",
+			Usage: "count the dependents of the `N` top modules",
+			Value: 100,
+		},
+		&cli.StringFlag{
+			Name:  "deps-dev-url",
+			Usage: "use the deps.dev API at `URL`",
+			Value: depsdev.DefaultURL,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		window, err := parseAge(cmd.String("window"))
+		if err != nil || window <= 0 {
+			return fmt.Errorf("invalid --window %q", cmd.String("window"))
+		}
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithMigrate())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		end, err := c.Newest(ctx)
+		if err != nil {
+			return err
+		}
+		start := end.Add(-window)
+		activity, err := c.Activity(ctx, start, end)
+		if err != nil {
+			return err
+		}
+
+		var modules []trendingModule
+		for _, a := range activity {
+			if a.Recent >= int(cmd.Int("min-releases")) {
+				modules = append(modules, newTrendingModule(a, window, end))
+			}
+		}
+		sortTrending(modules)
+
+		if cmd.Bool("deps-dev") {
+			top := modules[:min(len(modules), int(cmd.Int("top")))]
+			if err := countDependents(ctx, c, depsdev.New(cmd.String("deps-dev-url"), nil), top); err != nil {
+				return err
+			}
+		}
+		for i := range modules {
+			from, to, err := c.DependentGrowth(ctx, modules[i].Path, start)
+			if errors.Is(err, modindex.ErrNoDependentCount) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			modules[i].addGrowth(from, to)
+		}
+		sortTrending(modules)
+
+		curated := make(map[string]bool, len(lookup.Packages))
+		for key := range lookup.Packages {
+			curated[modname.Normalize(packageModulePath(lookup, key))] = true
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "SCORE\tRELEASES\tYEAR BEFORE\tFIRST SEEN\tDEPENDENTS\tCURATED\tMODULE")
+		for _, m := range modules[:min(len(modules), int(cmd.Int("limit")))] {
+			_, _ = fmt.Fprintf(w, "%.1f\t%d\t%d\t%s\t%s\t%s\t%s\n", m.score, m.Recent, m.Before, formatDate(m.FirstSeen),
+				m.dependents, curatedMark(curated[modname.Normalize(m.Path)]), m.Path)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d modules were released at least %d times between %s and %s\n",
+			len(modules), cmd.Int("min-releases"), start.Format(time.DateOnly), end.Format(time.DateOnly))
+		return nil
+	},
+}
+
+// trendingModule is the release activity of a module and how much it
+// trends.
+type trendingModule struct {
+	modindex.Activity
+	score      float64
+	dependents string // growth on deps.dev, e.g. "120 → 180"
+}
+
+// newTrendingModule scores a by its releases within the window ending at
+// end compared to the releases expected from the year before. Modules
+// first seen within that year are expected to be released less.
+func newTrendingModule(a modindex.Activity, window time.Duration, end time.Time) trendingModule {
+	const year = 365 * 24 * time.Hour
+	history := min(end.Sub(a.FirstSeen)-window, year)
+	var expected float64
+	if history > 0 {
+		expected = float64(a.Before) * window.Hours() / history.Hours()
+	}
+	return trendingModule{
+		Activity:   a,
+		score:      float64(a.Recent) / (expected + 1),
+		dependents: "-",
+	}
+}
+
+// addGrowth weighs the score by the relative growth of dependents.
+func (m *trendingModule) addGrowth(from, to modindex.DependentCount) {
+	m.dependents = fmt.Sprint(to.Total)
+	if from.FetchedAt.Equal(to.FetchedAt) {
+		return
+	}
+	m.dependents = fmt.Sprintf("%d → %d", from.Total, to.Total)
+	growth := float64(to.Total-from.Total) / float64(max(from.Total, 1))
+	m.score *= 1 + math.Max(growth, 0)
+}
+
+func sortTrending(modules []trendingModule) {
+	slices.SortFunc(modules, func(a, b trendingModule) int {
+		return cmp.Or(cmp.Compare(b.score, a.score), cmp.Compare(b.Recent, a.Recent), strings.Compare(a.Path, b.Path))
+	})
+}
+
+// countDependents counts the dependents of the latest versions of
+// modules on deps.dev and stores them.
+func countDependents(ctx context.Context, c *modindex.Client, dd *depsdev.Client, modules []trendingModule) error {
+	latest := make(map[string]string, len(modules))
+	paths := make([]string, len(modules))
+	for i, m := range modules {
+		latest[m.Path] = m.Latest
+		paths[i] = m.Path
+	}
+	type counted struct {
+		d   depsdev.Dependents
+		err error
+	}
+	var n int
+	var firstErr error
+	forEachParallel(paths, 4, func(path string) counted {
+		d, err := dd.Dependents(ctx, path, latest[path])
+		return counted{d, err}
+	}, func(path string, r counted) {
+		n++
+		err := r.err
+		if err == nil {
+			err = c.StoreDependentCount(ctx, modindex.DependentCount{
+				Module:    path,
+				Version:   latest[path],
+				Total:     r.d.Total,
+				Direct:    r.d.Direct,
+				FetchedAt: time.Now(),
+			})
+		}
+		switch {
+		case errors.Is(err, depsdev.ErrNotFound):
+			_, _ = fmt.Fprintf(os.Stderr, "%d/%d | %s@%s: unknown to deps.dev\n", n, len(paths), path, latest[path])
+		case err != nil && firstErr == nil:
+			firstErr = fmt.Errorf("count dependents of %s: %w", path, err)
+		}
+	})
+	return firstErr
+}
+
+func curatedMark(curated bool) string {
+	if curated {
+		return "yes"
+	}
+	return "-"
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var untaggedCommand = &cli.Command{
+	Name:  "untagged",
+	Usage: "list curated modules that never tagged a release",
+	Description: "A module whose versions in the index are all pseudo-versions was only\n" +
+		"ever fetched at a commit. Without releases, users cannot depend on a\n" +
+		"stable version, so such modules are flagged in search and compare as\n" +
+		"well. Modules the index has no versions of are not listed.",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+		c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		modules := curatedModules(lookup)
+		pseudoOnly, err := c.PseudoOnlyPaths(ctx, modules)
+		if err != nil {
+			return err
+		}
+		byModule := make(map[string][]string)
+		for name := range lookup.Packages {
+			m := packageModulePath(lookup, name)
+			byModule[m] = append(byModule[m], name)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "MODULE\tPSEUDO-VERSIONS\tLATEST\tPUBLISHED\tPACKAGES")
+		for _, p := range pseudoOnly {
+			packages := byModule[p.Path]
+			slices.Sort(packages)
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", p.Path, p.Versions, p.Latest.Version, releaseDate(p.Latest), packages[0]+moreCount(len(packages)-1))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d of %d curated modules never tagged a release\n", len(pseudoOnly), len(modules))
+		return nil
+	},
+}
+
+// moreCount returns " (+n more)" for n > 0 and "" otherwise.
+func moreCount(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (+%d more)", n)
+}
+
+// loadPseudoOnly returns which of modules have only pseudo-versions in
+// the index. Without a usable database, it warns and returns none.
+func loadPseudoOnly(ctx context.Context, cmd *cli.Command, modules []string) map[string]modindex.PseudoOnly {
+	c, err := openIndex(ctx, cmd, modindex.WithReadOnly())
+	var pseudoOnly []modindex.PseudoOnly
+	if err == nil {
+		defer c.Close()
+		pseudoOnly, err = c.PseudoOnlyPaths(ctx, modules)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "not checking for untagged modules: %v\n", err)
+		return nil
+	}
+	m := make(map[string]modindex.PseudoOnly, len(pseudoOnly))
+	for _, p := range pseudoOnly {
+		m[p.Path] = p
+	}
+	return m
+}
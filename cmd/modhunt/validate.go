@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/mod/module"
+
+	"github.com/ngrash/modhunt/internal/pkglists"
+	"github.com/ngrash/modhunt/modindex"
+)
+
+var validatePathsCommand = &cli.Command{
+	Name:  "validate-paths",
+	Usage: "report curated module paths that are no valid module path",
+	Description: "The module path of every curated package, as resolved by 'links sync'\n" +
+		"or else guessed from its URL, is checked like the go command checks\n" +
+		"import and module paths: paths with invalid characters or elements, or\n" +
+		"whose first element is no domain name, are reported with the reason.\n" +
+		"Commands that ask the module proxy for curated modules leave them out.\n\n" +
+		"Answers of the proxy stored for invalid paths are reported as well;\n" +
+		"--prune deletes them. The command fails if it reports any path.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "prune",
+			Usage: "delete the stored answers of the proxy for invalid paths",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		lookup, err := newLookup(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("init lookup: %w", err)
+		}
+
+		var invalid int
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PATH\tPROBLEM\tURL")
+		for _, key := range slices.Sorted(maps.Keys(lookup.Packages)) {
+			path := packageModulePath(lookup, key)
+			if err := checkModulePath(path); err != nil {
+				invalid++
+				_, _ = fmt.Fprintf(w, "%s\t%v\t%s\n", path, err, lookup.Packages[key][0].URL)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("%d of %d curated module paths are invalid\n", invalid, len(lookup.Packages))
+
+		// The index only stores answers for paths; it need not exist.
+		c, err := openIndex(ctx, cmd)
+		if err == nil {
+			defer c.Close()
+			n, err := validateProxyLookups(ctx, c, cmd.Bool("prune"))
+			if err != nil {
+				return err
+			}
+			invalid += n
+		}
+		if invalid > 0 {
+			return fmt.Errorf("found %d invalid paths", invalid)
+		}
+		return nil
+	},
+}
+
+// checkModulePath reports why path is no valid module path, checking it
+// as an import path first for the more specific reason.
+func checkModulePath(path string) error {
+	if err := module.CheckImportPath(path); err != nil {
+		return err
+	}
+	return module.CheckPath(path)
+}
+
+// validateProxyLookups reports the stored answers of the proxy for invalid
+// paths, deleting them if prune is set, and returns how many it reported.
+func validateProxyLookups(ctx context.Context, c *modindex.Client, prune bool) (int, error) {
+	lookups, err := c.ProxyLookups(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var invalid []string
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STORED LOOKUP\tPROBLEM")
+	for _, l := range lookups {
+		if err := checkModulePath(l.Path); err != nil {
+			invalid = append(invalid, l.Path)
+			_, _ = fmt.Fprintf(w, "%s\t%v\n", l.Path, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	fmt.Printf("%d of %d stored lookups are for invalid paths\n", len(invalid), len(lookups))
+	if !prune || len(invalid) == 0 {
+		return len(invalid), nil
+	}
+	deleted, err := c.DeleteProxyLookups(ctx, invalid)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("Deleted %d stored lookups\n", deleted)
+	return 0, nil
+}
+
+// curatedModules returns the module paths of all curated packages, sorted
+// and without duplicates. Invalid paths, which the proxy would only answer
+// with errors, are left out with a warning.
+func curatedModules(lookup *pkglists.Lookup) []string {
+	var modules []string
+	for name := range lookup.Packages {
+		path := packageModulePath(lookup, name)
+		if err := checkModulePath(path); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", name, err)
+			continue
+		}
+		modules = append(modules, path)
+	}
+	slices.Sort(modules)
+	return slices.Compact(modules)
+}
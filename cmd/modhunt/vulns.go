@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/internal/osv"
+)
+
+var osvURLFlag = &cli.StringFlag{
+	Name:  "osv-url",
+	Usage: "query the OSV.dev API at `URL` for vulnerabilities",
+	Value: osv.DefaultURL,
+}
+
+var vulnsCommand = &cli.Command{
+	Name:      "vulns",
+	Usage:     "list the advisories affecting the latest version of a module",
+	ArgsUsage: "MODULE[@VERSION]",
+	Flags:     []cli.Flag{osvURLFlag},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("expected exactly one argument")
+		}
+		proxy, err := newProxyClient(cmd)
+		if err != nil {
+			return err
+		}
+		module, version, err := resolveVersion(ctx, proxy, cmd.Args().First())
+		if err != nil {
+			return err
+		}
+
+		vulns, err := osv.New(cmd.String(osvURLFlag.Name), nil).Vulns(ctx, osv.Package{Module: module, Version: version})
+		if err != nil {
+			return fmt.Errorf("query OSV: %w", err)
+		}
+		fmt.Printf("%d advisories affect %s@%s\n", len(vulns), module, version)
+		if len(vulns) == 0 {
+			return nil
+		}
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tALIASES\tPUBLISHED\tFIXED IN\tSUMMARY")
+		for _, v := range vulns {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.ID, orDash(strings.Join(v.Aliases, ", ")),
+				formatDate(v.Published), orDash(strings.Join(v.Fixed(module), ", ")), v.Summary)
+		}
+		return w.Flush()
+	},
+}
+
+// moduleVulns is the number of advisories affecting a version of a module.
+type moduleVulns struct {
+	count   int
+	version string
+}
+
+func (v moduleVulns) String() string {
+	return fmt.Sprintf("%d vulns in %s", v.count, v.version)
+}
+
+// latestVersions returns the latest version of each module. Modules
+// whose latest version is unknown are missing.
+func latestVersions(ctx context.Context, proxy *goproxy.Client, modules []string) map[string]*goproxy.Info {
+	latest := make(map[string]*goproxy.Info, len(modules))
+	forEachParallel(modules, 10, func(m string) *goproxy.Info {
+		info, err := proxy.Latest(ctx, m)
+		if err != nil {
+			return nil
+		}
+		return info
+	}, func(m string, info *goproxy.Info) {
+		if info != nil {
+			latest[m] = info
+		}
+	})
+	return latest
+}
+
+// vulnCounts returns the number of advisories affecting the given
+// version of each module. If OSV cannot be queried, it warns and returns
+// nil.
+func vulnCounts(ctx context.Context, cmd *cli.Command, versions map[string]string) map[string]moduleVulns {
+	if len(versions) == 0 {
+		return nil
+	}
+	var pkgs []osv.Package
+	for m, version := range versions {
+		pkgs = append(pkgs, osv.Package{Module: m, Version: version})
+	}
+	counts, err := osv.New(cmd.String(osvURLFlag.Name), nil).Count(ctx, pkgs)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "skipping vulnerabilities: %v\n", err)
+		return nil
+	}
+	vulns := make(map[string]moduleVulns, len(pkgs))
+	for i, p := range pkgs {
+		vulns[p.Module] = moduleVulns{count: counts[i], version: p.Version}
+	}
+	return vulns
+}
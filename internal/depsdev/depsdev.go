@@ -0,0 +1,69 @@
+// Package depsdev implements a small client for the deps.dev API,
+// which knows the dependents of Go modules across the whole ecosystem.
+package depsdev
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultURL is the base URL of the deps.dev API.
+const DefaultURL = "https://api.deps.dev"
+
+// ErrNotFound is returned for modules and versions deps.dev does not know.
+var ErrNotFound = errors.New("not found")
+
+// Client talks to the deps.dev API. It is safe for concurrent use.
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// New returns a client for the API at baseURL, e.g. DefaultURL.
+// If httpClient is nil, http.DefaultClient is used.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// Dependents counts the packages depending on a version of a module.
+type Dependents struct {
+	Total    int `json:"dependentCount"`
+	Direct   int `json:"directDependentCount"`
+	Indirect int `json:"indirectDependentCount"`
+}
+
+// Dependents returns the number of packages depending on module at version.
+func (c *Client) Dependents(ctx context.Context, module, version string) (Dependents, error) {
+	var d Dependents
+	u := fmt.Sprintf("%s/v3alpha/systems/go/packages/%s/versions/%s:dependents",
+		c.url, url.PathEscape(module), url.PathEscape(version))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return d, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return d, fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return d, fmt.Errorf("%s@%s: %w", module, version, ErrNotFound)
+	default:
+		return d, fmt.Errorf("get %s: unexpected status: %s", u, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return d, fmt.Errorf("decode dependents: %w", err)
+	}
+	return d, nil
+}
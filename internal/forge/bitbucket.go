@@ -0,0 +1,103 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BitbucketURL is the base URL of the Bitbucket Cloud API.
+const BitbucketURL = "https://api.bitbucket.org"
+
+// Bitbucket talks to the API of Bitbucket Cloud. Bitbucket has no stars,
+// so watchers are reported instead, no archived flag and no releases. It is safe for
+// concurrent use.
+type Bitbucket struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewBitbucket returns a client for the API at baseURL, e.g. BitbucketURL.
+// If httpClient is nil, http.DefaultClient is used.
+func NewBitbucket(baseURL string, httpClient *http.Client) *Bitbucket {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Bitbucket{url: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// Repo returns the metadata of the repository "workspace/repo".
+func (c *Bitbucket) Repo(ctx context.Context, name string) (*Repo, error) {
+	workspace, repo, ok := strings.Cut(name, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository name %q", name)
+	}
+	base := fmt.Sprintf("%s/2.0/repositories/%s/%s", c.url, url.PathEscape(workspace), url.PathEscape(repo))
+	var r struct {
+		Description string    `json:"description"`
+		UpdatedOn   time.Time `json:"updated_on"`
+	}
+	if err := c.get(ctx, base, name, &r); err != nil {
+		return nil, err
+	}
+	// Paginated collections report their total size on every page.
+	var watchers, forks struct {
+		Size int `json:"size"`
+	}
+	if err := c.get(ctx, base+"/watchers?pagelen=1", name, &watchers); err != nil {
+		return nil, err
+	}
+	if err := c.get(ctx, base+"/forks?pagelen=1", name, &forks); err != nil {
+		return nil, err
+	}
+	var tags struct {
+		Values []struct {
+			Name   string `json:"name"`
+			Target struct {
+				Date time.Time `json:"date"`
+			} `json:"target"`
+		} `json:"values"`
+	}
+	if err := c.get(ctx, base+"/refs/tags?sort=-target.date&pagelen=1", name, &tags); err != nil {
+		return nil, err
+	}
+	info := &Repo{
+		Name:        name,
+		Description: r.Description,
+		Stars:       watchers.Size,
+		Forks:       forks.Size,
+		PushedAt:    r.UpdatedOn,
+	}
+	if len(tags.Values) > 0 {
+		info.LatestRelease, info.LatestReleaseAt = tags.Values[0].Name, tags.Values[0].Target.Date
+	}
+	return info, nil
+}
+
+func (c *Bitbucket) get(ctx context.Context, u, name string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	default:
+		return fmt.Errorf("get %s: unexpected status: %s", u, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode %s: %w", u, err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package forge fetches repository metadata from the code hosting sites
+// curated packages live on. Each forge has its own API; a Client maps the
+// answers of one into the common Repo model, so that stars, activity and
+// the archived status compare across forges.
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for repositories a forge does not know.
+var ErrNotFound = errors.New("repository not found")
+
+// Client fetches repository metadata from a forge.
+type Client interface {
+	// Repo returns the metadata of the repository with the given name,
+	// e.g. "owner/repo" or "group/subgroup/project".
+	Repo(ctx context.Context, name string) (*Repo, error)
+}
+
+// A FileLister lists the files of repositories. Forges whose API can list
+// a whole tree implement it.
+type FileLister interface {
+	// Files returns the paths of the files in the default branch of the
+	// repository with the given name, e.g. "sub/go.mod".
+	Files(ctx context.Context, name string) ([]string, error)
+}
+
+// A ReadmeReader reads the READMEs of repositories. Forges whose API
+// serves them implement it.
+type ReadmeReader interface {
+	// Readme returns the README in the default branch of the repository
+	// with the given name.
+	Readme(ctx context.Context, name string) (string, error)
+}
+
+// Repo is the metadata of a repository common to all forges. Forges
+// without a notion of stars report the closest they have, e.g. watchers.
+type Repo struct {
+	Name        string
+	Description string
+	Stars       int
+	Forks       int
+	Archived    bool
+	PushedAt    time.Time // last activity, zero if unknown
+	License     string    // license identifier, empty if unknown
+	Topics      []string
+
+	// LatestRelease is the tag of the latest release or, on forges without
+	// releases or for repositories without any, the latest tag. Empty if
+	// there is none.
+	LatestRelease   string
+	LatestReleaseAt time.Time // zero if unknown
+}
+
+// Location is a repository and, for deep links, a directory within it.
+type Location struct {
+	Host string
+	Name string // e.g. "owner/repo" or "group/subgroup/project"
+	Ref  string // branch or tag of a deep link, empty if none
+	Dir  string // directory within the repository, empty for the root
+}
+
+// Path returns the import path of the directory, e.g.
+// github.com/owner/repo/sub: the path of a nested module or of a package
+// in the module of the repository.
+func (l Location) Path() string {
+	p := l.Host + "/" + l.Name
+	if l.Dir != "" {
+		p += "/" + l.Dir
+	}
+	return p
+}
+
+// ownerRepoHosts are forges whose repositories are named "owner/repo",
+// so that the rest of a URL points into the repository.
+var ownerRepoHosts = []string{"github.com", "bitbucket.org", "codeberg.org", "gitea.com", "git.sr.ht"}
+
+// Owner returns the owner of an import path on a forge with owner/repo
+// names or GitLab, e.g. "github.com/uber-go" for github.com/uber-go/zap,
+// and else the host, e.g. "go.uber.org" for go.uber.org/zap.
+func Owner(path string) string {
+	host, rest, ok := strings.Cut(path, "/")
+	if !ok || !slices.Contains(ownerRepoHosts, host) && host != "gitlab.com" {
+		return host
+	}
+	owner, _, _ := strings.Cut(rest, "/")
+	return host + "/" + owner
+}
+
+// githubPages are pages of a GitHub repository that are not directories.
+var githubPages = []string{"issues", "pulls", "wiki", "actions", "releases", "tags", "graphs", "discussions", "security", "raw", "commits", "compare"}
+
+// ParseURL returns the repository a URL points to, e.g. "gitlab.com" and
+// "group/project" for https://gitlab.com/group/project/-/tree/main, and
+// the directory of deep links like https://github.com/owner/repo/tree/main/sub.
+// Refs are assumed not to contain slashes.
+func ParseURL(rawurl string) (Location, error) {
+	var l Location
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return l, fmt.Errorf("parse URL: %w", err)
+	}
+	l.Host = strings.ToLower(u.Host)
+	if l.Host == "sr.ht" {
+		// Project pages on the sourcehut hub usually share the name of
+		// their main repository.
+		l.Host = "git.sr.ht"
+	}
+	// GitLab separates the pages of a project from its path with "/-/".
+	p, pages, _ := strings.Cut(u.Path, "/-/")
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	if p == "" {
+		return l, fmt.Errorf("no repository in URL %s", rawurl)
+	}
+	var rest []string
+	if pages != "" {
+		rest = strings.Split(strings.Trim(pages, "/"), "/")
+	}
+	l.Name = p
+	if slices.Contains(ownerRepoHosts, l.Host) {
+		parts := strings.Split(p, "/")
+		if len(parts) < 2 {
+			return l, fmt.Errorf("no repository in URL %s", rawurl)
+		}
+		l.Name, rest = parts[0]+"/"+parts[1], parts[2:]
+	}
+
+	var dir []string
+	switch {
+	case l.Host == "github.com" || l.Host == "gitlab.com":
+		if len(rest) >= 2 && (rest[0] == "tree" || rest[0] == "blob") {
+			l.Ref, dir = rest[1], rest[2:]
+			if rest[0] == "blob" && len(dir) > 0 {
+				dir = dir[:len(dir)-1] // the file
+			}
+		} else if l.Host == "github.com" && len(rest) > 0 && !slices.Contains(githubPages, rest[0]) {
+			dir = rest
+		}
+	case l.Host == "bitbucket.org":
+		// Other pages, e.g. /overview, are not directories.
+		if len(rest) >= 2 && rest[0] == "src" {
+			l.Ref, dir = rest[1], rest[2:]
+		}
+	case l.Host == "git.sr.ht":
+		if len(rest) >= 4 && rest[0] == "tree" && rest[2] == "item" {
+			l.Ref, dir = rest[1], rest[3:]
+		}
+	case slices.Contains(ownerRepoHosts, l.Host):
+		// Gitea: /src/branch/main/dir, /src/tag/v1.0.0/dir.
+		if len(rest) >= 3 && rest[0] == "src" {
+			l.Ref, dir = rest[2], rest[3:]
+		}
+	}
+	l.Dir = strings.Join(dir, "/")
+	return l, nil
+}
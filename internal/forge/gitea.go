@@ -0,0 +1,115 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Gitea talks to the REST API of a Gitea or Forgejo instance, such as
+// codeberg.org and gitea.com. It is safe for concurrent use.
+type Gitea struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewGitea returns a client for the instance at baseURL, e.g.
+// https://codeberg.org. If httpClient is nil, http.DefaultClient is used.
+func NewGitea(baseURL string, httpClient *http.Client) *Gitea {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Gitea{url: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// Repo returns the metadata of the repository "owner/repo".
+func (c *Gitea) Repo(ctx context.Context, name string) (*Repo, error) {
+	owner, repo, ok := strings.Cut(name, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository name %q", name)
+	}
+	base := fmt.Sprintf("%s/api/v1/repos/%s/%s", c.url, url.PathEscape(owner), url.PathEscape(repo))
+	var r struct {
+		Description string    `json:"description"`
+		Stars       int       `json:"stars_count"`
+		Forks       int       `json:"forks_count"`
+		Archived    bool      `json:"archived"`
+		UpdatedAt   time.Time `json:"updated_at"`
+		Licenses    []string  `json:"licenses"` // since Gitea 1.22
+	}
+	if err := c.get(ctx, base, name, &r); err != nil {
+		return nil, err
+	}
+	var topics struct {
+		Topics []string `json:"topics"`
+	}
+	if err := c.get(ctx, base+"/topics", name, &topics); err != nil {
+		return nil, err
+	}
+	// Repositories without releases answer 404 here.
+	var release struct {
+		TagName     string    `json:"tag_name"`
+		PublishedAt time.Time `json:"published_at"`
+	}
+	if err := c.get(ctx, base+"/releases/latest", name, &release); err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	var tags []struct {
+		Name   string `json:"name"`
+		Commit struct {
+			Created time.Time `json:"created"`
+		} `json:"commit"`
+	}
+	if release.TagName == "" {
+		if err := c.get(ctx, base+"/tags?limit=1", name, &tags); err != nil {
+			return nil, err
+		}
+	}
+	info := &Repo{
+		Name:        name,
+		Description: r.Description,
+		Stars:       r.Stars,
+		Forks:       r.Forks,
+		Archived:    r.Archived,
+		PushedAt:    r.UpdatedAt,
+		Topics:      topics.Topics,
+	}
+	if len(r.Licenses) > 0 {
+		info.License = r.Licenses[0]
+	}
+	if release.TagName != "" {
+		info.LatestRelease, info.LatestReleaseAt = release.TagName, release.PublishedAt
+	} else if len(tags) > 0 {
+		info.LatestRelease, info.LatestReleaseAt = tags[0].Name, tags[0].Commit.Created
+	}
+	return info, nil
+}
+
+func (c *Gitea) get(ctx context.Context, u, name string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	default:
+		return fmt.Errorf("get %s: unexpected status: %s", u, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode %s: %w", u, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+
+	"github.com/ngrash/modhunt/internal/ghclient"
+)
+
+// GitHub fetches repositories with the REST API of GitHub. Bulk jobs
+// should prefer ghclient.Client.BatchRepositories, which fetches many
+// repositories per request.
+type GitHub struct {
+	client *ghclient.Client
+}
+
+// NewGitHub returns a Client using client.
+func NewGitHub(client *ghclient.Client) *GitHub {
+	return &GitHub{client: client}
+}
+
+// Repo returns the metadata of the repository "owner/repo".
+func (g *GitHub) Repo(ctx context.Context, name string) (*Repo, error) {
+	owner, repo, ok := strings.Cut(name, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository name %q", name)
+	}
+	r, _, err := g.client.Repositories.Get(ctx, owner, repo)
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	info := &Repo{
+		Name:        name,
+		Description: r.GetDescription(),
+		Stars:       r.GetStargazersCount(),
+		Forks:       r.GetForksCount(),
+		Archived:    r.GetArchived(),
+		PushedAt:    r.GetPushedAt().Time,
+		License:     r.GetLicense().GetSPDXID(),
+		Topics:      r.Topics,
+	}
+	rel, _, err := g.client.Repositories.GetLatestRelease(ctx, owner, repo)
+	switch {
+	case err == nil:
+		info.LatestRelease, info.LatestReleaseAt = rel.GetTagName(), rel.GetPublishedAt().Time
+	case errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound:
+		// Tags carry no date in the REST API.
+		tags, _, err := g.client.Repositories.ListTags(ctx, owner, repo, &github.ListOptions{PerPage: 1})
+		if err != nil {
+			return nil, fmt.Errorf("list tags: %w", err)
+		}
+		if len(tags) > 0 {
+			info.LatestRelease = tags[0].GetName()
+		}
+	default:
+		return nil, fmt.Errorf("get latest release: %w", err)
+	}
+	return info, nil
+}
+
+// Files returns the paths of the files in the default branch of the
+// repository "owner/repo". Trees too large for the API are truncated.
+func (g *GitHub) Files(ctx context.Context, name string) ([]string, error) {
+	owner, repo, ok := strings.Cut(name, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository name %q", name)
+	}
+	tree, _, err := g.client.Git.GetTree(ctx, owner, repo, "HEAD", true)
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get tree: %w", err)
+	}
+	var files []string
+	for _, e := range tree.Entries {
+		if e.GetType() == "blob" {
+			files = append(files, e.GetPath())
+		}
+	}
+	return files, nil
+}
+
+// Readme returns the README in the default branch of the repository
+// "owner/repo".
+func (g *GitHub) Readme(ctx context.Context, name string) (string, error) {
+	owner, repo, ok := strings.Cut(name, "/")
+	if !ok {
+		return "", fmt.Errorf("invalid repository name %q", name)
+	}
+	readme, _, err := g.client.Repositories.GetReadme(ctx, owner, repo, nil)
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("get readme: %w", err)
+	}
+	content, err := readme.GetContent()
+	if err != nil {
+		return "", fmt.Errorf("decode readme: %w", err)
+	}
+	return content, nil
+}
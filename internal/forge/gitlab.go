@@ -0,0 +1,132 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitLabURL is the base URL of gitlab.com.
+const GitLabURL = "https://gitlab.com"
+
+// GitLab talks to the REST API of a GitLab instance. It is safe for
+// concurrent use.
+type GitLab struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewGitLab returns a client for the instance at baseURL, e.g. GitLabURL.
+// Without a token, requests are subject to the lower rate limit of
+// anonymous users. If httpClient is nil, http.DefaultClient is used.
+func NewGitLab(baseURL, token string, httpClient *http.Client) *GitLab {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &GitLab{url: strings.TrimRight(baseURL, "/"), token: token, httpClient: httpClient}
+}
+
+// Repo returns the metadata of the project at the path name.
+func (c *GitLab) Repo(ctx context.Context, name string) (*Repo, error) {
+	// Projects are addressed by their URL-encoded path.
+	base := fmt.Sprintf("%s/api/v4/projects/%s", c.url, url.PathEscape(name))
+	var p struct {
+		Description    string    `json:"description"`
+		StarCount      int       `json:"star_count"`
+		ForksCount     int       `json:"forks_count"`
+		Archived       bool      `json:"archived"`
+		LastActivityAt time.Time `json:"last_activity_at"`
+		Topics         []string  `json:"topics"`
+		License        *struct {
+			Key string `json:"key"`
+		} `json:"license"`
+	}
+	if err := c.get(ctx, base+"?license=true", name, &p); err != nil {
+		return nil, err
+	}
+	// Releases are made from tags, so the latest tag is at least as new.
+	var tags []struct {
+		Name   string `json:"name"`
+		Commit struct {
+			CommittedDate time.Time `json:"committed_date"`
+		} `json:"commit"`
+	}
+	if err := c.get(ctx, base+"/repository/tags?order_by=updated&sort=desc&per_page=1", name, &tags); err != nil {
+		return nil, err
+	}
+	r := &Repo{
+		Name:        name,
+		Description: p.Description,
+		Stars:       p.StarCount,
+		Forks:       p.ForksCount,
+		Archived:    p.Archived,
+		PushedAt:    p.LastActivityAt,
+		Topics:      p.Topics,
+	}
+	if p.License != nil {
+		r.License = p.License.Key
+	}
+	if len(tags) > 0 {
+		r.LatestRelease, r.LatestReleaseAt = tags[0].Name, tags[0].Commit.CommittedDate
+	}
+	return r, nil
+}
+
+// gitlabPageSize is the largest page size of the GitLab API.
+const gitlabPageSize = 100
+
+// Files returns the paths of the files in the default branch of the
+// project at the path name.
+func (c *GitLab) Files(ctx context.Context, name string) ([]string, error) {
+	var files []string
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/api/v4/projects/%s/repository/tree?recursive=true&per_page=%d&page=%d", c.url, url.PathEscape(name), gitlabPageSize, page)
+		var entries []struct {
+			Type string `json:"type"`
+			Path string `json:"path"`
+		}
+		if err := c.get(ctx, u, name, &entries); err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.Type == "blob" {
+				files = append(files, e.Path)
+			}
+		}
+		if len(entries) < gitlabPageSize {
+			return files, nil
+		}
+	}
+}
+
+func (c *GitLab) get(ctx context.Context, u, name string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	default:
+		return fmt.Errorf("get %s: unexpected status: %s", u, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode %s: %w", u, err)
+	}
+	return nil
+}
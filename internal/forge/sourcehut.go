@@ -0,0 +1,112 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// SourceHutURL is the base URL of the git service of sourcehut.
+const SourceHutURL = "https://git.sr.ht"
+
+// SourceHut talks to the GraphQL API of git.sr.ht. Sourcehut has neither
+// stars nor forks, so only the description, activity and latest tag are
+// reported.
+// It is safe for concurrent use.
+type SourceHut struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewSourceHut returns a client for the git service at baseURL, e.g.
+// SourceHutURL. The API requires a personal access token, even for public
+// repositories. If httpClient is nil, http.DefaultClient is used.
+func NewSourceHut(baseURL, token string, httpClient *http.Client) *SourceHut {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &SourceHut{url: strings.TrimRight(baseURL, "/"), token: token, httpClient: httpClient}
+}
+
+const sourceHutQuery = `query($owner: String!, $name: String!) {
+	user(username: $owner) { repository(name: $name) {
+		description updated
+		references { results { name } }
+	} }
+}`
+
+// Repo returns the metadata of the repository "~owner/repo".
+func (c *SourceHut) Repo(ctx context.Context, name string) (*Repo, error) {
+	owner, repo, ok := strings.Cut(name, "/")
+	if !ok || !strings.HasPrefix(owner, "~") {
+		return nil, fmt.Errorf("invalid repository name %q", name)
+	}
+	body, err := json.Marshal(map[string]any{
+		"query":     sourceHutQuery,
+		"variables": map[string]string{"owner": owner[1:], "name": repo},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode query: %w", err)
+	}
+	u := c.url + "/query"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("post %s: unexpected status: %s", u, resp.Status)
+	}
+	var result struct {
+		Data struct {
+			User *struct {
+				Repository *struct {
+					Description string    `json:"description"`
+					Updated     time.Time `json:"updated"`
+					References  struct {
+						Results []struct {
+							Name string `json:"name"` // e.g. refs/tags/v1.0.0
+						} `json:"results"`
+					} `json:"references"`
+				} `json:"repository"`
+			} `json:"user"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, errors.New("sourcehut: " + result.Errors[0].Message)
+	}
+	if result.Data.User == nil || result.Data.User.Repository == nil {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	r := result.Data.User.Repository
+	info := &Repo{Name: name, Description: r.Description, PushedAt: r.Updated}
+	// References carry no date, so the highest semantic version wins.
+	for _, ref := range r.References.Results {
+		tag, ok := strings.CutPrefix(ref.Name, "refs/tags/")
+		if ok && semver.IsValid(tag) && semver.Compare(tag, info.LatestRelease) > 0 {
+			info.LatestRelease = tag
+		}
+	}
+	return info, nil
+}
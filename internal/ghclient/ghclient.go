@@ -0,0 +1,233 @@
+// Package ghclient creates GitHub API clients that respect rate limits.
+//
+// Unauthenticated clients may send 60 requests per hour, clients with a
+// token 5000. The transport of a client tracks the quota reported with
+// every response and paces requests once less than a tenth of it is left,
+// so that bulk jobs spread the rest evenly until the reset. Responses
+// rejected for exceeding the primary or a secondary rate limit are retried
+// after the reset or Retry-After delay if that is not longer than the
+// maximum wait; otherwise the error of go-github is returned, e.g. a
+// *github.RateLimitError.
+package ghclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// DefaultMaxWait is the longest a client waits for a rate limit by default.
+const DefaultMaxWait = 5 * time.Minute
+
+// Client is a GitHub API client whose transport waits out rate limits.
+type Client struct {
+	*github.Client
+	transport *transport
+}
+
+// An Option configures a Client.
+type Option func(*config)
+
+type config struct {
+	base    http.RoundTripper
+	baseURL string
+	maxWait time.Duration
+	log     io.Writer
+}
+
+// WithTransport sets the transport requests are sent with.
+// The default is http.DefaultTransport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *config) { c.base = rt }
+}
+
+// WithBaseURL talks to the API at rawurl instead of api.github.com,
+// e.g. a GitHub Enterprise server or a fake in tests.
+func WithBaseURL(rawurl string) Option {
+	return func(c *config) { c.baseURL = rawurl }
+}
+
+// WithMaxWait sets the longest a request waits for a rate limit reset.
+// Zero never waits. The default is DefaultMaxWait.
+func WithMaxWait(d time.Duration) Option {
+	return func(c *config) { c.maxWait = d }
+}
+
+// WithLog writes the remaining quota to w after every response.
+func WithLog(w io.Writer) Option {
+	return func(c *config) { c.log = w }
+}
+
+// New returns a client authenticated with token or, if token is empty,
+// an unauthenticated client.
+func New(token string, opts ...Option) (*Client, error) {
+	cfg := config{base: http.DefaultTransport, maxWait: DefaultMaxWait}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	t := &transport{base: cfg.base, maxWait: cfg.maxWait, log: cfg.log}
+	gh := github.NewClient(&http.Client{Transport: t})
+	if token != "" {
+		gh = gh.WithAuthToken(token)
+	}
+	if cfg.baseURL != "" {
+		var err error
+		if gh, err = gh.WithEnterpriseURLs(cfg.baseURL, cfg.baseURL); err != nil {
+			return nil, fmt.Errorf("invalid base URL: %w", err)
+		}
+	}
+	return &Client{Client: gh, transport: t}, nil
+}
+
+// Rate is the quota of the client as last reported by GitHub.
+type Rate struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Rate returns the quota reported with the last response.
+// It is the zero Rate before the first response.
+func (c *Client) Rate() Rate {
+	return c.transport.rate()
+}
+
+// maxRetries is the number of times a rate limited request is retried.
+const maxRetries = 3
+
+type transport struct {
+	base    http.RoundTripper
+	maxWait time.Duration
+	log     io.Writer
+
+	mu   sync.Mutex
+	last Rate
+}
+
+func (t *transport) rate() Rate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := sleep(req.Context(), t.pace()); err != nil {
+			return nil, err
+		}
+		r := req
+		if attempt > 0 {
+			var err error
+			if r, err = rewind(req); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		t.update(resp)
+
+		wait, limited := retryDelay(resp)
+		if !limited || attempt >= maxRetries || wait > t.maxWait || req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		if t.log != nil {
+			_, _ = fmt.Fprintf(t.log, "github: rate limited, retrying in %s\n", wait.Round(time.Second))
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// pace returns how long to wait before the next request. Once less than
+// a tenth of the quota is left, the rest is spread until the reset.
+func (t *transport) pace() time.Duration {
+	r := t.rate()
+	until := time.Until(r.Reset)
+	if r.Limit == 0 || r.Remaining >= r.Limit/10 || until <= 0 {
+		return 0
+	}
+	if r.Remaining == 0 {
+		// go-github fails fast before the reset, unless we wait.
+		if until <= t.maxWait {
+			return until
+		}
+		return 0
+	}
+	return until / time.Duration(r.Remaining+1)
+}
+
+// update records the quota reported with resp.
+func (t *transport) update(resp *http.Response) {
+	limit, err1 := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	remaining, err2 := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, err3 := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return
+	}
+	r := Rate{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}
+	t.mu.Lock()
+	t.last = r
+	t.mu.Unlock()
+	if t.log != nil {
+		_, _ = fmt.Fprintf(t.log, "github: %d/%d requests left until %s\n", r.Remaining, r.Limit, r.Reset.Format(time.TimeOnly))
+	}
+}
+
+// retryDelay reports whether resp was rejected by a rate limit and how
+// long to wait before retrying.
+func retryDelay(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	// Secondary rate limits tell how long to wait.
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0) + time.Second, true
+		}
+	}
+	return 0, false
+}
+
+// rewind returns a copy of req with a fresh body for retrying it.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("rewind request body: %w", err)
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,224 @@
+package ghclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The GraphQL API fetches many repositories with one request, which costs
+// a single point of the GraphQL rate limit instead of one REST request per
+// repository. It requires authentication.
+
+// MaxBatch is the largest number of repositories fetched per request.
+const MaxBatch = 100
+
+// ErrRateLimited is returned when the GraphQL rate limit is exhausted.
+var ErrRateLimited = errors.New("github: GraphQL rate limit exceeded")
+
+// Repository is the metadata of a repository fetched with GraphQL.
+type Repository struct {
+	NameWithOwner string
+	Description   string
+	Stars         int
+	Forks         int
+	Archived      bool
+	PushedAt      time.Time
+	License       string // SPDX identifier
+	Topics        []string
+	LatestRelease *Release // nil if there is none
+	LatestTag     *Release // nil if there is none
+	// CommitAuthors are the logins, or emails of authors without an
+	// account, of the most recent commits on the default branch.
+	CommitAuthors []string
+}
+
+// Release is a published release or a tag of a repository.
+type Release struct {
+	Tag         string
+	PublishedAt time.Time
+}
+
+const repositoryFields = `nameWithOwner description stargazerCount forkCount isArchived pushedAt
+	licenseInfo { spdxId }
+	repositoryTopics(first: 20) { nodes { topic { name } } }
+	latestRelease { tagName publishedAt }
+	refs(refPrefix: "refs/tags/", first: 1, orderBy: {field: TAG_COMMIT_DATE, direction: DESC}) {
+		nodes { name target { ... on Commit { committedDate } ... on Tag { target { ... on Commit { committedDate } } } } }
+	}
+	defaultBranchRef { target { ... on Commit { history(first: 100) { nodes { author { email user { login } } } } } } }`
+
+type graphRepository struct {
+	NameWithOwner  string    `json:"nameWithOwner"`
+	Description    string    `json:"description"`
+	StargazerCount int       `json:"stargazerCount"`
+	ForkCount      int       `json:"forkCount"`
+	IsArchived     bool      `json:"isArchived"`
+	PushedAt       time.Time `json:"pushedAt"`
+	LicenseInfo    *struct {
+		SPDXID string `json:"spdxId"`
+	} `json:"licenseInfo"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	LatestRelease *struct {
+		TagName     string    `json:"tagName"`
+		PublishedAt time.Time `json:"publishedAt"`
+	} `json:"latestRelease"`
+	Refs struct {
+		Nodes []struct {
+			Name   string `json:"name"`
+			Target struct {
+				CommittedDate time.Time `json:"committedDate"` // lightweight tags
+				Target        *struct {
+					CommittedDate time.Time `json:"committedDate"`
+				} `json:"target"` // annotated tags
+			} `json:"target"`
+		} `json:"nodes"`
+	} `json:"refs"`
+	DefaultBranchRef *struct {
+		Target struct {
+			History struct {
+				Nodes []struct {
+					Author struct {
+						Email string `json:"email"`
+						User  *struct {
+							Login string `json:"login"`
+						} `json:"user"`
+					} `json:"author"`
+				} `json:"nodes"`
+			} `json:"history"`
+		} `json:"target"`
+	} `json:"defaultBranchRef"`
+}
+
+// BatchRepositories fetches the repositories named "owner/name" with as
+// few GraphQL requests as possible. Repositories that do not exist are
+// missing from the result.
+func (c *Client) BatchRepositories(ctx context.Context, names []string) (map[string]*Repository, error) {
+	repos := make(map[string]*Repository, len(names))
+	for len(names) > 0 {
+		n := min(len(names), MaxBatch)
+		if err := c.batch(ctx, names[:n], repos); err != nil {
+			return repos, err
+		}
+		names = names[n:]
+	}
+	return repos, nil
+}
+
+func (c *Client) batch(ctx context.Context, names []string, repos map[string]*Repository) error {
+	q, err := repositoriesQuery(names, repositoryFields)
+	if err != nil {
+		return err
+	}
+	var data map[string]*graphRepository
+	if err := c.query(ctx, q, &data); err != nil {
+		return err
+	}
+	for i, name := range names {
+		r := data["r"+strconv.Itoa(i)]
+		if r == nil {
+			continue
+		}
+		repo := &Repository{
+			NameWithOwner: r.NameWithOwner,
+			Description:   r.Description,
+			Stars:         r.StargazerCount,
+			Forks:         r.ForkCount,
+			Archived:      r.IsArchived,
+			PushedAt:      r.PushedAt,
+		}
+		if r.LicenseInfo != nil {
+			repo.License = r.LicenseInfo.SPDXID
+		}
+		for _, n := range r.RepositoryTopics.Nodes {
+			repo.Topics = append(repo.Topics, n.Topic.Name)
+		}
+		if r.LatestRelease != nil {
+			repo.LatestRelease = &Release{Tag: r.LatestRelease.TagName, PublishedAt: r.LatestRelease.PublishedAt}
+		}
+		if len(r.Refs.Nodes) > 0 {
+			tag := r.Refs.Nodes[0]
+			repo.LatestTag = &Release{Tag: tag.Name, PublishedAt: tag.Target.CommittedDate}
+			if tag.Target.Target != nil {
+				repo.LatestTag.PublishedAt = tag.Target.Target.CommittedDate
+			}
+		}
+		if r.DefaultBranchRef != nil {
+			for _, c := range r.DefaultBranchRef.Target.History.Nodes {
+				author := c.Author.Email
+				if c.Author.User != nil {
+					author = c.Author.User.Login
+				}
+				repo.CommitAuthors = append(repo.CommitAuthors, author)
+			}
+		}
+		repos[name] = repo
+	}
+	return nil
+}
+
+// repositoriesQuery returns a query selecting fields of every repository
+// in names, aliased as r0, r1 and so on.
+func repositoriesQuery(names []string, fields string) (string, error) {
+	var q strings.Builder
+	q.WriteString("query {\n")
+	for i, name := range names {
+		owner, repo, ok := strings.Cut(name, "/")
+		if !ok {
+			return "", fmt.Errorf("invalid repository name %q", name)
+		}
+		fmt.Fprintf(&q, "r%d: repository(owner: %s, name: %s) { %s }\n", i, strconv.Quote(owner), strconv.Quote(repo), fields)
+	}
+	q.WriteString("}")
+	return q.String(), nil
+}
+
+// query posts the GraphQL query q and decodes its data into data. Errors
+// for repositories that do not exist are ignored, their fields are null.
+func (c *Client) query(ctx context.Context, q string, data any) error {
+	// The GraphQL endpoint is /graphql on github.com and /api/graphql on
+	// GitHub Enterprise, whose REST API is at /api/v3/.
+	endpoint := "graphql"
+	if strings.HasSuffix(c.BaseURL.Path, "/api/v3/") {
+		endpoint = "../graphql"
+	}
+	req, err := c.NewRequest(http.MethodPost, endpoint, map[string]string{"query": q})
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+	for _, e := range resp.Errors {
+		switch e.Type {
+		case "NOT_FOUND":
+			// Reported as missing.
+		case "RATE_LIMITED":
+			return ErrRateLimited
+		default:
+			return fmt.Errorf("github: GraphQL error: %s", e.Message)
+		}
+	}
+	if err := json.Unmarshal(resp.Data, data); err != nil {
+		return fmt.Errorf("decode GraphQL data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package ghclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MaxIssueBatch is the largest number of repositories whose issues are
+// sampled per request. Issues with their comments are expensive in terms
+// of the GraphQL rate limit, which counts the nodes a query may return.
+const MaxIssueBatch = 20
+
+// IssueSample is a sample of the most recent issues of a repository,
+// excluding pull requests.
+type IssueSample struct {
+	Open   int // number of open issues
+	Closed int // number of closed issues
+	Issues []Issue
+}
+
+// Issue is an issue and when it was first responded to.
+type Issue struct {
+	CreatedAt time.Time
+	ClosedAt  time.Time // zero if open
+	// FirstResponse is the time of the first comment by someone other
+	// than the author and not a bot, or zero if there is none.
+	FirstResponse time.Time
+}
+
+// issueComments is the number of comments inspected per issue to find
+// the first response.
+const issueComments = 10
+
+type graphIssues struct {
+	Open   struct{ TotalCount int } `json:"open"`
+	Closed struct{ TotalCount int } `json:"closed"`
+	Recent struct {
+		Nodes []struct {
+			CreatedAt time.Time  `json:"createdAt"`
+			ClosedAt  *time.Time `json:"closedAt"`
+			Author    *struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			Comments struct {
+				Nodes []struct {
+					CreatedAt time.Time `json:"createdAt"`
+					Author    *struct {
+						Login    string `json:"login"`
+						TypeName string `json:"__typename"`
+					} `json:"author"`
+				} `json:"nodes"`
+			} `json:"comments"`
+		} `json:"nodes"`
+	} `json:"recent"`
+}
+
+// BatchIssues samples the n most recent issues of the repositories named
+// "owner/name". Repositories that do not exist are missing from the
+// result.
+func (c *Client) BatchIssues(ctx context.Context, names []string, n int) (map[string]*IssueSample, error) {
+	fields := fmt.Sprintf(`open: issues(states: OPEN) { totalCount }
+	closed: issues(states: CLOSED) { totalCount }
+	recent: issues(first: %d, orderBy: {field: CREATED_AT, direction: DESC}) {
+		nodes { createdAt closedAt author { login } comments(first: %d) { nodes { createdAt author { login __typename } } } }
+	}`, n, issueComments)
+	samples := make(map[string]*IssueSample, len(names))
+	for len(names) > 0 {
+		batch := names[:min(len(names), MaxIssueBatch)]
+		names = names[len(batch):]
+
+		q, err := repositoriesQuery(batch, fields)
+		if err != nil {
+			return samples, err
+		}
+		var data map[string]*graphIssues
+		if err := c.query(ctx, q, &data); err != nil {
+			return samples, err
+		}
+		for i, name := range batch {
+			r := data["r"+strconv.Itoa(i)]
+			if r == nil {
+				continue
+			}
+			s := &IssueSample{Open: r.Open.TotalCount, Closed: r.Closed.TotalCount}
+			for _, node := range r.Recent.Nodes {
+				issue := Issue{CreatedAt: node.CreatedAt}
+				if node.ClosedAt != nil {
+					issue.ClosedAt = *node.ClosedAt
+				}
+				for _, comment := range node.Comments.Nodes {
+					a := comment.Author
+					if a == nil || a.TypeName == "Bot" || node.Author != nil && a.Login == node.Author.Login {
+						continue
+					}
+					issue.FirstResponse = comment.CreatedAt
+					break
+				}
+				s.Issues = append(s.Issues, issue)
+			}
+			samples[name] = s
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,105 @@
+// Package goimport resolves import paths on custom domains the way the go
+// command does: by fetching https://PATH?go-get=1 and reading the
+// go-import meta tags, which name the root of the repository or module
+// the path belongs to.
+package goimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ErrNotFound is returned for paths without a matching go-import tag.
+var ErrNotFound = errors.New("no go-import meta tag")
+
+// Import is a go-import meta tag.
+type Import struct {
+	Prefix   string // import path of the repository root
+	VCS      string // e.g. "git" or "mod"
+	RepoRoot string // URL of the repository or module proxy
+}
+
+// Client fetches go-import meta tags. It is safe for concurrent use.
+type Client struct {
+	httpClient *http.Client
+}
+
+// New returns a client. If httpClient is nil, http.DefaultClient is used.
+func New(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{httpClient: httpClient}
+}
+
+var (
+	metaRE    = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	nameRE    = regexp.MustCompile(`(?is)\sname\s*=\s*["']?go-import["'\s/>]`)
+	contentRE = regexp.MustCompile(`(?is)\scontent\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// Resolve returns the go-import tag of importPath with the longest
+// prefix, like the go command does.
+func (c *Client) Resolve(ctx context.Context, importPath string) (Import, error) {
+	u := "https://" + importPath + "?go-get=1"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return Import{}, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Import{}, fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	// Some servers serve the tags with error pages, so the status is
+	// only checked if there are none.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Import{}, fmt.Errorf("read %s: %w", u, err)
+	}
+
+	var best Import
+	for _, tag := range Parse(string(body)) {
+		if hasPathPrefix(importPath, tag.Prefix) && len(tag.Prefix) > len(best.Prefix) {
+			best = tag
+		}
+	}
+	if best.Prefix == "" {
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+			return Import{}, fmt.Errorf("get %s: unexpected status: %s", u, resp.Status)
+		}
+		return Import{}, fmt.Errorf("%s: %w", importPath, ErrNotFound)
+	}
+	return best, nil
+}
+
+// Parse returns the go-import meta tags of an HTML page.
+func Parse(page string) []Import {
+	var imports []Import
+	for _, meta := range metaRE.FindAllString(page, -1) {
+		if !nameRE.MatchString(meta) {
+			continue
+		}
+		m := contentRE.FindStringSubmatch(meta)
+		if m == nil {
+			continue
+		}
+		fields := strings.Fields(html.UnescapeString(m[1] + m[2]))
+		if len(fields) != 3 {
+			continue
+		}
+		imports = append(imports, Import{Prefix: fields[0], VCS: fields[1], RepoRoot: fields[2]})
+	}
+	return imports
+}
+
+// hasPathPrefix reports whether path is prefix or a path below it.
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
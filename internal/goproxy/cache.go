@@ -0,0 +1,81 @@
+package goproxy
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/mod/module"
+
+	"github.com/ngrash/modhunt/internal/atomicfile"
+)
+
+// The on-disk cache uses the layout of a module proxy below its directory,
+// e.g. DIR/github.com/!burnt!sushi/toml/@v/v1.4.0.mod. Files of a version
+// never change and are kept forever. @latest and @v/list change with new
+// releases and expire after the TTL set with WithCacheTTL.
+
+// DefaultCacheTTL is the time @latest and @v/list responses are cached.
+const DefaultCacheTTL = time.Hour
+
+// WithCache caches responses below dir.
+func WithCache(dir string) Option {
+	return func(c *Client) { c.cacheDir = dir }
+}
+
+// WithCacheTTL sets the time @latest and @v/list responses are cached.
+// The default is DefaultCacheTTL. Zero disables caching them.
+func WithCacheTTL(d time.Duration) Option {
+	return func(c *Client) { c.cacheTTL = d }
+}
+
+// cacheFile returns the name of the cache file of the module file and
+// whether it may be cached at all.
+func (c *Client) cacheFile(path, file string) (string, bool) {
+	if c.cacheDir == "" {
+		return "", false
+	}
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return "", false
+	}
+	if version, ext, ok := splitFile(file); ok {
+		escVersion, err := module.EscapeVersion(version)
+		if err != nil {
+			return "", false
+		}
+		file = "@v/" + escVersion + "." + ext
+	} else if c.cacheTTL <= 0 {
+		return "", false
+	}
+	return filepath.Join(c.cacheDir, filepath.FromSlash(escPath), filepath.FromSlash(file)), true
+}
+
+// readCache returns the cached module file or nil if it is not cached
+// or expired.
+func (c *Client) readCache(path, file string) []byte {
+	name, ok := c.cacheFile(path, file)
+	if !ok {
+		return nil
+	}
+	if _, _, immutable := splitFile(file); !immutable {
+		fi, err := os.Stat(name)
+		if err != nil || time.Since(fi.ModTime()) > c.cacheTTL {
+			return nil
+		}
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// writeCache stores the module file.
+func (c *Client) writeCache(path, file string, data []byte) error {
+	name, ok := c.cacheFile(path, file)
+	if !ok {
+		return nil
+	}
+	return atomicfile.WriteFile(name, data)
+}
@@ -0,0 +1,94 @@
+package goproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// goCommand serves the entry "direct" by running the go command with
+// GOPROXY=direct, which resolves modules from version control.
+type goCommand struct{}
+
+func (g goCommand) Fetch(ctx context.Context, path, file string) ([]byte, error) {
+	switch file {
+	case "@latest":
+		return g.info(ctx, path+"@latest")
+	case "@v/list":
+		var m struct{ Versions []string }
+		if err := g.run(ctx, &m, "list", "-m", "-versions", "-json", path); err != nil {
+			return nil, err
+		}
+		return []byte(strings.Join(m.Versions, "\n")), nil
+	}
+
+	version, ext, ok := splitFile(file)
+	if !ok {
+		return nil, fmt.Errorf("unsupported file %q", file)
+	}
+	switch ext {
+	case "info":
+		return g.info(ctx, path+"@"+version)
+	case "mod", "zip":
+		var m struct {
+			Error string
+			GoMod string
+			Zip   string
+		}
+		if err := g.run(ctx, &m, "mod", "download", "-json", path+"@"+version); err != nil {
+			return nil, err
+		}
+		if m.Error != "" {
+			return nil, fmt.Errorf("%s@%s: %s: %w", path, version, m.Error, ErrNotFound)
+		}
+		name := m.GoMod
+		if ext == "zip" {
+			name = m.Zip
+		}
+		return os.ReadFile(name)
+	default:
+		return nil, fmt.Errorf("unsupported file %q", file)
+	}
+}
+
+// info runs go list for the module query and returns the result
+// in the format of the .info endpoint.
+func (g goCommand) info(ctx context.Context, query string) ([]byte, error) {
+	var info Info
+	if err := g.run(ctx, &info, "list", "-m", "-json", query); err != nil {
+		return nil, err
+	}
+	return json.Marshal(info)
+}
+
+// run runs the go command outside of any module and decodes its
+// JSON output into v.
+func (goCommand) run(ctx context.Context, v any, args ...string) error {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = os.TempDir()
+	cmd.Env = append(os.Environ(), "GOPROXY=direct", "GO111MODULE=on", "GOFLAGS=")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(out) == 0 {
+			// Without output the go command could not resolve
+			// the module at all.
+			return fmt.Errorf("go %s: %s: %w", strings.Join(args, " "), msg, ErrNotFound)
+		}
+		if len(out) == 0 {
+			return fmt.Errorf("go %s: %w", strings.Join(args, " "), err)
+		}
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("decode output of go %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+// Package goproxy implements a client for the Go module proxy protocol.
+//
+// The client takes a list of proxies in the format of the GOPROXY
+// environment variable and falls back to the next entry like the go
+// command does: after a 404 or 410 response for entries separated by a
+// comma and after any error for entries separated by a pipe. The entry
+// "direct" fetches from version control using the go command, "off"
+// fails every request.
+//
+// Responses can be cached on disk with WithCache, verified against the
+// checksum database with WithVerify and requests limited per host with
+// WithRateLimit. Concurrent requests for the same file are deduplicated.
+package goproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+)
+
+// DefaultGOPROXY is used by the go command if GOPROXY is not set.
+const DefaultGOPROXY = "https://proxy.golang.org,direct"
+
+var (
+	// ErrNotFound is returned if no entry of the list has the requested
+	// module or version.
+	ErrNotFound = errors.New("not found")
+
+	// ErrDisabled is returned for requests reaching the entry "off".
+	ErrDisabled = errors.New("module lookup disabled by GOPROXY=off")
+)
+
+// Info is the metadata of a version served by the .info and @latest
+// endpoints.
+type Info struct {
+	Version string    `json:"Version"`
+	Time    time.Time `json:"Time"`
+	Origin  struct {
+		VCS  string `json:"VCS"`
+		URL  string `json:"URL"`
+		Ref  string `json:"Ref"`
+		Hash string `json:"Hash"`
+	} `json:"Origin"`
+}
+
+// A Source serves the files of the proxy protocol for one entry of the list.
+type Source interface {
+	// Fetch returns the file of the module path. file is "@latest",
+	// "@v/list" or "@v/VERSION.EXT" with EXT being info, mod or zip.
+	// Paths and versions are not escaped. Missing modules and versions
+	// are reported by errors wrapping ErrNotFound.
+	Fetch(ctx context.Context, path, file string) ([]byte, error)
+}
+
+// A Client fetches module metadata through a list of sources.
+// It is safe for concurrent use.
+type Client struct {
+	entries    []entry
+	httpClient *http.Client
+	direct     Source
+
+	cacheDir string
+	cacheTTL time.Duration
+
+	limit    rate.Limit
+	burst    int
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter // by host
+
+	gosumdb string
+	sumdb   *sumdb.Client
+
+	group singleflight.Group
+}
+
+type entry struct {
+	source Source
+
+	// host is the host of a proxy, requests are rate limited by it.
+	// It is empty for direct, which is limited by the host of the module.
+	host string
+
+	// fallbackOnError is true if the entry is followed by a pipe,
+	// i.e. any error moves on to the next entry.
+	fallbackOnError bool
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used to talk to proxies.
+// The default is http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithDirect replaces the go command serving the entry "direct",
+// e.g. with a fake in tests.
+func WithDirect(s Source) Option {
+	return func(c *Client) { c.direct = s }
+}
+
+// WithRateLimit limits the requests to every host to perSecond with bursts
+// of up to burst requests. By default, requests are not limited.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *Client) { c.limit, c.burst = rate.Limit(perSecond), max(burst, 1) }
+}
+
+// New returns a client for the given GOPROXY list.
+// An empty list is treated as DefaultGOPROXY.
+func New(goproxy string, opts ...Option) (*Client, error) {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		direct:     goCommand{},
+		cacheTTL:   DefaultCacheTTL,
+		limit:      rate.Inf,
+		limiters:   make(map[string]*rate.Limiter),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if goproxy == "" {
+		goproxy = DefaultGOPROXY
+	}
+	for goproxy != "" {
+		var e entry
+		var item string
+		if i := strings.IndexAny(goproxy, ",|"); i >= 0 {
+			item = goproxy[:i]
+			e.fallbackOnError = goproxy[i] == '|'
+			goproxy = goproxy[i+1:]
+		} else {
+			item, goproxy = goproxy, ""
+		}
+		item = strings.TrimSpace(item)
+		switch item {
+		case "":
+			continue
+		case "direct":
+			e.source = c.direct
+		case "off":
+			e.source = offSource{}
+		default:
+			if !strings.Contains(item, "://") {
+				// Like the go command, assume https for bare hosts.
+				item = "https://" + item
+			}
+			u, err := url.Parse(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy %q: %w", item, err)
+			}
+			e.host = u.Host
+			e.source = &proxySource{url: strings.TrimRight(item, "/"), httpClient: c.httpClient}
+		}
+		c.entries = append(c.entries, e)
+	}
+	if len(c.entries) == 0 {
+		return nil, fmt.Errorf("empty GOPROXY list")
+	}
+	if c.gosumdb != "" {
+		db, err := c.newSumDB(c.gosumdb)
+		if err != nil {
+			return nil, err
+		}
+		c.sumdb = db
+	}
+	return c, nil
+}
+
+// Fetch returns the file of the module path from the cache or the first
+// source that has it. See Source for the format of file.
+func (c *Client) Fetch(ctx context.Context, path, file string) ([]byte, error) {
+	if data := c.readCache(path, file); data != nil {
+		// The cache may predate enabling verification.
+		if err := c.verify(path, file, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	v, err, _ := c.group.Do(path+"/"+file, func() (any, error) {
+		data, err := c.fetch(ctx, path, file)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.verify(path, file, data); err != nil {
+			return nil, err
+		}
+		// A broken cache only costs another request next time.
+		_ = c.writeCache(path, file, data)
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+func (c *Client) fetch(ctx context.Context, path, file string) ([]byte, error) {
+	var errs []error
+	for _, e := range c.entries {
+		host := e.host
+		if host == "" {
+			host, _, _ = strings.Cut(path, "/")
+		}
+		if err := c.limiter(host).Wait(ctx); err != nil {
+			return nil, errors.Join(append(errs, err)...)
+		}
+		data, err := e.source.Fetch(ctx, path, file)
+		if err == nil {
+			return data, nil
+		}
+		errs = append(errs, err)
+		if !e.fallbackOnError && !errors.Is(err, ErrNotFound) {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// limiter returns the rate limiter of host.
+func (c *Client) limiter(host string) *rate.Limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.limiters[host]
+	if !ok {
+		l = rate.NewLimiter(c.limit, c.burst)
+		c.limiters[host] = l
+	}
+	return l
+}
+
+// Latest returns the latest version of the module path.
+func (c *Client) Latest(ctx context.Context, path string) (*Info, error) {
+	return c.info(ctx, path, "@latest")
+}
+
+// Info returns the metadata of the version.
+func (c *Client) Info(ctx context.Context, path, version string) (*Info, error) {
+	return c.info(ctx, path, "@v/"+version+".info")
+}
+
+func (c *Client) info(ctx context.Context, path, file string) (*Info, error) {
+	data, err := c.Fetch(ctx, path, file)
+	if err != nil {
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("decode %s/%s: %w", path, file, err)
+	}
+	return &info, nil
+}
+
+// List returns the known tagged versions of the module path.
+func (c *Client) List(ctx context.Context, path string) ([]string, error) {
+	data, err := c.Fetch(ctx, path, "@v/list")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// GoMod returns the go.mod file of the version.
+func (c *Client) GoMod(ctx context.Context, path, version string) ([]byte, error) {
+	return c.Fetch(ctx, path, "@v/"+version+".mod")
+}
+
+// Zip returns the zip file of the version.
+func (c *Client) Zip(ctx context.Context, path, version string) ([]byte, error) {
+	return c.Fetch(ctx, path, "@v/"+version+".zip")
+}
+
+// splitFile splits "@v/VERSION.EXT" into version and extension.
+// ok is false for other files.
+func splitFile(file string) (version, ext string, ok bool) {
+	rest, ok := strings.CutPrefix(file, "@v/")
+	if !ok || rest == "list" {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, ".")
+	if i < 0 {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+// proxySource fetches from a module proxy over HTTP.
+type proxySource struct {
+	url        string
+	httpClient *http.Client
+}
+
+func (p *proxySource) Fetch(ctx context.Context, path, file string) ([]byte, error) {
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("escape path: %w", err)
+	}
+	if version, ext, ok := splitFile(file); ok {
+		escVersion, err := module.EscapeVersion(version)
+		if err != nil {
+			return nil, fmt.Errorf("escape version: %w", err)
+		}
+		file = "@v/" + escVersion + "." + ext
+	}
+	u := p.url + "/" + escPath + "/" + file
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: u, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", u, err)
+	}
+	return data, nil
+}
+
+// A StatusError is returned for responses of a proxy other than 200 OK.
+// It wraps ErrNotFound for 404 Not Found and 410 Gone.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	if e.notFound() {
+		return fmt.Sprintf("get %s: %v", e.URL, ErrNotFound)
+	}
+	return fmt.Sprintf("get %s: unexpected status: %s", e.URL, e.Status)
+}
+
+func (e *StatusError) Unwrap() error {
+	if e.notFound() {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (e *StatusError) notFound() bool {
+	return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+}
+
+type offSource struct{}
+
+func (offSource) Fetch(ctx context.Context, path, file string) ([]byte, error) {
+	return nil, fmt.Errorf("%s/%s: %w", path, file, ErrDisabled)
+}
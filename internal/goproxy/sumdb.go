@@ -0,0 +1,264 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/dirhash"
+
+	"github.com/ngrash/modhunt/internal/atomicfile"
+)
+
+// Files can be verified against a checksum database with WithVerify.
+// The hashes of .mod and .zip files are compared to the go.sum lines the
+// database serves for the version, .info and @latest responses are
+// checked to name a version the database knows. @v/list is not verified.
+// Like the go command, the signed tree heads and tiles of the database are
+// kept next to the cache if there is one, below DIR/sumdb.
+
+// DefaultGOSUMDB is used by the go command if GOSUMDB is not set.
+const DefaultGOSUMDB = "sum.golang.org"
+
+// sumdbTimeout bounds every request to the checksum database. Lookups
+// can't be canceled, sumdb.ClientOps takes no context.
+const sumdbTimeout = 30 * time.Second
+
+// ErrChecksum is returned for files not matching the checksum database.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// knownSumDBs are the checksum databases the go command accepts by name.
+var knownSumDBs = map[string]string{
+	"sum.golang.org":       "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ja0jsePiVpaz8S1u",
+	"sum.golang.google.cn": "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ja0jsePiVpaz8S1u",
+}
+
+// WithVerify verifies fetched files against the checksum database gosumdb,
+// given in the format of the GOSUMDB environment variable: a known name
+// like DefaultGOSUMDB or a verifier key optionally followed by the URL of
+// the database. An empty gosumdb is treated as DefaultGOSUMDB.
+func WithVerify(gosumdb string) Option {
+	return func(c *Client) {
+		if gosumdb == "" {
+			gosumdb = DefaultGOSUMDB
+		}
+		c.gosumdb = gosumdb
+	}
+}
+
+// newSumDB returns a checksum database client for gosumdb.
+func (c *Client) newSumDB(gosumdb string) (*sumdb.Client, error) {
+	key, u, _ := strings.Cut(strings.TrimSpace(gosumdb), " ")
+	if key == "off" {
+		return nil, fmt.Errorf("cannot verify with GOSUMDB=off")
+	}
+	if known, ok := knownSumDBs[key]; ok {
+		if u == "" {
+			u = "https://" + key
+		}
+		key = known
+	}
+	name, _, ok := strings.Cut(key, "+")
+	if !ok {
+		return nil, fmt.Errorf("invalid GOSUMDB %q: missing verifier key", gosumdb)
+	}
+	if u = strings.TrimSpace(u); u == "" {
+		u = "https://" + name
+	} else if !strings.Contains(u, "://") {
+		u = "https://" + u
+	}
+	ops := &sumdbOps{
+		key:        key,
+		url:        strings.TrimRight(u, "/"),
+		httpClient: c.httpClient,
+		config:     make(map[string][]byte),
+	}
+	if c.cacheDir != "" {
+		ops.dir = filepath.Join(c.cacheDir, "sumdb")
+	}
+	return sumdb.NewClient(ops), nil
+}
+
+// verify checks the module file against the checksum database,
+// if verification is enabled.
+func (c *Client) verify(path, file string, data []byte) error {
+	if c.sumdb == nil {
+		return nil
+	}
+	version, ext, ok := splitFile(file)
+	if file == "@latest" {
+		ext = "info"
+	} else if !ok {
+		return nil
+	}
+
+	var key, hash string
+	switch ext {
+	case "info":
+		var info Info
+		if err := json.Unmarshal(data, &info); err != nil {
+			return fmt.Errorf("verify %s/%s: decode info: %w", path, file, err)
+		}
+		if version != "" && info.Version != version {
+			return fmt.Errorf("verify %s/%s: info is for version %s: %w", path, file, info.Version, ErrChecksum)
+		}
+		key = info.Version
+	case "mod":
+		key = version + "/go.mod"
+		h, err := dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		})
+		if err != nil {
+			return fmt.Errorf("verify %s/%s: %w", path, file, err)
+		}
+		hash = h
+	case "zip":
+		key = version
+		h, err := hashZip(data)
+		if err != nil {
+			return fmt.Errorf("verify %s/%s: %w", path, file, err)
+		}
+		hash = h
+	default:
+		return nil
+	}
+
+	lines, err := c.sumdb.Lookup(path, key)
+	if err != nil {
+		return fmt.Errorf("verify %s/%s: %w", path, file, err)
+	}
+	if hash == "" {
+		// The version exists, which is all an .info file can be checked for.
+		return nil
+	}
+	if !slices.Contains(lines, path+" "+key+" "+hash) {
+		return fmt.Errorf("verify %s/%s: got %s: %w", path, file, hash, ErrChecksum)
+	}
+	return nil
+}
+
+// hashZip is dirhash.HashZip for a module zip in memory.
+func hashZip(data []byte) (string, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("open zip: %w", err)
+	}
+	files := make(map[string]*zip.File, len(z.File))
+	var names []string
+	for _, f := range z.File {
+		files[f.Name] = f
+		names = append(names, f.Name)
+	}
+	return dirhash.Hash1(names, func(name string) (io.ReadCloser, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("file %q not found in zip", name)
+		}
+		return f.Open()
+	})
+}
+
+// sumdbOps implements sumdb.ClientOps. The latest signed tree head is
+// kept in memory and, like tiles and lookups, on disk if dir is set.
+type sumdbOps struct {
+	key        string
+	url        string
+	httpClient *http.Client
+	dir        string
+
+	mu     sync.Mutex
+	config map[string][]byte
+}
+
+func (o *sumdbOps) ReadRemote(path string) ([]byte, error) {
+	u := o.url + path
+	ctx, cancel := context.WithTimeout(context.Background(), sumdbTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: unexpected status: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", u, err)
+	}
+	return data, nil
+}
+
+func (o *sumdbOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(o.key), nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if data, ok := o.config[file]; ok {
+		return data, nil
+	}
+	if o.dir != "" {
+		if data, err := os.ReadFile(o.configFile(file)); err == nil {
+			return data, nil
+		}
+	}
+	// Start with an empty tree.
+	return []byte{}, nil
+}
+
+func (o *sumdbOps) WriteConfig(file string, old, new []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	cur, ok := o.config[file]
+	if !ok && o.dir != "" {
+		cur, _ = os.ReadFile(o.configFile(file))
+	}
+	if !bytes.Equal(cur, old) {
+		return sumdb.ErrWriteConflict
+	}
+	o.config[file] = new
+	if o.dir != "" {
+		// Losing the tree head only costs verifying it again next time.
+		_ = atomicfile.WriteFile(o.configFile(file), new)
+	}
+	return nil
+}
+
+func (o *sumdbOps) configFile(file string) string {
+	return filepath.Join(o.dir, "config", filepath.FromSlash(file))
+}
+
+func (o *sumdbOps) ReadCache(file string) ([]byte, error) {
+	if o.dir == "" {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(filepath.Join(o.dir, filepath.FromSlash(file)))
+}
+
+func (o *sumdbOps) WriteCache(file string, data []byte) {
+	if o.dir != "" {
+		_ = atomicfile.WriteFile(filepath.Join(o.dir, filepath.FromSlash(file)), data)
+	}
+}
+
+func (o *sumdbOps) Log(string) {}
+
+// SecurityError is reported through the error sumdb.Client returns.
+func (o *sumdbOps) SecurityError(string) {}
@@ -0,0 +1,260 @@
+// Package httpcache implements an http.RoundTripper that keeps responses
+// on disk for as long as their headers allow, so that commands run one
+// after another don't fetch the same files again.
+//
+// It behaves like a shared cache as described in RFC 9111, reduced to
+// what the module proxy needs: GET responses are stored by URL
+// and served while fresh by Cache-Control (s-maxage, max-age) or
+// Expires, taking the Age the response had when stored into account.
+// Stale responses with an ETag or Last-Modified header are revalidated
+// with a conditional request. Responses marked no-store or private,
+// responses varying by request headers other than Accept-Encoding and
+// requests with credentials are passed through. So are module zips, which
+// would have to be read into memory to be stored; goproxy.WithCache keeps
+// them on disk instead.
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngrash/modhunt/internal/atomicfile"
+)
+
+// Transport caches the responses of its base transport below a directory.
+// It is safe for concurrent use.
+type Transport struct {
+	dir  string
+	base http.RoundTripper
+	now  func() time.Time
+}
+
+// New returns a transport caching the responses of base below dir.
+// If base is nil, http.DefaultTransport is used.
+func New(dir string, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{dir: dir, base: base, now: time.Now}
+}
+
+// cacheableStatus are the status codes of stored responses. Missing
+// modules and versions are answered with 404 and 410 by proxies.
+var cacheableStatus = map[int]bool{
+	http.StatusOK:       true,
+	http.StatusNotFound: true,
+	http.StatusGone:     true,
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheableRequest(req) {
+		return t.base.RoundTrip(req)
+	}
+	name := t.file(req)
+	cached, stored := t.read(name, req)
+	if cached != nil {
+		if lifetime, _ := freshness(cached.Header); age(cached.Header, stored, t.now()) < lifetime {
+			return cached, nil
+		}
+		req = conditional(req, cached.Header)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		for k, v := range resp.Header {
+			cached.Header[k] = v
+		}
+		// Failing to store the new headers only costs another
+		// revalidation next time.
+		_ = t.write(name, cached)
+		return cached, nil
+	}
+	if !t.storable(resp) {
+		return resp, nil
+	}
+	_ = t.write(name, resp)
+	return resp, nil
+}
+
+// cacheableRequest reports whether the response to req may be served
+// from and stored in the cache.
+func cacheableRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" || req.Header.Get("Range") != "" {
+		return false
+	}
+	if strings.HasSuffix(req.URL.Path, ".zip") {
+		return false
+	}
+	cc := cacheControl(req.Header)
+	_, noStore := cc["no-store"]
+	_, noCache := cc["no-cache"]
+	return !noStore && !noCache
+}
+
+// storable reports whether resp may be stored: its status and headers
+// allow it and it is either fresh for a while or can be revalidated.
+func (t *Transport) storable(resp *http.Response) bool {
+	if !cacheableStatus[resp.StatusCode] {
+		return false
+	}
+	for _, v := range resp.Header.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" && !strings.EqualFold(f, "Accept-Encoding") {
+				return false
+			}
+		}
+	}
+	lifetime, ok := freshness(resp.Header)
+	if !ok {
+		return false
+	}
+	return lifetime > 0 || resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// freshness returns how long a response with header h stays fresh and
+// whether it may be stored at all.
+func freshness(h http.Header) (time.Duration, bool) {
+	cc := cacheControl(h)
+	if _, ok := cc["no-store"]; ok {
+		return 0, false
+	}
+	if _, ok := cc["private"]; ok {
+		return 0, false
+	}
+	if _, ok := cc["no-cache"]; ok {
+		return 0, true
+	}
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if v, ok := cc[directive]; ok {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+				return time.Duration(n) * time.Second, true
+			}
+			return 0, true // invalid values mean stale
+		}
+	}
+	if v := h.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0, true
+		}
+		date, err := http.ParseTime(h.Get("Date"))
+		if err != nil {
+			return 0, true
+		}
+		return max(expires.Sub(date), 0), true
+	}
+	return 0, true
+}
+
+// age returns the age of a response with header h stored at stored: the
+// Age it had when stored plus the time since.
+func age(h http.Header, stored, now time.Time) time.Duration {
+	var initial time.Duration
+	if n, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && n > 0 {
+		initial = time.Duration(n) * time.Second
+	}
+	return initial + max(now.Sub(stored), 0)
+}
+
+// cacheControl returns the directives of the Cache-Control headers in h
+// by lowercase name, with their unquoted values.
+func cacheControl(h http.Header) map[string]string {
+	cc := make(map[string]string)
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+		}
+	}
+	return cc
+}
+
+// conditional returns a copy of req asking to revalidate the stored
+// response with header h.
+func conditional(req *http.Request, h http.Header) *http.Request {
+	etag, modified := h.Get("ETag"), h.Get("Last-Modified")
+	if etag == "" && modified == "" {
+		return req
+	}
+	req = req.Clone(req.Context())
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if modified != "" {
+		req.Header.Set("If-Modified-Since", modified)
+	}
+	return req
+}
+
+// file returns the name of the cache file of the response to req.
+func (t *Transport) file(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String()))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(t.dir, key[:2], key)
+}
+
+// read returns the response stored in the file name and when it was
+// stored, or nil if there is none.
+func (t *Transport) read(name string, req *http.Request) (*http.Response, time.Time) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	return resp, fi.ModTime()
+}
+
+// write stores resp in the file name and replaces its body, which it
+// reads, with a copy.
+func (t *Transport) write(name string, resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		// The caller gets the error from reading the body.
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+		return err
+	}
+
+	stored := *resp
+	stored.Proto, stored.ProtoMajor, stored.ProtoMinor = "HTTP/1.1", 1, 1
+	stored.ContentLength = int64(len(body))
+	stored.TransferEncoding = nil
+	stored.Close = false
+	stored.Body = io.NopCloser(bytes.NewReader(body))
+	var buf bytes.Buffer
+	if err := stored.Write(&buf); err != nil {
+		return fmt.Errorf("encode response: %w", err)
+	}
+	return atomicfile.WriteFile(name, buf.Bytes())
+}
+
+// errReader fails every read with err.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,45 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestTransportSkipsZips(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: New(t.TempDir(), nil)}
+
+	for _, path := range []string{"/example.com/m/@v/v1.0.0.mod", "/example.com/m/@v/v1.0.0.zip"} {
+		for range 2 {
+			resp, err := client.Get(srv.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil || string(body) != path {
+				t.Fatalf("GET %s = %q, %v, want %q", path, body, err, path)
+			}
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if n := requests["/example.com/m/@v/v1.0.0.mod"]; n != 1 {
+		t.Errorf("fetched the .mod file %d times, want 1", n)
+	}
+	if n := requests["/example.com/m/@v/v1.0.0.zip"]; n != 2 {
+		t.Errorf("fetched the zip %d times, want 2", n)
+	}
+}
@@ -0,0 +1,244 @@
+// Package linkcheck flags anomalies in the links of curated lists, e.g.
+// links to hosts that shut down or deep into a repository, with a
+// suggestion how to fix each. What is flagged is decided by rules, which
+// list maintainers can tune in a rules file.
+package linkcheck
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/modname"
+)
+
+// A Finding is an anomaly a rule flagged in a link.
+type Finding struct {
+	Rule    string `json:"rule"`
+	URL     string `json:"url"`
+	Problem string `json:"problem"`
+	Fix     string `json:"fix"`
+}
+
+// A Rule flags one kind of anomaly. Args parameterize it, e.g. with the
+// hosts it applies to.
+type Rule struct {
+	Name string
+	Args []string
+
+	check func(args []string, u *url.URL) (problem, fix string, ok bool)
+}
+
+func (r Rule) String() string {
+	return strings.TrimSpace(r.Name + " " + strings.Join(r.Args, " "))
+}
+
+// Check returns the anomalies the rules flag in link. Links that are no
+// valid absolute URL are flagged by a finding of the rule "invalid".
+func Check(rules []Rule, link string) []Finding {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return []Finding{{Rule: "invalid", URL: link, Problem: "not an absolute URL", Fix: "link the repository"}}
+	}
+	var findings []Finding
+	for _, r := range rules {
+		if problem, fix, ok := r.check(r.Args, u); ok {
+			findings = append(findings, Finding{Rule: r.Name, URL: link, Problem: problem, Fix: fix})
+		}
+	}
+	return findings
+}
+
+// Hosts the rules apply to by default.
+var (
+	forges        = []string{"github.com", "gitlab.com", "bitbucket.org", "codeberg.org", "gitea.com", "git.sr.ht", "sr.ht"}
+	shutDownHosts = []string{"code.google.com", "gitorious.org", "godoc.org"}
+	docsHosts     = []string{"pkg.go.dev", "go.dev", "godoc.org"}
+)
+
+// checks are the rules by name, with a description of their arguments
+// and their default arguments.
+var checks = map[string]struct {
+	args        string
+	defaultArgs []string
+	check       func(args []string, u *url.URL) (string, string, bool)
+}{
+	"insecure":      {"", nil, checkInsecure},
+	"decorated":     {"", nil, checkDecorated},
+	"shut-down":     {"HOST...", shutDownHosts, checkShutDown},
+	"docs":          {"HOST...", docsHosts, checkDocs},
+	"non-forge":     {"FORGE...", forges, checkNonForge},
+	"deep-path":     {"[N]", []string{"3"}, checkDeepPath},
+	"repo-subpages": {"", nil, checkRepoSubpages},
+}
+
+// NewRule returns the rule name with args, or its default arguments if
+// args is empty.
+func NewRule(name string, args ...string) (Rule, error) {
+	c, ok := checks[name]
+	if !ok {
+		return Rule{}, fmt.Errorf("unknown rule %q", name)
+	}
+	if len(args) == 0 {
+		args = c.defaultArgs
+	}
+	if c.args == "" && len(args) > 0 {
+		return Rule{}, fmt.Errorf("rule %s takes no arguments", name)
+	}
+	if name == "deep-path" {
+		if len(args) != 1 {
+			return Rule{}, fmt.Errorf("rule %s takes one argument", name)
+		}
+		if n, err := strconv.Atoi(args[0]); err != nil || n < 1 {
+			return Rule{}, fmt.Errorf("rule %s: invalid depth %q", name, args[0])
+		}
+	}
+	return Rule{Name: name, Args: args, check: c.check}, nil
+}
+
+// Usage returns the names of all rules with their arguments, sorted.
+func Usage() []string {
+	var usage []string
+	for name, c := range checks {
+		usage = append(usage, strings.TrimSpace(name+" "+c.args))
+	}
+	slices.Sort(usage)
+	return usage
+}
+
+// DefaultRules returns all rules with their default arguments.
+func DefaultRules() []Rule {
+	var rules []Rule
+	for _, name := range []string{"insecure", "decorated", "shut-down", "docs", "non-forge", "repo-subpages", "deep-path"} {
+		r, _ := NewRule(name)
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// ParseRules reads rules in the format of the --rules file of 'modhunt
+// strange': one rule per line, its name followed by its arguments,
+// separated by white space. A rule without arguments uses its defaults.
+// Empty lines and lines starting with # are ignored.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		rule, err := NewRule(fields[0], fields[1:]...)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, s.Err()
+}
+
+func checkInsecure(_ []string, u *url.URL) (string, string, bool) {
+	if u.Scheme != "http" {
+		return "", "", false
+	}
+	fixed := *u
+	fixed.Scheme = "https"
+	return "plain HTTP link", "link " + fixed.String(), true
+}
+
+// checkDecorated flags queries, fragments and .git suffixes, which link
+// to the same repository as the URL without them.
+func checkDecorated(_ []string, u *url.URL) (string, string, bool) {
+	var what []string
+	if u.RawQuery != "" {
+		what = append(what, "query")
+	}
+	if u.Fragment != "" {
+		what = append(what, "fragment")
+	}
+	if strings.HasSuffix(u.Path, ".git") {
+		what = append(what, ".git suffix")
+	}
+	if len(what) == 0 {
+		return "", "", false
+	}
+	fixed := *u
+	fixed.RawQuery, fixed.Fragment = "", ""
+	fixed.Path = strings.TrimSuffix(fixed.Path, ".git")
+	fixed.RawPath = ""
+	return "URL with " + strings.Join(what, " and "), "link " + fixed.String(), true
+}
+
+func checkShutDown(hosts []string, u *url.URL) (string, string, bool) {
+	host := hostOf(u)
+	if !slices.Contains(hosts, host) {
+		return "", "", false
+	}
+	fix := "link the new home of the project or remove it"
+	if r, ok := modname.MatchRule(host + u.Path); ok && r.To != "" {
+		fix = "link https://" + r.Apply(host+strings.TrimSuffix(u.Path, "/"))
+	}
+	return host + " shut down", fix, true
+}
+
+func checkDocs(hosts []string, u *url.URL) (string, string, bool) {
+	host := hostOf(u)
+	if !slices.Contains(hosts, host) {
+		return "", "", false
+	}
+	fix := "link the repository"
+	// pkg.go.dev/github.com/owner/repo/pkg documents a package of that
+	// repository.
+	if loc, err := forge.ParseURL("https:/" + u.Path); err == nil && slices.Contains(forges, loc.Host) {
+		fix = "link https://" + loc.Host + "/" + loc.Name
+	}
+	return "documentation instead of repository", fix, true
+}
+
+// checkNonForge flags hosts other than the forges in known, which may be
+// vanity import paths but also websites about the project. Hosts other
+// rules flag by default are left to them.
+func checkNonForge(known []string, u *url.URL) (string, string, bool) {
+	host := hostOf(u)
+	if slices.Contains(known, host) || slices.Contains(shutDownHosts, host) || slices.Contains(docsHosts, host) {
+		return "", "", false
+	}
+	return "not on a known forge", "check that it is a go-gettable import path or link the repository", true
+}
+
+// checkDeepPath flags links with more than the given number of path
+// elements to hosts other than the known forges, whose layout
+// repo-subpages knows, and those other rules flag by default.
+func checkDeepPath(args []string, u *url.URL) (string, string, bool) {
+	n, _ := strconv.Atoi(args[0])
+	host := hostOf(u)
+	if slices.Contains(forges, host) || slices.Contains(shutDownHosts, host) || slices.Contains(docsHosts, host) || strings.Count(strings.Trim(u.Path, "/"), "/")+1 <= n {
+		return "", "", false
+	}
+	return "path deeper than " + args[0] + " elements", "link the repository or the import path of the package", true
+}
+
+// checkRepoSubpages flags links into a branch or directory of a
+// repository, which are fine for nested modules but often link a
+// README or one package of a module.
+func checkRepoSubpages(_ []string, u *url.URL) (string, string, bool) {
+	loc, err := forge.ParseURL(u.String())
+	if err != nil || loc.Ref == "" && loc.Dir == "" {
+		return "", "", false
+	}
+	what := "directory " + loc.Dir
+	if loc.Dir == "" {
+		what = "branch " + loc.Ref
+	}
+	return "links " + what + " of " + loc.Host + "/" + loc.Name, "link https://" + loc.Host + "/" + loc.Name + " unless it is a nested module", true
+}
+
+func hostOf(u *url.URL) string {
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,69 @@
+package modhunter
+
+import (
+	"cmp"
+	"context"
+	"sync"
+)
+
+// An Outcome is the result of searching for one path with SearchAll.
+type Outcome struct {
+	Path   string
+	Result Result
+	Err    error
+}
+
+// SearchAll searches for every path on n goroutines. Paths listed more
+// than once are searched for once. The outcomes are returned in the order
+// the paths are first listed and, if progress is not nil, reported to it
+// as they complete, on the calling goroutine, together with the number of
+// outcomes so far and in total. Searches start as fast as the limiter set
+// by WithLimiter allows. Once ctx is done, the paths not searched for yet
+// fail with its error.
+func (h *Hunter) SearchAll(ctx context.Context, paths []string, n int, progress func(done, total int, o Outcome)) []Outcome {
+	index := make(map[string]int)
+	var unique []string
+	for _, p := range paths {
+		if _, ok := index[p]; !ok {
+			index[p] = len(unique)
+			unique = append(unique, p)
+		}
+	}
+
+	todo := make(chan string)
+	results := make(chan Outcome)
+	var wg sync.WaitGroup
+	for range max(n, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range todo {
+				if err := h.limiter.Wait(ctx); err != nil {
+					results <- Outcome{Path: p, Err: cmp.Or(ctx.Err(), err)}
+					continue
+				}
+				res, err := h.Search(ctx, p)
+				results <- Outcome{Path: p, Result: res, Err: err}
+			}
+		}()
+	}
+	go func() {
+		for _, p := range unique {
+			todo <- p
+		}
+		close(todo)
+		wg.Wait()
+		close(results)
+	}()
+
+	outcomes := make([]Outcome, len(unique))
+	done := 0
+	for o := range results {
+		outcomes[index[o.Path]] = o
+		done++
+		if progress != nil {
+			progress(done, len(unique), o)
+		}
+	}
+	return outcomes
+}
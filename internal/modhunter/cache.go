@@ -0,0 +1,95 @@
+package modhunter
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+)
+
+// A CacheEntry is the answer of the proxy for a candidate module path.
+type CacheEntry struct {
+	Info      *goproxy.Info // the latest version, nil if the proxy did not know the path
+	CheckedAt time.Time
+}
+
+// A Cache remembers the answers of the proxy across searches, so that
+// candidates tried before are not asked for again. Only definite answers
+// are cached: the latest version of a module or that the proxy does not
+// know a path. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(path string) (CacheEntry, bool)
+	Put(path string, e CacheEntry)
+}
+
+// WithCache answers candidates from c. Entries of modules are used for
+// ttl, those of unknown paths for negativeTTL, which is usually shorter,
+// because new modules are published but few disappear.
+func WithCache(c Cache, ttl, negativeTTL time.Duration) Option {
+	return func(h *Hunter) {
+		h.cache = c
+		h.cacheTTL = ttl
+		h.negativeTTL = negativeTTL
+	}
+}
+
+// cached returns the fresh cached answer for path, if any.
+func (h *Hunter) cached(path string) (CacheEntry, bool) {
+	if h.cache == nil {
+		return CacheEntry{}, false
+	}
+	e, ok := h.cache.Get(path)
+	if !ok {
+		return CacheEntry{}, false
+	}
+	ttl := h.cacheTTL
+	if e.Info == nil {
+		ttl = h.negativeTTL
+	}
+	return e, time.Since(e.CheckedAt) < ttl
+}
+
+// MemoryCache is a Cache held in memory. It tells the entries put since
+// it was loaded, so that they can be persisted after searching.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+	added   map[string]CacheEntry
+}
+
+// NewMemoryCache returns an empty cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]CacheEntry),
+		added:   make(map[string]CacheEntry),
+	}
+}
+
+// Load adds an entry that was persisted before.
+func (c *MemoryCache) Load(path string, e CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = e
+}
+
+func (c *MemoryCache) Get(path string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	return e, ok
+}
+
+func (c *MemoryCache) Put(path string, e CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = e
+	c.added[path] = e
+}
+
+// Added returns the entries put since the cache was created.
+func (c *MemoryCache) Added() map[string]CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.added)
+}
@@ -0,0 +1,186 @@
+package modhunter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ngrash/modhunt/internal/goproxy"
+)
+
+// An Attempt is the outcome of asking the proxy for one candidate, or of
+// a strategy that could not propose candidates, in which case Path is
+// empty.
+type Attempt struct {
+	Strategy string
+	Path     string // the candidate module path
+	Status   int    // HTTP status of the proxy, 0 if it did not answer
+	Err      error
+}
+
+// NotFound reports whether the proxy does not know the candidate.
+func (a Attempt) NotFound() bool {
+	return errors.Is(a.Err, goproxy.ErrNotFound)
+}
+
+// Retryable reports whether the attempt may succeed later: the proxy or
+// the host asked by the strategy did not answer, failed or rate limited
+// the request.
+func (a Attempt) Retryable() bool {
+	switch {
+	case a.Err == nil || a.NotFound() || a.report() != nil:
+		return false
+	case a.Status == 0:
+		return true
+	default:
+		return a.Status == http.StatusTooManyRequests || a.Status >= 500
+	}
+}
+
+// Categories of failed attempts, as returned by Category.
+const (
+	CategoryNotFound    = "not found"    // the proxy does not know the path
+	CategoryGone        = "gone"         // the proxy refuses to serve the path
+	CategoryRateLimited = "rate limited" // the proxy or host rate limited the request
+	CategoryServer      = "server error" // the proxy or host failed
+	CategoryNetwork     = "network"      // the proxy or host did not answer
+	CategoryOther       = "error"
+)
+
+// Category returns the category of a failed attempt. The proxy answers
+// 410 Gone for paths it will not serve, e.g. because they are invalid or
+// were removed at the request of their authors, and 404 for paths that no
+// version was found for yet.
+func (a Attempt) Category() string {
+	switch {
+	case a.Status == http.StatusGone:
+		return CategoryGone
+	case a.NotFound():
+		return CategoryNotFound
+	case a.Status == http.StatusTooManyRequests:
+		return CategoryRateLimited
+	case a.Status >= 500:
+		return CategoryServer
+	case a.Status == 0 && a.report() == nil:
+		return CategoryNetwork
+	default:
+		return CategoryOther
+	}
+}
+
+func (a Attempt) String() string {
+	if a.Path == "" {
+		return fmt.Sprintf("%s: %v", a.Strategy, a.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", a.Strategy, a.Path, a.Err)
+}
+
+// report returns what Nested found, if a is its outcome.
+func (a Attempt) report() *RepoModules {
+	var r *RepoModules
+	if errors.As(a.Err, &r) {
+		return r
+	}
+	return nil
+}
+
+// newAttempt records the outcome err of asking for the candidate path.
+func newAttempt(strategy, path string, err error) Attempt {
+	a := Attempt{Strategy: strategy, Path: path, Err: err}
+	var se *goproxy.StatusError
+	if errors.As(err, &se) {
+		a.Status = se.StatusCode
+	}
+	return a
+}
+
+// A SearchError is returned by Search if it found no module. It tells
+// what every strategy tried and wraps ErrNotFound unless an attempt is
+// retryable, as well as the errors of failed attempts and the RepoModules
+// found by Nested.
+type SearchError struct {
+	Path     string
+	Attempts []Attempt
+}
+
+func (e *SearchError) Error() string {
+	tried := 0
+	for _, a := range e.Attempts {
+		if a.Path != "" {
+			tried++
+		}
+	}
+	msg := fmt.Sprintf("%s: %v, tried %d paths", e.Path, ErrNotFound, tried)
+	if failed := e.Failed(); len(failed) > 0 {
+		msg = fmt.Sprintf("%s: %v, %d of %d attempts failed, first: %v", e.Path, ErrNotFound, len(failed), len(e.Attempts), failed[0])
+	}
+	if r := e.Repo(); r != nil {
+		msg += "; " + r.Error()
+	}
+	return msg
+}
+
+func (e *SearchError) Unwrap() []error {
+	var errs []error
+	if !e.Retryable() {
+		errs = append(errs, ErrNotFound)
+	}
+	for _, a := range e.Failed() {
+		errs = append(errs, a.Err)
+	}
+	if r := e.Repo(); r != nil {
+		errs = append(errs, r)
+	}
+	return errs
+}
+
+// Failed returns the attempts that failed for another reason than the
+// proxy not knowing the candidate.
+func (e *SearchError) Failed() []Attempt {
+	var failed []Attempt
+	for _, a := range e.Attempts {
+		if !a.NotFound() && a.report() == nil {
+			failed = append(failed, a)
+		}
+	}
+	return failed
+}
+
+// Retryable reports whether searching again may find a module because an
+// attempt is retryable.
+func (e *SearchError) Retryable() bool {
+	for _, a := range e.Attempts {
+		if a.Retryable() {
+			return true
+		}
+	}
+	return false
+}
+
+// Repo returns what the Nested strategy found in the repository of the
+// path, nil if it did not list it.
+func (e *SearchError) Repo() *RepoModules {
+	for _, a := range e.Attempts {
+		if r := a.report(); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+// Category returns the category of the search: that of the first
+// retryable attempt, if any, and otherwise CategoryGone if the proxy
+// refused to serve the path itself and CategoryNotFound if not.
+func (e *SearchError) Category() string {
+	for _, a := range e.Attempts {
+		if a.Retryable() {
+			return a.Category()
+		}
+	}
+	for _, a := range e.Attempts {
+		if a.Path == e.Path && a.Category() == CategoryGone {
+			return CategoryGone
+		}
+	}
+	return CategoryNotFound
+}
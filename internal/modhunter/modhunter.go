@@ -0,0 +1,439 @@
+// Package modhunter finds the module a path names that may not be a module
+// path itself: a package or directory in a module, a path spelled with the
+// capitalization of its repository URL, the GitHub path of a gopkg.in
+// module, a vanity import path or the old path of a module that moved.
+//
+// Strategies derive candidate module paths from the path, in order of
+// preference. The first candidate the module proxy knows is the result,
+// together with the name of the strategy that proposed it.
+package modhunter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/time/rate"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/goimport"
+	"github.com/ngrash/modhunt/internal/goproxy"
+	"github.com/ngrash/modhunt/internal/modname"
+	"github.com/ngrash/modhunt/internal/pkgsite"
+)
+
+// ErrNotFound is wrapped by the SearchError returned if the proxy knows
+// none of the candidates.
+var ErrNotFound = errors.New("no module found")
+
+// A Strategy proposes candidate module paths for a path.
+type Strategy struct {
+	Name string
+	// Candidates returns the module paths to try for path, most likely
+	// first. Strategies that do not apply return none.
+	Candidates func(ctx context.Context, h *Hunter, path string) ([]string, error)
+}
+
+// Result is a module found for a path.
+type Result struct {
+	Path     string        // the path searched for
+	Module   string        // the module path found
+	Strategy string        // the name of the strategy that proposed it
+	Info     *goproxy.Info // the latest version of the module
+}
+
+// Moved reports whether the module moved away from the path searched for,
+// e.g. because its repository was transferred, and was found under its
+// new path.
+func (r Result) Moved() bool {
+	return r.Strategy == Redirect.Name
+}
+
+// Hunter searches the module proxy for modules. It is safe for concurrent
+// use. The proxy client it is created with sets the proxy base URL, the
+// HTTP client and the rate limit of the requests to the proxy.
+type Hunter struct {
+	proxy      *goproxy.Client
+	imports    *goimport.Client
+	pkgsite    *pkgsite.Client
+	origins    func(path string) string
+	httpClient *http.Client
+	timeout    time.Duration
+	repoFiles  func(ctx context.Context, host string) (forge.FileLister, bool)
+	strategies []Strategy
+	limiter    *rate.Limiter
+
+	cache       Cache
+	cacheTTL    time.Duration
+	negativeTTL time.Duration
+}
+
+// An Option configures a Hunter.
+type Option func(*Hunter)
+
+// WithImports sets the client resolving vanity import paths. The default
+// is goimport.New with the client set by WithHTTPClient.
+func WithImports(c *goimport.Client) Option {
+	return func(h *Hunter) { h.imports = c }
+}
+
+// WithPkgsite asks pkg.go.dev where it shows paths the proxy does not
+// know. By default, pkg.go.dev is not asked.
+func WithPkgsite(c *pkgsite.Client) Option {
+	return func(h *Hunter) { h.pkgsite = c }
+}
+
+// WithOrigins looks up the repository URL the proxy reported as origin of
+// a module path earlier, e.g. when it was last resolved. It returns "" for
+// unknown paths. By default, no origins are known.
+func WithOrigins(origins func(path string) string) Option {
+	return func(h *Hunter) { h.origins = origins }
+}
+
+// WithHTTPClient sets the HTTP client following the redirects of origin
+// URLs and, unless set by WithImports, resolving vanity import paths. The
+// default is http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(h *Hunter) { h.httpClient = hc }
+}
+
+// WithRepoFiles sets the clients listing the files of repositories on the
+// forge at host for Nested. Forges it returns false for are not listed.
+// By default, no repositories are listed.
+func WithRepoFiles(lister func(ctx context.Context, host string) (forge.FileLister, bool)) Option {
+	return func(h *Hunter) { h.repoFiles = lister }
+}
+
+// WithTimeout limits the time a strategy may take to propose candidates
+// and the time the proxy may take to answer for each candidate. A timed
+// out attempt is retryable and does not stop the search. By default, only
+// the context passed to Search limits them.
+func WithTimeout(d time.Duration) Option {
+	return func(h *Hunter) { h.timeout = d }
+}
+
+// WithLimiter limits the searches SearchAll starts to those l allows.
+// By default, they are not limited.
+func WithLimiter(l *rate.Limiter) Option {
+	return func(h *Hunter) { h.limiter = l }
+}
+
+// WithStrategies replaces the strategies tried, in order. The default is
+// DefaultStrategies.
+func WithStrategies(s ...Strategy) Option {
+	return func(h *Hunter) { h.strategies = s }
+}
+
+// New returns a hunter asking proxy for candidates.
+func New(proxy *goproxy.Client, opts ...Option) *Hunter {
+	h := &Hunter{
+		proxy:      proxy,
+		origins:    func(string) string { return "" },
+		repoFiles:  func(context.Context, string) (forge.FileLister, bool) { return nil, false },
+		httpClient: http.DefaultClient,
+		strategies: DefaultStrategies,
+		limiter:    rate.NewLimiter(rate.Inf, 1),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.imports == nil {
+		h.imports = goimport.New(h.httpClient)
+	}
+	return h
+}
+
+// Search returns the module of path found by the first strategy whose
+// candidate the proxy knows. Candidates proposed by several strategies
+// are asked for once, those that are no valid module path not at all.
+// Failing strategies and requests do not stop the search. If no module
+// is found, the error is a *SearchError.
+func (h *Hunter) Search(ctx context.Context, path string) (Result, error) {
+	e := &SearchError{Path: path}
+	tried := make(map[string]bool)
+	for _, s := range h.strategies {
+		candidates, err := h.candidates(ctx, s, path)
+		if ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+		if err != nil {
+			e.Attempts = append(e.Attempts, newAttempt(s.Name, "", err))
+		}
+		for _, c := range candidates {
+			if tried[c] || module.CheckPath(c) != nil {
+				continue
+			}
+			tried[c] = true
+			info, err := h.latest(ctx, c)
+			if err == nil {
+				return Result{Path: path, Module: c, Strategy: s.Name, Info: info}, nil
+			}
+			if ctx.Err() != nil {
+				return Result{}, ctx.Err()
+			}
+			e.Attempts = append(e.Attempts, newAttempt(s.Name, c, err))
+		}
+	}
+	return Result{}, e
+}
+
+// candidates returns the candidates s proposes for path within the
+// timeout of the hunter.
+func (h *Hunter) candidates(ctx context.Context, s Strategy, path string) ([]string, error) {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	return s.Candidates(ctx, h, path)
+}
+
+// latest asks the proxy for the latest version of the candidate path
+// within the timeout of the hunter, unless its answer is cached.
+func (h *Hunter) latest(ctx context.Context, path string) (*goproxy.Info, error) {
+	if e, ok := h.cached(path); ok {
+		if e.Info == nil {
+			return nil, fmt.Errorf("%s: cached: %w", path, goproxy.ErrNotFound)
+		}
+		return e.Info, nil
+	}
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	info, err := h.proxy.Latest(ctx, path)
+	if h.cache != nil {
+		switch {
+		case err == nil:
+			h.cache.Put(path, CacheEntry{Info: info, CheckedAt: time.Now()})
+		case errors.Is(err, goproxy.ErrNotFound):
+			h.cache.Put(path, CacheEntry{CheckedAt: time.Now()})
+		}
+	}
+	return info, err
+}
+
+func (h *Hunter) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if h.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, h.timeout)
+}
+
+// DefaultStrategies are tried unless replaced with WithStrategies. Cheap
+// rewrites of the path come before climbing to parent paths, which asks
+// the proxy once per path element, and vanity imports, which ask the host
+// of the path. Redirects are followed when the module is gone. Listing the
+// repository, which costs requests to the API of its forge, comes last.
+var DefaultStrategies = []Strategy{
+	None,
+	Lowercase,
+	GopkgIn,
+	Climb,
+	Vanity,
+	Major,
+	Redirect,
+	Nested,
+}
+
+// None tries the path as is.
+var None = Strategy{
+	Name: "none",
+	Candidates: func(ctx context.Context, h *Hunter, path string) ([]string, error) {
+		return []string{path}, nil
+	},
+}
+
+// Lowercase tries the lower-cased path. Lists often use the capitalization
+// of the repository URL, which rarely matches the module path.
+var Lowercase = Strategy{
+	Name: "lowercase",
+	Candidates: func(ctx context.Context, h *Hunter, path string) ([]string, error) {
+		if lower := strings.ToLower(path); lower != path {
+			return []string{lower}, nil
+		}
+		return nil, nil
+	},
+}
+
+// gopkgInMajors is the highest major version GopkgIn tries. Few packages
+// on gopkg.in went beyond v3.
+const gopkgInMajors = 3
+
+// GopkgIn tries the gopkg.in paths serving the root of a GitHub
+// repository, highest major version first, and the GitHub path of a
+// gopkg.in path.
+var GopkgIn = Strategy{
+	Name: "gopkg.in",
+	Candidates: func(ctx context.Context, h *Hunter, path string) ([]string, error) {
+		if github, _, ok := modname.FromGopkgIn(path); ok {
+			return []string{github}, nil
+		}
+		if strings.Count(path, "/") != 2 {
+			return nil, nil
+		}
+		var candidates []string
+		for n := gopkgInMajors; n >= 1; n-- {
+			if p, ok := modname.ToGopkgIn(path, fmt.Sprintf("v%d", n)); ok {
+				candidates = append(candidates, p)
+			}
+		}
+		return candidates, nil
+	},
+}
+
+// Climb tries the parent paths of a package or directory path, nearest
+// first, down to the first two elements. Each is tried lower-cased, too.
+var Climb = Strategy{
+	Name: "climb",
+	Candidates: func(ctx context.Context, h *Hunter, path string) ([]string, error) {
+		var candidates []string
+		elems := strings.Split(path, "/")
+		for i := len(elems) - 1; i >= 2; i-- {
+			parent := strings.Join(elems[:i], "/")
+			candidates = append(candidates, parent)
+			if lower := strings.ToLower(parent); lower != parent {
+				candidates = append(candidates, lower)
+			}
+		}
+		return candidates, nil
+	},
+}
+
+// Vanity tries the root named by the go-import meta tag of the path, like
+// the go command resolves paths on custom domains.
+var Vanity = Strategy{
+	Name: "vanity",
+	Candidates: func(ctx context.Context, h *Hunter, path string) ([]string, error) {
+		imp, err := h.imports.Resolve(ctx, path)
+		if errors.Is(err, goimport.ErrNotFound) {
+			// Most paths are not served with go-import tags.
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []string{imp.Prefix}, nil
+	},
+}
+
+// majorSiblings is the highest major version Major tries.
+const majorSiblings = 5
+
+// Major tries the other major versions of the module path, highest
+// first, for projects that only publish modules with a /vN suffix.
+var Major = Strategy{
+	Name: "major",
+	Candidates: func(ctx context.Context, h *Hunter, path string) ([]string, error) {
+		project, _ := modname.SplitMajor(path)
+		if strings.HasPrefix(project, "gopkg.in/") {
+			return nil, nil
+		}
+		var candidates []string
+		for n := majorSiblings; n >= 2; n-- {
+			candidates = append(candidates, fmt.Sprintf("%s/v%d", project, n))
+		}
+		return append(candidates, project), nil
+	},
+}
+
+// Redirect tries the new path of a module that moved: the path of the
+// repository its known origin URL redirects to, as forges do for renamed
+// and transferred repositories, and the path pkg.go.dev shows instead of
+// path, if the hunter was configured to ask it.
+var Redirect = Strategy{
+	Name: "redirect",
+	Candidates: func(ctx context.Context, h *Hunter, path string) ([]string, error) {
+		var candidates []string
+		var errs []error
+		if origin := h.origins(path); origin != "" {
+			moved, err := h.followOrigin(ctx, origin)
+			if err != nil {
+				errs = append(errs, err)
+			} else if moved != "" {
+				candidates = append(candidates, moved)
+			}
+		}
+		if h.pkgsite != nil {
+			m, err := h.pkgsite.Metadata(ctx, path)
+			switch {
+			case errors.Is(err, pkgsite.ErrNotFound):
+				// pkg.go.dev cannot tell about paths it does not know.
+			case err != nil:
+				errs = append(errs, err)
+			case m.Redirected(path):
+				candidates = append(candidates, m.Path)
+			}
+		}
+		return candidates, errors.Join(errs...)
+	},
+}
+
+// followOrigin returns the module path of the repository the origin URL
+// redirects to, or "" if it does not redirect.
+func (h *Hunter) followOrigin(ctx context.Context, origin string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, origin, nil)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("head %s: %w", origin, err)
+	}
+	_ = resp.Body.Close()
+	final := resp.Request.URL.String()
+	if strings.EqualFold(strings.TrimSuffix(final, "/"), strings.TrimSuffix(strings.TrimSuffix(origin, ".git"), "/")) {
+		return "", nil
+	}
+	return modname.FromRepoURL(final)
+}
+
+// Nested tries the directories of the repository of the path that hold a
+// go.mod file, if the hunter was configured to list repositories. Many
+// projects predate modules and have none; others keep their modules in
+// subdirectories the path does not name. What it finds is reported as a
+// *RepoModules error, so that a SearchError explains the failure.
+var Nested = Strategy{
+	Name: "nested",
+	Candidates: func(ctx context.Context, h *Hunter, path string) ([]string, error) {
+		loc, err := forge.ParseURL("https://" + modname.RepoPath(path))
+		if err != nil {
+			return nil, nil
+		}
+		lister, ok := h.repoFiles(ctx, loc.Host)
+		if !ok {
+			return nil, nil
+		}
+		files, err := lister.Files(ctx, loc.Name)
+		if errors.Is(err, forge.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list files of %s: %w", loc.Name, err)
+		}
+		repo := loc.Host + "/" + loc.Name
+		found := &RepoModules{Repo: repo}
+		for _, f := range files {
+			if f == "go.mod" {
+				found.Modules = append(found.Modules, repo)
+			} else if dir, ok := strings.CutSuffix(f, "/go.mod"); ok && !strings.Contains("/"+dir+"/", "/testdata/") && !strings.Contains("/"+dir+"/", "/vendor/") {
+				found.Modules = append(found.Modules, repo+"/"+dir)
+			}
+		}
+		return found.Modules, found
+	},
+}
+
+// RepoModules is what Nested found in the repository of a path: the paths
+// of the directories holding a go.mod file, none for projects predating
+// modules.
+type RepoModules struct {
+	Repo    string
+	Modules []string
+}
+
+func (r *RepoModules) Error() string {
+	if len(r.Modules) == 0 {
+		return fmt.Sprintf("%s: pre-modules project, no go.mod in repository", r.Repo)
+	}
+	return fmt.Sprintf("%s: repository contains modules %s", r.Repo, strings.Join(r.Modules, ", "))
+}
@@ -0,0 +1,232 @@
+// Package modname canonicalizes module paths, so that the names under
+// which lists and the module index know the same module compare equal.
+//
+// Module paths are case-sensitive, but lists often use the capitalization
+// of the repository URL, so names are compared lower-cased. Some hosts
+// serve modules whose source lives elsewhere; gopkg.in, for example,
+// redirects the go command to GitHub repositories (https://labix.org/gopkg.in):
+//
+//	gopkg.in/pkg.v3      → github.com/go-pkg/pkg (branch/tag v3, v3.N, or v3.N.M)
+//	gopkg.in/user/pkg.v3 → github.com/user/pkg   (branch/tag v3, v3.N, or v3.N.M)
+//
+// The golang.org/x modules are hosted at go.googlesource.com, whose
+// repository URLs lists link to as well:
+//
+//	golang.org/x/net → go.googlesource.com/net
+//
+// Other aliases, like hosts known under several names or projects that
+// moved, are rewrite rules (see Rule), which can be replaced with SetRules.
+package modname
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/module"
+
+	"github.com/ngrash/modhunt/internal/forge"
+)
+
+// gopkgInElemRE matches the element of a gopkg.in path naming the package
+// and its major version, e.g. "yaml.v2" or "tomb.v1-unstable".
+var gopkgInElemRE = regexp.MustCompile(`^([A-Za-z0-9_.-]+)\.(v[0-9]+(?:-unstable)?)$`)
+
+// FromGopkgIn returns the GitHub path gopkg.in redirects path to and the
+// major version it selects, e.g. "github.com/go-yaml/yaml" and "v2" for
+// "gopkg.in/yaml.v2". Subdirectories of the package are kept. It reports
+// false if path is not on gopkg.in.
+func FromGopkgIn(path string) (github, major string, ok bool) {
+	repo, major, dir, ok := splitGopkgIn(path)
+	return repo + dir, major, ok
+}
+
+// splitGopkgIn splits a gopkg.in path into the GitHub repository, the
+// major version and the subdirectory, which starts with a slash.
+func splitGopkgIn(path string) (repo, major, dir string, ok bool) {
+	rest, ok := strings.CutPrefix(path, "gopkg.in/")
+	if !ok {
+		return "", "", "", false
+	}
+	elems := strings.Split(rest, "/")
+	if m := gopkgInElemRE.FindStringSubmatch(elems[0]); m != nil {
+		return "github.com/go-" + m[1] + "/" + m[1], m[2], join("", elems[1:]), true
+	}
+	if len(elems) < 2 {
+		return "", "", "", false
+	}
+	if m := gopkgInElemRE.FindStringSubmatch(elems[1]); m != nil {
+		return "github.com/" + elems[0] + "/" + m[1], m[2], join("", elems[2:]), true
+	}
+	return "", "", "", false
+}
+
+// ToGopkgIn returns the gopkg.in path that serves the major version major,
+// e.g. "v2", of the GitHub path. It is the inverse of FromGopkgIn: the
+// repositories of "go-pkg" users are served without the user. It reports
+// false if path is not on GitHub or major is not a major version.
+func ToGopkgIn(github, major string) (string, bool) {
+	rest, ok := strings.CutPrefix(github, "github.com/")
+	if !ok {
+		return "", false
+	}
+	elems := strings.Split(rest, "/")
+	if len(elems) < 2 || !gopkgInElemRE.MatchString(elems[1]+"."+major) {
+		return "", false
+	}
+	user, pkg := elems[0], elems[1]
+	if user == "go-"+pkg {
+		return join("gopkg.in/"+pkg+"."+major, elems[2:]), true
+	}
+	return join("gopkg.in/"+user+"/"+pkg+"."+major, elems[2:]), true
+}
+
+func join(path string, elems []string) string {
+	if len(elems) == 0 {
+		return path
+	}
+	return path + "/" + strings.Join(elems, "/")
+}
+
+// FromGoogleSource returns the golang.org/x path of a path or URL path on
+// go.googlesource.com, e.g. "golang.org/x/net" for "go.googlesource.com/net".
+// Gitiles paths into a revision, e.g. "go.googlesource.com/crypto/+/master/scrypt",
+// name the package in the directory they point to. It reports false if
+// path is not on go.googlesource.com.
+func FromGoogleSource(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "go.googlesource.com/")
+	if !ok || rest == "" {
+		return "", false
+	}
+	repo, tree, found := strings.Cut(rest, "/+/")
+	if !found {
+		return "golang.org/x/" + rest, true
+	}
+	// The revision is a branch or tag name or a full ref.
+	elems := strings.Split(strings.TrimSuffix(tree, "/"), "/")
+	skip := 1
+	if len(elems) >= 3 && elems[0] == "refs" {
+		skip = 3
+	}
+	return join("golang.org/x/"+repo, elems[min(skip, len(elems)):]), true
+}
+
+// ToGoogleSource returns the path on go.googlesource.com of the repository
+// of a golang.org/x path, the inverse of FromGoogleSource. It reports false
+// if path is not in golang.org/x.
+func ToGoogleSource(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "golang.org/x/")
+	if !ok || rest == "" {
+		return "", false
+	}
+	return "go.googlesource.com/" + rest, true
+}
+
+// FromRepoURL returns the module path a URL of a repository, or of a page
+// or directory in it, most likely names, e.g. "github.com/owner/repo/sub"
+// for https://github.com/owner/repo/tree/main/sub#readme. Pages that are
+// not directories, like releases or the wiki, name the repository. URLs
+// on pkg.go.dev and godoc.org name the path they document. The scheme may
+// be omitted. The rules in effect are applied to the host and path. The
+// path keeps the case of the URL, which may differ from the case of the
+// module path.
+func FromRepoURL(rawurl string) (string, error) {
+	if !strings.HasPrefix(rawurl, "http://") && !strings.HasPrefix(rawurl, "https://") {
+		rawurl = "https://" + rawurl
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", fmt.Errorf("parse URL: %w", err)
+	}
+	u.RawQuery, u.Fragment = "", ""
+	host, path, _ := strings.Cut(rewrite(strings.ToLower(u.Host)+u.Path), "/")
+	u.Host, u.Path, u.RawPath = host, "/"+path, ""
+	switch u.Host {
+	case "pkg.go.dev", "godoc.org":
+		path, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "@")
+		if path == "" {
+			return "", fmt.Errorf("no package in URL %s", rawurl)
+		}
+		return path, nil
+	case "go.googlesource.com":
+		if path, ok := FromGoogleSource(u.Host + u.Path); ok {
+			return strings.TrimSuffix(path, "/"), nil
+		}
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		// Vanity import paths may consist of the domain only.
+		return u.Host, nil
+	}
+	loc, err := forge.ParseURL(u.String())
+	if err != nil {
+		return "", err
+	}
+	return loc.Path(), nil
+}
+
+// RepoPath returns the path of the repository the source of the module or
+// package path lives in, followed by its subdirectory, if another host
+// serves it. Other paths are returned unchanged.
+func RepoPath(path string) string {
+	if github, _, ok := FromGopkgIn(path); ok {
+		return github
+	}
+	if repo, ok := ToGoogleSource(path); ok {
+		return repo
+	}
+	return path
+}
+
+// Normalize returns the canonical name of the module path. The first rule
+// in effect matching path is applied, names are lower-cased,
+// go.googlesource.com paths are replaced with their
+// golang.org/x path and gopkg.in paths with their GitHub path, with the
+// major version appended as semantic import versioning would from v2 on.
+// Paths of the same module normalize to the same name.
+func Normalize(path string) string {
+	path = rewrite(path)
+	if x, ok := FromGoogleSource(path); ok {
+		path = x
+	}
+	if repo, major, dir, ok := splitGopkgIn(path); ok {
+		major, _, _ = strings.Cut(major, "-")
+		if major != "v0" && major != "v1" {
+			repo += "/" + major
+		}
+		path = repo + dir
+	}
+	return strings.ToLower(path)
+}
+
+// SplitMajor splits a module path into the path shared by the major
+// versions of its project and its major version suffix, e.g.
+// "github.com/foo/bar" and "v3" for "github.com/foo/bar/v3", or
+// "gopkg.in/yaml" and "v3" for "gopkg.in/yaml.v3". The major version is
+// empty for paths without a suffix.
+func SplitMajor(path string) (project, major string) {
+	prefix, pathMajor, ok := module.SplitPathVersion(path)
+	if !ok || pathMajor == "" {
+		return path, ""
+	}
+	return prefix, pathMajor[1:]
+}
+
+// Project returns the normalized path shared by all major versions of the
+// module path, e.g. "github.com/go-yaml/yaml" for "gopkg.in/yaml.v3".
+func Project(path string) string {
+	project, _ := SplitMajor(Normalize(path))
+	return project
+}
+
+// MajorNumber returns the number of a major version suffix as returned by
+// SplitMajor, e.g. 3 for "v3" or "v3-unstable", and 1 for no suffix.
+func MajorNumber(major string) int {
+	major, _, _ = strings.Cut(strings.TrimPrefix(major, "v"), "-")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 1
+	}
+	return max(n, 1)
+}
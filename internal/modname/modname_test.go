@@ -0,0 +1,175 @@
+package modname
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFromGopkgIn(t *testing.T) {
+	tests := []struct {
+		path   string
+		github string
+		major  string
+		ok     bool
+	}{
+		{"gopkg.in/yaml.v2", "github.com/go-yaml/yaml", "v2", true},
+		{"gopkg.in/yaml.v3", "github.com/go-yaml/yaml", "v3", true},
+		{"gopkg.in/check.v1", "github.com/go-check/check", "v1", true},
+		{"gopkg.in/tomb.v1-unstable", "github.com/go-tomb/tomb", "v1-unstable", true},
+		{"gopkg.in/src-d/go-git.v4", "github.com/src-d/go-git", "v4", true},
+		{"gopkg.in/mgo.v2/bson", "github.com/go-mgo/mgo/bson", "v2", true},
+		{"gopkg.in/src-d/go-git.v4/plumbing/object", "github.com/src-d/go-git/plumbing/object", "v4", true},
+		{"gopkg.in/natefinch/lumberjack.v2", "github.com/natefinch/lumberjack", "v2", true},
+
+		// Not a gopkg.in path or no major version.
+		{"github.com/go-yaml/yaml", "", "", false},
+		{"gopkg.in/yaml", "", "", false},
+		{"gopkg.in/user/pkg", "", "", false},
+		{"gopkg.in/yaml.2", "", "", false},
+		{"gopkg.in/yaml.v2beta", "", "", false},
+		{"gopkg.in/", "", "", false},
+		{"example.com/gopkg.in/yaml.v2", "", "", false},
+	}
+	for _, tt := range tests {
+		github, major, ok := FromGopkgIn(tt.path)
+		if github != tt.github || major != tt.major || ok != tt.ok {
+			t.Errorf("FromGopkgIn(%q) = %q, %q, %v, want %q, %q, %v", tt.path, github, major, ok, tt.github, tt.major, tt.ok)
+		}
+	}
+}
+
+func TestToGopkgIn(t *testing.T) {
+	tests := []struct {
+		github string
+		major  string
+		path   string
+		ok     bool
+	}{
+		{"github.com/go-yaml/yaml", "v2", "gopkg.in/yaml.v2", true},
+		{"github.com/go-tomb/tomb", "v1-unstable", "gopkg.in/tomb.v1-unstable", true},
+		{"github.com/go-mgo/mgo/bson", "v2", "gopkg.in/mgo.v2/bson", true},
+		{"github.com/src-d/go-git", "v4", "gopkg.in/src-d/go-git.v4", true},
+		{"github.com/src-d/go-git/plumbing/object", "v4", "gopkg.in/src-d/go-git.v4/plumbing/object", true},
+
+		// Not on GitHub, no repository or not a major version.
+		{"gitlab.com/go-yaml/yaml", "v2", "", false},
+		{"github.com/go-yaml", "v2", "", false},
+		{"github.com/go-yaml/yaml", "2", "", false},
+		{"github.com/go-yaml/yaml", "v2.1", "", false},
+		{"github.com/go-yaml/yaml", "", "", false},
+	}
+	for _, tt := range tests {
+		path, ok := ToGopkgIn(tt.github, tt.major)
+		if path != tt.path || ok != tt.ok {
+			t.Errorf("ToGopkgIn(%q, %q) = %q, %v, want %q, %v", tt.github, tt.major, path, ok, tt.path, tt.ok)
+		}
+	}
+}
+
+func TestGopkgInRoundTrip(t *testing.T) {
+	for _, path := range []string{
+		"gopkg.in/yaml.v2",
+		"gopkg.in/yaml.v3",
+		"gopkg.in/tomb.v1-unstable",
+		"gopkg.in/mgo.v2/bson",
+		"gopkg.in/src-d/go-git.v4",
+		"gopkg.in/src-d/go-git.v4/plumbing/object",
+		"gopkg.in/natefinch/lumberjack.v2",
+	} {
+		github, major, ok := FromGopkgIn(path)
+		if !ok {
+			t.Errorf("FromGopkgIn(%q) failed", path)
+			continue
+		}
+		if got, ok := ToGopkgIn(github, major); got != path || !ok {
+			t.Errorf("ToGopkgIn(FromGopkgIn(%q)) = %q, %v, want %q, true", path, got, ok, path)
+		}
+	}
+}
+
+// The URLs are taken from the curated lists in internal/testdata.
+var repoURLTests = []struct {
+	url  string
+	path string // empty if FromRepoURL fails
+}{
+	{"https://github.com/spf13/cobra", "github.com/spf13/cobra"},
+	{"https://github.com/SimonWaldherr/golibs/tree/master/cache", "github.com/SimonWaldherr/golibs/cache"},
+	{"https://github.com/ardanlabs/service/wiki", "github.com/ardanlabs/service"},
+	{"https://github.com/cockroachdb/cockroach/blob/master/docs/style.md", "github.com/cockroachdb/cockroach/docs"},
+	{"https://gitlab.com/cznic/strutil", "gitlab.com/cznic/strutil"},
+	{"https://codeberg.org/anaseto/goal", "codeberg.org/anaseto/goal"},
+	{"https://bitbucket.org/dchapes/humanize", "bitbucket.org/dchapes/humanize"},
+	{"https://bitbucket.org/jaybill/sawsij/src", "bitbucket.org/jaybill/sawsij"},
+	{"https://pkg.go.dev/modernc.org/sqlite", "modernc.org/sqlite"},
+	{"https://pkg.go.dev/golang.org/x/net/html#Node", "golang.org/x/net/html"},
+	{"https://pkg.go.dev/", ""},
+	{"https://go.googlesource.com/crypto/+/master/scrypt/", "golang.org/x/crypto/scrypt"},
+
+	// Rewritten by DefaultRules.
+	{"https://www.github.com/aphistic/gomol", "github.com/aphistic/gomol"},
+	{"http://code.google.com/p/goprotobuf/", "github.com/golang/protobuf"},
+	{"http://code.google.com/p/gogoprotobuf/", "github.com/gogo/protobuf"},
+	{"http://code.google.com/p/gomock/", "code.google.com/p/gomock"},
+	{"http://code.google.com/p/snappy-go/", "code.google.com/p/snappy-go"}, // the rule covers snappy-go/snappy only
+}
+
+func TestFromRepoURL(t *testing.T) {
+	var lists []byte
+	for _, name := range []string{"awesome-go-README.md", "go-wiki-Projects.md"} {
+		data, err := os.ReadFile(filepath.Join("..", "testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lists = append(lists, data...)
+	}
+	for _, tt := range repoURLTests {
+		if !bytes.Contains(lists, []byte(tt.url)) {
+			t.Errorf("%s is not in the curated lists in testdata", tt.url)
+		}
+		path, err := FromRepoURL(tt.url)
+		if tt.path == "" {
+			if err == nil {
+				t.Errorf("FromRepoURL(%q) = %q, want error", tt.url, path)
+			}
+			continue
+		}
+		if err != nil || path != tt.path {
+			t.Errorf("FromRepoURL(%q) = %q, %v, want %q", tt.url, path, err, tt.path)
+		}
+	}
+}
+
+func TestFromRepoURLRules(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(`
+# hosts known under several names
+git.example.org/ example.org/
+code.google.com/p/gomock github.com/golang/mock
+go.example.com/mirror/
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetRules(Rules())
+	SetRules(rules)
+
+	tests := []struct {
+		url  string
+		path string
+	}{
+		{"https://git.example.org/team/tool", "example.org/team/tool"},
+		{"http://code.google.com/p/gomock/", "github.com/golang/mock"},
+		{"http://code.google.com/p/gomockery/", "code.google.com/p/gomockery"},
+		{"https://go.example.com/mirror/github.com/spf13/cobra", "github.com/spf13/cobra"},
+		{"https://go.googlesource.com/crypto/+/master/scrypt/", "golang.org/x/crypto/scrypt"},
+		// The default rules are replaced.
+		{"https://www.github.com/aphistic/gomol", "www.github.com/aphistic/gomol"},
+	}
+	for _, tt := range tests {
+		if path, err := FromRepoURL(tt.url); err != nil || path != tt.path {
+			t.Errorf("FromRepoURL(%q) = %q, %v, want %q", tt.url, path, err, tt.path)
+		}
+	}
+}
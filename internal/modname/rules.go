@@ -0,0 +1,113 @@
+package modname
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Rule rewrites paths starting with the path elements From to start
+// with To instead. An empty To strips From, e.g. a prefix lists put in
+// front of module paths; otherwise To usually names the new host or home
+// of the module, e.g. for hosts known under several names.
+type Rule struct {
+	From string
+	To   string
+}
+
+// Match reports whether path starts with the path elements of r.From,
+// ignoring case. A From ending in a slash matches any path below it.
+func (r Rule) Match(path string) bool {
+	if len(path) < len(r.From) || !strings.EqualFold(path[:len(r.From)], r.From) {
+		return false
+	}
+	return len(path) == len(r.From) || strings.HasSuffix(r.From, "/") || path[len(r.From)] == '/'
+}
+
+// Apply returns path with the prefix matched by r replaced.
+func (r Rule) Apply(path string) string {
+	return r.To + path[len(r.From):]
+}
+
+func (r Rule) String() string {
+	if r.To == "" {
+		return r.From
+	}
+	return r.From + " " + r.To
+}
+
+// DefaultRules are the rules applied unless replaced with SetRules. The
+// code.google.com rules follow the projects that moved when Google Code
+// was shut down to the paths the go command resolves today.
+var DefaultRules = []Rule{
+	{"www.github.com/", "github.com/"},
+	{"code.google.com/p/go.net", "golang.org/x/net"},
+	{"code.google.com/p/go.crypto", "golang.org/x/crypto"},
+	{"code.google.com/p/go.text", "golang.org/x/text"},
+	{"code.google.com/p/go.tools", "golang.org/x/tools"},
+	{"code.google.com/p/go.image", "golang.org/x/image"},
+	{"code.google.com/p/go.exp", "golang.org/x/exp"},
+	{"code.google.com/p/go.blog", "golang.org/x/blog"},
+	{"code.google.com/p/go.talks", "golang.org/x/talks"},
+	{"code.google.com/p/goprotobuf", "github.com/golang/protobuf"},
+	{"code.google.com/p/snappy-go/snappy", "github.com/golang/snappy"},
+	{"code.google.com/p/gogoprotobuf", "github.com/gogo/protobuf"},
+}
+
+// rules are the rules in effect.
+var rules = DefaultRules
+
+// SetRules replaces the rules Normalize and FromRepoURL apply. It is not
+// safe to call concurrently with them.
+func SetRules(r []Rule) {
+	rules = r
+}
+
+// Rules returns the rules in effect.
+func Rules() []Rule {
+	return rules
+}
+
+// MatchRule returns the first rule in effect matching path.
+func MatchRule(path string) (Rule, bool) {
+	for _, r := range rules {
+		if r.Match(path) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// rewrite applies the first rule in effect matching path.
+func rewrite(path string) string {
+	if r, ok := MatchRule(path); ok {
+		return r.Apply(path)
+	}
+	return path
+}
+
+// ParseRules reads rules in the format of the --modname-rules file of
+// modhunt: one rule per line, the prefix followed by its replacement,
+// separated by white space. A prefix without replacement is stripped.
+// Empty lines and lines starting with # are ignored.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		switch len(fields) {
+		case 1:
+			rules = append(rules, Rule{From: fields[0]})
+		case 2:
+			rules = append(rules, Rule{From: fields[0], To: fields[1]})
+		default:
+			return nil, fmt.Errorf("line %d: want prefix and at most one replacement", line)
+		}
+	}
+	return rules, s.Err()
+}
@@ -0,0 +1,174 @@
+// Package osv implements a client for the OSV.dev vulnerability database,
+// which includes the advisories of the Go vulnerability database.
+package osv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the base URL of the OSV.dev API.
+const DefaultURL = "https://api.osv.dev"
+
+// Client queries the OSV.dev API. It is safe for concurrent use.
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// New returns a client for the API at baseURL, e.g. DefaultURL.
+// If httpClient is nil, http.DefaultClient is used.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// Vuln is an advisory in the OSV format, reduced to what modhunt shows.
+type Vuln struct {
+	ID        string     `json:"id"`
+	Summary   string     `json:"summary"`
+	Aliases   []string   `json:"aliases"`
+	Published time.Time  `json:"published"`
+	Modified  time.Time  `json:"modified"`
+	Affected  []Affected `json:"affected"`
+
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// Severity returns the severity the database of the advisory assigned,
+// e.g. "HIGH" for GitHub advisories, or "" if it has none. Advisories of
+// the Go vulnerability database have none, their aliases may.
+func (v *Vuln) Severity() string {
+	return strings.ToUpper(v.DatabaseSpecific.Severity)
+}
+
+// Affected describes the affected versions of a package.
+type Affected struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"`
+		Events []struct {
+			Introduced string `json:"introduced,omitempty"`
+			Fixed      string `json:"fixed,omitempty"`
+		} `json:"events"`
+	} `json:"ranges"`
+}
+
+// Fixed returns the versions of module fixing the vulnerability,
+// with the "v" prefix used by the go command.
+func (v *Vuln) Fixed(module string) []string {
+	var fixed []string
+	for _, a := range v.Affected {
+		if a.Package.Name != module {
+			continue
+		}
+		for _, r := range a.Ranges {
+			for _, e := range r.Events {
+				if e.Fixed != "" {
+					fixed = append(fixed, "v"+e.Fixed)
+				}
+			}
+		}
+	}
+	return fixed
+}
+
+// Package identifies a version of a Go module.
+type Package struct {
+	Module  string
+	Version string
+}
+
+type query struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Version string `json:"version,omitempty"`
+}
+
+func newQuery(p Package) query {
+	var q query
+	q.Package.Name = p.Module
+	q.Package.Ecosystem = "Go"
+	// OSV records Go versions without the "v" prefix.
+	q.Version = strings.TrimPrefix(p.Version, "v")
+	return q
+}
+
+// Vulns returns the advisories affecting the version of the module.
+func (c *Client) Vulns(ctx context.Context, p Package) ([]Vuln, error) {
+	var resp struct {
+		Vulns []Vuln `json:"vulns"`
+	}
+	if err := c.post(ctx, "/v1/query", newQuery(p), &resp); err != nil {
+		return nil, err
+	}
+	return resp.Vulns, nil
+}
+
+// Count returns the number of advisories affecting each package,
+// using a single request.
+func (c *Client) Count(ctx context.Context, pkgs []Package) ([]int, error) {
+	var req struct {
+		Queries []query `json:"queries"`
+	}
+	for _, p := range pkgs {
+		req.Queries = append(req.Queries, newQuery(p))
+	}
+	var resp struct {
+		Results []struct {
+			Vulns []struct {
+				ID string `json:"id"`
+			} `json:"vulns"`
+		} `json:"results"`
+	}
+	if err := c.post(ctx, "/v1/querybatch", req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(pkgs) {
+		return nil, fmt.Errorf("got %d results for %d queries", len(resp.Results), len(pkgs))
+	}
+	counts := make([]int, len(pkgs))
+	for i, r := range resp.Results {
+		counts[i] = len(r.Vulns)
+	}
+	return counts, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body, v any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+	u := c.url + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("post %s: unexpected status: %s", u, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
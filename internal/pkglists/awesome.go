@@ -107,6 +107,7 @@ func ParseAwesomeGoReadme(r io.Reader) (*Source, error) {
 					if len(parts) != 2 {
 						return nil, fmt.Errorf("link without '](': %s", line)
 					}
+					name := strings.TrimPrefix(strings.TrimLeft(parts[0], "- "), "[")
 					parts = strings.SplitN(parts[1], ")", 2)
 					if len(parts) != 2 {
 						return nil, fmt.Errorf("link without ')': %s", line)
@@ -115,6 +116,7 @@ func ParseAwesomeGoReadme(r io.Reader) (*Source, error) {
 					desc := strings.TrimLeft(parts[1], " -")
 					cat.Links = append(cat.Links, Link{
 						URL:         url,
+						Name:        name,
 						Description: desc,
 						Category:    cat,
 						Source:      source,
@@ -131,7 +133,7 @@ func ParseAwesomeGoReadme(r io.Reader) (*Source, error) {
 
 				log.Warn("Ignoring unexpected line.")
 			default:
-				return nil, fmt.Errorf("BUG: unexpected state: %d", st)
+				return nil, fmt.Errorf("BUG: unexpected state: %s", st)
 			}
 
 			prevWasEmpty = false
@@ -5,13 +5,21 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+
+	"github.com/ngrash/modhunt/internal/modname"
 )
 
 type Link struct {
 	URL         string
+	Name        string // link text, if the source has one
 	Description string
 	Category    *Category
 	Source      *Source
+
+	// Module is the verified module path the link points to, empty if it
+	// was not resolved. NotModule is set if it was resolved to no module.
+	Module    string
+	NotModule bool
 }
 
 type Category struct {
@@ -27,6 +35,10 @@ type Source struct {
 	URL  string
 
 	Root *Category
+
+	// Dead are links the source marks as dead or broken. Links in a
+	// dedicated section are not part of Root.
+	Dead []Link
 }
 
 type Lookup struct {
@@ -47,6 +59,10 @@ func Key(pkgURL string) (string, error) {
 	}
 	u.Scheme = ""
 	key := u.String()[2:] // remove leading "//"
+	// Links to the repositories of golang.org/x modules name the module.
+	if path, ok := modname.FromGoogleSource(key); ok {
+		key = path
+	}
 	return key, nil
 }
 
@@ -79,6 +95,32 @@ func (l *Lookup) addCategory(c *Category, root bool) error {
 	return nil
 }
 
+// SetModules records the resolved module paths of links by URL. An empty
+// path marks links that are not a Go module.
+func (l *Lookup) SetModules(paths map[string]string) {
+	set := func(links []Link) {
+		for i := range links {
+			if path, ok := paths[links[i].URL]; ok {
+				links[i].Module, links[i].NotModule = path, path == ""
+			}
+		}
+	}
+	for _, links := range l.Packages {
+		set(links)
+	}
+	var walk func(c *Category)
+	walk = func(c *Category) {
+		set(c.Links)
+		for _, sub := range c.Categories {
+			walk(sub)
+		}
+	}
+	for _, s := range l.Sources {
+		walk(s.Root)
+		set(s.Dead)
+	}
+}
+
 func checkCategory(c *Category, root bool) error {
 	if c.Name == "" {
 		return fmt.Errorf("category has no name")
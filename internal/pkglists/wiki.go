@@ -2,6 +2,7 @@ package pkglists
 
 import (
 	"io"
+	"regexp"
 	"slices"
 	"strings"
 
@@ -19,7 +20,8 @@ func ParseGoWikiProjects(r io.Reader) (*Source, error) {
 		},
 	}
 
-	skipHeadings := []string{"title: Projects", "Indexes and search engines", "Dead projects", "Table of Contents"}
+	skipHeadings := []string{"title: Projects", "Indexes and search engines", "Table of Contents"}
+	dead := &Category{Name: deadHeading}
 
 	cat := source.Root
 
@@ -39,6 +41,11 @@ func ParseGoWikiProjects(r io.Reader) (*Source, error) {
 		if slices.Contains(skipHeadings, title) {
 			continue
 		}
+		if title == deadHeading {
+			dead.Links = parseWikiLinks(heading, data, dead, source)
+			source.Dead = append(source.Dead, dead.Links...)
+			continue
+		}
 		level := heading.Level
 		if level <= cat.Level {
 			for cat = cat.Parent; cat.Level >= level; cat = cat.Parent {
@@ -52,49 +59,66 @@ func ParseGoWikiProjects(r io.Reader) (*Source, error) {
 			Name:   title,
 		}
 		parent.Categories = append(parent.Categories, cat)
+		cat.Links = parseWikiLinks(heading, data, cat, source)
+		for _, l := range cat.Links {
+			if deadMarkerRE.MatchString(l.Description) {
+				source.Dead = append(source.Dead, l)
+			}
+		}
+	}
 
-		for c := heading.NextSibling(); c != nil; c = c.NextSibling() {
-			switch list := c.(type) {
-			case *ast.Heading:
-				goto nextHeading
-			case *ast.List:
-				for li := list.FirstChild(); li != nil; li = li.NextSibling() {
-					item := li.(*ast.ListItem)
-					for i := item.FirstChild(); i != nil; i = i.NextSibling() {
-						tb, ok := i.(*ast.TextBlock)
-						if !ok {
-							continue
-						}
+	return source, nil
+}
 
-						var url string
-						for j := tb.FirstChild(); j != nil; j = j.NextSibling() {
-							if link, ok := j.(*ast.Link); ok {
-								url = string(link.Destination)
-								break
-							}
-						}
-						if url == "" {
-							continue
-						}
+// deadHeading is the section of the wiki listing projects known to be
+// dead or broken. They are not curated.
+const deadHeading = "Dead projects"
 
-						tbLines := string(tb.Lines().Value(data))
-						urlIdx := strings.Index(tbLines, url)
-						desc := tbLines[urlIdx+len(url)+1:]
-						desc = strings.TrimLeft(desc, " -")
+// deadMarkerRE matches descriptions of projects marked as dead in place.
+var deadMarkerRE = regexp.MustCompile(`(?i)[(\[]\s*(dead|broken|abandoned|unmaintained)\s*[)\]]`)
 
-						cat.Links = append(cat.Links, Link{
-							URL:         url,
-							Description: desc,
-							Category:    cat,
-							Source:      source,
-						})
+// parseWikiLinks returns the links in the lists following heading up to
+// the next heading.
+func parseWikiLinks(heading *ast.Heading, data []byte, cat *Category, source *Source) []Link {
+	var links []Link
+	for c := heading.NextSibling(); c != nil; c = c.NextSibling() {
+		switch list := c.(type) {
+		case *ast.Heading:
+			return links
+		case *ast.List:
+			for li := list.FirstChild(); li != nil; li = li.NextSibling() {
+				item := li.(*ast.ListItem)
+				for i := item.FirstChild(); i != nil; i = i.NextSibling() {
+					tb, ok := i.(*ast.TextBlock)
+					if !ok {
+						continue
 					}
+
+					var url string
+					for j := tb.FirstChild(); j != nil; j = j.NextSibling() {
+						if link, ok := j.(*ast.Link); ok {
+							url = string(link.Destination)
+							break
+						}
+					}
+					if url == "" {
+						continue
+					}
+
+					tbLines := string(tb.Lines().Value(data))
+					urlIdx := strings.Index(tbLines, url)
+					desc := tbLines[urlIdx+len(url)+1:]
+					desc = strings.TrimLeft(desc, " -")
+
+					links = append(links, Link{
+						URL:         url,
+						Description: desc,
+						Category:    cat,
+						Source:      source,
+					})
 				}
 			}
 		}
-
-	nextHeading:
 	}
-
-	return source, nil
+	return links
 }
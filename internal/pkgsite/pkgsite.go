@@ -0,0 +1,134 @@
+// Package pkgsite reads module metadata from the pages of pkg.go.dev.
+//
+// pkg.go.dev has no public API, so the header of the page of a module is
+// scraped for the version shown, its licenses and the number of importing
+// packages. pkg.go.dev redirects paths it knows under another name, e.g.
+// github.com/go-yaml/yaml to gopkg.in/yaml.v2; the path it ends up at is
+// reported as well.
+package pkgsite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultURL is the base URL of pkg.go.dev.
+const DefaultURL = "https://pkg.go.dev"
+
+// ErrNotFound is returned for paths pkg.go.dev does not know.
+var ErrNotFound = errors.New("not found")
+
+// Client fetches pages from pkg.go.dev. It is safe for concurrent use.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	limiter    *rate.Limiter
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client. The default is http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRateLimit limits requests to perSecond. pkg.go.dev answers
+// bursts of requests with 429 Too Many Requests. By default, requests
+// are not limited.
+func WithRateLimit(perSecond float64) Option {
+	return func(c *Client) { c.limiter = rate.NewLimiter(rate.Limit(perSecond), 1) }
+}
+
+// New returns a client for the site at baseURL, e.g. DefaultURL.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		url:        strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		limiter:    rate.NewLimiter(rate.Inf, 1),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Metadata is what the header of a module page shows.
+type Metadata struct {
+	Path       string   // path of the page after redirects
+	Version    string   // version shown, usually the latest
+	Licenses   []string // license types, e.g. "MIT"
+	ImportedBy int      // number of importing packages
+}
+
+// Redirected reports whether pkg.go.dev shows path under another name.
+func (m *Metadata) Redirected(path string) bool {
+	return !strings.EqualFold(m.Path, path)
+}
+
+var (
+	versionRE    = regexp.MustCompile(`(?s)data-test-id="UnitHeader-version".*?</span>\s*([^<\s]+)`)
+	licenseRE    = regexp.MustCompile(`data-test-id="UnitHeader-license"[^>]*>\s*([^<]+?)\s*<`)
+	importedByRE = regexp.MustCompile(`(?s)data-test-id="UnitHeader-importedby".*?Imported by:?\s*(?:</span>)?\s*([\d,]+)`)
+)
+
+// Metadata returns the metadata of the latest version of path.
+func (c *Client) Metadata(ctx context.Context, path string) (*Metadata, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	u := c.url + "/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	default:
+		return nil, fmt.Errorf("get %s: unexpected status: %s", u, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", u, err)
+	}
+	page := string(body)
+
+	// The client followed redirects, the final request names the page.
+	final, _, _ := strings.Cut(strings.TrimPrefix(resp.Request.URL.Path, "/"), "@")
+	m := &Metadata{Path: final}
+	if match := versionRE.FindStringSubmatch(page); match != nil {
+		m.Version = html.UnescapeString(match[1])
+	}
+	for _, match := range licenseRE.FindAllStringSubmatch(page, -1) {
+		for _, l := range strings.Split(html.UnescapeString(match[1]), ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				m.Licenses = append(m.Licenses, l)
+			}
+		}
+	}
+	if match := importedByRE.FindStringSubmatch(page); match != nil {
+		n, err := strconv.Atoi(strings.ReplaceAll(match[1], ",", ""))
+		if err != nil {
+			return nil, fmt.Errorf("parse imported by count %q: %w", match[1], err)
+		}
+		m.ImportedBy = n
+	}
+	return m, nil
+}
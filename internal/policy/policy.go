@@ -0,0 +1,221 @@
+// Package policy checks modules against the rules an organization sets
+// for taking on dependencies, e.g. as a gate in CI.
+//
+// A policy is a YAML document; rules that are not set are not checked:
+//
+//	# SPDX identifiers of the licenses modules may have.
+//	licenses: [MIT, Apache-2.0, BSD-2-Clause, BSD-3-Clause]
+//	# The highest severity of advisories affecting the version:
+//	# none, low, moderate, high or critical.
+//	max_severity: moderate
+//	# The lowest health score, see package modscore.
+//	min_score: 50
+//	# The most requirements in go.mod, direct and indirect.
+//	max_dependencies: 40
+//	# Hosts neither the module path nor the repository may be on.
+//	banned_hosts: [example.com]
+//
+// Facts that could not be collected fail their rule, a gate should not
+// let through what it cannot check.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities are the severities of advisories from least to most severe,
+// as GitHub assigns them. None allows no advisories at all.
+var Severities = []string{"none", "low", "moderate", "high", "critical"}
+
+// Policy are the rules modules are checked against.
+type Policy struct {
+	Licenses        []string `yaml:"licenses"`
+	MaxSeverity     string   `yaml:"max_severity"`
+	MinScore        *int     `yaml:"min_score"`
+	MaxDependencies *int     `yaml:"max_dependencies"`
+	BannedHosts     []string `yaml:"banned_hosts"`
+}
+
+// Load reads the policy in the YAML file name.
+func Load(name string) (*Policy, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return p, nil
+}
+
+// Parse parses a policy. Unknown keys are errors, so that misspelled
+// rules are not silently skipped.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	if p.MaxSeverity != "" {
+		p.MaxSeverity = strings.ToLower(p.MaxSeverity)
+		if !slices.Contains(Severities, p.MaxSeverity) {
+			return nil, fmt.Errorf("invalid max_severity %q, want one of %s", p.MaxSeverity, strings.Join(Severities, ", "))
+		}
+	}
+	if p.MinScore != nil && (*p.MinScore < 0 || *p.MinScore > 100) {
+		return nil, fmt.Errorf("invalid min_score %d, want 0 to 100", *p.MinScore)
+	}
+	if p.MaxDependencies != nil && *p.MaxDependencies < 0 {
+		return nil, fmt.Errorf("invalid max_dependencies %d", *p.MaxDependencies)
+	}
+	return &p, nil
+}
+
+// Facts are what is known about a version of a module.
+type Facts struct {
+	Module  string
+	Version string
+
+	License string // SPDX identifier, empty if unknown
+
+	VulnsKnown bool
+	Vulns      []Vuln // advisories affecting Version
+
+	ScoreKnown bool
+	Score      int
+
+	DependenciesKnown bool
+	Dependencies      int // requirements in the go.mod of Version
+
+	// Hosts are the hosts of the module path and its repository.
+	Hosts []string
+}
+
+// Vuln is an advisory affecting a module.
+type Vuln struct {
+	ID       string
+	Severity string // one of Severities except none, empty if unknown
+}
+
+// Result is the outcome of checking one rule.
+type Result struct {
+	Rule   string `json:"rule"` // key of the rule in the policy
+	Pass   bool   `json:"pass"`
+	Reason string `json:"reason"`
+}
+
+// Check checks the facts against every rule set in the policy.
+func (p *Policy) Check(f Facts) []Result {
+	var results []Result
+	if len(p.Licenses) > 0 {
+		results = append(results, p.checkLicense(f))
+	}
+	if p.MaxSeverity != "" {
+		results = append(results, p.checkSeverity(f))
+	}
+	if p.MinScore != nil {
+		r := Result{Rule: "min_score"}
+		switch {
+		case !f.ScoreKnown:
+			r.Reason = "score unknown"
+		case f.Score < *p.MinScore:
+			r.Reason = fmt.Sprintf("score %d, below %d", f.Score, *p.MinScore)
+		default:
+			r.Pass, r.Reason = true, fmt.Sprintf("score %d", f.Score)
+		}
+		results = append(results, r)
+	}
+	if p.MaxDependencies != nil {
+		r := Result{Rule: "max_dependencies"}
+		switch {
+		case !f.DependenciesKnown:
+			r.Reason = "dependencies unknown"
+		case f.Dependencies > *p.MaxDependencies:
+			r.Reason = fmt.Sprintf("%d dependencies, more than %d", f.Dependencies, *p.MaxDependencies)
+		default:
+			r.Pass, r.Reason = true, fmt.Sprintf("%d dependencies", f.Dependencies)
+		}
+		results = append(results, r)
+	}
+	if len(p.BannedHosts) > 0 {
+		results = append(results, p.checkHosts(f))
+	}
+	return results
+}
+
+func (p *Policy) checkLicense(f Facts) Result {
+	r := Result{Rule: "licenses"}
+	switch {
+	case f.License == "":
+		r.Reason = "license unknown"
+	case !slices.ContainsFunc(p.Licenses, func(l string) bool { return strings.EqualFold(l, f.License) }):
+		r.Reason = fmt.Sprintf("license %s not allowed", f.License)
+	default:
+		r.Pass, r.Reason = true, "license "+f.License
+	}
+	return r
+}
+
+// checkSeverity fails for advisories more severe than allowed. Advisories
+// without a severity count as critical.
+func (p *Policy) checkSeverity(f Facts) Result {
+	r := Result{Rule: "max_severity"}
+	if !f.VulnsKnown {
+		r.Reason = "advisories unknown"
+		return r
+	}
+	allowed := slices.Index(Severities, p.MaxSeverity)
+	var above []string
+	for _, v := range f.Vulns {
+		severity := strings.ToLower(v.Severity)
+		if !slices.Contains(Severities[1:], severity) {
+			severity = "unknown"
+		}
+		if severity == "unknown" || slices.Index(Severities, severity) > allowed {
+			above = append(above, fmt.Sprintf("%s (%s)", v.ID, severity))
+		}
+	}
+	switch {
+	case len(above) > 0:
+		r.Reason = fmt.Sprintf("advisories above %s: %s", p.MaxSeverity, strings.Join(above, ", "))
+	case len(f.Vulns) == 0:
+		r.Pass, r.Reason = true, "no advisories"
+	default:
+		r.Pass, r.Reason = true, fmt.Sprintf("%d advisories, none above %s", len(f.Vulns), p.MaxSeverity)
+	}
+	return r
+}
+
+func (p *Policy) checkHosts(f Facts) Result {
+	r := Result{Rule: "banned_hosts"}
+	for _, h := range f.Hosts {
+		for _, banned := range p.BannedHosts {
+			if strings.EqualFold(h, banned) || strings.HasSuffix(strings.ToLower(h), "."+strings.ToLower(banned)) {
+				r.Reason = fmt.Sprintf("hosted on %s", h)
+				return r
+			}
+		}
+	}
+	r.Pass, r.Reason = true, "hosted on "+strings.Join(f.Hosts, ", ")
+	return r
+}
+
+// Passed reports whether every result passed.
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if !r.Pass {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want *Policy // nil if Parse fails
+	}{
+		{"", &Policy{}},
+		{"# only a comment\n", &Policy{}},
+		{
+			"licenses: [MIT, Apache-2.0]\nmax_severity: moderate\nmin_score: 50\nmax_dependencies: 40\nbanned_hosts: [example.com]\n",
+			&Policy{
+				Licenses:        []string{"MIT", "Apache-2.0"},
+				MaxSeverity:     "moderate",
+				MinScore:        intPtr(50),
+				MaxDependencies: intPtr(40),
+				BannedHosts:     []string{"example.com"},
+			},
+		},
+		{"max_severity: HIGH\n", &Policy{MaxSeverity: "high"}},
+		{"max_severity: None\n", &Policy{MaxSeverity: "none"}},
+		{"min_score: 0\nmax_dependencies: 0\n", &Policy{MinScore: intPtr(0), MaxDependencies: intPtr(0)}},
+
+		// Unknown keys and invalid values.
+		{"licences: [MIT]\n", nil},
+		{"max_severity: severe\n", nil},
+		{"min_score: 101\n", nil},
+		{"min_score: -1\n", nil},
+		{"max_dependencies: -1\n", nil},
+		{"licenses: MIT\n", nil},
+	}
+	for _, tt := range tests {
+		got, err := Parse([]byte(tt.yaml))
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("Parse(%q) = %+v, want error", tt.yaml, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.yaml, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.yaml, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p, err := Parse([]byte("licenses: [MIT, BSD-3-Clause]\nmax_severity: Moderate\nmin_score: 50\nmax_dependencies: 10\nbanned_hosts: [example.com]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	known := Facts{
+		Module:            "github.com/foo/bar",
+		Version:           "v1.0.0",
+		License:           "MIT",
+		VulnsKnown:        true,
+		ScoreKnown:        true,
+		Score:             80,
+		DependenciesKnown: true,
+		Dependencies:      5,
+		Hosts:             []string{"github.com"},
+	}
+
+	tests := []struct {
+		name   string
+		change func(*Facts)
+		rule   string
+		pass   bool
+		reason string
+	}{
+		{"license allowed", nil, "licenses", true, "license MIT"},
+		{"license ignoring case", func(f *Facts) { f.License = "bsd-3-clause" }, "licenses", true, "license bsd-3-clause"},
+		{"license not allowed", func(f *Facts) { f.License = "GPL-3.0" }, "licenses", false, "license GPL-3.0 not allowed"},
+		{"license unknown", func(f *Facts) { f.License = "" }, "licenses", false, "license unknown"},
+
+		{"no advisories", nil, "max_severity", true, "no advisories"},
+		{"advisories at most allowed", func(f *Facts) {
+			f.Vulns = []Vuln{{ID: "GO-1", Severity: "LOW"}, {ID: "GO-2", Severity: "moderate"}}
+		}, "max_severity", true, "2 advisories, none above moderate"},
+		{"advisory above allowed", func(f *Facts) {
+			f.Vulns = []Vuln{{ID: "GO-1", Severity: "low"}, {ID: "GO-2", Severity: "High"}}
+		}, "max_severity", false, "advisories above moderate: GO-2 (high)"},
+		{"advisory without severity", func(f *Facts) { f.Vulns = []Vuln{{ID: "GO-1"}} },
+			"max_severity", false, "advisories above moderate: GO-1 (unknown)"},
+		{"advisories unknown", func(f *Facts) { f.VulnsKnown = false }, "max_severity", false, "advisories unknown"},
+
+		{"score high enough", nil, "min_score", true, "score 80"},
+		{"score at minimum", func(f *Facts) { f.Score = 50 }, "min_score", true, "score 50"},
+		{"score too low", func(f *Facts) { f.Score = 49 }, "min_score", false, "score 49, below 50"},
+		{"score unknown", func(f *Facts) { f.ScoreKnown = false }, "min_score", false, "score unknown"},
+
+		{"few dependencies", nil, "max_dependencies", true, "5 dependencies"},
+		{"too many dependencies", func(f *Facts) { f.Dependencies = 11 }, "max_dependencies", false, "11 dependencies, more than 10"},
+		{"dependencies unknown", func(f *Facts) { f.DependenciesKnown = false }, "max_dependencies", false, "dependencies unknown"},
+
+		{"host allowed", nil, "banned_hosts", true, "hosted on github.com"},
+		{"host banned", func(f *Facts) { f.Hosts = []string{"Example.com"} }, "banned_hosts", false, "hosted on Example.com"},
+		{"subdomain banned", func(f *Facts) { f.Hosts = []string{"github.com", "git.example.com"} }, "banned_hosts", false, "hosted on git.example.com"},
+		{"similar host allowed", func(f *Facts) { f.Hosts = []string{"notexample.com"} }, "banned_hosts", true, "hosted on notexample.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := known
+			if tt.change != nil {
+				tt.change(&f)
+			}
+			results := p.Check(f)
+			if len(results) != 5 {
+				t.Fatalf("Check returned %d results, want one per rule", len(results))
+			}
+			for _, r := range results {
+				if r.Rule != tt.rule {
+					continue
+				}
+				if r.Pass != tt.pass || r.Reason != tt.reason {
+					t.Errorf("%s = %v, %q, want %v, %q", r.Rule, r.Pass, r.Reason, tt.pass, tt.reason)
+				}
+				if Passed(results) != tt.pass {
+					t.Errorf("Passed = %v, want %v", !tt.pass, tt.pass)
+				}
+				return
+			}
+			t.Errorf("no result for %s", tt.rule)
+		})
+	}
+}
+
+func TestCheckUnsetRules(t *testing.T) {
+	p, err := Parse([]byte("min_score: 50\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := p.Check(Facts{Module: "example.com/m"})
+	want := []Result{{Rule: "min_score", Reason: "score unknown"}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Check = %+v, want %+v", results, want)
+	}
+}
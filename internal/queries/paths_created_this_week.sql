@@ -0,0 +1,4 @@
+select path, first_seen
+from paths
+where first_seen >= strftime('%Y-%m-%dT%H:%M:%SZ', 'now', '-7 days')
+order by first_seen desc;
@@ -0,0 +1,4 @@
+select path, last_seen
+from paths
+where last_seen < strftime('%Y-%m-%dT%H:%M:%SZ', 'now', '-3 years')
+order by last_seen;
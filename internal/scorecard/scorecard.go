@@ -0,0 +1,81 @@
+// Package scorecard implements a small client for the API of the OpenSSF
+// Scorecard project, which rates the supply-chain security practices of
+// open source repositories, e.g. branch protection or pinned dependencies,
+// in weekly scans of popular GitHub repositories.
+package scorecard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultURL is the base URL of the public Scorecard API.
+const DefaultURL = "https://api.securityscorecards.dev"
+
+// ErrNotFound is returned for repositories that were not scanned.
+var ErrNotFound = errors.New("not found")
+
+// Client talks to the Scorecard API. It is safe for concurrent use.
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// New returns a client for the API at baseURL, e.g. DefaultURL.
+// If httpClient is nil, http.DefaultClient is used.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// Result is the latest scan of a repository.
+type Result struct {
+	Date  string  `json:"date"` // of the scan, e.g. "2024-05-13"
+	Score float64 `json:"score"`
+	Repo  struct {
+		Name   string `json:"name"`
+		Commit string `json:"commit"`
+	} `json:"repo"`
+	Checks []Check `json:"checks"`
+}
+
+// Check is the result of one check, e.g. "Branch-Protection".
+type Check struct {
+	Name   string `json:"name"`
+	Score  int    `json:"score"` // 0 to 10, -1 if the check did not apply
+	Reason string `json:"reason"`
+}
+
+// Project returns the latest scan of the repository, e.g.
+// "github.com/owner/repo".
+func (c *Client) Project(ctx context.Context, repo string) (*Result, error) {
+	u := c.url + "/projects/" + repo
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", repo, ErrNotFound)
+	default:
+		return nil, fmt.Errorf("get %s: unexpected status: %s", u, resp.Status)
+	}
+	var r Result
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("decode result: %w", err)
+	}
+	return &r, nil
+}
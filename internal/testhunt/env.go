@@ -0,0 +1,244 @@
+package testhunt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	"github.com/ngrash/modhunt/modindex/index"
+	"github.com/ngrash/modhunt/modindex/indextest"
+)
+
+// Env serves a Fixture through a fake module index, module proxy and
+// forge on loopback addresses. It must be closed after use.
+type Env struct {
+	Index *indextest.Server
+	Proxy *httptest.Server
+	Forge *httptest.Server
+}
+
+// Start serves f as of now.
+func Start(f *Fixture, now time.Time) *Env {
+	var versions []index.VersionInfo
+	for _, m := range f.Modules {
+		for _, v := range m.Versions {
+			versions = append(versions, index.VersionInfo{Path: m.Path, Version: v.Version, Timestamp: now.Add(-v.Age)})
+		}
+	}
+	return &Env{
+		Index: indextest.NewServer(versions...),
+		Proxy: httptest.NewServer(&proxyHandler{f: f, now: now}),
+		Forge: httptest.NewServer(&forgeHandler{f: f, now: now}),
+	}
+}
+
+// IndexURL returns the URL of the module index endpoint.
+func (e *Env) IndexURL() string { return e.Index.IndexURL() }
+
+// ProxyURL returns the URL of the module proxy, usable as GOPROXY.
+func (e *Env) ProxyURL() string { return e.Proxy.URL }
+
+// GitHubURL returns the base URL of the GitHub REST API, which is served
+// below /api/v3/ like that of GitHub Enterprise.
+func (e *Env) GitHubURL() string { return e.Forge.URL }
+
+// ScorecardURL returns the base URL of the Scorecard API.
+func (e *Env) ScorecardURL() string { return e.Forge.URL }
+
+// Close shuts the servers down.
+func (e *Env) Close() {
+	e.Index.Close()
+	e.Proxy.Close()
+	e.Forge.Close()
+}
+
+// proxyHandler serves the module proxy protocol: @v/list lists the tagged
+// versions, @latest returns the latest tagged version or else the latest
+// pseudo-version, and @v/VERSION.info and .mod describe a version.
+type proxyHandler struct {
+	f   *Fixture
+	now time.Time
+}
+
+func (h *proxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	escaped, file, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/@")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	path, err := module.UnescapePath(escaped)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	i := slices.IndexFunc(h.f.Modules, func(m Module) bool { return m.Path == path })
+	if i < 0 {
+		http.Error(w, "not found: "+path, http.StatusNotFound)
+		return
+	}
+	m := h.f.Modules[i]
+
+	switch {
+	case file == "v/list":
+		for _, v := range m.Versions {
+			if !module.IsPseudoVersion(v.Version) {
+				_, _ = fmt.Fprintln(w, v.Version)
+			}
+		}
+		return
+	case file == "latest":
+		h.serveInfo(w, m, latest(m))
+		return
+	}
+	name, ok := strings.CutPrefix(file, "v/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	i = strings.LastIndex(name, ".")
+	if i < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	version, err := module.UnescapeVersion(name[:i])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	j := slices.IndexFunc(m.Versions, func(v Version) bool { return v.Version == version })
+	if j < 0 {
+		http.Error(w, "not found: "+path+"@"+version, http.StatusNotFound)
+		return
+	}
+	switch name[i+1:] {
+	case "info":
+		h.serveInfo(w, m, m.Versions[j])
+	case "mod":
+		_, _ = fmt.Fprintf(w, "module %s\n\ngo 1.22\n", m.Path)
+		if j == len(m.Versions)-1 && len(m.Requires) > 0 {
+			_, _ = fmt.Fprintln(w, "\nrequire (")
+			for _, req := range m.Requires {
+				_, _ = fmt.Fprintf(w, "\t%s %s\n", req, h.latestOf(req))
+			}
+			_, _ = fmt.Fprintln(w, ")")
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *proxyHandler) serveInfo(w http.ResponseWriter, m Module, v Version) {
+	info := map[string]any{
+		"Version": v.Version,
+		"Time":    h.now.Add(-v.Age).UTC(),
+		"Origin":  map[string]string{"VCS": "git", "URL": "https://" + repoPath(m.Path)},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(info)
+}
+
+// latestOf returns the latest version of the module path, or v0.0.0 if
+// the fixture does not contain it.
+func (h *proxyHandler) latestOf(path string) string {
+	for _, m := range h.f.Modules {
+		if m.Path == path {
+			return latest(m).Version
+		}
+	}
+	return "v0.0.0"
+}
+
+// latest returns the highest tagged version of m or, if there is none,
+// its latest pseudo-version.
+func latest(m Module) Version {
+	var tagged []Version
+	for _, v := range m.Versions {
+		if !module.IsPseudoVersion(v.Version) {
+			tagged = append(tagged, v)
+		}
+	}
+	if len(tagged) == 0 {
+		return m.Versions[len(m.Versions)-1]
+	}
+	return slices.MaxFunc(tagged, func(a, b Version) int { return semver.Compare(a.Version, b.Version) })
+}
+
+// repoPath returns the path of the repository of the module path,
+// dropping a major version suffix.
+func repoPath(path string) string {
+	prefix, _, ok := module.SplitPathVersion(path)
+	if !ok {
+		return path
+	}
+	return prefix
+}
+
+// forgeHandler serves the repositories of a fixture through the
+// endpoints of the GitHub REST API and the Scorecard API that modhunt
+// uses: /api/v3/repos/OWNER/NAME and /projects/github.com/OWNER/NAME.
+type forgeHandler struct {
+	f   *Fixture
+	now time.Time
+}
+
+func (h *forgeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if name, ok := strings.CutPrefix(r.URL.Path, "/api/v3/repos/"); ok {
+		repo, ok := h.repo(name)
+		if !ok {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		body := map[string]any{
+			"full_name":        repo.Name,
+			"description":      repo.Description,
+			"stargazers_count": repo.Stars,
+			"forks_count":      repo.Forks,
+			"archived":         repo.Archived,
+			"topics":           repo.Topics,
+			"pushed_at":        h.now.Add(-repo.Pushed).UTC(),
+		}
+		if repo.License != "" {
+			body["license"] = map[string]string{"spdx_id": repo.License}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, strings.ReplaceAll(repo.Name, "/", "-"), repo.Stars))
+		_ = json.NewEncoder(w).Encode(body)
+		return
+	}
+	if name, ok := strings.CutPrefix(r.URL.Path, "/projects/github.com/"); ok {
+		repo, ok := h.repo(name)
+		if !ok || repo.Scorecard == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		body := map[string]any{
+			"date":  h.now.Add(-7 * day).Format(time.DateOnly),
+			"score": repo.Scorecard,
+			"repo":  map[string]string{"name": "github.com/" + repo.Name, "commit": "0123456789abcdef0123456789abcdef01234567"},
+			"checks": []map[string]any{
+				{"name": "Maintained", "score": min(10, int(repo.Scorecard)+2), "reason": "fixture"},
+				{"name": "Pinned-Dependencies", "score": int(repo.Scorecard) - 2, "reason": "fixture"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (h *forgeHandler) repo(name string) (Repo, bool) {
+	for _, r := range h.f.Repos {
+		if strings.EqualFold(r.Name, name) {
+			return r, true
+		}
+	}
+	return Repo{}, false
+}
@@ -0,0 +1,171 @@
+// Package testhunt provides a small, fixed Go ecosystem for exercising the
+// whole modhunt pipeline without the network: curated lists, a fake module
+// proxy, a fake module index and a fake forge serving the GitHub REST API
+// and the OpenSSF Scorecard API.
+//
+// Release and push times are given as ages, so data collected from an Env
+// started at now ages with the clock and scores stay the same from run to
+// run. Golden holds the results expected from the pipeline on Default.
+package testhunt
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ngrash/modhunt/internal/pkglists"
+)
+
+var (
+	//go:embed testdata/awesome-go-README.md
+	awesomeReadme []byte
+	//go:embed testdata/go-wiki-Projects.md
+	wikiProjects []byte
+	// Golden is the output expected from the pipeline on Default, one
+	// result per line.
+	//go:embed testdata/golden.txt
+	Golden string
+)
+
+const day = 24 * time.Hour
+
+// A Fixture describes the modules and repositories of an ecosystem.
+type Fixture struct {
+	Modules []Module
+	Repos   []Repo
+}
+
+// Module is a module known to the proxy and the index.
+type Module struct {
+	Path     string
+	Versions []Version // oldest first
+	Requires []string  // modules required by the latest version
+}
+
+// Version is a version of a module published Age before the Env started.
+type Version struct {
+	Version string
+	Age     time.Duration
+}
+
+// Repo is a GitHub repository, e.g. "testhunt/router".
+type Repo struct {
+	Name        string
+	Description string
+	Stars       int
+	Forks       int
+	Archived    bool
+	License     string // SPDX ID
+	Topics      []string
+	Pushed      time.Duration // age of the last push
+	Scorecard   float64       // 0 if Scorecard did not scan the repository
+}
+
+// Default returns the ecosystem of the curated lists in testdata: a
+// maintained router and the framework built on it, an archived logger,
+// a configuration loader with a second major version and a command line
+// library that was never tagged.
+func Default() *Fixture {
+	return &Fixture{
+		Modules: []Module{
+			{
+				Path: "github.com/testhunt/cli",
+				Versions: []Version{
+					{"v0.0.0-20250301120000-1a2b3c4d5e6f", 400 * day},
+					{"v0.0.0-20250901120000-2b3c4d5e6f70", 200 * day},
+				},
+				Requires: []string{"github.com/testhunt/config"},
+			},
+			{
+				Path: "github.com/testhunt/config",
+				Versions: []Version{
+					{"v1.0.0", 900 * day},
+					{"v1.1.0", 600 * day},
+				},
+			},
+			{
+				Path: "github.com/testhunt/config/v2",
+				Versions: []Version{
+					{"v2.0.0", 120 * day},
+					{"v2.1.0", 45 * day},
+				},
+			},
+			{
+				Path: "github.com/testhunt/logger",
+				Versions: []Version{
+					{"v0.9.0", 1800 * day},
+					{"v1.0.0", 1500 * day},
+				},
+			},
+			{
+				Path: "github.com/testhunt/router",
+				Versions: []Version{
+					{"v1.0.0", 700 * day},
+					{"v1.1.0", 500 * day},
+					{"v1.2.0", 300 * day},
+					{"v1.3.0", 150 * day},
+					{"v1.4.0", 20 * day},
+				},
+				Requires: []string{"github.com/testhunt/logger"},
+			},
+			{
+				Path: "github.com/testhunt/web",
+				Versions: []Version{
+					{"v1.0.0", 400 * day},
+					{"v1.0.1", 90 * day},
+				},
+				Requires: []string{"github.com/testhunt/logger", "github.com/testhunt/router"},
+			},
+		},
+		Repos: []Repo{
+			{Name: "testhunt/cli", Description: "Command line applications from declarative command trees", Stars: 40, Forks: 3, License: "MIT", Topics: []string{"cli"}, Pushed: 30 * day},
+			{Name: "testhunt/config", Description: "Configuration from files, environment variables and flags", Stars: 800, Forks: 60, License: "Apache-2.0", Topics: []string{"config", "env"}, Pushed: 10 * day, Scorecard: 6.2},
+			{Name: "testhunt/logger", Description: "Leveled, structured logging", Stars: 300, Forks: 25, Archived: true, License: "BSD-3-Clause", Topics: []string{"logging"}, Pushed: 1400 * day},
+			{Name: "testhunt/router", Description: "Fast HTTP request router", Stars: 2400, Forks: 210, License: "MIT", Topics: []string{"http", "router"}, Pushed: 5 * day, Scorecard: 7.4},
+			{Name: "testhunt/web", Description: "Web framework built on router", Stars: 150, Forks: 12, Topics: []string{"http", "web"}, Pushed: 60 * day},
+		},
+	}
+}
+
+// Lookup returns the curated lists in testdata.
+func Lookup() (*pkglists.Lookup, error) {
+	l := pkglists.NewLookup()
+	wiki, err := pkglists.ParseGoWikiProjects(bytes.NewReader(wikiProjects))
+	if err != nil {
+		return nil, fmt.Errorf("parse wiki: %w", err)
+	}
+	if err := l.AddSource(wiki); err != nil {
+		return nil, fmt.Errorf("add wiki source: %w", err)
+	}
+	awesome, err := pkglists.ParseAwesomeGoReadme(bytes.NewReader(awesomeReadme))
+	if err != nil {
+		return nil, fmt.Errorf("parse awesome: %w", err)
+	}
+	if err := l.AddSource(awesome); err != nil {
+		return nil, fmt.Errorf("add awesome source: %w", err)
+	}
+	return &l, nil
+}
+
+// Diff compares the output of a run with want, line by line regardless
+// of order. It returns the lines missing from got prefixed with "-" and
+// the unexpected ones prefixed with "+", or nil if they match.
+func Diff(want, got string) []string {
+	wantLines := strings.Split(strings.TrimSpace(want), "\n")
+	gotLines := strings.Split(strings.TrimSpace(got), "\n")
+	var diff []string
+	for _, l := range wantLines {
+		if !slices.Contains(gotLines, l) {
+			diff = append(diff, "-"+l)
+		}
+	}
+	for _, l := range gotLines {
+		if !slices.Contains(wantLines, l) {
+			diff = append(diff, "+"+l)
+		}
+	}
+	return diff
+}
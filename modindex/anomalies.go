@@ -0,0 +1,138 @@
+package modindex
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Anomalies in the release cadence of a path point at noise, like
+// automation tagging a release per commit, or at projects that were
+// abandoned, like a busy path that has released nothing for a long time.
+
+// Kinds of cadence anomalies.
+const (
+	AnomalyBurst  = "burst"  // many releases on one day
+	AnomalySilent = "silent" // a busy path stopped releasing
+)
+
+// An Anomaly is a change in the release cadence of a path.
+type Anomaly struct {
+	Path string
+	Kind string
+
+	// Day is the day of a burst or the time of the last release of a
+	// silent path.
+	Day time.Time
+
+	// Releases is the number of versions on the day of a burst or in the
+	// year before the last release of a silent path.
+	Releases int
+
+	// Total is the number of versions of the path.
+	Total int
+}
+
+// AnomalyOptions tune what counts as an anomaly.
+type AnomalyOptions struct {
+	// Burst is the number of versions on one day that are a burst.
+	// Bursts are looked for on the days since BurstSince.
+	Burst      int
+	BurstSince time.Time
+
+	// A path is busy if it has at least Busy versions in the year before
+	// its last release, and silent if it is busy and its last release
+	// was at least Silence before the newest version of the index.
+	Busy    int
+	Silence time.Duration
+
+	IncludeGenerated bool
+}
+
+// Anomalies returns the bursts by the number of versions on their day
+// followed by the silent paths by their number of versions in their
+// last year. Case variants are skipped, and generated paths unless
+// opts.IncludeGenerated is set.
+func (c *Client) Anomalies(ctx context.Context, opts AnomalyOptions) ([]Anomaly, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+
+	// Grouping the versions since BurstSince by day walks the timestamp
+	// index instead of all versions.
+	rows, err := db.QueryContext(ctx, `SELECT p.path, b.day, b.releases, COALESCE(m.releases, 0)
+		FROM (SELECT path_id, substr(timestamp, 1, 10) AS day, COUNT(*) AS releases
+			FROM versions WHERE timestamp >= ?
+			GROUP BY path_id, day HAVING COUNT(*) >= ?) AS b
+		JOIN paths AS p ON p.id = b.path_id
+		LEFT JOIN module_release_counts AS m ON m.path_id = b.path_id
+		WHERE (? OR p.class IS NULL) AND p.case_of IS NULL`,
+		opts.BurstSince.UTC().Format(time.RFC3339Nano), opts.Burst, opts.IncludeGenerated)
+	if err != nil {
+		return nil, fmt.Errorf("query bursts: %w", err)
+	}
+	var bursts []Anomaly
+	for rows.Next() {
+		a := Anomaly{Kind: AnomalyBurst}
+		var day string
+		if err := rows.Scan(&a.Path, &day, &a.Releases, &a.Total); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan burst: %w", err)
+		}
+		if a.Day, err = time.Parse(time.DateOnly, day); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("parse day of %s: %w", a.Path, err)
+		}
+		bursts = append(bursts, a)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("close bursts: %w", err)
+	}
+
+	last, err := lastVersionInfo(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var silent []Anomaly
+	if !last.Timestamp.IsZero() {
+		// The summaries narrow the paths down to those with enough
+		// versions before the silence; only their versions are counted.
+		rows, err := db.QueryContext(ctx, `SELECT p.path, m.latest, m.releases,
+				(SELECT COUNT(*) FROM versions AS v WHERE v.path_id = m.path_id AND v.timestamp >= strftime('%Y-%m-%dT%H:%M:%fZ', m.latest, '-1 year'))
+			FROM module_release_counts AS m JOIN paths AS p ON p.id = m.path_id
+			WHERE m.latest < ? AND m.releases >= ? AND (? OR p.class IS NULL) AND p.case_of IS NULL`,
+			last.Timestamp.Add(-opts.Silence).UTC().Format(time.RFC3339Nano), opts.Busy, opts.IncludeGenerated)
+		if err != nil {
+			return nil, fmt.Errorf("query silent paths: %w", err)
+		}
+		for rows.Next() {
+			a := Anomaly{Kind: AnomalySilent}
+			var latest string
+			if err := rows.Scan(&a.Path, &latest, &a.Total, &a.Releases); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("scan silent path: %w", err)
+			}
+			if a.Releases < opts.Busy {
+				continue
+			}
+			if a.Day, err = time.Parse(time.RFC3339Nano, latest); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("parse last release of %s: %w", a.Path, err)
+			}
+			silent = append(silent, a)
+		}
+		if err := rows.Close(); err != nil {
+			return nil, fmt.Errorf("close silent paths: %w", err)
+		}
+	}
+
+	for _, s := range [][]Anomaly{bursts, silent} {
+		slices.SortFunc(s, func(a, b Anomaly) int {
+			return cmp.Or(cmp.Compare(b.Releases, a.Releases), cmp.Compare(a.Path, b.Path), a.Day.Compare(b.Day))
+		})
+	}
+	return append(bursts, silent...), nil
+}
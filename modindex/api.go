@@ -0,0 +1,179 @@
+package modindex
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// PackageAPI summarizes the exported API of a package. Declarations are
+// counted once even if files for several platforms declare them.
+type PackageAPI struct {
+	ImportPath string
+	Name       string
+	Internal   bool // importable only from within the module
+	Files      int
+	Types      int
+	Funcs      int
+	Methods    int // exported methods of exported types
+	Consts     int
+	Vars       int
+}
+
+// Exported returns the number of exported declarations.
+func (p PackageAPI) Exported() int {
+	return p.Types + p.Funcs + p.Methods + p.Consts + p.Vars
+}
+
+// AnalyzeAPI parses the Go files of the module zip of path at version and
+// summarizes the API of its packages, ordered by import path. Test files,
+// testdata and vendored code are skipped. Files with syntax errors count
+// as far as they could be parsed.
+func AnalyzeAPI(path, version string, data []byte) ([]PackageAPI, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open zip: %w", err)
+	}
+	prefix := path + "@" + version + "/"
+	fset := token.NewFileSet()
+	pkgs := make(map[string]*PackageAPI)
+	seen := make(map[string]map[string]bool) // by import path
+	for _, f := range z.File {
+		name := strings.TrimPrefix(f.Name, prefix)
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || skipAPIDir(name) {
+			continue
+		}
+		src, err := readZipFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		file, _ := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+		if file == nil || file.Name == nil {
+			continue
+		}
+
+		importPath := path
+		if dir := pathDir(name); dir != "" {
+			importPath += "/" + dir
+		}
+		p, ok := pkgs[importPath]
+		if !ok {
+			p = &PackageAPI{ImportPath: importPath, Name: file.Name.Name, Internal: isInternal(importPath)}
+			pkgs[importPath] = p
+			seen[importPath] = make(map[string]bool)
+		}
+		p.Files++
+		countDecls(p, seen[importPath], file)
+	}
+
+	var apis []PackageAPI
+	for _, p := range pkgs {
+		apis = append(apis, *p)
+	}
+	slices.SortFunc(apis, func(a, b PackageAPI) int { return cmp.Compare(a.ImportPath, b.ImportPath) })
+	return apis, nil
+}
+
+// countDecls adds the exported declarations of file to p, skipping those
+// already in seen.
+func countDecls(p *PackageAPI, seen map[string]bool, file *ast.File) {
+	count := func(key string, n *int) {
+		if !seen[key] {
+			seen[key] = true
+			*n++
+		}
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv == nil {
+				count("func "+d.Name.Name, &p.Funcs)
+			} else if recv := receiverType(d.Recv.List[0].Type); ast.IsExported(recv) {
+				count("method "+recv+"."+d.Name.Name, &p.Methods)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						count("type "+s.Name.Name, &p.Types)
+					}
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						if !n.IsExported() {
+							continue
+						}
+						if d.Tok == token.CONST {
+							count("const "+n.Name, &p.Consts)
+						} else {
+							count("var "+n.Name, &p.Vars)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// receiverType returns the name of the base type of a method receiver.
+func receiverType(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// skipAPIDir reports whether the file is in a directory the go command
+// ignores or that holds vendored code.
+func skipAPIDir(name string) bool {
+	for _, dir := range strings.Split(path.Dir(name), "/") {
+		if dir == "testdata" || strings.HasPrefix(dir, "_") || strings.HasPrefix(dir, ".") && dir != "." || slices.Contains(vendorDirs, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func isInternal(importPath string) bool {
+	return strings.HasSuffix(importPath, "/internal") || strings.Contains(importPath, "/internal/")
+}
+
+// pathDir is path.Dir without the "." for files in the root.
+func pathDir(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,322 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Versions of past years rarely change and make up most of a full mirror.
+// Archive moves them out of the live database into one zstd-compressed
+// JSONL file of Records per year. Export reads the archives transparently.
+// Paths and coverage stay in the database, so sync does not fetch archived
+// versions again.
+
+// Archive describes the archive file of one year.
+type Archive struct {
+	Year     int
+	File     string
+	Versions int64
+}
+
+// archiveFile returns the name of the archive file for year.
+func (c *Client) archiveFile(year int) string {
+	return filepath.Join(c.archiveDir, "versions-"+strconv.Itoa(year)+".jsonl.zst")
+}
+
+// Archives returns the archived years in ascending order.
+func (c *Client) Archives(ctx context.Context) ([]Archive, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT year, versions FROM archives ORDER BY year")
+	if err != nil {
+		return nil, fmt.Errorf("query archives: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var archives []Archive
+	for rows.Next() {
+		var a Archive
+		if err := rows.Scan(&a.Year, &a.Versions); err != nil {
+			return nil, fmt.Errorf("scan archive: %w", err)
+		}
+		a.File = c.archiveFile(a.Year)
+		archives = append(archives, a)
+	}
+	return archives, rows.Err()
+}
+
+// ArchiveBefore moves all versions published before the start of year
+// into the archive files of their years and deletes them from the database.
+// Versions of years that were archived before are merged into the existing
+// files. It returns the archives written.
+func (c *Client) ArchiveBefore(ctx context.Context, year int) ([]Archive, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+
+	var oldest sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT MIN(timestamp) FROM versions").Scan(&oldest); err != nil {
+		return nil, fmt.Errorf("select oldest: %w", err)
+	}
+	if !oldest.Valid {
+		return nil, nil
+	}
+	first, err := time.Parse(time.RFC3339Nano, oldest.String)
+	if err != nil {
+		return nil, fmt.Errorf("parse oldest timestamp: %w", err)
+	}
+
+	if err := os.MkdirAll(c.archiveDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create archive directory: %w", err)
+	}
+
+	var written []Archive
+	for y := first.Year(); y < year; y++ {
+		a, err := c.archiveYear(ctx, db, y)
+		if err != nil {
+			return written, fmt.Errorf("archive %d: %w", y, err)
+		}
+		if a != nil {
+			written = append(written, *a)
+		}
+	}
+	if len(written) > 0 {
+		return written, c.refreshSummaries(ctx, time.Time{})
+	}
+	return written, nil
+}
+
+// archiveYear archives the versions of year that are still in the database.
+// It returns nil if there are none.
+func (c *Client) archiveYear(ctx context.Context, db *sql.DB, year int) (_ *Archive, err error) {
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339Nano)
+	to := time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339Nano)
+
+	var live int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM versions WHERE timestamp >= ? AND timestamp < ?", from, to).Scan(&live); err != nil {
+		return nil, fmt.Errorf("count versions: %w", err)
+	}
+	if live == 0 {
+		return nil, nil
+	}
+
+	name := c.archiveFile(year)
+	tmp := name + ".tmp"
+	a := &Archive{Year: year, File: name}
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, fmt.Errorf("create archive: %w", err)
+	}
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return nil, fmt.Errorf("create zstd writer: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = zw.Close()
+			_ = f.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+	enc := json.NewEncoder(zw)
+
+	// Merge the existing archive and the live versions, both
+	// ordered by timestamp, into the new file.
+	var old func() (Record, bool, error)
+	if prev, err := os.Open(name); err == nil {
+		defer prev.Close()
+		zr, err := zstd.NewReader(prev)
+		if err != nil {
+			return nil, fmt.Errorf("open existing archive: %w", err)
+		}
+		defer zr.Close()
+		old = recordReader(zr)
+	} else if errors.Is(err, os.ErrNotExist) {
+		old = func() (Record, bool, error) { return Record{}, false, nil }
+	} else {
+		return nil, fmt.Errorf("open existing archive: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT p.path, v.version, v.timestamp, v.incompatible, v.retracted
+		FROM versions AS v
+		JOIN paths AS p ON p.id = v.path_id
+		WHERE v.timestamp >= ? AND v.timestamp < ?
+		ORDER BY v.timestamp`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query versions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	next, ok, err := old()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		for ok && !next.Timestamp.After(r.Timestamp) {
+			// Skip versions archived by an earlier run that
+			// failed before deleting them from the database.
+			if next.Path != r.Path || next.Version != r.Version {
+				if err := enc.Encode(next); err != nil {
+					return nil, fmt.Errorf("write archive: %w", err)
+				}
+				a.Versions++
+			}
+			if next, ok, err = old(); err != nil {
+				return nil, err
+			}
+		}
+		if err := enc.Encode(r); err != nil {
+			return nil, fmt.Errorf("write archive: %w", err)
+		}
+		a.Versions++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate versions: %w", err)
+	}
+	for ok {
+		if err := enc.Encode(next); err != nil {
+			return nil, fmt.Errorf("write archive: %w", err)
+		}
+		a.Versions++
+		if next, ok, err = old(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close zstd writer: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("close archive: %w", err)
+	}
+
+	// Replace the file before committing the deletion: a failure in
+	// between leaves versions in both places rather than in neither.
+	// They are exported twice until the next run merges them.
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM versions WHERE timestamp >= ? AND timestamp < ?", from, to); err != nil {
+		return nil, fmt.Errorf("delete versions: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO archives (year, versions, archived_at) VALUES (?, ?, ?) ON CONFLICT (year) DO UPDATE SET versions = excluded.versions, archived_at = excluded.archived_at",
+		year, a.Versions, time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, fmt.Errorf("record archive: %w", err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		return nil, fmt.Errorf("replace archive: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return a, nil
+}
+
+// recordReader returns a function reading the next Record from r.
+// It reports false at the end of the stream.
+func recordReader(r io.Reader) func() (Record, bool, error) {
+	dec := json.NewDecoder(r)
+	return func() (Record, bool, error) {
+		if !dec.More() {
+			return Record{}, false, nil
+		}
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			return Record{}, false, fmt.Errorf("decode archive: %w", err)
+		}
+		return rec, true, nil
+	}
+}
+
+// exportArchives calls fn for all archived versions published at or after
+// since, in timestamp order. Versions of generated paths are skipped
+// unless includeGenerated is set.
+func (c *Client) exportArchives(ctx context.Context, db *sql.DB, since time.Time, includeGenerated bool, fn func(Record) error) error {
+	archives, err := c.Archives(ctx)
+	if err != nil {
+		return err
+	}
+
+	generated := make(map[string]bool)
+	if !includeGenerated && len(archives) > 0 {
+		rows, err := db.QueryContext(ctx, "SELECT path FROM paths WHERE class IS NOT NULL")
+		if err != nil {
+			return fmt.Errorf("query generated paths: %w", err)
+		}
+		for rows.Next() {
+			var p string
+			if err := rows.Scan(&p); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("scan generated path: %w", err)
+			}
+			generated[p] = true
+		}
+		if err := rows.Close(); err != nil {
+			return fmt.Errorf("close generated paths: %w", err)
+		}
+	}
+
+	for _, a := range archives {
+		if a.Year < since.Year() {
+			continue
+		}
+		if err := exportArchive(a.File, since, generated, fn); err != nil {
+			return fmt.Errorf("export archive %d: %w", a.Year, err)
+		}
+	}
+	return nil
+}
+
+func exportArchive(name string, since time.Time, skip map[string]bool, fn func(Record) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	next := recordReader(zr)
+	for {
+		r, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if r.Timestamp.Before(since) || skip[r.Path] {
+			continue
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package modindex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// The proxy serves a module under every spelling of its path that its
+// repository host resolves, e.g. github.com/Sirupsen/logrus as well as
+// github.com/sirupsen/logrus, so the index holds a path per spelling
+// somebody fetched. DetectCaseVariants links the spellings of a path to
+// the canonical one in paths.case_of, which statistics skip.
+
+// Reasons a path was chosen as the canonical spelling, stored in
+// paths.case_reason of its variants.
+const (
+	// CaseGoMod marks variants of the path the go.mod of its latest
+	// version declares, as served by the proxy.
+	CaseGoMod = "go.mod"
+
+	// CaseLatest marks variants of the most recently released path, if no
+	// go.mod declares one of them.
+	CaseLatest = "latest"
+)
+
+// DetectCaseVariants links the paths that differ only in case to their
+// canonical spelling, replacing the results of earlier runs. The canonical
+// path is the one the module directive of its go.mod, as recorded by
+// lookup-mods or deps sync, names exactly; if none does, it is the one
+// released most recently. It returns the number of families and of paths
+// linked.
+func (c *Client) DetectCaseVariants(ctx context.Context) (families, variants int, err error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	rows, err := tx.QueryContext(ctx, `SELECT p.id, p.path, COALESCE(p.gomod_module, g.module_path, ''),
+			COALESCE((SELECT MAX(timestamp) FROM versions WHERE path_id = p.id), '')
+		FROM paths AS p LEFT JOIN gomods AS g ON g.module = p.path
+		WHERE lower(p.path) IN (SELECT lower(path) FROM paths GROUP BY lower(path) HAVING COUNT(*) > 1)
+		ORDER BY lower(p.path), p.path`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("query case variants: %w", err)
+	}
+	type variant struct {
+		id                     int64
+		path, declared, latest string
+	}
+	var family [][]variant
+	for rows.Next() {
+		var v variant
+		if err := rows.Scan(&v.id, &v.path, &v.declared, &v.latest); err != nil {
+			_ = rows.Close()
+			return 0, 0, fmt.Errorf("scan case variant: %w", err)
+		}
+		if n := len(family); n > 0 && strings.EqualFold(family[n-1][0].path, v.path) {
+			family[n-1] = append(family[n-1], v)
+		} else {
+			family = append(family, []variant{v})
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, 0, fmt.Errorf("close case variants: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE paths SET case_of = NULL, case_reason = NULL WHERE case_of IS NOT NULL"); err != nil {
+		return 0, 0, fmt.Errorf("reset case variants: %w", err)
+	}
+	for _, vs := range family {
+		canonical, reason := vs[0], CaseLatest
+		for _, v := range vs {
+			if v.declared == v.path {
+				canonical, reason = v, CaseGoMod
+				break
+			}
+			if v.latest > canonical.latest {
+				canonical = v
+			}
+		}
+		for _, v := range vs {
+			if v.id == canonical.id {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE paths SET case_of = ?, case_reason = ? WHERE id = ?", canonical.id, reason, v.id); err != nil {
+				return 0, 0, fmt.Errorf("link case variant: %w", err)
+			}
+			variants++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return len(family), variants, nil
+}
+
+// A CaseFamily is a canonical path with the variants linked to it by
+// DetectCaseVariants.
+type CaseFamily struct {
+	Canonical string
+	Reason    string
+	Latest    Version // of the canonical path
+	Variants  []CaseVariant
+}
+
+// CaseVariant is a spelling of a canonical path.
+type CaseVariant struct {
+	Path     string
+	Declared string  // module path of its go.mod, empty if unknown
+	Latest   Version // zero if the path has no usable version
+}
+
+// CaseFamilies returns the families linked by DetectCaseVariants, ordered
+// by canonical path.
+func (c *Client) CaseFamilies(ctx context.Context) ([]CaseFamily, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT p.id, p.path, p.case_reason, p.case_of, cp.path, COALESCE(p.gomod_module, g.module_path, '')
+		FROM paths AS p JOIN paths AS cp ON cp.id = p.case_of LEFT JOIN gomods AS g ON g.module = p.path
+		ORDER BY cp.path, p.path`)
+	if err != nil {
+		return nil, fmt.Errorf("query case families: %w", err)
+	}
+	type row struct {
+		id, canonicalID int64
+		canonical       string
+		reason          string
+		v               CaseVariant
+	}
+	var found []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.v.Path, &r.reason, &r.canonicalID, &r.canonical, &r.v.Declared); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan case variant: %w", err)
+		}
+		found = append(found, r)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("close case variants: %w", err)
+	}
+
+	var families []CaseFamily
+	for _, r := range found {
+		if r.v.Latest, err = latestOf(ctx, db, r.id); err != nil {
+			return nil, err
+		}
+		if n := len(families); n > 0 && families[n-1].Canonical == r.canonical {
+			families[n-1].Variants = append(families[n-1].Variants, r.v)
+			continue
+		}
+		f := CaseFamily{Canonical: r.canonical, Reason: r.reason, Variants: []CaseVariant{r.v}}
+		if f.Latest, err = latestOf(ctx, db, r.canonicalID); err != nil {
+			return nil, err
+		}
+		families = append(families, f)
+	}
+	return families, nil
+}
@@ -0,0 +1,191 @@
+package modindex
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// A ClassRule tags module paths matching Pattern with Class. Tagged paths
+// are machine-generated or otherwise uninteresting and are excluded from
+// queries unless generated paths are requested explicitly.
+type ClassRule struct {
+	Class   string
+	Pattern *regexp.Regexp
+}
+
+// DefaultClassRules are the rules installed into new databases.
+var DefaultClassRules = []ClassRule{
+	{"generated", regexp.MustCompile(`^buf\.build/gen/`)},
+	{"generated", regexp.MustCompile(`^go\.buf\.build/`)},
+	{"mirror", regexp.MustCompile(`^github\.com/[^/]+/[^/]+-mirror(/|$)`)},
+	{"fanout", regexp.MustCompile(`^github\.com/aws/aws-sdk-go-v2/service/`)},
+	{"fanout", regexp.MustCompile(`^github\.com/Azure/azure-sdk-for-go/sdk/resourcemanager/`)},
+	{"fanout", regexp.MustCompile(`^cloud\.google\.com/go/.`)},
+}
+
+// Classify returns the class of the first rule matching path
+// or the empty string if no rule matches.
+func Classify(rules []ClassRule, path string) string {
+	for _, r := range rules {
+		if r.Pattern.MatchString(path) {
+			return r.Class
+		}
+	}
+	return ""
+}
+
+// classOf is Classify for storage in the nullable class column.
+func classOf(rules []ClassRule, path string) sql.NullString {
+	class := Classify(rules, path)
+	return sql.NullString{String: class, Valid: class != ""}
+}
+
+// ParseClassRules reads rules in the format of 'modhunt index classify':
+// one rule per line, the class followed by a regular expression, separated
+// by white space. Empty lines and lines starting with # are ignored.
+func ParseClassRules(r io.Reader) ([]ClassRule, error) {
+	var rules []ClassRule
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		class, pattern, ok := strings.Cut(text, " ")
+		if !ok {
+			class, pattern, ok = strings.Cut(text, "\t")
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d: want class and pattern", line)
+		}
+		re, err := regexp.Compile(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, ClassRule{Class: class, Pattern: re})
+	}
+	return rules, s.Err()
+}
+
+// querier is implemented by *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// classRules returns the rules stored in the database in order.
+func classRules(ctx context.Context, q querier) ([]ClassRule, error) {
+	rows, err := q.QueryContext(ctx, "SELECT class, pattern FROM class_rules ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("query class rules: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var rules []ClassRule
+	for rows.Next() {
+		var class, pattern string
+		if err := rows.Scan(&class, &pattern); err != nil {
+			return nil, fmt.Errorf("scan class rule: %w", err)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile class rule %q: %w", pattern, err)
+		}
+		rules = append(rules, ClassRule{Class: class, Pattern: re})
+	}
+	return rules, rows.Err()
+}
+
+// ClassRules returns the rules stored in the index database.
+func (c *Client) ClassRules(ctx context.Context) ([]ClassRule, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	return classRules(ctx, db)
+}
+
+// Reclassify tags all paths in the index database. If rules is not nil,
+// it replaces the stored rules first, otherwise the stored rules are used.
+// It returns the number of paths whose class changed.
+func (c *Client) Reclassify(ctx context.Context, rules []ClassRule) (int, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	if rules != nil {
+		if err := storeClassRules(ctx, tx, rules); err != nil {
+			return 0, err
+		}
+	} else if rules, err = classRules(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	changed, err := classifyPaths(ctx, tx, rules)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return changed, nil
+}
+
+func storeClassRules(ctx context.Context, tx *sql.Tx, rules []ClassRule) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM class_rules"); err != nil {
+		return fmt.Errorf("clear class rules: %w", err)
+	}
+	for _, r := range rules {
+		_, err := tx.ExecContext(ctx, "INSERT INTO class_rules (class, pattern) VALUES (?, ?)", r.Class, r.Pattern.String())
+		if err != nil {
+			return fmt.Errorf("insert class rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// classifyPaths updates the class of every path and returns
+// the number of paths whose class changed.
+func classifyPaths(ctx context.Context, tx *sql.Tx, rules []ClassRule) (int, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, path, COALESCE(class, '') FROM paths")
+	if err != nil {
+		return 0, fmt.Errorf("query paths: %w", err)
+	}
+	type change struct {
+		id    int64
+		class sql.NullString
+	}
+	var changes []change
+	for rows.Next() {
+		var id int64
+		var path, old string
+		if err := rows.Scan(&id, &path, &old); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("scan path: %w", err)
+		}
+		if class := classOf(rules, path); class.String != old {
+			changes = append(changes, change{id, class})
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("close paths: %w", err)
+	}
+
+	for _, c := range changes {
+		if _, err := tx.ExecContext(ctx, "UPDATE paths SET class = ? WHERE id = ?", c.class, c.id); err != nil {
+			return 0, fmt.Errorf("update path: %w", err)
+		}
+	}
+	return len(changes), nil
+}
@@ -0,0 +1,260 @@
+// Package modindex mirrors the Go module index into a local SQLite database
+// and answers questions about the module paths and versions it contains.
+//
+// A mirror is opened with Open and kept up-to-date with Client.Sync or
+// Client.Follow:
+//
+//	c, err := modindex.Open(ctx, modindex.WithDBPath("index.db"), modindex.WithMigrate())
+//	if err != nil {
+//		// ...
+//	}
+//	defer c.Close()
+//	if _, err := c.Sync(ctx, modindex.SyncOptions{}); err != nil {
+//		// ...
+//	}
+//	versions, err := c.Versions(ctx, "golang.org/x/mod")
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ngrash/modhunt/modindex/index"
+)
+
+// Defaults used by Open.
+const (
+	DefaultDBPath    = "index.db"
+	DefaultIndexURL  = "https://index.golang.org/index"
+	DefaultBatchSize = 2000
+
+	// DefaultBusyTimeout is how long a connection waits for the locks
+	// of others, e.g. while a sync stores a batch.
+	DefaultBusyTimeout = 5 * time.Second
+)
+
+// ErrNoDatabase is returned by queries on a Client that synchronizes
+// into Postgres and has no SQLite database.
+var ErrNoDatabase = errors.New("queries require the SQLite database")
+
+// Client is a local mirror of the module index.
+// It is safe for concurrent use.
+type Client struct {
+	db          *sql.DB // nil with WithPostgres
+	dbPath      string
+	busyTimeout time.Duration
+	store       Store
+	index       *index.Client
+	batchSize   int
+	log         io.Writer
+	archiveDir  string
+}
+
+type config struct {
+	dbPath      string
+	postgresDSN string
+	indexURL    string
+	batchSize   int
+	httpClient  *http.Client
+	indexOpts   []index.Option
+	migrate     bool
+	readOnly    bool
+	busyTimeout time.Duration
+	log         io.Writer
+	archiveDir  string
+}
+
+// An Option configures a Client.
+type Option func(*config)
+
+// WithDBPath sets the path of the SQLite database. The default is DefaultDBPath.
+func WithDBPath(path string) Option {
+	return func(c *config) { c.dbPath = path }
+}
+
+// WithPostgres synchronizes into the Postgres database at dsn, a postgres://
+// or postgresql:// URL, instead of SQLite. Only Sync, Heal and Follow are
+// available on such a client; queries return ErrNoDatabase.
+func WithPostgres(dsn string) Option {
+	return func(c *config) { c.postgresDSN = dsn }
+}
+
+// WithIndexURL sets the URL of the module index. The default is DefaultIndexURL.
+func WithIndexURL(rawurl string) Option {
+	return func(c *config) { c.indexURL = rawurl }
+}
+
+// WithBatchSize sets the number of versions requested from the index at once.
+// The default is DefaultBatchSize, which is also the maximum of index.golang.org.
+func WithBatchSize(n int) Option {
+	return func(c *config) { c.batchSize = n }
+}
+
+// WithHTTPClient sets the HTTP client used to talk to the index.
+// The default is http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *config) { c.httpClient = hc }
+}
+
+// WithIndexOptions configures the index client,
+// e.g. its retries with index.WithRetries.
+func WithIndexOptions(opts ...index.Option) Option {
+	return func(c *config) { c.indexOpts = append(c.indexOpts, opts...) }
+}
+
+// WithMigrate applies pending schema migrations on Open. Without it, Open
+// fails with an error wrapping ErrNeedsMigration if the schema is outdated.
+func WithMigrate() Option {
+	return func(c *config) { c.migrate = true }
+}
+
+// WithReadOnly opens the SQLite database read-only, e.g. for queries
+// while another process synchronizes it. The database must exist and be
+// at the latest schema version; methods storing data fail.
+func WithReadOnly() Option {
+	return func(c *config) { c.readOnly = true }
+}
+
+// WithBusyTimeout sets how long a connection waits for the locks of
+// others before failing with an error IsBusy recognizes. The default is
+// DefaultBusyTimeout.
+func WithBusyTimeout(d time.Duration) Option {
+	return func(c *config) { c.busyTimeout = d }
+}
+
+// WithLog sets where Follow and Sync report their status.
+// By default, nothing is reported.
+func WithLog(w io.Writer) Option {
+	return func(c *config) { c.log = w }
+}
+
+// WithArchiveDir sets the directory of the yearly archives written by
+// Client.ArchiveBefore. The default is the directory "archive" next to
+// the database.
+func WithArchiveDir(dir string) Option {
+	return func(c *config) { c.archiveDir = dir }
+}
+
+// Open opens the mirror configured by opts.
+func Open(ctx context.Context, opts ...Option) (*Client, error) {
+	cfg := config{
+		dbPath:      DefaultDBPath,
+		indexURL:    DefaultIndexURL,
+		batchSize:   DefaultBatchSize,
+		httpClient:  http.DefaultClient,
+		busyTimeout: DefaultBusyTimeout,
+		log:         io.Discard,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", cfg.batchSize)
+	}
+	if cfg.readOnly && cfg.migrate {
+		return nil, errors.New("cannot migrate a read-only database")
+	}
+
+	ic, err := index.New(cfg.indexURL, cfg.httpClient, append([]index.Option{index.WithPageSize(cfg.batchSize)}, cfg.indexOpts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("new index client: %w", err)
+	}
+	c := &Client{
+		dbPath:      cfg.dbPath,
+		busyTimeout: cfg.busyTimeout,
+		index:       ic,
+		batchSize:   cfg.batchSize,
+		log:         cfg.log,
+		archiveDir:  cfg.archiveDir,
+	}
+	if c.archiveDir == "" {
+		c.archiveDir = filepath.Join(filepath.Dir(cfg.dbPath), "archive")
+	}
+
+	if cfg.postgresDSN != "" {
+		if !strings.HasPrefix(cfg.postgresDSN, "postgres://") && !strings.HasPrefix(cfg.postgresDSN, "postgresql://") {
+			return nil, fmt.Errorf("unsupported DSN %q", cfg.postgresDSN)
+		}
+		if c.store, err = openPostgresStore(ctx, cfg.postgresDSN); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	if cfg.readOnly {
+		// Read-only connections can't create the database.
+		if _, err := os.Stat(cfg.dbPath); errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("open database: %s does not exist (run 'modhunt index sync')", cfg.dbPath)
+		} else if err != nil {
+			return nil, fmt.Errorf("open database: %w", err)
+		}
+	}
+	if c.db, err = sql.Open("sqlite", sqliteDSN(cfg.dbPath, cfg.busyTimeout, cfg.readOnly)); err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if cfg.migrate {
+		_, err = Migrate(ctx, c.db)
+		if err != nil {
+			err = fmt.Errorf("migrate database: %w", err)
+		}
+	} else {
+		err = checkSchema(ctx, c.db)
+	}
+	if err != nil {
+		_ = c.db.Close()
+		return nil, err
+	}
+	c.store = &sqliteStore{db: c.db}
+	return c, nil
+}
+
+// openSQLite opens the SQLite database at path without checking or
+// migrating its schema. Connections wait for the locks of others, e.g.
+// a query of a worker while another stores its results, instead of
+// failing with SQLITE_BUSY right away.
+func openSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", sqliteDSN(path, DefaultBusyTimeout, false))
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return db, nil
+}
+
+// sqliteDSN returns the data source name of the SQLite database at path.
+// Read-only connections are refused writes by both SQLite and the file
+// system.
+func sqliteDSN(path string, busyTimeout time.Duration, readOnly bool) string {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(%d)&_time_format=sqlite", path, busyTimeout.Milliseconds())
+	if readOnly {
+		dsn += "&mode=ro&_pragma=query_only(1)"
+	}
+	return dsn
+}
+
+// DB returns the SQLite database of the mirror or nil if the
+// client synchronizes into Postgres.
+func (c *Client) DB() *sql.DB {
+	return c.db
+}
+
+// sqlite returns the SQLite database or ErrNoDatabase.
+func (c *Client) sqlite() (*sql.DB, error) {
+	if c.db == nil {
+		return nil, ErrNoDatabase
+	}
+	return c.db, nil
+}
+
+// Close closes the underlying database.
+func (c *Client) Close() error {
+	return c.store.Close()
+}
@@ -0,0 +1,130 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// The coverage table records which time ranges of the index have been
+// fetched completely. Ranges are kept merged, so every gap between two
+// rows is a window that was never synchronized.
+
+// Coverage returns the synchronized time ranges in ascending order.
+func Coverage(ctx context.Context, db *sql.DB) ([]Gap, error) {
+	rows, err := db.QueryContext(ctx, "SELECT range_start, range_end FROM coverage ORDER BY range_start")
+	if err != nil {
+		return nil, fmt.Errorf("query coverage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ranges []Gap
+	for rows.Next() {
+		var start, end string
+		if err := rows.Scan(&start, &end); err != nil {
+			return nil, fmt.Errorf("scan coverage: %w", err)
+		}
+		var r Gap
+		if r.From, err = time.Parse(time.RFC3339Nano, start); err != nil {
+			return nil, fmt.Errorf("parse coverage start: %w", err)
+		}
+		if r.To, err = time.Parse(time.RFC3339Nano, end); err != nil {
+			return nil, fmt.Errorf("parse coverage end: %w", err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, rows.Err()
+}
+
+// addCoverage marks [from, to] as synchronized and merges it
+// with overlapping or adjacent ranges.
+func addCoverage(ctx context.Context, db *sql.DB, from, to time.Time) error {
+	ranges, err := Coverage(ctx, db)
+	if err != nil {
+		return err
+	}
+	merged, err := mergeCoverage(ranges, from, to)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM coverage"); err != nil {
+		return fmt.Errorf("clear coverage: %w", err)
+	}
+	for _, r := range merged {
+		_, err := tx.ExecContext(ctx, "INSERT INTO coverage (range_start, range_end) VALUES (?, ?)",
+			r.From.Format(time.RFC3339Nano), r.To.Format(time.RFC3339Nano))
+		if err != nil {
+			return fmt.Errorf("insert coverage: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// mergeCoverage adds [from, to] to ranges and merges
+// overlapping or adjacent ranges.
+func mergeCoverage(ranges []Gap, from, to time.Time) ([]Gap, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("invalid coverage range %s - %s", from, to)
+	}
+	ranges = append(ranges, Gap{From: from, To: to})
+	slices.SortFunc(ranges, func(a, b Gap) int { return a.From.Compare(b.From) })
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if !r.From.After(last.To) {
+			if r.To.After(last.To) {
+				last.To = r.To
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged, nil
+}
+
+// coverageHoles returns the windows between synchronized ranges.
+func coverageHoles(ranges []Gap) []Gap {
+	var holes []Gap
+	for i := 1; i < len(ranges); i++ {
+		holes = append(holes, Gap{From: ranges[i-1].To, To: ranges[i].From})
+	}
+	return holes
+}
+
+// Heal re-fetches all windows between synchronized ranges, e.g. after
+// an interrupted sync or an import, and returns the holes it filled.
+func (c *Client) Heal(ctx context.Context) (_ []Gap, err error) {
+	ranges, err := c.store.Coverage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	holes := coverageHoles(ranges)
+	if len(holes) == 0 {
+		return nil, nil
+	}
+	// The summaries are brought up-to-date with what was filled, also if
+	// filling failed halfway.
+	defer func() {
+		err = errors.Join(err, c.refreshSummaries(context.WithoutCancel(ctx), holes[0].From))
+	}()
+	for _, h := range holes {
+		if _, err := c.fillWindow(ctx, c.store, h.From, h.To); err != nil {
+			return nil, fmt.Errorf("fill %s: %w", h, err)
+		}
+	}
+	return holes, nil
+}
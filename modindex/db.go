@@ -0,0 +1,259 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/ngrash/modhunt/modindex/index"
+)
+
+// SyncOptions configure Client.Sync.
+type SyncOptions struct {
+	// Heal re-fetches windows missed by earlier, interrupted runs
+	// before catching up. See Client.Heal.
+	Heal bool
+
+	// Progress, if set, is called before every batch.
+	Progress func(SyncProgress)
+}
+
+// SyncProgress describes the state of a running Sync.
+type SyncProgress struct {
+	Start   time.Time     // when catching up started
+	Current time.Time     // timestamp of the most recent version stored
+	Covered time.Duration // index time covered since Start
+}
+
+// SyncResult summarizes a Sync.
+type SyncResult struct {
+	Inserted int
+	Healed   []Gap
+}
+
+// Sync fetches all versions newer than the most recent version in the store.
+// It fails with ErrSyncInProgress if another process synchronizes the
+// SQLite database.
+func (c *Client) Sync(ctx context.Context, opts SyncOptions) (SyncResult, error) {
+	var res SyncResult
+	unlock, err := c.lockSync()
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+	if opts.Heal {
+		healed, err := c.Heal(ctx)
+		if err != nil {
+			return res, fmt.Errorf("heal: %w", err)
+		}
+		res.Healed = healed
+	}
+	n, err := c.catchUp(ctx, opts.Progress)
+	res.Inserted = n
+	return res, err
+}
+
+// catchUp fetches and inserts versions from the index until the store
+// is up-to-date. It returns the number of versions inserted. If progress
+// is not nil, it is called before every batch.
+func (c *Client) catchUp(ctx context.Context, progress func(SyncProgress)) (_ int, err error) {
+	last, err := c.store.LastVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+	observeLastTimestamp(last.Timestamp)
+
+	start := time.Now()
+	covered := time.Duration(0)
+	inserted := 0
+
+	// The summaries are brought up-to-date with what was inserted, also
+	// if catching up failed halfway.
+	since := last.Timestamp
+	defer func() {
+		if inserted > 0 {
+			err = errors.Join(err, c.refreshSummaries(context.WithoutCancel(ctx), since))
+		}
+	}()
+
+	batch := make([]*index.VersionInfo, 0, c.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		batchesFetched.Inc()
+
+		insertStart := time.Now()
+		n, err := c.store.InsertVersions(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("insert batch: %w", err)
+		}
+		insertDuration.Observe(time.Since(insertStart).Seconds())
+		versionsIngested.Add(float64(n))
+		inserted += n
+
+		// Everything between the last version we had and the last
+		// version of this batch is now in the database.
+		coveredFrom := last.Timestamp
+		if coveredFrom.IsZero() {
+			coveredFrom = batch[0].Timestamp
+		}
+		end := batch[len(batch)-1]
+		if err := c.store.AddCoverage(ctx, coveredFrom, end.Timestamp); err != nil {
+			return fmt.Errorf("add coverage: %w", err)
+		}
+		err = c.store.RecordBatch(ctx, SyncBatch{
+			RunStart:   start,
+			IngestedAt: time.Now(),
+			Versions:   n,
+			Oldest:     batch[0].Timestamp,
+			Newest:     end.Timestamp,
+		})
+		if err != nil {
+			return fmt.Errorf("record batch: %w", err)
+		}
+
+		// If this was the first batch, 'last' is zero and the time
+		// covered is the time between the first and last version in
+		// the batch. Otherwise it grows by the time between the last
+		// version of the previous batch and the last version of this one.
+		covered += end.Timestamp.Sub(coveredFrom)
+		last = *end
+		observeLastTimestamp(last.Timestamp)
+		batch = batch[:0]
+
+		if progress != nil {
+			progress(SyncProgress{Start: start, Current: last.Timestamp, Covered: covered})
+		}
+		return nil
+	}
+
+	if progress != nil {
+		progress(SyncProgress{Start: start, Current: last.Timestamp, Covered: covered})
+	}
+
+	// The stream starts at the timestamp of the last version we have,
+	// so it repeats that version first. It is skipped here; anything
+	// else already stored is ignored by InsertVersions.
+	for v, err := range c.index.Stream(ctx, last.Timestamp) {
+		if err != nil {
+			httpErrors.Inc()
+			return inserted, errors.Join(fmt.Errorf("get versions: %w", err), flush())
+		}
+		if v.Path == last.Path && v.Version == last.Version && v.Timestamp.Equal(last.Timestamp) {
+			continue
+		}
+		batch = append(batch, v)
+		if len(batch) == c.batchSize {
+			if err := flush(); err != nil {
+				return inserted, err
+			}
+		}
+	}
+	return inserted, flush()
+}
+
+// insertVersions inserts versions and their paths. Versions that are already
+// in the database are skipped. It returns the number of versions inserted.
+func insertVersions(ctx context.Context, db *sql.DB, versions []*index.VersionInfo) (int, error) {
+	// The transactions primary purpose is to speed up the inserts
+	// as it allows the database to batch them together on commit.
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	rules, err := classRules(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	inserted := 0
+	for _, v := range versions {
+		timestamp := v.Timestamp.Format(time.RFC3339Nano)
+
+		row := tx.QueryRowContext(ctx, "SELECT id FROM paths WHERE path = ?", v.Path)
+		var pathID int64
+		err = row.Scan(&pathID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Insert a new path.
+			res, err := tx.ExecContext(ctx, "INSERT INTO paths (path, first_seen, last_seen, class) VALUES (?, ?, ?, ?)", v.Path, timestamp, timestamp, classOf(rules, v.Path))
+			if err != nil {
+				return 0, fmt.Errorf("insert path: %w", err)
+			}
+			pathID, err = res.LastInsertId()
+			if err != nil {
+				return 0, fmt.Errorf("last insert id: %w", err)
+			}
+		} else if err != nil {
+			return 0, fmt.Errorf("select path: %w", err)
+		} else {
+			// Versions usually arrive in order, but keep the columns
+			// correct if they don't (e.g. when filling gaps).
+			_, err := tx.ExecContext(ctx, "UPDATE paths SET first_seen = MIN(COALESCE(first_seen, ?), ?), last_seen = MAX(COALESCE(last_seen, ?), ?) WHERE id = ?", timestamp, timestamp, timestamp, timestamp, pathID)
+			if err != nil {
+				return 0, fmt.Errorf("update path: %w", err)
+			}
+		}
+
+		incompatible := strings.HasSuffix(v.Version, "+incompatible")
+		res, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO versions (path_id, version, timestamp, incompatible) VALUES (?, ?, ?, ?)", pathID, v.Version, timestamp, incompatible)
+		if err != nil {
+			return 0, fmt.Errorf("insert version: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("rows affected: %w", err)
+		}
+		inserted += int(n)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return inserted, nil
+}
+
+// lastVersionInfo returns the most recent version or the zero value if
+// there is none. Timestamps are stored as text, where the full second
+// sorts after its fractions, e.g. "10:00:00Z" after "10:00:00.5Z", so the
+// versions of the second that sorts last are compared as times.
+func lastVersionInfo(ctx context.Context, db *sql.DB) (index.VersionInfo, error) {
+	var last index.VersionInfo
+	var newest string
+	err := db.QueryRowContext(ctx, "SELECT timestamp FROM versions ORDER BY timestamp DESC LIMIT 1").Scan(&newest)
+	if errors.Is(err, sql.ErrNoRows) {
+		return last, nil
+	}
+	if err != nil {
+		return last, fmt.Errorf("scan max row: %w", err)
+	}
+	second := newest[:min(len(newest), len("2006-01-02T15:04:05"))]
+	rows, err := db.QueryContext(ctx, "SELECT p.path, v.version, v.timestamp FROM versions AS v JOIN paths AS p ON p.id = v.path_id WHERE v.timestamp >= ? AND v.timestamp <= ?",
+		second, second+"Z")
+	if err != nil {
+		return last, fmt.Errorf("query last second: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var v index.VersionInfo
+		var timestamp string
+		if err := rows.Scan(&v.Path, &v.Version, &timestamp); err != nil {
+			return last, fmt.Errorf("scan last second: %w", err)
+		}
+		if v.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp); err != nil {
+			return last, fmt.Errorf("parse timestamp: %w", err)
+		}
+		if v.Timestamp.After(last.Timestamp) {
+			last = v
+		}
+	}
+	return last, rows.Err()
+}
@@ -0,0 +1,386 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/mod/modfile"
+)
+
+// The dependency graph is built from the go.mod files of one version per
+// module, usually the latest. gomods records which version was read and
+// requires holds its require directives, so that both directions of the
+// graph can be queried. Like releases, it is keyed by module path.
+
+// GoMod is the information modhunt extracts from a go.mod file.
+type GoMod struct {
+	Module     string // as declared by the module directive
+	Deprecated string // message of a "// Deprecated:" comment on the module directive
+	GoVersion  string // minimum Go version of the go directive, e.g. "1.21"
+	Toolchain  string // toolchain directive, e.g. "go1.22.3"
+	Requires   []Dependency
+}
+
+// Dependency is a require directive.
+type Dependency struct {
+	Path     string
+	Version  string
+	Indirect bool
+}
+
+// ParseGoMod parses the go.mod file of path at version.
+func ParseGoMod(path, version string, data []byte) (*GoMod, error) {
+	f, err := modfile.ParseLax(path+"@"+version+"/go.mod", data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parse go.mod: %w", err)
+	}
+	var gm GoMod
+	if f.Module != nil {
+		gm.Module = f.Module.Mod.Path
+		gm.Deprecated = f.Module.Deprecated
+	}
+	if f.Go != nil {
+		gm.GoVersion = f.Go.Version
+	}
+	if f.Toolchain != nil {
+		gm.Toolchain = f.Toolchain.Name
+	}
+	for _, r := range f.Require {
+		gm.Requires = append(gm.Requires, Dependency{Path: r.Mod.Path, Version: r.Mod.Version, Indirect: r.Indirect})
+	}
+	return &gm, nil
+}
+
+// StoreGoMod replaces the stored go.mod information of module with gm,
+// read from the go.mod file of version.
+func (c *Client) StoreGoMod(ctx context.Context, module, version string, gm *GoMod) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO gomods (module, version, module_path, deprecated, go_version, toolchain, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (module) DO UPDATE SET
+			version = excluded.version,
+			module_path = excluded.module_path,
+			deprecated = excluded.deprecated,
+			go_version = excluded.go_version,
+			toolchain = excluded.toolchain,
+			fetched_at = excluded.fetched_at`,
+		module, version, gm.Module, nullString(gm.Deprecated), nullString(gm.GoVersion), nullString(gm.Toolchain),
+		time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("upsert go.mod: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM requires WHERE module = ?", module); err != nil {
+		return fmt.Errorf("delete requires: %w", err)
+	}
+	// A go.mod may require a module twice, the go command uses the higher version.
+	requires := make(map[string]Dependency)
+	for _, d := range gm.Requires {
+		if prev, ok := requires[d.Path]; !ok || CompareVersions(d.Version, prev.Version) > 0 {
+			requires[d.Path] = d
+		}
+	}
+	for _, d := range requires {
+		_, err := tx.ExecContext(ctx, "INSERT INTO requires (module, dep, dep_version, indirect) VALUES (?, ?, ?, ?)",
+			module, d.Path, d.Version, d.Indirect)
+		if err != nil {
+			return fmt.Errorf("insert require: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GoModVersion returns the version whose go.mod was stored for module
+// or the empty string if none was.
+func (c *Client) GoModVersion(ctx context.Context, module string) (string, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return "", err
+	}
+	var version string
+	err = db.QueryRowContext(ctx, "SELECT version FROM gomods WHERE module = ?", module).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("select go.mod version: %w", err)
+	}
+	return version, nil
+}
+
+// Dependencies returns the modules required by module, ordered by path.
+func (c *Client) Dependencies(ctx context.Context, module string) ([]Dependency, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT dep, dep_version, indirect FROM requires WHERE module = ? ORDER BY dep", module)
+	if err != nil {
+		return nil, fmt.Errorf("query requires: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var deps []Dependency
+	for rows.Next() {
+		var d Dependency
+		if err := rows.Scan(&d.Path, &d.Version, &d.Indirect); err != nil {
+			return nil, fmt.Errorf("scan require: %w", err)
+		}
+		deps = append(deps, d)
+	}
+	return deps, rows.Err()
+}
+
+// Dependent is a module requiring another one.
+type Dependent struct {
+	Module   string
+	Version  string // version of the dependent whose go.mod was read
+	Requires string // required version of the dependency
+	Indirect bool
+}
+
+// Dependents returns the modules requiring dep, ordered by path.
+func (c *Client) Dependents(ctx context.Context, dep string) ([]Dependent, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT r.module, g.version, r.dep_version, r.indirect
+		FROM requires AS r
+		JOIN gomods AS g ON g.module = r.module
+		WHERE r.dep = ?
+		ORDER BY r.module`, dep)
+	if err != nil {
+		return nil, fmt.Errorf("query dependents: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var dependents []Dependent
+	for rows.Next() {
+		var d Dependent
+		if err := rows.Scan(&d.Module, &d.Version, &d.Requires, &d.Indirect); err != nil {
+			return nil, fmt.Errorf("scan dependent: %w", err)
+		}
+		dependents = append(dependents, d)
+	}
+	return dependents, rows.Err()
+}
+
+// DepCount is the number of modules related to Module.
+type DepCount struct {
+	Module   string
+	Direct   int
+	Indirect int
+}
+
+// MostRequired returns the n modules required by the most stored
+// go.mod files, directly or indirectly.
+func (c *Client) MostRequired(ctx context.Context, n int) ([]DepCount, error) {
+	return c.depCounts(ctx, "dep", n)
+}
+
+// MostRequiring returns the n modules with the most requirements,
+// a measure of dependency bloat.
+func (c *Client) MostRequiring(ctx context.Context, n int) ([]DepCount, error) {
+	return c.depCounts(ctx, "module", n)
+}
+
+func (c *Client) depCounts(ctx context.Context, column string, n int) ([]DepCount, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT `+column+`, SUM(NOT indirect), SUM(indirect)
+		FROM requires
+		GROUP BY `+column+`
+		ORDER BY COUNT(*) DESC, `+column+`
+		LIMIT ?`, n)
+	if err != nil {
+		return nil, fmt.Errorf("query dependency counts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var counts []DepCount
+	for rows.Next() {
+		var dc DepCount
+		if err := rows.Scan(&dc.Module, &dc.Direct, &dc.Indirect); err != nil {
+			return nil, fmt.Errorf("scan dependency count: %w", err)
+		}
+		counts = append(counts, dc)
+	}
+	return counts, rows.Err()
+}
+
+// Paths returns all module paths in the index, ordered by path.
+// Generated paths are skipped unless includeGenerated is set.
+func (c *Client) Paths(ctx context.Context, includeGenerated bool) ([]string, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT path FROM paths WHERE ? OR class IS NULL ORDER BY path", includeGenerated)
+	if err != nil {
+		return nil, fmt.Errorf("query paths: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("scan path: %w", err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// ImportedBy returns the number of stored go.mod files requiring each
+// module, directly or indirectly.
+func (c *Client) ImportedBy(ctx context.Context) (map[string]int, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT dep, COUNT(*) FROM requires GROUP BY dep")
+	if err != nil {
+		return nil, fmt.Errorf("query dependents: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var dep string
+		var n int
+		if err := rows.Scan(&dep, &n); err != nil {
+			return nil, fmt.Errorf("scan dependents: %w", err)
+		}
+		counts[dep] = n
+	}
+	return counts, rows.Err()
+}
+
+// Deprecations returns the deprecation messages of all modules whose
+// stored go.mod declares them deprecated.
+func (c *Client) Deprecations(ctx context.Context) (map[string]string, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT module, deprecated FROM gomods WHERE deprecated IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("query deprecations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	deprecated := make(map[string]string)
+	for rows.Next() {
+		var module, msg string
+		if err := rows.Scan(&module, &msg); err != nil {
+			return nil, fmt.Errorf("scan deprecation: %w", err)
+		}
+		deprecated[module] = msg
+	}
+	return deprecated, rows.Err()
+}
+
+// GoDirectives is the go and toolchain directives of a stored go.mod.
+type GoDirectives struct {
+	Module    string
+	GoVersion string // empty if the go.mod has no go directive
+	Toolchain string
+}
+
+// GoDirectives returns the go and toolchain directives of all stored
+// go.mod files, ordered by module.
+func (c *Client) GoDirectives(ctx context.Context) ([]GoDirectives, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT module, COALESCE(go_version, ''), COALESCE(toolchain, '') FROM gomods ORDER BY module")
+	if err != nil {
+		return nil, fmt.Errorf("query go directives: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var directives []GoDirectives
+	for rows.Next() {
+		var d GoDirectives
+		if err := rows.Scan(&d.Module, &d.GoVersion, &d.Toolchain); err != nil {
+			return nil, fmt.Errorf("scan go directives: %w", err)
+		}
+		directives = append(directives, d)
+	}
+	return directives, rows.Err()
+}
+
+// nullString stores the empty string as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// Overlap is how many modules require both a module and Module.
+type Overlap struct {
+	Module     string
+	Shared     int // modules requiring both
+	Dependents int // modules requiring Module
+}
+
+// Jaccard returns the share of the modules requiring either module that
+// require both, given the number of modules requiring the other one.
+func (o Overlap) Jaccard(dependents int) float64 {
+	union := dependents + o.Dependents - o.Shared
+	if union == 0 {
+		return 0
+	}
+	return float64(o.Shared) / float64(union)
+}
+
+// DependentOverlap returns the number of modules directly requiring
+// module and the other modules they directly require, with most shared
+// dependents first.
+func (c *Client) DependentOverlap(ctx context.Context, module string) (int, []Overlap, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return 0, nil, err
+	}
+	var dependents int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM requires WHERE dep = ? AND NOT indirect", module).Scan(&dependents); err != nil {
+		return 0, nil, fmt.Errorf("count dependents: %w", err)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT o.dep, COUNT(*), (SELECT COUNT(*) FROM requires WHERE dep = o.dep AND NOT indirect)
+		FROM requires AS r
+		JOIN requires AS o ON o.module = r.module AND o.dep != r.dep AND NOT o.indirect
+		WHERE r.dep = ? AND NOT r.indirect
+		GROUP BY o.dep
+		ORDER BY COUNT(*) DESC, o.dep`, module)
+	if err != nil {
+		return 0, nil, fmt.Errorf("query dependent overlap: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var overlaps []Overlap
+	for rows.Next() {
+		var o Overlap
+		if err := rows.Scan(&o.Module, &o.Shared, &o.Dependents); err != nil {
+			return 0, nil, fmt.Errorf("scan dependent overlap: %w", err)
+		}
+		overlaps = append(overlaps, o)
+	}
+	return dependents, overlaps, rows.Err()
+}
@@ -0,0 +1,78 @@
+package modindex
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// download-info records the curated packages it could not find a module
+// for in download_failures, keyed by package, with the category and the
+// reason of the failure. Only permanent failures are recorded; packages
+// found later are removed.
+
+// DownloadFailure is a curated package no module was found for.
+type DownloadFailure struct {
+	Package  string
+	Path     string // the module path searched for
+	Category string // e.g. "not found" or "gone"
+	Reason   string
+	FailedAt time.Time
+}
+
+// StoreDownloadResults records the failures and removes the packages that
+// were found from the recorded failures.
+func (c *Client) StoreDownloadResults(ctx context.Context, failures []DownloadFailure, found []string) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, f := range failures {
+		_, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO download_failures (package, path, category, reason, failed_at) VALUES (?, ?, ?, ?, ?)",
+			f.Package, f.Path, f.Category, f.Reason, f.FailedAt.UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return fmt.Errorf("insert download failure: %w", err)
+		}
+	}
+	for _, p := range found {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM download_failures WHERE package = ?", p); err != nil {
+			return fmt.Errorf("delete download failure: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DownloadFailures returns the recorded failures ordered by package.
+func (c *Client) DownloadFailures(ctx context.Context) ([]DownloadFailure, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT package, path, category, reason, failed_at FROM download_failures ORDER BY package")
+	if err != nil {
+		return nil, fmt.Errorf("query download failures: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var failures []DownloadFailure
+	for rows.Next() {
+		var f DownloadFailure
+		var failed string
+		if err := rows.Scan(&f.Package, &f.Path, &f.Category, &f.Reason, &failed); err != nil {
+			return nil, fmt.Errorf("scan download failure: %w", err)
+		}
+		if f.FailedAt, err = time.Parse(time.RFC3339Nano, failed); err != nil {
+			return nil, fmt.Errorf("parse failure of %s: %w", f.Package, err)
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
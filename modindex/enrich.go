@@ -0,0 +1,151 @@
+package modindex
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 'modhunt enrich' runs the stages that collect data on curated packages
+// one after another. When each stage last started and finished, or why it
+// failed, is kept in enrich_stages keyed by stage, so that a stopped run
+// resumes with the stage that did not finish. The health scores computed
+// by its last stage are kept in module_scores.
+
+// EnrichStage is the state of a stage of the enrichment pipeline.
+type EnrichStage struct {
+	Stage      string
+	StartedAt  time.Time
+	FinishedAt time.Time // zero unless the last run completed
+	Err        string    // error of the last run, empty unless it failed
+}
+
+// StartEnrichStage records that stage started at, forgetting how its last
+// run ended.
+func (c *Client) StartEnrichStage(ctx context.Context, stage string, at time.Time) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "INSERT OR REPLACE INTO enrich_stages (stage, started_at) VALUES (?, ?)",
+		stage, at.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("start enrich stage: %w", err)
+	}
+	return nil
+}
+
+// FinishEnrichStage records that stage ended at, successfully if stageErr
+// is nil.
+func (c *Client) FinishEnrichStage(ctx context.Context, stage string, at time.Time, stageErr error) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	var finished, reason string
+	if stageErr == nil {
+		finished = at.UTC().Format(time.RFC3339Nano)
+	} else {
+		reason = stageErr.Error()
+	}
+	_, err = db.ExecContext(ctx, "UPDATE enrich_stages SET finished_at = ?, error = ? WHERE stage = ?", nullString(finished), nullString(reason), stage)
+	if err != nil {
+		return fmt.Errorf("finish enrich stage: %w", err)
+	}
+	return nil
+}
+
+// EnrichStages returns the states of the stages that ever started, by
+// stage.
+func (c *Client) EnrichStages(ctx context.Context) (map[string]EnrichStage, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT stage, started_at, COALESCE(finished_at, ''), COALESCE(error, '') FROM enrich_stages")
+	if err != nil {
+		return nil, fmt.Errorf("query enrich stages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	stages := make(map[string]EnrichStage)
+	for rows.Next() {
+		var s EnrichStage
+		var started, finished string
+		if err := rows.Scan(&s.Stage, &started, &finished, &s.Err); err != nil {
+			return nil, fmt.Errorf("scan enrich stage: %w", err)
+		}
+		if s.StartedAt, err = time.Parse(time.RFC3339Nano, started); err != nil {
+			return nil, fmt.Errorf("parse start of %s: %w", s.Stage, err)
+		}
+		if finished != "" {
+			if s.FinishedAt, err = time.Parse(time.RFC3339Nano, finished); err != nil {
+				return nil, fmt.Errorf("parse end of %s: %w", s.Stage, err)
+			}
+		}
+		stages[s.Stage] = s
+	}
+	return stages, rows.Err()
+}
+
+// ModuleScore is the health score of a curated module.
+type ModuleScore struct {
+	Module   string
+	Score    int    // from 0 to 100
+	Cap      string // why the score was capped, e.g. "archived", or empty
+	ScoredAt time.Time
+}
+
+// StoreModuleScores replaces all stored scores with scores.
+func (c *Client) StoreModuleScores(ctx context.Context, scores []ModuleScore) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM module_scores"); err != nil {
+		return fmt.Errorf("delete module scores: %w", err)
+	}
+	for _, s := range scores {
+		_, err := tx.ExecContext(ctx, "INSERT INTO module_scores (module, score, cap, scored_at) VALUES (?, ?, ?, ?)",
+			s.Module, s.Score, nullString(s.Cap), s.ScoredAt.UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return fmt.Errorf("insert module score: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ModuleScores returns the stored scores, the highest first.
+func (c *Client) ModuleScores(ctx context.Context) ([]ModuleScore, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT module, score, COALESCE(cap, ''), scored_at FROM module_scores ORDER BY score DESC, module")
+	if err != nil {
+		return nil, fmt.Errorf("query module scores: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var scores []ModuleScore
+	for rows.Next() {
+		var s ModuleScore
+		var scored string
+		if err := rows.Scan(&s.Module, &s.Score, &s.Cap, &scored); err != nil {
+			return nil, fmt.Errorf("scan module score: %w", err)
+		}
+		if s.ScoredAt, err = time.Parse(time.RFC3339Nano, scored); err != nil {
+			return nil, fmt.Errorf("parse score of %s: %w", s.Module, err)
+		}
+		scores = append(scores, s)
+	}
+	return scores, rows.Err()
+}
@@ -0,0 +1,81 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Record is a flattened version row as produced by Export.
+// The struct tags define the JSON and Parquet column names.
+type Record struct {
+	Path         string    `json:"path" parquet:"path,dict"`
+	Version      string    `json:"version" parquet:"version"`
+	Timestamp    time.Time `json:"timestamp" parquet:"timestamp,timestamp(nanosecond)"`
+	Incompatible bool      `json:"incompatible" parquet:"incompatible"`
+	Retracted    bool      `json:"retracted" parquet:"retracted"`
+}
+
+// Export calls fn for every version published at or after since,
+// in timestamp order. Versions of generated paths are skipped unless
+// includeGenerated is set. Archived versions are read from their archive
+// files before the live rows. Rows are streamed from the database, so fn
+// should not block for long.
+func (c *Client) Export(ctx context.Context, since time.Time, includeGenerated bool, fn func(Record) error) (err error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+
+	if err := c.exportArchives(ctx, db, since, includeGenerated, fn); err != nil {
+		return err
+	}
+
+	var sinceArg string
+	if !since.IsZero() {
+		sinceArg = since.UTC().Format(time.RFC3339Nano)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT p.path, v.version, v.timestamp, v.incompatible, v.retracted
+		FROM versions AS v
+		JOIN paths AS p ON p.id = v.path_id
+		WHERE v.timestamp >= ? AND (? OR p.class IS NULL)
+		ORDER BY v.timestamp`, sinceArg, includeGenerated)
+	if err != nil {
+		return fmt.Errorf("query versions: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// scanRecord scans a row of path, version, timestamp, incompatible
+// and retracted.
+func scanRecord(rows *sql.Rows) (Record, error) {
+	var r Record
+	var timestamp string
+	if err := rows.Scan(&r.Path, &r.Version, &timestamp, &r.Incompatible, &r.Retracted); err != nil {
+		return Record{}, fmt.Errorf("scan version: %w", err)
+	}
+	var err error
+	r.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return Record{}, fmt.Errorf("parse timestamp of %s@%s: %w", r.Path, r.Version, err)
+	}
+	return r, nil
+}
@@ -0,0 +1,58 @@
+package modindex
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff boundaries used by Follow after a failed synchronization.
+const (
+	minBackoff = 5 * time.Second
+	maxBackoff = 10 * time.Minute
+)
+
+// Follow catches up with the module index and then keeps polling it
+// every interval, appending new versions to the store until ctx is done.
+// Failed polls are retried with an exponential, jittered backoff.
+// The outcome of every poll is reported to the writer set with WithLog.
+// Like Sync, it fails with ErrSyncInProgress if another process
+// synchronizes the SQLite database.
+func (c *Client) Follow(ctx context.Context, interval time.Duration) error {
+	unlock, err := c.lockSync()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	backoff := time.Duration(0)
+	for {
+		inserted, err := c.catchUp(ctx, nil)
+		wait := interval
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil // stopped while syncing
+			}
+			backoff = nextBackoff(backoff)
+			wait = backoff
+			_, _ = fmt.Fprintf(c.log, "%s | Sync failed, retrying in %s: %v\n", time.Now().Format(time.RFC3339), wait.Round(time.Second), err)
+		} else {
+			backoff = 0
+			_, _ = fmt.Fprintf(c.log, "%s | Added %d versions\n", time.Now().Format(time.RFC3339), inserted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// nextBackoff doubles the previous backoff within [minBackoff, maxBackoff]
+// and adds up to 50% of random jitter so that several followers
+// don't hit the index in lockstep.
+func nextBackoff(prev time.Duration) time.Duration {
+	next := min(max(2*prev, minBackoff), maxBackoff)
+	return next + rand.N(next/2)
+}
@@ -0,0 +1,208 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// Fork reasons stored in paths.fork_reason.
+const (
+	// ForkGoMod marks paths whose go.mod declares the module path of the upstream.
+	ForkGoMod = "go.mod"
+
+	// ForkName marks paths on the same host whose repository name
+	// matches the upstream's, e.g. github.com/someone/logrus.
+	ForkName = "name"
+)
+
+// RepoName returns the last element of a module path without its major
+// version suffix in lower case, e.g. "logrus" for github.com/Sirupsen/logrus/v2.
+func RepoName(path string) string {
+	prefix, _, ok := module.SplitPathVersion(path)
+	if !ok {
+		prefix = path
+	}
+	return strings.ToLower(prefix[strings.LastIndex(prefix, "/")+1:])
+}
+
+// host returns the first element of a module path.
+func host(path string) string {
+	h, _, _ := strings.Cut(path, "/")
+	return h
+}
+
+// DetectForks marks paths that are probable forks of one of the upstream
+// module paths, replacing the results of earlier runs. Upstreams that are
+// not in the database are ignored. A path is a fork if the module directive
+// of its go.mod, as recorded by SetGoModModule, names an upstream, or if it
+// shares host and repository name with exactly one upstream. It returns the
+// number of paths marked.
+func (c *Client) DetectForks(ctx context.Context, upstreams []string) (int, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	// Curated lists don't always spell paths in the right case,
+	// so upstreams are matched case-insensitively.
+	wanted := make(map[string]bool)
+	for _, p := range upstreams {
+		wanted[strings.ToLower(p)] = true
+	}
+
+	type upstream struct {
+		id   int64
+		path string
+	}
+	byPath := make(map[string]upstream)
+	byName := make(map[string][]upstream)
+	rows, err := tx.QueryContext(ctx, "SELECT id, path FROM paths")
+	if err != nil {
+		return 0, fmt.Errorf("query upstreams: %w", err)
+	}
+	for rows.Next() {
+		var u upstream
+		if err := rows.Scan(&u.id, &u.path); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("scan upstream: %w", err)
+		}
+		if !wanted[strings.ToLower(u.path)] {
+			continue
+		}
+		byPath[strings.ToLower(u.path)] = u
+		key := host(u.path) + "/" + RepoName(u.path)
+		byName[key] = append(byName[key], u)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("close upstreams: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE paths SET fork_of = NULL, fork_reason = NULL WHERE fork_of IS NOT NULL"); err != nil {
+		return 0, fmt.Errorf("reset forks: %w", err)
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT id, path, COALESCE(gomod_module, '') FROM paths")
+	if err != nil {
+		return 0, fmt.Errorf("query paths: %w", err)
+	}
+	type fork struct {
+		id, upstreamID int64
+		reason         string
+	}
+	var forks []fork
+	for rows.Next() {
+		var id int64
+		var path, gomod string
+		if err := rows.Scan(&id, &path, &gomod); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("scan path: %w", err)
+		}
+		if u, ok := byPath[strings.ToLower(gomod)]; ok && !isSameModule(path, u.path) {
+			forks = append(forks, fork{id, u.id, ForkGoMod})
+			continue
+		}
+		if us := byName[host(path)+"/"+RepoName(path)]; len(us) == 1 && !isSameModule(path, us[0].path) {
+			forks = append(forks, fork{id, us[0].id, ForkName})
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("close paths: %w", err)
+	}
+
+	for _, f := range forks {
+		if _, err := tx.ExecContext(ctx, "UPDATE paths SET fork_of = ?, fork_reason = ? WHERE id = ?", f.upstreamID, f.reason, f.id); err != nil {
+			return 0, fmt.Errorf("mark fork: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return len(forks), nil
+}
+
+// isSameModule reports whether path is upstream itself, another major
+// version of it or one of its nested modules.
+func isSameModule(path, upstream string) bool {
+	prefix, _, _ := module.SplitPathVersion(path)
+	upstreamPrefix, _, _ := module.SplitPathVersion(upstream)
+	return strings.EqualFold(prefix, upstreamPrefix) ||
+		strings.HasPrefix(strings.ToLower(path), strings.ToLower(upstreamPrefix)+"/")
+}
+
+// Fork is a path marked by DetectForks with its latest version.
+type Fork struct {
+	Path   string
+	Reason string
+	Latest Version // zero if the path has no usable version
+}
+
+// Forks returns the latest version of upstream and the paths marked as its
+// forks, most recently released first.
+func (c *Client) Forks(ctx context.Context, upstream string) (Version, []Fork, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return Version{}, nil, err
+	}
+
+	upstreamID, err := lookupPathID(ctx, db, upstream)
+	if err != nil {
+		return Version{}, nil, err
+	}
+	latest, err := latestOf(ctx, db, upstreamID)
+	if err != nil {
+		return Version{}, nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, path, fork_reason FROM paths WHERE fork_of = ?", upstreamID)
+	if err != nil {
+		return Version{}, nil, fmt.Errorf("query forks: %w", err)
+	}
+	type row struct {
+		id int64
+		f  Fork
+	}
+	var found []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.f.Path, &r.f.Reason); err != nil {
+			_ = rows.Close()
+			return Version{}, nil, fmt.Errorf("scan fork: %w", err)
+		}
+		found = append(found, r)
+	}
+	if err := rows.Close(); err != nil {
+		return Version{}, nil, fmt.Errorf("close forks: %w", err)
+	}
+
+	forks := make([]Fork, 0, len(found))
+	for _, r := range found {
+		if r.f.Latest, err = latestOf(ctx, db, r.id); err != nil {
+			return Version{}, nil, err
+		}
+		forks = append(forks, r.f)
+	}
+	slices.SortFunc(forks, func(a, b Fork) int {
+		return strings.Compare(b.Latest.Timestamp, a.Latest.Timestamp)
+	})
+	return latest, forks, nil
+}
+
+// SetGoModModule records the module path declared in the go.mod file
+// of the latest version of the path with the given ID.
+func SetGoModModule(ctx context.Context, db *sql.DB, pathID int64, modulePath string) error {
+	if _, err := db.ExecContext(ctx, "UPDATE paths SET gomod_module = ? WHERE id = ?", modulePath, pathID); err != nil {
+		return fmt.Errorf("update go.mod module: %w", err)
+	}
+	return nil
+}
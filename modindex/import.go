@@ -0,0 +1,152 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ngrash/modhunt/modindex/index"
+)
+
+// importBatchSize is the number of versions inserted per transaction
+// during Import. Larger batches are faster but hold the write lock longer.
+const importBatchSize = 50000
+
+// Import bulk-loads a stream of JSON-encoded version infos, as served by
+// index.golang.org or written by Export, into the database. Versions that
+// already exist are skipped. After every batch, progress is called with
+// the number of versions read so far. Import returns the number of
+// versions inserted.
+func (c *Client) Import(ctx context.Context, r io.Reader, progress func(read int)) (inserted int, err error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return 0, err
+	}
+
+	// Pragmas are per connection, so import on a dedicated one.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get connection: %w", err)
+	}
+	defer func() {
+		// Restore the default before the connection goes back to the pool.
+		_, resetErr := conn.ExecContext(context.WithoutCancel(ctx), "PRAGMA synchronous = FULL;")
+		err = errors.Join(err, resetErr, conn.Close())
+	}()
+	if _, err := conn.ExecContext(ctx, "PRAGMA synchronous = OFF;"); err != nil {
+		return 0, fmt.Errorf("disable synchronous writes: %w", err)
+	}
+	// Imported versions may be of any time, so the summaries are rebuilt.
+	defer func() {
+		if inserted > 0 {
+			err = errors.Join(err, c.refreshSummaries(context.WithoutCancel(ctx), time.Time{}))
+		}
+	}()
+
+	rules, err := classRules(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	pathIDs := make(map[string]int64)
+	dec := json.NewDecoder(r)
+	read := 0
+	for {
+		batch := make([]*index.VersionInfo, 0, importBatchSize)
+		for len(batch) < importBatchSize && dec.More() {
+			var v index.VersionInfo
+			if err := dec.Decode(&v); err != nil {
+				return inserted, fmt.Errorf("decode version %d: %w", read+1, err)
+			}
+			batch = append(batch, &v)
+			read++
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		n, err := importBatch(ctx, conn, rules, pathIDs, batch)
+		inserted += n
+		if err != nil {
+			return inserted, err
+		}
+		if progress != nil {
+			progress(read)
+		}
+	}
+
+	return inserted, nil
+}
+
+func importBatch(ctx context.Context, conn *sql.Conn, rules []ClassRule, pathIDs map[string]int64, batch []*index.VersionInfo) (int, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	selectPath, err := tx.PrepareContext(ctx, "SELECT id FROM paths WHERE path = ?")
+	if err != nil {
+		return 0, fmt.Errorf("prepare select path: %w", err)
+	}
+	insertPath, err := tx.PrepareContext(ctx, "INSERT INTO paths (path, first_seen, last_seen, class) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("prepare insert path: %w", err)
+	}
+	updatePath, err := tx.PrepareContext(ctx, "UPDATE paths SET first_seen = MIN(COALESCE(first_seen, ?), ?), last_seen = MAX(COALESCE(last_seen, ?), ?) WHERE id = ?")
+	if err != nil {
+		return 0, fmt.Errorf("prepare update path: %w", err)
+	}
+	insertVersion, err := tx.PrepareContext(ctx, "INSERT OR IGNORE INTO versions (path_id, version, timestamp, incompatible) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("prepare insert version: %w", err)
+	}
+
+	inserted := 0
+	for _, v := range batch {
+		timestamp := v.Timestamp.Format(time.RFC3339Nano)
+
+		pathID, ok := pathIDs[v.Path]
+		if !ok {
+			err := selectPath.QueryRowContext(ctx, v.Path).Scan(&pathID)
+			if errors.Is(err, sql.ErrNoRows) {
+				res, err := insertPath.ExecContext(ctx, v.Path, timestamp, timestamp, classOf(rules, v.Path))
+				if err != nil {
+					return 0, fmt.Errorf("insert path: %w", err)
+				}
+				if pathID, err = res.LastInsertId(); err != nil {
+					return 0, fmt.Errorf("last insert id: %w", err)
+				}
+			} else if err != nil {
+				return 0, fmt.Errorf("select path: %w", err)
+			}
+			pathIDs[v.Path] = pathID
+		}
+
+		incompatible := strings.HasSuffix(v.Version, "+incompatible")
+		res, err := insertVersion.ExecContext(ctx, pathID, v.Version, timestamp, incompatible)
+		if err != nil {
+			return 0, fmt.Errorf("insert version: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return 0, fmt.Errorf("rows affected: %w", err)
+		} else if n == 0 {
+			continue // already known
+		}
+		inserted++
+
+		if _, err := updatePath.ExecContext(ctx, timestamp, timestamp, timestamp, timestamp, pathID); err != nil {
+			return 0, fmt.Errorf("update path: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return inserted, nil
+}
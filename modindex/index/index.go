@@ -0,0 +1,294 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package index provides a client for communicating with the module index.
+//
+// It started as a copy of the client used by pkgsite and adds retries
+// with exponential backoff, so that long-running synchronizations
+// survive transient failures of the index or the network.
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Client is used to communicate with the module index.
+type Client struct {
+	// URL of the module index
+	url string
+
+	// client used for HTTP requests.
+	httpClient *http.Client
+
+	header     http.Header
+	httpHosts  []string
+	pageSize   int
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	timeout    time.Duration
+}
+
+// Defaults used by New.
+const (
+	DefaultPageSize   = 2000
+	DefaultRetries    = 5
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+	DefaultTimeout    = time.Minute
+)
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithHeader adds a header to every request, e.g. for authentication
+// with a private index.
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.header.Add(key, value) }
+}
+
+// WithAllowHTTP allows plain http:// URLs for the given hosts in addition
+// to loopback addresses, e.g. for an index on a trusted internal network.
+func WithAllowHTTP(hosts ...string) Option {
+	return func(c *Client) { c.httpHosts = append(c.httpHosts, hosts...) }
+}
+
+// WithPageSize sets the number of versions Stream requests at once.
+// The default is DefaultPageSize, which is also the maximum of index.golang.org.
+func WithPageSize(n int) Option {
+	return func(c *Client) { c.pageSize = n }
+}
+
+// WithRetries sets how often a failed request is retried.
+// Zero disables retries. The default is DefaultRetries.
+func WithRetries(n int) Option {
+	return func(c *Client) { c.retries = n }
+}
+
+// WithBackoff sets the wait before the first retry, which doubles with
+// every further retry up to max. The defaults are DefaultMinBackoff and
+// DefaultMaxBackoff.
+func WithBackoff(min, max time.Duration) Option {
+	return func(c *Client) { c.minBackoff, c.maxBackoff = min, max }
+}
+
+// WithTimeout limits the duration of a single request, including reading
+// the response. Zero disables the limit. The default is DefaultTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// New constructs a *Client using the provided rawurl, which is expected to
+// be an absolute URI that can be directly passed to http.Get. The scheme
+// must be https, except for loopback hosts like a local test server and
+// hosts allowed with WithAllowHTTP.
+func New(rawurl string, httpClient *http.Client, opts ...Option) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("url.Parse(%q): %v", rawurl, err)
+	}
+	c := &Client{
+		url:        strings.TrimRight(rawurl, "/"),
+		httpClient: httpClient,
+		header:     make(http.Header),
+		pageSize:   DefaultPageSize,
+		retries:    DefaultRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+		timeout:    DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && c.allowHTTP(u.Hostname()):
+	case u.Scheme == "http":
+		return nil, fmt.Errorf("scheme must be https for non-loopback host %s (see WithAllowHTTP)", u.Hostname())
+	default:
+		return nil, fmt.Errorf("scheme must be https (got %s)", u.Scheme)
+	}
+	if c.pageSize <= 0 {
+		return nil, fmt.Errorf("invalid page size %d", c.pageSize)
+	}
+	return c, nil
+}
+
+func (c *Client) allowHTTP(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, h := range c.httpHosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) pollURL(since time.Time, limit int) string {
+	values := url.Values{}
+	values.Set("since", since.Format(time.RFC3339Nano))
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+	return fmt.Sprintf("%s?%s", c.url, values.Encode())
+}
+
+// VersionInfo holds the version information returned by the module index.
+type VersionInfo struct {
+	Path      string
+	Version   string
+	Timestamp time.Time
+}
+
+func (v *VersionInfo) DebugString() string {
+	return fmt.Sprintf("%s@%s@%s", v.Path, v.Version, v.Timestamp.Format(time.RFC3339Nano))
+}
+
+// GetVersions queries the index for new versions. Requests failing with
+// a network error, a 5xx status or 429 Too Many Requests are retried.
+func (c *Client) GetVersions(ctx context.Context, since time.Time, limit int) ([]*VersionInfo, error) {
+	u := c.pollURL(since, limit)
+
+	backoff := time.Duration(0)
+	for attempt := 0; ; attempt++ {
+		versions, retryAfter, err := c.getVersions(ctx, u)
+		if err == nil {
+			return versions, nil
+		}
+		if attempt >= c.retries || !isRetryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		backoff = min(max(2*backoff, c.minBackoff), c.maxBackoff)
+		wait := max(backoff, retryAfter)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// ErrPageFull is returned by Stream if a full page of versions shares a
+// single timestamp, which makes it impossible to page any further.
+var ErrPageFull = errors.New("page full of versions with the same timestamp")
+
+// Stream returns an iterator over all versions published at or after since,
+// in the order of the index. Pages are fetched as needed. The index includes
+// versions published at the timestamp a page starts at, which were already
+// part of the previous page; Stream yields them only once. Iteration stops
+// after the first error.
+func (c *Client) Stream(ctx context.Context, since time.Time) iter.Seq2[*VersionInfo, error] {
+	return func(yield func(*VersionInfo, error) bool) {
+		// seen holds the versions already yielded with timestamp since.
+		seen := make(map[string]bool)
+		for {
+			page, err := c.GetVersions(ctx, since, c.pageSize)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			fresh := 0
+			for _, v := range page {
+				key := v.Path + "@" + v.Version
+				if !v.Timestamp.Equal(since) {
+					since = v.Timestamp
+					clear(seen)
+				} else if seen[key] {
+					continue
+				}
+				seen[key] = true
+				fresh++
+				if !yield(v, nil) {
+					return
+				}
+			}
+
+			if fresh == 0 {
+				if len(page) >= c.pageSize {
+					yield(nil, fmt.Errorf("%w: %s", ErrPageFull, since.Format(time.RFC3339Nano)))
+				}
+				return
+			}
+		}
+	}
+}
+
+// getVersions performs a single request. For 429 responses it returns
+// the wait requested by the Retry-After header.
+func (c *Client) getVersions(ctx context.Context, u string) (_ []*VersionInfo, retryAfter time.Duration, _ error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("http.NewRequest(%q, %q, nil): %v", http.MethodGet, u, err)
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	r, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, &temporaryError{fmt.Errorf("ctxhttp.Get(ctx, nil, %q): %w", u, err)}
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		err := fmt.Errorf("GET %q: %s", u, r.Status)
+		if r.StatusCode >= 500 || r.StatusCode == http.StatusTooManyRequests {
+			if secs, convErr := strconv.Atoi(r.Header.Get("Retry-After")); convErr == nil {
+				retryAfter = time.Duration(secs) * time.Second
+			}
+			return nil, retryAfter, &temporaryError{err}
+		}
+		return nil, 0, err
+	}
+
+	var versions []*VersionInfo
+	dec := json.NewDecoder(r.Body)
+
+	// The module index returns a stream of JSON objects formatted with newline
+	// as the delimiter.
+	for dec.More() {
+		var l VersionInfo
+		if err := dec.Decode(&l); err != nil {
+			// Most likely the connection was reset while reading.
+			return nil, 0, &temporaryError{fmt.Errorf("decoding JSON: %w", err)}
+		}
+		versions = append(versions, &l)
+	}
+	return versions, 0, nil
+}
+
+// temporaryError marks errors worth retrying.
+type temporaryError struct {
+	err error
+}
+
+func (e *temporaryError) Error() string { return e.err.Error() }
+func (e *temporaryError) Unwrap() error { return e.err }
+
+func isRetryable(err error) bool {
+	_, ok := err.(*temporaryError)
+	return ok
+}
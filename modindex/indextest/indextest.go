@@ -0,0 +1,150 @@
+// Package indextest provides an in-memory fake of the module index for
+// tests that must not depend on the network.
+//
+// The fake follows the semantics of index.golang.org: versions are served
+// in timestamp order as newline-delimited JSON, the since parameter is
+// inclusive and limit defaults to and is capped at 2000.
+package indextest
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ngrash/modhunt/modindex/index"
+)
+
+// MaxLimit is the largest number of versions served per request.
+const MaxLimit = 2000
+
+// Server is a fake module index. Its zero value is not usable,
+// create it with NewServer or NewHandler.
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// NewServer starts a fake index on a loopback address serving versions.
+// Point clients at IndexURL, which is accepted by index.New despite being
+// plain HTTP. The server must be closed after use.
+func NewServer(versions ...index.VersionInfo) *Server {
+	h := NewHandler(versions...)
+	return &Server{Server: httptest.NewServer(h), Handler: h}
+}
+
+// IndexURL returns the URL of the index endpoint.
+func (s *Server) IndexURL() string {
+	return s.Server.URL + "/index"
+}
+
+// Handler serves the /index endpoint of a fake module index.
+// It is safe for concurrent use.
+type Handler struct {
+	mu       sync.Mutex
+	versions []index.VersionInfo // sorted by timestamp
+	requests int
+	failures []int // status codes of the next responses
+}
+
+// NewHandler returns a handler serving versions.
+func NewHandler(versions ...index.VersionInfo) *Handler {
+	h := &Handler{}
+	h.Add(versions...)
+	return h
+}
+
+// Add publishes versions. They may be given in any order.
+func (h *Handler) Add(versions ...index.VersionInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.versions = append(h.versions, versions...)
+	slices.SortStableFunc(h.versions, func(a, b index.VersionInfo) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+}
+
+// Fail makes the next len(statuses) requests fail with the given
+// status codes, in order, e.g. to exercise retries.
+func (h *Handler) Fail(statuses ...int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures = append(h.failures, statuses...)
+}
+
+// Requests returns the number of requests served so far, including failures.
+func (h *Handler) Requests() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/index" {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.mu.Lock()
+	h.requests++
+	if len(h.failures) > 0 {
+		status := h.failures[0]
+		h.failures = h.failures[1:]
+		h.mu.Unlock()
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			h.mu.Unlock()
+			http.Error(w, fmt.Sprintf("invalid since: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := MaxLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			h.mu.Unlock()
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(cmp.Or(n, MaxLimit), MaxLimit)
+	}
+
+	i, _ := slices.BinarySearchFunc(h.versions, since, func(v index.VersionInfo, t time.Time) int {
+		return v.Timestamp.Compare(t)
+	})
+	page := slices.Clone(h.versions[i:min(i+limit, len(h.versions))])
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	for _, v := range page {
+		if err := enc.Encode(v); err != nil {
+			return
+		}
+	}
+}
+
+// Generate returns n versions of distinct paths below example.com,
+// published every step starting at start.
+func Generate(n int, start time.Time, step time.Duration) []index.VersionInfo {
+	versions := make([]index.VersionInfo, n)
+	for i := range versions {
+		versions[i] = index.VersionInfo{
+			Path:      fmt.Sprintf("example.com/mod%d", i),
+			Version:   "v1.0.0",
+			Timestamp: start.Add(time.Duration(i) * step).UTC(),
+		}
+	}
+	return versions
+}
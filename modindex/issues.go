@@ -0,0 +1,123 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Responsiveness is measured on a sample of the most recent issues of a
+// repository: the time until someone other than the author responded,
+// by commenting or by closing the issue. Issues younger than
+// minIssueAge without a response are left out, they may still get one.
+
+// minIssueAge is how long an issue may go without a response before it
+// counts as unanswered.
+const minIssueAge = 7 * 24 * time.Hour
+
+// IssueResponse is an issue of a sample and when it was first responded to.
+type IssueResponse struct {
+	Created   time.Time
+	Responded time.Time // zero if there was no response
+}
+
+// IssueStats summarizes how the maintainers of a repository respond to issues.
+type IssueStats struct {
+	Host           string
+	Name           string
+	Open           int // all open issues
+	Closed         int // all closed issues
+	Sampled        int // recent issues in the sample
+	Responded      int // issues of the sample that got a response
+	MedianResponse time.Duration
+	FetchedAt      time.Time
+}
+
+// ClosedRatio returns the share of closed issues, or 0 if there are none.
+func (s IssueStats) ClosedRatio() float64 {
+	if s.Open+s.Closed == 0 {
+		return 0
+	}
+	return float64(s.Closed) / float64(s.Open+s.Closed)
+}
+
+// IssueStatsOf summarizes a sample of issues as of now.
+func IssueStatsOf(host, name string, open, closed int, sample []IssueResponse, now time.Time) IssueStats {
+	s := IssueStats{Host: host, Name: name, Open: open, Closed: closed, FetchedAt: now}
+	var waits []time.Duration
+	for _, issue := range sample {
+		if issue.Responded.IsZero() {
+			if now.Sub(issue.Created) >= minIssueAge {
+				s.Sampled++
+			}
+			continue
+		}
+		s.Sampled++
+		s.Responded++
+		waits = append(waits, issue.Responded.Sub(issue.Created))
+	}
+	if len(waits) > 0 {
+		slices.Sort(waits)
+		s.MedianResponse = waits[len(waits)/2]
+	}
+	return s
+}
+
+// StoreIssueStats replaces the stored issue statistics of the repository.
+func (c *Client) StoreIssueStats(ctx context.Context, s IssueStats) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	var median sql.NullInt64
+	if s.Responded > 0 {
+		median = sql.NullInt64{Int64: int64(s.MedianResponse / time.Second), Valid: true}
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO repo_issues (host, name, open, closed, sampled, responded, median_response_secs, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (host, name) DO UPDATE SET
+			open = excluded.open,
+			closed = excluded.closed,
+			sampled = excluded.sampled,
+			responded = excluded.responded,
+			median_response_secs = excluded.median_response_secs,
+			fetched_at = excluded.fetched_at`,
+		s.Host, s.Name, s.Open, s.Closed, s.Sampled, s.Responded, median, s.FetchedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("upsert issue stats: %w", err)
+	}
+	return nil
+}
+
+// IssueStats returns the stored issue statistics of all repositories,
+// ordered by host and name.
+func (c *Client) IssueStats(ctx context.Context) ([]IssueStats, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT host, name, open, closed, sampled, responded, median_response_secs, fetched_at
+		FROM repo_issues ORDER BY host, name`)
+	if err != nil {
+		return nil, fmt.Errorf("query issue stats: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var stats []IssueStats
+	for rows.Next() {
+		var s IssueStats
+		var median sql.NullInt64
+		var fetched string
+		if err := rows.Scan(&s.Host, &s.Name, &s.Open, &s.Closed, &s.Sampled, &s.Responded, &median, &fetched); err != nil {
+			return nil, fmt.Errorf("scan issue stats: %w", err)
+		}
+		s.MedianResponse = time.Duration(median.Int64) * time.Second
+		if s.FetchedAt, err = time.Parse(time.RFC3339Nano, fetched); err != nil {
+			return nil, fmt.Errorf("parse issue stats of %s/%s: %w", s.Host, s.Name, err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
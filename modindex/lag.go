@@ -0,0 +1,136 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// The sync_runs table records every batch stored by Sync and Follow with
+// the time it was ingested and the publish timestamps of its versions.
+// The difference tells how far the mirror lagged behind the index at that
+// time, which matters when the mirror is used for release monitoring.
+
+// SyncBatch describes a batch of versions stored by a sync run.
+type SyncBatch struct {
+	RunStart   time.Time // when the sync run started
+	IngestedAt time.Time // when the batch was stored
+	Versions   int       // number of versions inserted
+	Oldest     time.Time // publish timestamp of the first version
+	Newest     time.Time // publish timestamp of the last version
+}
+
+// Lag returns the time between publishing the newest version of the
+// batch and storing it, i.e. how far behind the mirror was afterwards.
+func (b SyncBatch) Lag() time.Duration {
+	return b.IngestedAt.Sub(b.Newest)
+}
+
+// MaxDelay returns the time between publishing the oldest version of
+// the batch and storing it.
+func (b SyncBatch) MaxDelay() time.Duration {
+	return b.IngestedAt.Sub(b.Oldest)
+}
+
+// LagBucket summarizes the batches ingested in [Start, Start+bucket).
+type LagBucket struct {
+	Start    time.Time
+	Batches  int
+	Versions int
+	AvgLag   time.Duration
+	MaxLag   time.Duration
+	MaxDelay time.Duration
+}
+
+// LagReport describes how far the mirror is behind the index.
+type LagReport struct {
+	Newest       time.Time     // publish timestamp of the most recent version
+	LastIngested time.Time     // when the most recent batch was stored
+	Current      time.Duration // time since Newest
+	Buckets      []LagBucket   // in ascending order, empty buckets omitted
+}
+
+// Lag reports the current lag and summarizes the batches ingested
+// at or after since in buckets of the given size.
+func (c *Client) Lag(ctx context.Context, since time.Time, bucket time.Duration) (LagReport, error) {
+	var rep LagReport
+	if bucket <= 0 {
+		return rep, fmt.Errorf("invalid bucket size %s", bucket)
+	}
+	db, err := c.sqlite()
+	if err != nil {
+		return rep, err
+	}
+
+	last, err := lastVersionInfo(ctx, db)
+	if err != nil {
+		return rep, err
+	}
+	rep.Newest = last.Timestamp
+	if !rep.Newest.IsZero() {
+		rep.Current = time.Since(rep.Newest)
+	}
+
+	var lastIngested sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT MAX(ingested_at) FROM sync_runs").Scan(&lastIngested); err != nil {
+		return rep, fmt.Errorf("select last ingest: %w", err)
+	}
+	if lastIngested.Valid {
+		if rep.LastIngested, err = time.Parse(time.RFC3339Nano, lastIngested.String); err != nil {
+			return rep, fmt.Errorf("parse last ingest: %w", err)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT run_start, ingested_at, versions, oldest, newest
+		FROM sync_runs
+		WHERE ingested_at >= ?
+		ORDER BY ingested_at`, since.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return rep, fmt.Errorf("query sync runs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sum time.Duration
+	for rows.Next() {
+		var runStart, ingestedAt, oldest, newest string
+		var b SyncBatch
+		if err := rows.Scan(&runStart, &ingestedAt, &b.Versions, &oldest, &newest); err != nil {
+			return rep, fmt.Errorf("scan sync run: %w", err)
+		}
+		for _, f := range []struct {
+			s string
+			t *time.Time
+		}{{runStart, &b.RunStart}, {ingestedAt, &b.IngestedAt}, {oldest, &b.Oldest}, {newest, &b.Newest}} {
+			if *f.t, err = time.Parse(time.RFC3339Nano, f.s); err != nil {
+				return rep, fmt.Errorf("parse sync run: %w", err)
+			}
+		}
+		start := b.IngestedAt.Truncate(bucket)
+		if n := len(rep.Buckets); n == 0 || !rep.Buckets[n-1].Start.Equal(start) {
+			sum = 0
+			rep.Buckets = append(rep.Buckets, LagBucket{Start: start})
+		}
+		lb := &rep.Buckets[len(rep.Buckets)-1]
+		lb.Batches++
+		lb.Versions += b.Versions
+		sum += b.Lag()
+		lb.AvgLag = sum / time.Duration(lb.Batches)
+		lb.MaxLag = max(lb.MaxLag, b.Lag())
+		lb.MaxDelay = max(lb.MaxDelay, b.MaxDelay())
+	}
+	return rep, rows.Err()
+}
+
+func recordBatch(ctx context.Context, db *sql.DB, b SyncBatch) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO sync_runs (run_start, ingested_at, versions, oldest, newest) VALUES (?, ?, ?, ?, ?)",
+		b.RunStart.UTC().Format(time.RFC3339Nano),
+		b.IngestedAt.UTC().Format(time.RFC3339Nano),
+		b.Versions,
+		b.Oldest.UTC().Format(time.RFC3339Nano),
+		b.Newest.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("insert sync run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package modindex
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Curated links are mostly repository URLs. The module path each one
+// resolves to, verified with the module proxy, is kept in link_modules
+// keyed by URL. Links that were resolved to no module have an empty
+// module. The repository URL the proxy reported as origin of the module
+// is kept even if the module disappears, to follow it when it moves.
+
+// LinkModule is the module a curated link resolved to.
+type LinkModule struct {
+	URL        string
+	Module     string // empty if the link is not a Go module
+	Origin     string // repository URL of the module, empty if unknown
+	ResolvedAt time.Time
+}
+
+// StoreLinkModule replaces the stored resolution of l.URL. A known origin
+// is kept if l.Origin is empty.
+func (c *Client) StoreLinkModule(ctx context.Context, l LinkModule) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO link_modules (url, module, origin, resolved_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET
+			module = excluded.module,
+			origin = COALESCE(excluded.origin, link_modules.origin),
+			resolved_at = excluded.resolved_at`,
+		l.URL, nullString(l.Module), nullString(l.Origin), l.ResolvedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("upsert link module: %w", err)
+	}
+	return nil
+}
+
+// LinkModules returns the stored resolutions of all links, ordered by URL.
+func (c *Client) LinkModules(ctx context.Context) ([]LinkModule, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT url, COALESCE(module, ''), COALESCE(origin, ''), resolved_at FROM link_modules ORDER BY url")
+	if err != nil {
+		return nil, fmt.Errorf("query link modules: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var links []LinkModule
+	for rows.Next() {
+		var l LinkModule
+		var resolved string
+		if err := rows.Scan(&l.URL, &l.Module, &l.Origin, &resolved); err != nil {
+			return nil, fmt.Errorf("scan link module: %w", err)
+		}
+		if l.ResolvedAt, err = time.Parse(time.RFC3339Nano, resolved); err != nil {
+			return nil, fmt.Errorf("parse resolution of %s: %w", l.URL, err)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
@@ -0,0 +1,120 @@
+package modindex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Sync and Follow hold the write lock of the SQLite database for as long
+// as they store a batch. Other connections wait for it up to the busy
+// timeout, see WithBusyTimeout, and then fail with an error IsBusy
+// recognizes. To tell such errors from one-off contention, Sync and
+// Follow announce themselves in a lock file next to the database, which
+// also keeps two of them from synchronizing the same database at once.
+
+// ErrSyncInProgress is returned by Sync and Follow if another process
+// synchronizes the database.
+var ErrSyncInProgress = errors.New("sync in progress")
+
+// SyncLock describes a process synchronizing a SQLite database.
+type SyncLock struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+}
+
+func (l SyncLock) String() string {
+	return fmt.Sprintf("pid %d on %s, running since %s", l.PID, l.Host, l.Started.Local().Format(time.DateTime))
+}
+
+// syncLockPath returns the path of the lock file of the database at dbPath.
+func syncLockPath(dbPath string) string {
+	return dbPath + ".sync-lock"
+}
+
+// SyncInProgress returns the process synchronizing the database at dbPath
+// and whether there is one. Lock files left by processes that no longer
+// run are ignored.
+func SyncInProgress(dbPath string) (SyncLock, bool) {
+	data, err := os.ReadFile(syncLockPath(dbPath))
+	if err != nil {
+		return SyncLock{}, false
+	}
+	var l SyncLock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return SyncLock{}, false // being written or corrupt
+	}
+	return l, l.alive()
+}
+
+// alive reports whether the process holding l may still run. Processes
+// on other hosts, sharing the database over the network, can't be
+// checked and are assumed to run.
+func (l SyncLock) alive() bool {
+	if host, err := os.Hostname(); err != nil || host != l.Host {
+		return true
+	}
+	p, err := os.FindProcess(l.PID)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || !errors.Is(err, os.ErrProcessDone) && !errors.Is(err, syscall.ESRCH)
+}
+
+// lockSync creates the lock file of the database, replacing one left by
+// a process that no longer runs, and returns a function removing it.
+// Clients without a SQLite database need no lock.
+func (c *Client) lockSync() (unlock func(), err error) {
+	if c.db == nil {
+		return func() {}, nil
+	}
+	host, _ := os.Hostname()
+	data, err := json.Marshal(SyncLock{PID: os.Getpid(), Host: host, Started: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	path := syncLockPath(c.dbPath)
+	for range 2 {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			if l, ok := SyncInProgress(c.dbPath); ok {
+				return nil, fmt.Errorf("%w (%s), remove %s if it was interrupted", ErrSyncInProgress, l, path)
+			}
+			_ = os.Remove(path) // stale
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("create sync lock: %w", err)
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+			return nil, fmt.Errorf("write sync lock: %w", err)
+		}
+		return func() { _ = os.Remove(path) }, nil
+	}
+	return nil, fmt.Errorf("%w: cannot replace stale lock %s", ErrSyncInProgress, path)
+}
+
+// IsBusy reports whether err is caused by another connection holding a
+// lock of the SQLite database for longer than the busy timeout.
+func IsBusy(err error) bool {
+	var e *sqlite.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	code := e.Code() & 0xff // primary result code
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
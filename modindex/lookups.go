@@ -0,0 +1,152 @@
+package modindex
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Resolving names to modules asks the module proxy for the latest version
+// of many candidate paths, most of which are no module. The answers are
+// kept in proxy_lookups keyed by path, so that later runs need not ask
+// again. Paths the proxy did not know have no version.
+
+// ProxyLookup is the answer of the module proxy for the latest version of
+// a path.
+type ProxyLookup struct {
+	Path      string
+	Version   string    // empty if the proxy did not know the path
+	Time      time.Time // release time of the version
+	OriginVCS string
+	OriginURL string
+	CheckedAt time.Time
+}
+
+// StoreProxyLookups replaces the stored answers for the paths of lookups.
+func (c *Client) StoreProxyLookups(ctx context.Context, lookups []ProxyLookup) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, l := range lookups {
+		var released string
+		if l.Version != "" {
+			released = l.Time.UTC().Format(time.RFC3339Nano)
+		}
+		_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO proxy_lookups (path, version, time, origin_vcs, origin_url, checked_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			l.Path, nullString(l.Version), nullString(released), nullString(l.OriginVCS), nullString(l.OriginURL), l.CheckedAt.UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return fmt.Errorf("insert proxy lookup: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ProxyLookups returns all stored answers.
+func (c *Client) ProxyLookups(ctx context.Context) ([]ProxyLookup, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT path, COALESCE(version, ''), COALESCE(time, ''), COALESCE(origin_vcs, ''), COALESCE(origin_url, ''), checked_at
+		FROM proxy_lookups`)
+	if err != nil {
+		return nil, fmt.Errorf("query proxy lookups: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var lookups []ProxyLookup
+	for rows.Next() {
+		var l ProxyLookup
+		var released, checked string
+		if err := rows.Scan(&l.Path, &l.Version, &released, &l.OriginVCS, &l.OriginURL, &checked); err != nil {
+			return nil, fmt.Errorf("scan proxy lookup: %w", err)
+		}
+		if released != "" {
+			if l.Time, err = time.Parse(time.RFC3339Nano, released); err != nil {
+				return nil, fmt.Errorf("parse release of %s: %w", l.Path, err)
+			}
+		}
+		if l.CheckedAt, err = time.Parse(time.RFC3339Nano, checked); err != nil {
+			return nil, fmt.Errorf("parse check of %s: %w", l.Path, err)
+		}
+		lookups = append(lookups, l)
+	}
+	return lookups, rows.Err()
+}
+
+// StaleLookup is a stored answer of the module proxy due for a refresh.
+type StaleLookup struct {
+	Path       string
+	CheckedAt  time.Time
+	Expired    bool // checked before the threshold
+	Superseded bool // the index saw a version of the path since
+}
+
+// StaleProxyLookups returns the stored answers checked before the given
+// time or before the index synced a version of their path, ordered by
+// path.
+func (c *Client) StaleProxyLookups(ctx context.Context, before time.Time) ([]StaleLookup, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	threshold := before.UTC().Format(time.RFC3339Nano)
+	rows, err := db.QueryContext(ctx, `SELECT l.path, l.checked_at, l.checked_at < ?, COALESCE(p.last_seen > l.checked_at, 0)
+		FROM proxy_lookups l LEFT JOIN paths p ON p.path = l.path
+		WHERE l.checked_at < ? OR p.last_seen > l.checked_at
+		ORDER BY l.path`, threshold, threshold)
+	if err != nil {
+		return nil, fmt.Errorf("query stale proxy lookups: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var stale []StaleLookup
+	for rows.Next() {
+		var s StaleLookup
+		var checked string
+		if err := rows.Scan(&s.Path, &checked, &s.Expired, &s.Superseded); err != nil {
+			return nil, fmt.Errorf("scan stale proxy lookup: %w", err)
+		}
+		if s.CheckedAt, err = time.Parse(time.RFC3339Nano, checked); err != nil {
+			return nil, fmt.Errorf("parse check of %s: %w", s.Path, err)
+		}
+		stale = append(stale, s)
+	}
+	return stale, rows.Err()
+}
+
+// DeleteProxyLookups deletes the stored answers for paths, e.g. for paths
+// that should never have been asked for, and returns how many it deleted.
+func (c *Client) DeleteProxyLookups(ctx context.Context, paths []string) (int64, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	var deleted int64
+	for _, p := range paths {
+		res, err := tx.ExecContext(ctx, `DELETE FROM proxy_lookups WHERE path = ?`, p)
+		if err != nil {
+			return 0, fmt.Errorf("delete proxy lookup: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,119 @@
+package modindex
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	"github.com/ngrash/modhunt/internal/modname"
+)
+
+// MajorActivity is the release activity of a major version of a project
+// as recorded in the index.
+type MajorActivity struct {
+	Major string // e.g. "v2"
+	Path  string // module path the major version is published under
+
+	First    time.Time // publish time of the first version
+	Last     time.Time // publish time of the last version
+	Latest   string    // highest version that is no pseudo-version
+	Releases int       // versions that are no pseudo-versions
+	Recent   int       // releases in the year before the newest version of the index
+	Pseudo   int       // pseudo-versions
+}
+
+// Majors returns the activity of all major versions of the project of
+// the module path, ordered by major version. Major versions are told
+// apart by the versions, so that v2.0.0+incompatible of a path without
+// suffix counts for v2. Case variants are skipped.
+func (c *Client) Majors(ctx context.Context, path string) ([]MajorActivity, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	last, err := lastVersionInfo(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	recent := last.Timestamp.AddDate(-1, 0, 0)
+
+	// The paths of the project are ranges of the unique index on path:
+	// prefix/vN and gopkg.in's prefix.vN.
+	prefix, _ := modname.SplitMajor(path)
+	rows, err := db.QueryContext(ctx, `SELECT id, path FROM paths
+		WHERE (path = ? OR (path >= ? AND path < ?) OR (path >= ? AND path < ?)) AND case_of IS NULL`,
+		prefix, prefix+"/v", prefix+"/w", prefix+".v", prefix+".w")
+	if err != nil {
+		return nil, fmt.Errorf("query paths: %w", err)
+	}
+	paths := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var p string
+		if err := rows.Scan(&id, &p); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan path: %w", err)
+		}
+		// Skip nested modules like prefix/vendor.
+		if pre, _ := modname.SplitMajor(p); pre == prefix {
+			paths[id] = p
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("close paths: %w", err)
+	}
+
+	type key struct{ path, major string }
+	majors := make(map[key]*MajorActivity)
+	for id, p := range paths {
+		versions, err := PathVersions(ctx, db, id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		for _, v := range versions {
+			major := semver.Major(v.Version)
+			if major == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339Nano, v.Timestamp)
+			if err != nil {
+				return nil, fmt.Errorf("parse timestamp of %s@%s: %w", p, v.Version, err)
+			}
+			m, ok := majors[key{p, major}]
+			if !ok {
+				m = &MajorActivity{Major: major, Path: p, First: t, Last: t}
+				majors[key{p, major}] = m
+			}
+			if t.Before(m.First) {
+				m.First = t
+			}
+			if t.After(m.Last) {
+				m.Last = t
+			}
+			if module.IsPseudoVersion(v.Version) {
+				m.Pseudo++
+				continue
+			}
+			m.Releases++
+			if !t.Before(recent) {
+				m.Recent++
+			}
+			// PathVersions sorts the versions in ascending order.
+			m.Latest = v.Version
+		}
+	}
+
+	activity := make([]MajorActivity, 0, len(majors))
+	for _, m := range majors {
+		activity = append(activity, *m)
+	}
+	slices.SortFunc(activity, func(a, b MajorActivity) int {
+		return cmp.Or(semver.Compare(a.Major, b.Major), cmp.Compare(a.Path, b.Path))
+	})
+	return activity, nil
+}
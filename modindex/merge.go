@@ -0,0 +1,378 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ngrash/modhunt/modindex/index"
+)
+
+// Merge copies what another database has and this one lacks, e.g. the
+// versions synchronized by a server into a laptop copy. Versions are added
+// like imported ones, keyed by path and version, and the coverage of the
+// other database is added. Collected data is keyed by what it describes,
+// e.g. a module or repository; rows missing here are added and rows that
+// were fetched later there replace those here, together with the rows
+// belonging to them, e.g. the topics of a repository. Caches, the state of
+// local runs and data derived from path IDs, like projects, are not
+// merged; the commands computing them fill them in again.
+
+// mergeTable is a table of collected data that Merge and Diff handle.
+type mergeTable struct {
+	name string
+	key  []string // columns identifying what a row describes
+	// stamp is the column of the time a row was fetched, newer rows
+	// replacing older ones. Tables without one only gain missing rows.
+	stamp    string
+	children []string // tables of rows belonging to a row, by key
+}
+
+var mergeTables = []mergeTable{
+	{"releases", []string{"module", "version"}, "", nil},
+	{"release_cadence", []string{"module"}, "fetched_at", nil},
+	{"gomods", []string{"module"}, "fetched_at", []string{"requires"}},
+	{"module_sizes", []string{"module"}, "analyzed_at", nil},
+	{"pkgsite", []string{"module"}, "fetched_at", nil},
+	{"repos", []string{"host", "name"}, "fetched_at", []string{"repo_topics"}},
+	{"repo_issues", []string{"host", "name"}, "fetched_at", nil},
+	{"module_topics", []string{"module", "topic"}, "", nil},
+	{"dependent_counts", []string{"module", "fetched_at"}, "", nil},
+	{"link_modules", []string{"url"}, "resolved_at", nil},
+	{"vanity_imports", []string{"path"}, "resolved_at", nil},
+	{"module_scores", []string{"module"}, "scored_at", nil},
+	{"scorecards", []string{"host", "name"}, "fetched_at", []string{"scorecard_checks"}},
+}
+
+// versionsTable names the versions of the index in diffs and merges.
+const versionsTable = "versions"
+
+// TableDiff compares the rows of a table in two databases, A and B.
+type TableDiff struct {
+	Table        string
+	OnlyA, OnlyB int64
+	// NewerA and NewerB count the rows in both that were fetched later in
+	// A or B. They are always 0 for versions and tables without times.
+	NewerA, NewerB int64
+}
+
+// TableMerge counts the rows Merge added to or replaced in a table.
+type TableMerge struct {
+	Table    string
+	Added    int64
+	Replaced int64
+}
+
+// attach opens a connection to the database of c with the SQLite database
+// at path attached as "other", after checking that both have the same
+// schema version.
+func (c *Client) attach(ctx context.Context, path string) (*sql.Conn, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	other, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	version, err := SchemaVersion(ctx, other)
+	_ = other.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if latest := LatestSchemaVersion(); version != latest {
+		return nil, fmt.Errorf("%s: schema version %d, want %d (run 'modhunt --db %s index migrate')", path, version, latest, path)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get connection: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS other", path); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("attach %s: %w", path, err)
+	}
+	return conn, nil
+}
+
+// detach detaches the other database before conn goes back to the pool.
+func detach(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(context.WithoutCancel(ctx), "DETACH DATABASE other")
+	return errors.Join(err, conn.Close())
+}
+
+// Diff compares the versions and collected data of this database, A, with
+// those of the database at path, B.
+func (c *Client) Diff(ctx context.Context, path string) (_ []TableDiff, err error) {
+	conn, err := c.attach(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, detach(ctx, conn)) }()
+
+	const missing = `SELECT COUNT(*) FROM %[1]s.versions AS v JOIN %[1]s.paths AS p ON p.id = v.path_id
+		WHERE NOT EXISTS (SELECT 1 FROM %[2]s.paths AS q JOIN %[2]s.versions AS w ON w.path_id = q.id
+			WHERE q.path = p.path AND w.version = v.version)`
+	versions := TableDiff{Table: versionsTable}
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf(missing, "main", "other")).Scan(&versions.OnlyA); err != nil {
+		return nil, fmt.Errorf("diff versions: %w", err)
+	}
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf(missing, "other", "main")).Scan(&versions.OnlyB); err != nil {
+		return nil, fmt.Errorf("diff versions: %w", err)
+	}
+	diffs := []TableDiff{versions}
+
+	for _, t := range mergeTables {
+		d := TableDiff{Table: t.name}
+		only := fmt.Sprintf("SELECT COUNT(*) FROM %%[1]s.%[1]s AS a WHERE NOT EXISTS (SELECT 1 FROM %%[2]s.%[1]s AS b WHERE %[2]s)", t.name, t.keyMatch("a", "b"))
+		if err := conn.QueryRowContext(ctx, fmt.Sprintf(only, "main", "other")).Scan(&d.OnlyA); err != nil {
+			return nil, fmt.Errorf("diff %s: %w", t.name, err)
+		}
+		if err := conn.QueryRowContext(ctx, fmt.Sprintf(only, "other", "main")).Scan(&d.OnlyB); err != nil {
+			return nil, fmt.Errorf("diff %s: %w", t.name, err)
+		}
+		if t.stamp != "" {
+			newer := fmt.Sprintf("SELECT COUNT(*) FROM %%[1]s.%[1]s AS a JOIN %%[2]s.%[1]s AS b ON %[2]s WHERE julianday(a.%[3]s) > julianday(b.%[3]s)", t.name, t.keyMatch("a", "b"), t.stamp)
+			if err := conn.QueryRowContext(ctx, fmt.Sprintf(newer, "main", "other")).Scan(&d.NewerA); err != nil {
+				return nil, fmt.Errorf("diff %s: %w", t.name, err)
+			}
+			if err := conn.QueryRowContext(ctx, fmt.Sprintf(newer, "other", "main")).Scan(&d.NewerB); err != nil {
+				return nil, fmt.Errorf("diff %s: %w", t.name, err)
+			}
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// keyMatch returns the condition matching the rows of aliases a and b by
+// the key of t.
+func (t mergeTable) keyMatch(a, b string) string {
+	conds := make([]string, len(t.key))
+	for i, k := range t.key {
+		conds[i] = fmt.Sprintf("%s.%s = %s.%s", a, k, b, k)
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// Merge adds the versions, coverage and collected data of the database at
+// path that this one lacks and replaces collected data fetched later there.
+// progress, if not nil, is called with the number of versions read so far.
+func (c *Client) Merge(ctx context.Context, path string, progress func(read int)) (_ []TableMerge, err error) {
+	conn, err := c.attach(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, detach(ctx, conn)) }()
+
+	versions, err := mergeVersions(ctx, conn, progress)
+	merges := []TableMerge{{Table: versionsTable, Added: int64(versions)}}
+	// Merged versions may be of any time, so the summaries are rebuilt.
+	defer func() {
+		if versions > 0 {
+			err = errors.Join(err, c.refreshSummaries(context.WithoutCancel(ctx), time.Time{}))
+		}
+	}()
+	if err != nil {
+		return merges, err
+	}
+	if err := c.mergeCoverage(ctx, conn); err != nil {
+		return merges, err
+	}
+	for _, t := range mergeTables {
+		m, err := t.merge(ctx, conn)
+		if err != nil {
+			return merges, fmt.Errorf("merge %s: %w", t.name, err)
+		}
+		merges = append(merges, m)
+	}
+	return merges, nil
+}
+
+// mergeVersions imports the versions of the other database missing from
+// the main one, in batches read in path and version order.
+func mergeVersions(ctx context.Context, conn *sql.Conn, progress func(read int)) (int, error) {
+	rules, err := classRules(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	pathIDs := make(map[string]int64)
+	var lastPath, lastVersion string
+	inserted, read := 0, 0
+	for {
+		batch, err := otherVersions(ctx, conn, lastPath, lastVersion)
+		if err != nil {
+			return inserted, err
+		}
+		if len(batch) == 0 {
+			return inserted, nil
+		}
+		read += len(batch)
+		last := batch[len(batch)-1]
+		lastPath, lastVersion = last.Path, last.Version
+
+		n, err := importBatch(ctx, conn, rules, pathIDs, batch)
+		inserted += n
+		if err != nil {
+			return inserted, err
+		}
+		if progress != nil {
+			progress(read)
+		}
+	}
+}
+
+// otherVersions returns the next batch of versions of the other database
+// after the given path and version.
+func otherVersions(ctx context.Context, conn *sql.Conn, afterPath, afterVersion string) ([]*index.VersionInfo, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT p.path, v.version, v.timestamp
+		FROM other.versions AS v
+		JOIN other.paths AS p ON p.id = v.path_id
+		WHERE (p.path, v.version) > (?, ?)
+		ORDER BY p.path, v.version
+		LIMIT ?`, afterPath, afterVersion, importBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("query versions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var batch []*index.VersionInfo
+	for rows.Next() {
+		var v index.VersionInfo
+		var timestamp string
+		if err := rows.Scan(&v.Path, &v.Version, &timestamp); err != nil {
+			return nil, fmt.Errorf("scan version: %w", err)
+		}
+		if v.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp); err != nil {
+			return nil, fmt.Errorf("parse timestamp of %s@%s: %w", v.Path, v.Version, err)
+		}
+		batch = append(batch, &v)
+	}
+	return batch, rows.Err()
+}
+
+// mergeCoverage adds the ranges the other database attached to conn
+// covers, whose versions were merged, to the coverage of c.
+func (c *Client) mergeCoverage(ctx context.Context, conn *sql.Conn) error {
+	rows, err := conn.QueryContext(ctx, "SELECT range_start, range_end FROM other.coverage")
+	if err != nil {
+		return fmt.Errorf("query coverage: %w", err)
+	}
+	var ranges [][2]string
+	for rows.Next() {
+		var r [2]string
+		if err := rows.Scan(&r[0], &r[1]); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("scan coverage: %w", err)
+		}
+		ranges = append(ranges, r)
+	}
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return fmt.Errorf("query coverage: %w", err)
+	}
+	for _, r := range ranges {
+		from, err := time.Parse(time.RFC3339Nano, r[0])
+		if err != nil {
+			return fmt.Errorf("parse coverage start: %w", err)
+		}
+		to, err := time.Parse(time.RFC3339Nano, r[1])
+		if err != nil {
+			return fmt.Errorf("parse coverage end: %w", err)
+		}
+		if err := c.store.AddCoverage(ctx, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge copies the rows of t that the main database lacks or fetched
+// earlier from the other one, with their children.
+func (t mergeTable) merge(ctx context.Context, conn *sql.Conn) (TableMerge, error) {
+	m := TableMerge{Table: t.name}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return m, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	cols, err := tableColumns(ctx, tx, t.name)
+	if err != nil {
+		return m, err
+	}
+	if t.stamp == "" {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT OR IGNORE INTO main.%[1]s (%[2]s) SELECT %[2]s FROM other.%[1]s", t.name, cols))
+		if err != nil {
+			return m, fmt.Errorf("insert rows: %w", err)
+		}
+		if m.Added, err = res.RowsAffected(); err != nil {
+			return m, fmt.Errorf("rows affected: %w", err)
+		}
+		return m, tx.Commit()
+	}
+
+	// The keys of rows to copy are collected first, as copying them
+	// changes which rows the main database lacks.
+	key := strings.Join(t.key, ", ")
+	stmts := []string{
+		"DROP TABLE IF EXISTS temp.merge_keys",
+		fmt.Sprintf(`CREATE TEMP TABLE merge_keys AS SELECT %[2]s, EXISTS (SELECT 1 FROM main.%[1]s AS a WHERE %[3]s) AS replaced
+			FROM other.%[1]s AS b
+			WHERE NOT EXISTS (SELECT 1 FROM main.%[1]s AS a WHERE %[3]s AND julianday(a.%[4]s) >= julianday(b.%[4]s))`,
+			t.name, key, t.keyMatch("a", "b"), t.stamp),
+	}
+	for _, child := range t.children {
+		stmts = append(stmts, fmt.Sprintf("DELETE FROM main.%s WHERE (%s) IN (SELECT %[2]s FROM temp.merge_keys)", child, key))
+	}
+	stmts = append(stmts, fmt.Sprintf("INSERT OR REPLACE INTO main.%[1]s (%[2]s) SELECT %[2]s FROM other.%[1]s WHERE (%[3]s) IN (SELECT %[3]s FROM temp.merge_keys)", t.name, cols, key))
+	for _, child := range t.children {
+		childCols, err := tableColumns(ctx, tx, child)
+		if err != nil {
+			return m, err
+		}
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO main.%[1]s (%[2]s) SELECT %[2]s FROM other.%[1]s WHERE (%[3]s) IN (SELECT %[3]s FROM temp.merge_keys)", child, childCols, key))
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return m, fmt.Errorf("%s: %w", strings.Fields(stmt)[0], err)
+		}
+	}
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) - COALESCE(SUM(replaced), 0), COALESCE(SUM(replaced), 0) FROM temp.merge_keys").Scan(&m.Added, &m.Replaced)
+	if err != nil {
+		return m, fmt.Errorf("count merged rows: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DROP TABLE temp.merge_keys"); err != nil {
+		return m, fmt.Errorf("drop merge keys: %w", err)
+	}
+	return m, tx.Commit()
+}
+
+// tableColumns returns the comma-separated columns of the main table.
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?, 'main')", table)
+	if err != nil {
+		return "", fmt.Errorf("columns of %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+	var cols []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return "", fmt.Errorf("scan column: %w", err)
+		}
+		cols = append(cols, c)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if len(cols) == 0 {
+		return "", fmt.Errorf("table %s not found", table)
+	}
+	return strings.Join(cols, ", "), nil
+}
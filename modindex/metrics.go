@@ -0,0 +1,63 @@
+package modindex
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics describing the progress of the synchronization. They are registered
+// with the default Prometheus registry and can be exposed by serving
+// promhttp.Handler, which is what the --metrics-addr flag of the CLI does.
+var (
+	versionsIngested = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "modhunt",
+		Subsystem: "index",
+		Name:      "versions_ingested_total",
+		Help:      "Number of module versions inserted into the database.",
+	})
+	batchesFetched = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "modhunt",
+		Subsystem: "index",
+		Name:      "batches_fetched_total",
+		Help:      "Number of batches fetched from the module index.",
+	})
+	httpErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "modhunt",
+		Subsystem: "index",
+		Name:      "http_errors_total",
+		Help:      "Number of failed requests to the module index.",
+	})
+	insertDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "modhunt",
+		Subsystem: "index",
+		Name:      "db_insert_duration_seconds",
+		Help:      "Time it takes to insert a batch of versions into the database.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+
+	// lastTimestamp holds the index timestamp (in Unix nanoseconds) of the
+	// most recent version in the database. It backs the lag gauge below.
+	lastTimestamp atomic.Int64
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "modhunt",
+		Subsystem: "index",
+		Name:      "lag_seconds",
+		Help:      "Time between now and the timestamp of the most recent version in the database.",
+	}, func() float64 {
+		last := lastTimestamp.Load()
+		if last == 0 {
+			return 0 // nothing synchronized yet
+		}
+		return time.Since(time.Unix(0, last)).Seconds()
+	})
+)
+
+func observeLastTimestamp(t time.Time) {
+	if !t.IsZero() {
+		lastTimestamp.Store(t.UnixNano())
+	}
+}
@@ -0,0 +1,306 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNeedsMigration is returned when opening a database whose schema
+// is older than what this version of modhunt expects.
+var ErrNeedsMigration = errors.New("database needs migration")
+
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrations must be ordered by version. Never change a migration that has
+// been released, append a new one instead. The first migrations use
+// IF NOT EXISTS so that they can be applied to databases created before
+// the schema_migrations table existed.
+var migrations = []migration{
+	{1, "create paths and versions", execAll(
+		"CREATE TABLE IF NOT EXISTS paths (id INTEGER PRIMARY KEY ASC, path TEXT NOT NULL UNIQUE);",
+		"CREATE TABLE IF NOT EXISTS versions (path_id INTEGER REFERENCES paths(id), version TEXT, timestamp TEXT, PRIMARY KEY(path_id, version)) WITHOUT ROWID;",
+		"CREATE INDEX IF NOT EXISTS idx_versions_timestamp ON versions(timestamp);",
+	)},
+	{2, "create modules", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS modules (id INTEGER PRIMARY KEY ASC, module TEXT NOT NULL UNIQUE);")
+		if err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+		// Older versions of normalize-index added the column on demand.
+		if err := addColumnIfMissing(ctx, tx, "paths", "module_id", "INTEGER REFERENCES modules(id)"); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_paths_module_id ON paths(module_id);")
+		if err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+		return nil
+	}},
+	{3, "track first and last seen per path", execAll(
+		"ALTER TABLE paths ADD COLUMN first_seen TEXT;",
+		"ALTER TABLE paths ADD COLUMN last_seen TEXT;",
+		"UPDATE paths SET first_seen = (SELECT MIN(timestamp) FROM versions WHERE path_id = paths.id), last_seen = (SELECT MAX(timestamp) FROM versions WHERE path_id = paths.id);",
+		"CREATE INDEX idx_paths_first_seen ON paths(first_seen);",
+		"CREATE INDEX idx_paths_last_seen ON paths(last_seen);",
+	)},
+	{4, "flag incompatible and retracted versions", execAll(
+		"ALTER TABLE versions ADD COLUMN incompatible INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE versions ADD COLUMN retracted INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE versions ADD COLUMN retract_rationale TEXT;",
+		"UPDATE versions SET incompatible = 1 WHERE version LIKE '%+incompatible';",
+	)},
+	{5, "track synchronized time ranges", execAll(
+		"CREATE TABLE coverage (range_start TEXT PRIMARY KEY, range_end TEXT NOT NULL);",
+		// Assume existing databases were synchronized without interruption.
+		"INSERT INTO coverage (range_start, range_end) SELECT MIN(timestamp), MAX(timestamp) FROM versions HAVING COUNT(*) > 0;",
+	)},
+	{6, "classify generated paths", func(ctx context.Context, tx *sql.Tx) error {
+		err := execAll(
+			"ALTER TABLE paths ADD COLUMN class TEXT;",
+			"CREATE INDEX idx_paths_class ON paths(class);",
+			"CREATE TABLE class_rules (id INTEGER PRIMARY KEY ASC, class TEXT NOT NULL, pattern TEXT NOT NULL);",
+		)(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if err := storeClassRules(ctx, tx, DefaultClassRules); err != nil {
+			return err
+		}
+		_, err = classifyPaths(ctx, tx, DefaultClassRules)
+		return err
+	}},
+	{7, "track forks", execAll(
+		"ALTER TABLE paths ADD COLUMN gomod_module TEXT;",
+		"ALTER TABLE paths ADD COLUMN fork_of INTEGER REFERENCES paths(id);",
+		"ALTER TABLE paths ADD COLUMN fork_reason TEXT;",
+		"CREATE INDEX idx_paths_fork_of ON paths(fork_of);",
+	)},
+	{8, "archive versions by year", execAll(
+		"CREATE TABLE archives (year INTEGER PRIMARY KEY, versions INTEGER NOT NULL, archived_at TEXT NOT NULL);",
+	)},
+	{9, "record sync lag", execAll(
+		"CREATE TABLE sync_runs (id INTEGER PRIMARY KEY ASC, run_start TEXT NOT NULL, ingested_at TEXT NOT NULL, versions INTEGER NOT NULL, oldest TEXT NOT NULL, newest TEXT NOT NULL);",
+		"CREATE INDEX idx_sync_runs_ingested_at ON sync_runs(ingested_at);",
+	)},
+	{10, "store proxy releases", execAll(
+		"CREATE TABLE releases (module TEXT NOT NULL, version TEXT NOT NULL, time TEXT NOT NULL, PRIMARY KEY(module, version)) WITHOUT ROWID;",
+		"CREATE TABLE release_cadence (module TEXT PRIMARY KEY, first_release TEXT, last_release TEXT, releases INTEGER NOT NULL, per_year REAL NOT NULL, fetched_at TEXT NOT NULL);",
+	)},
+	{11, "store dependency graph", execAll(
+		"CREATE TABLE gomods (module TEXT PRIMARY KEY, version TEXT NOT NULL, module_path TEXT NOT NULL, fetched_at TEXT NOT NULL);",
+		"CREATE TABLE requires (module TEXT NOT NULL REFERENCES gomods(module) ON DELETE CASCADE, dep TEXT NOT NULL, dep_version TEXT NOT NULL, indirect INTEGER NOT NULL DEFAULT 0, PRIMARY KEY(module, dep)) WITHOUT ROWID;",
+		"CREATE INDEX idx_requires_dep ON requires(dep);",
+	)},
+	{12, "record deprecations", execAll(
+		"ALTER TABLE gomods ADD COLUMN deprecated TEXT;",
+	)},
+	{13, "store module sizes", execAll(
+		"CREATE TABLE module_sizes (module TEXT PRIMARY KEY, version TEXT NOT NULL, zip_size INTEGER NOT NULL, size INTEGER NOT NULL, files INTEGER NOT NULL, vendor_files INTEGER NOT NULL, vendor_size INTEGER NOT NULL, binary_files INTEGER NOT NULL, binary_size INTEGER NOT NULL, largest_files TEXT NOT NULL, analyzed_at TEXT NOT NULL);",
+	)},
+	{14, "record go directives", execAll(
+		"ALTER TABLE gomods ADD COLUMN go_version TEXT;",
+		"ALTER TABLE gomods ADD COLUMN toolchain TEXT;",
+	)},
+	{15, "store pkg.go.dev metadata", execAll(
+		"CREATE TABLE pkgsite (module TEXT PRIMARY KEY, path TEXT NOT NULL, version TEXT NOT NULL, licenses TEXT NOT NULL, imported_by INTEGER NOT NULL, fetched_at TEXT NOT NULL);",
+	)},
+	{16, "store repository metadata", execAll(
+		"CREATE TABLE repos (host TEXT NOT NULL, name TEXT NOT NULL, description TEXT NOT NULL, stars INTEGER NOT NULL, forks INTEGER NOT NULL, archived INTEGER NOT NULL, pushed_at TEXT, license TEXT, fetched_at TEXT NOT NULL, PRIMARY KEY(host, name)) WITHOUT ROWID;",
+		"CREATE TABLE repo_topics (host TEXT NOT NULL, name TEXT NOT NULL, topic TEXT NOT NULL, PRIMARY KEY(host, name, topic), FOREIGN KEY(host, name) REFERENCES repos(host, name) ON DELETE CASCADE) WITHOUT ROWID;",
+		"CREATE INDEX idx_repo_topics_topic ON repo_topics(topic);",
+	)},
+	{17, "store repository etags", execAll(
+		"ALTER TABLE repos ADD COLUMN etag TEXT;",
+	)},
+	{18, "store latest releases", execAll(
+		"ALTER TABLE repos ADD COLUMN latest_release TEXT;",
+		"ALTER TABLE repos ADD COLUMN latest_release_at TEXT;",
+	)},
+	{19, "store issue responsiveness", execAll(
+		"CREATE TABLE repo_issues (host TEXT NOT NULL, name TEXT NOT NULL, open INTEGER NOT NULL, closed INTEGER NOT NULL, sampled INTEGER NOT NULL, responded INTEGER NOT NULL, median_response_secs INTEGER, fetched_at TEXT NOT NULL, PRIMARY KEY(host, name));",
+	)},
+	{20, "map topics to modules", execAll(
+		"CREATE TABLE module_topics (module TEXT NOT NULL, topic TEXT NOT NULL, PRIMARY KEY(module, topic)) WITHOUT ROWID;",
+		"CREATE INDEX idx_module_topics_topic ON module_topics(topic);",
+	)},
+	{21, "store bus factors", execAll(
+		"ALTER TABLE repos ADD COLUMN bus_factor INTEGER;",
+	)},
+	{22, "snapshot dependent counts", execAll(
+		"CREATE TABLE dependent_counts (module TEXT NOT NULL, fetched_at TEXT NOT NULL, version TEXT NOT NULL, total INTEGER NOT NULL, direct INTEGER NOT NULL, PRIMARY KEY(module, fetched_at)) WITHOUT ROWID;",
+	)},
+	{23, "resolve curated links to modules", execAll(
+		"CREATE TABLE link_modules (url TEXT PRIMARY KEY, module TEXT, resolved_at TEXT NOT NULL);",
+	)},
+	{24, "remember origins of link modules", execAll(
+		"ALTER TABLE link_modules ADD COLUMN origin TEXT;",
+	)},
+	{25, "cache proxy lookups", execAll(
+		"CREATE TABLE proxy_lookups (path TEXT PRIMARY KEY, version TEXT, time TEXT, origin_vcs TEXT, origin_url TEXT, checked_at TEXT NOT NULL);",
+	)},
+	{26, "record download failures", execAll(
+		"CREATE TABLE download_failures (package TEXT PRIMARY KEY, path TEXT NOT NULL, category TEXT NOT NULL, reason TEXT NOT NULL, failed_at TEXT NOT NULL);",
+	)},
+	{27, "track enrichment stages", execAll(
+		"CREATE TABLE enrich_stages (stage TEXT PRIMARY KEY, started_at TEXT NOT NULL, finished_at TEXT, error TEXT);",
+		"CREATE TABLE module_scores (module TEXT PRIMARY KEY, score INTEGER NOT NULL, cap TEXT, scored_at TEXT NOT NULL);",
+	)},
+	{28, "remember vanity import targets", execAll(
+		"CREATE TABLE vanity_imports (path TEXT PRIMARY KEY, prefix TEXT, vcs TEXT, repo_root TEXT, resolved_at TEXT NOT NULL);",
+	)},
+	{29, "link case variants", execAll(
+		"ALTER TABLE paths ADD COLUMN case_of INTEGER REFERENCES paths(id);",
+		"ALTER TABLE paths ADD COLUMN case_reason TEXT;",
+		"CREATE INDEX idx_paths_case_of ON paths(case_of);",
+	)},
+	{30, "track projects", execAll(
+		"CREATE TABLE projects (id INTEGER PRIMARY KEY ASC, name TEXT NOT NULL UNIQUE, module TEXT NOT NULL, repo TEXT, updated_at TEXT NOT NULL);",
+		"CREATE TABLE project_links (url TEXT PRIMARY KEY, project_id INTEGER NOT NULL REFERENCES projects(id));",
+		"CREATE INDEX idx_project_links_project_id ON project_links(project_id);",
+		"ALTER TABLE paths ADD COLUMN project_id INTEGER REFERENCES projects(id);",
+		"CREATE INDEX idx_paths_project_id ON paths(project_id);",
+	)},
+	{31, "summarize versions", func(ctx context.Context, tx *sql.Tx) error {
+		err := execAll(
+			"CREATE TABLE module_release_counts (path_id INTEGER PRIMARY KEY REFERENCES paths(id), releases INTEGER NOT NULL, latest TEXT NOT NULL);",
+			"CREATE INDEX idx_module_release_counts_releases ON module_release_counts(releases);",
+			"CREATE TABLE releases_per_day (day TEXT PRIMARY KEY, releases INTEGER NOT NULL);",
+			"CREATE TABLE host_counts (host TEXT PRIMARY KEY, paths INTEGER NOT NULL, releases INTEGER NOT NULL);",
+		)(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return summarize(ctx, tx, time.Time{})
+	}},
+	{32, "store scorecards", execAll(
+		"CREATE TABLE scorecards (host TEXT NOT NULL, name TEXT NOT NULL, score REAL NOT NULL, scanned TEXT NOT NULL, commit_sha TEXT NOT NULL, fetched_at TEXT NOT NULL, PRIMARY KEY(host, name)) WITHOUT ROWID;",
+		"CREATE TABLE scorecard_checks (host TEXT NOT NULL, name TEXT NOT NULL, check_name TEXT NOT NULL, score INTEGER NOT NULL, reason TEXT NOT NULL, PRIMARY KEY(host, name, check_name), FOREIGN KEY(host, name) REFERENCES scorecards(host, name) ON DELETE CASCADE) WITHOUT ROWID;",
+	)},
+}
+
+func execAll(stmts ...string) func(context.Context, *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("exec %q: %w", stmt, err)
+			}
+		}
+		return nil
+	}
+}
+
+func addColumnIfMissing(ctx context.Context, tx *sql.Tx, table, column, def string) error {
+	row := tx.QueryRowContext(ctx, "SELECT COUNT(cid) FROM pragma_table_info(?) WHERE name = ?;", table, column)
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return fmt.Errorf("check column %s.%s: %w", table, column, err)
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, def))
+	if err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the most recent migration applied to db.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TEXT NOT NULL);")
+	if err != nil {
+		return 0, fmt.Errorf("create schema_migrations table: %w", err)
+	}
+	var version int
+	row := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations;")
+	if err := row.Scan(&version); err != nil {
+		return 0, fmt.Errorf("select schema version: %w", err)
+	}
+	return version, nil
+}
+
+// LatestSchemaVersion is the schema version this version of modhunt expects.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Migrate applies all pending migrations to db, each in its own transaction.
+// It returns the number of migrations applied.
+func Migrate(ctx context.Context, db *sql.DB) (int, error) {
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	if err := m.up(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);",
+		m.version, m.name, time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("record migration: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// checkSchema returns ErrNeedsMigration if db is not at the latest schema version.
+func checkSchema(ctx context.Context, db *sql.DB) error {
+	current, err := currentSchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	if latest := LatestSchemaVersion(); current < latest {
+		return fmt.Errorf("%w: schema version %d, want %d (run 'modhunt index migrate')", ErrNeedsMigration, current, latest)
+	} else if current > latest {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, latest)
+	}
+	return nil
+}
+
+// currentSchemaVersion is SchemaVersion without creating the
+// schema_migrations table, for read-only connections.
+func currentSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations');").Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("check schema_migrations table: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations;").Scan(&version); err != nil {
+		return 0, fmt.Errorf("select schema version: %w", err)
+	}
+	return version, nil
+}
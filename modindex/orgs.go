@@ -0,0 +1,74 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ngrash/modhunt/internal/forge"
+	"github.com/ngrash/modhunt/internal/modname"
+)
+
+// Org aggregates the paths of an owner on a forge, e.g.
+// github.com/grpc-ecosystem, or of a host without owners, e.g.
+// go.uber.org.
+type Org struct {
+	Name     string
+	Paths    int
+	Releases int       // versions of all paths
+	First    time.Time // first version of any path
+	Last     time.Time // last version of any path
+}
+
+// Orgs returns all orgs of the index, in no particular order. Paths are
+// normalized first, so that gopkg.in paths count for the GitHub owner.
+// Case variants are skipped, and generated paths unless includeGenerated
+// is set.
+func (c *Client) Orgs(ctx context.Context, includeGenerated bool) ([]Org, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT p.path, p.first_seen, p.last_seen, COALESCE(m.releases, 0)
+		FROM paths AS p LEFT JOIN module_release_counts AS m ON m.path_id = p.id
+		WHERE (? OR p.class IS NULL) AND p.case_of IS NULL`, includeGenerated)
+	if err != nil {
+		return nil, fmt.Errorf("query paths: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	orgs := make(map[string]*Org)
+	for rows.Next() {
+		var path string
+		var firstSeen, lastSeen sql.NullString
+		var releases int
+		if err := rows.Scan(&path, &firstSeen, &lastSeen, &releases); err != nil {
+			return nil, fmt.Errorf("scan path: %w", err)
+		}
+		name := forge.Owner(modname.Normalize(path))
+		o, ok := orgs[name]
+		if !ok {
+			o = &Org{Name: name}
+			orgs[name] = o
+		}
+		o.Paths++
+		o.Releases += releases
+		// Paths without versions, e.g. left over by prune, have no
+		// first and last activity.
+		if first, err := time.Parse(time.RFC3339Nano, firstSeen.String); err == nil && (o.First.IsZero() || first.Before(o.First)) {
+			o.First = first
+		}
+		if last, err := time.Parse(time.RFC3339Nano, lastSeen.String); err == nil && last.After(o.Last) {
+			o.Last = last
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	list := make([]Org, 0, len(orgs))
+	for _, o := range orgs {
+		list = append(list, *o)
+	}
+	return list, nil
+}
@@ -0,0 +1,95 @@
+package modindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// pkg.go.dev shows metadata modhunt cannot get from the module proxy,
+// like the number of importing packages across the ecosystem, and knows
+// modules that moved. What it showed for a module is kept in the pkgsite
+// table, keyed by module path like releases.
+
+// PkgsiteInfo is what pkg.go.dev showed for a module.
+type PkgsiteInfo struct {
+	Module     string
+	Path       string // path pkg.go.dev redirected to, Module if it did not
+	Version    string
+	Licenses   []string
+	ImportedBy int
+	FetchedAt  time.Time
+}
+
+// StorePkgsite replaces the stored pkg.go.dev information of p.Module.
+func (c *Client) StorePkgsite(ctx context.Context, p PkgsiteInfo) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO pkgsite (module, path, version, licenses, imported_by, fetched_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (module) DO UPDATE SET
+			path = excluded.path,
+			version = excluded.version,
+			licenses = excluded.licenses,
+			imported_by = excluded.imported_by,
+			fetched_at = excluded.fetched_at`,
+		p.Module, p.Path, p.Version, strings.Join(p.Licenses, ","), p.ImportedBy, p.FetchedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("upsert pkgsite info: %w", err)
+	}
+	return nil
+}
+
+// ErrNoPkgsite is returned by Pkgsite for modules whose pkg.go.dev
+// information was never stored.
+var ErrNoPkgsite = errors.New("no pkg.go.dev information stored")
+
+// Pkgsite returns the stored pkg.go.dev information of module.
+func (c *Client) Pkgsite(ctx context.Context, module string) (PkgsiteInfo, error) {
+	infos, err := c.pkgsite(ctx, "WHERE module = ?", module)
+	if err != nil {
+		return PkgsiteInfo{}, err
+	}
+	if len(infos) == 0 {
+		return PkgsiteInfo{}, fmt.Errorf("%s: %w", module, ErrNoPkgsite)
+	}
+	return infos[0], nil
+}
+
+// PkgsiteInfos returns the stored pkg.go.dev information of all modules,
+// ordered by module.
+func (c *Client) PkgsiteInfos(ctx context.Context) ([]PkgsiteInfo, error) {
+	return c.pkgsite(ctx, "ORDER BY module")
+}
+
+func (c *Client) pkgsite(ctx context.Context, clause string, args ...any) ([]PkgsiteInfo, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT module, path, version, licenses, imported_by, fetched_at FROM pkgsite "+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query pkgsite info: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var infos []PkgsiteInfo
+	for rows.Next() {
+		var p PkgsiteInfo
+		var licenses, fetched string
+		if err := rows.Scan(&p.Module, &p.Path, &p.Version, &licenses, &p.ImportedBy, &fetched); err != nil {
+			return nil, fmt.Errorf("scan pkgsite info: %w", err)
+		}
+		if licenses != "" {
+			p.Licenses = strings.Split(licenses, ",")
+		}
+		if p.FetchedAt, err = time.Parse(time.RFC3339Nano, fetched); err != nil {
+			return nil, fmt.Errorf("parse pkgsite info of %s: %w", p.Module, err)
+		}
+		infos = append(infos, p)
+	}
+	return infos, rows.Err()
+}
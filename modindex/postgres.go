@@ -0,0 +1,189 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/ngrash/modhunt/modindex/index"
+)
+
+// postgresSchema mirrors the SQLite schema with native types.
+// All statements are idempotent and run every time the store is opened.
+var postgresSchema = []string{
+	`CREATE TABLE IF NOT EXISTS paths (
+		id BIGSERIAL PRIMARY KEY,
+		path TEXT NOT NULL UNIQUE,
+		first_seen TIMESTAMPTZ,
+		last_seen TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_paths_first_seen ON paths(first_seen)`,
+	`CREATE INDEX IF NOT EXISTS idx_paths_last_seen ON paths(last_seen)`,
+	`ALTER TABLE paths ADD COLUMN IF NOT EXISTS class TEXT`,
+	`CREATE INDEX IF NOT EXISTS idx_paths_class ON paths(class)`,
+	`CREATE TABLE IF NOT EXISTS versions (
+		path_id BIGINT NOT NULL REFERENCES paths(id),
+		version TEXT NOT NULL,
+		timestamp TIMESTAMPTZ NOT NULL,
+		incompatible BOOLEAN NOT NULL DEFAULT FALSE,
+		retracted BOOLEAN NOT NULL DEFAULT FALSE,
+		retract_rationale TEXT,
+		PRIMARY KEY (path_id, version)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_versions_timestamp ON versions(timestamp)`,
+	`CREATE TABLE IF NOT EXISTS coverage (
+		range_start TIMESTAMPTZ PRIMARY KEY,
+		range_end TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS sync_runs (
+		id BIGSERIAL PRIMARY KEY,
+		run_start TIMESTAMPTZ NOT NULL,
+		ingested_at TIMESTAMPTZ NOT NULL,
+		versions INTEGER NOT NULL,
+		oldest TIMESTAMPTZ NOT NULL,
+		newest TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_sync_runs_ingested_at ON sync_runs(ingested_at)`,
+}
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+func openPostgresStore(ctx context.Context, dsn string) (*postgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	for _, stmt := range postgresSchema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("create schema: %w", err)
+		}
+	}
+	return &postgresStore{db: db}, nil
+}
+
+func (s *postgresStore) LastVersion(ctx context.Context) (index.VersionInfo, error) {
+	var last index.VersionInfo
+	row := s.db.QueryRowContext(ctx, "SELECT p.path, v.version, v.timestamp FROM versions AS v JOIN paths AS p ON p.id = v.path_id ORDER BY v.timestamp DESC LIMIT 1")
+	err := row.Scan(&last.Path, &last.Version, &last.Timestamp)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return last, fmt.Errorf("scan max row: %w", err)
+	}
+	last.Timestamp = last.Timestamp.UTC()
+	return last, nil
+}
+
+func (s *postgresStore) InsertVersions(ctx context.Context, versions []*index.VersionInfo) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	// Postgres stores are classified with the default rules;
+	// custom rules only live in the SQLite database.
+	upsertPath, err := tx.PrepareContext(ctx, `INSERT INTO paths (path, first_seen, last_seen, class) VALUES ($1, $2, $2, $3)
+		ON CONFLICT (path) DO UPDATE SET
+			first_seen = LEAST(paths.first_seen, EXCLUDED.first_seen),
+			last_seen = GREATEST(paths.last_seen, EXCLUDED.last_seen)
+		RETURNING id`)
+	if err != nil {
+		return 0, fmt.Errorf("prepare upsert path: %w", err)
+	}
+	insertVersion, err := tx.PrepareContext(ctx, `INSERT INTO versions (path_id, version, timestamp, incompatible) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return 0, fmt.Errorf("prepare insert version: %w", err)
+	}
+
+	inserted := 0
+	for _, v := range versions {
+		var pathID int64
+		if err := upsertPath.QueryRowContext(ctx, v.Path, v.Timestamp, classOf(DefaultClassRules, v.Path)).Scan(&pathID); err != nil {
+			return 0, fmt.Errorf("upsert path: %w", err)
+		}
+		incompatible := strings.HasSuffix(v.Version, "+incompatible")
+		res, err := insertVersion.ExecContext(ctx, pathID, v.Version, v.Timestamp, incompatible)
+		if err != nil {
+			return 0, fmt.Errorf("insert version: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("rows affected: %w", err)
+		}
+		inserted += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return inserted, nil
+}
+
+func (s *postgresStore) Coverage(ctx context.Context) ([]Gap, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT range_start, range_end FROM coverage ORDER BY range_start")
+	if err != nil {
+		return nil, fmt.Errorf("query coverage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ranges []Gap
+	for rows.Next() {
+		var r Gap
+		if err := rows.Scan(&r.From, &r.To); err != nil {
+			return nil, fmt.Errorf("scan coverage: %w", err)
+		}
+		ranges = append(ranges, Gap{From: r.From.UTC(), To: r.To.UTC()})
+	}
+	return ranges, rows.Err()
+}
+
+func (s *postgresStore) AddCoverage(ctx context.Context, from, to time.Time) error {
+	ranges, err := s.Coverage(ctx)
+	if err != nil {
+		return err
+	}
+	merged, err := mergeCoverage(ranges, from, to)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM coverage"); err != nil {
+		return fmt.Errorf("clear coverage: %w", err)
+	}
+	for _, r := range merged {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO coverage (range_start, range_end) VALUES ($1, $2)", r.From, r.To); err != nil {
+			return fmt.Errorf("insert coverage: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresStore) RecordBatch(ctx context.Context, b SyncBatch) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO sync_runs (run_start, ingested_at, versions, oldest, newest) VALUES ($1, $2, $3, $4, $5)",
+		b.RunStart, b.IngestedAt, b.Versions, b.Oldest, b.Newest)
+	if err != nil {
+		return fmt.Errorf("insert sync run: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
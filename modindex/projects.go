@@ -0,0 +1,210 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ngrash/modhunt/internal/modname"
+)
+
+// A project is what curated links, paths of the index, repositories and
+// answers of the proxy are about: a module with all its major versions,
+// named by modname.Project. StoreProjects assigns every project an ID that
+// stays the same across runs and links the paths of the index to it.
+
+// Project is a curated project.
+type Project struct {
+	ID     int64 // assigned by StoreProjects
+	Name   string
+	Module string   // module path of the curated major version
+	Repo   string   // "host/name" of its repository, empty if unknown
+	Links  []string // URLs of the curated links
+	Paths  []string // paths of the index, set by Project only
+}
+
+// StoreProjects replaces the stored projects with projects, keeping the
+// IDs of projects stored before, and links every path of the index to the
+// project of its name. It returns the number of paths linked.
+func (c *Client) StoreProjects(ctx context.Context, projects []Project) (int, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range []string{
+		"UPDATE paths SET project_id = NULL WHERE project_id IS NOT NULL",
+		"DELETE FROM project_links",
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return 0, fmt.Errorf("reset projects: %w", err)
+		}
+	}
+
+	ids := make(map[string]int64, len(projects))
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, p := range projects {
+		var id int64
+		err := tx.QueryRowContext(ctx, `INSERT INTO projects (name, module, repo, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET module = excluded.module, repo = excluded.repo, updated_at = excluded.updated_at
+			RETURNING id`, p.Name, p.Module, nullString(p.Repo), now).Scan(&id)
+		if err != nil {
+			return 0, fmt.Errorf("store project %s: %w", p.Name, err)
+		}
+		ids[p.Name] = id
+		for _, url := range p.Links {
+			if _, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO project_links (url, project_id) VALUES (?, ?)", url, id); err != nil {
+				return 0, fmt.Errorf("store link of %s: %w", p.Name, err)
+			}
+		}
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM projects")
+	if err != nil {
+		return 0, fmt.Errorf("query projects: %w", err)
+	}
+	var gone []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("scan project: %w", err)
+		}
+		if _, ok := ids[name]; !ok {
+			gone = append(gone, name)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("close projects: %w", err)
+	}
+	for _, name := range gone {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM projects WHERE name = ?", name); err != nil {
+			return 0, fmt.Errorf("delete project %s: %w", name, err)
+		}
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT id, path FROM paths")
+	if err != nil {
+		return 0, fmt.Errorf("query paths: %w", err)
+	}
+	type link struct{ pathID, projectID int64 }
+	var links []link
+	for rows.Next() {
+		var id int64
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("scan path: %w", err)
+		}
+		if projectID, ok := ids[modname.Project(path)]; ok {
+			links = append(links, link{id, projectID})
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("close paths: %w", err)
+	}
+	for _, l := range links {
+		if _, err := tx.ExecContext(ctx, "UPDATE paths SET project_id = ? WHERE id = ?", l.projectID, l.pathID); err != nil {
+			return 0, fmt.Errorf("link path: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return len(links), nil
+}
+
+// ProjectSummary is a stored project with the number of its links and
+// paths.
+type ProjectSummary struct {
+	ID     int64
+	Name   string
+	Module string
+	Repo   string
+	Links  int
+	Paths  int
+}
+
+// Projects returns the stored projects ordered by ID.
+func (c *Client) Projects(ctx context.Context) ([]ProjectSummary, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT p.id, p.name, p.module, COALESCE(p.repo, ''),
+			(SELECT COUNT(*) FROM project_links WHERE project_id = p.id),
+			(SELECT COUNT(*) FROM paths WHERE project_id = p.id)
+		FROM projects AS p ORDER BY p.id`)
+	if err != nil {
+		return nil, fmt.Errorf("query projects: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var projects []ProjectSummary
+	for rows.Next() {
+		var p ProjectSummary
+		if err := rows.Scan(&p.ID, &p.Name, &p.Module, &p.Repo, &p.Links, &p.Paths); err != nil {
+			return nil, fmt.Errorf("scan project: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// ErrNoProject is returned by Project for unknown projects.
+var ErrNoProject = errors.New("project not found")
+
+// Project returns the stored project with the given ID or name, or the
+// project of the given module path, with its links and paths.
+func (c *Client) Project(ctx context.Context, idOrName string) (Project, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return Project{}, err
+	}
+	var p Project
+	var repo sql.NullString
+	query, arg := "SELECT id, name, module, repo FROM projects WHERE name = ?", any(modname.Project(idOrName))
+	if id, err := strconv.ParseInt(idOrName, 10, 64); err == nil {
+		query, arg = "SELECT id, name, module, repo FROM projects WHERE id = ?", id
+	}
+	err = db.QueryRowContext(ctx, query, arg).Scan(&p.ID, &p.Name, &p.Module, &repo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Project{}, fmt.Errorf("%s: %w", idOrName, ErrNoProject)
+	}
+	if err != nil {
+		return Project{}, fmt.Errorf("select project: %w", err)
+	}
+	p.Repo = repo.String
+
+	if p.Links, err = queryStrings(ctx, db, "SELECT url FROM project_links WHERE project_id = ? ORDER BY url", p.ID); err != nil {
+		return Project{}, fmt.Errorf("query links: %w", err)
+	}
+	if p.Paths, err = queryStrings(ctx, db, "SELECT path FROM paths WHERE project_id = ? ORDER BY path", p.ID); err != nil {
+		return Project{}, fmt.Errorf("query paths: %w", err)
+	}
+	return p, nil
+}
+
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,237 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+// PruneOptions select the retention policies applied by Prune.
+// Zero values disable a policy.
+type PruneOptions struct {
+	// KeepLatest keeps only the highest n versions of every path.
+	KeepLatest int
+
+	// PseudoBefore drops pseudo-versions published before this time.
+	PseudoBefore time.Time
+
+	// PseudoOnly drops paths that have only pseudo-versions.
+	PseudoOnly bool
+
+	// DryRun reports what would be deleted without deleting it.
+	DryRun bool
+}
+
+// PruneResult counts the rows deleted by Prune.
+type PruneResult struct {
+	Versions int64
+	Paths    int64
+}
+
+// Prune deletes versions and paths according to opts. Pruned versions are
+// not fetched again by sync, but show up as gaps in Verify.
+func (c *Client) Prune(ctx context.Context, opts PruneOptions) (PruneResult, error) {
+	var res PruneResult
+
+	db, err := c.sqlite()
+	if err != nil {
+		return res, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return res, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	if opts.PseudoOnly {
+		if err := prunePseudoOnly(ctx, tx, &res); err != nil {
+			return res, fmt.Errorf("prune pseudo-only paths: %w", err)
+		}
+	}
+	if !opts.PseudoBefore.IsZero() {
+		if err := prunePseudoBefore(ctx, tx, opts.PseudoBefore, &res); err != nil {
+			return res, fmt.Errorf("prune old pseudo-versions: %w", err)
+		}
+	}
+	if opts.KeepLatest > 0 {
+		if err := pruneKeepLatest(ctx, tx, opts.KeepLatest, &res); err != nil {
+			return res, fmt.Errorf("prune to latest versions: %w", err)
+		}
+	}
+
+	if opts.DryRun {
+		return res, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return res, fmt.Errorf("commit transaction: %w", err)
+	}
+	if res.Versions > 0 {
+		return res, c.refreshSummaries(ctx, time.Time{})
+	}
+	return res, nil
+}
+
+// queryIDs returns the first column of all rows as int64.
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Close()
+}
+
+func pathVersionsTx(ctx context.Context, tx *sql.Tx, pathID int64) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT version FROM versions WHERE path_id = ?", pathID)
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Close()
+}
+
+func deleteVersion(ctx context.Context, tx *sql.Tx, pathID int64, version string, res *PruneResult) error {
+	r, err := tx.ExecContext(ctx, "DELETE FROM versions WHERE path_id = ? AND version = ?", pathID, version)
+	if err != nil {
+		return fmt.Errorf("delete version: %w", err)
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	res.Versions += n
+	return nil
+}
+
+func prunePseudoOnly(ctx context.Context, tx *sql.Tx, res *PruneResult) error {
+	// Every pseudo-version contains a hyphen, so paths with at least one
+	// version without a hyphen are skipped without looking at them.
+	ids, err := queryIDs(ctx, tx, "SELECT path_id FROM versions GROUP BY path_id HAVING SUM(version NOT LIKE '%-%') = 0")
+	if err != nil {
+		return fmt.Errorf("query candidates: %w", err)
+	}
+	for _, id := range ids {
+		versions, err := pathVersionsTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("query versions: %w", err)
+		}
+		pseudoOnly := true
+		for _, v := range versions {
+			if !module.IsPseudoVersion(v) {
+				pseudoOnly = false
+				break
+			}
+		}
+		if !pseudoOnly {
+			continue
+		}
+
+		r, err := tx.ExecContext(ctx, "DELETE FROM versions WHERE path_id = ?", id)
+		if err != nil {
+			return fmt.Errorf("delete versions: %w", err)
+		}
+		n, err := r.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("rows affected: %w", err)
+		}
+		res.Versions += n
+		if _, err := tx.ExecContext(ctx, "UPDATE paths SET fork_of = NULL, fork_reason = NULL WHERE fork_of = ?", id); err != nil {
+			return fmt.Errorf("unlink forks: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE paths SET case_of = NULL, case_reason = NULL WHERE case_of = ?", id); err != nil {
+			return fmt.Errorf("unlink case variants: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM module_release_counts WHERE path_id = ?", id); err != nil {
+			return fmt.Errorf("delete release count: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM paths WHERE id = ?", id); err != nil {
+			return fmt.Errorf("delete path: %w", err)
+		}
+		res.Paths++
+	}
+	return nil
+}
+
+func prunePseudoBefore(ctx context.Context, tx *sql.Tx, before time.Time, res *PruneResult) error {
+	rows, err := tx.QueryContext(ctx, "SELECT path_id, version FROM versions WHERE timestamp < ? AND version LIKE '%-%'",
+		before.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("query candidates: %w", err)
+	}
+	type candidate struct {
+		pathID  int64
+		version string
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.pathID, &c.version); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("scan candidate: %w", err)
+		}
+		if module.IsPseudoVersion(c.version) {
+			candidates = append(candidates, c)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("close candidates: %w", err)
+	}
+
+	for _, c := range candidates {
+		if err := deleteVersion(ctx, tx, c.pathID, c.version, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pruneKeepLatest(ctx context.Context, tx *sql.Tx, keep int, res *PruneResult) error {
+	ids, err := queryIDs(ctx, tx, "SELECT path_id FROM versions GROUP BY path_id HAVING COUNT(*) > ?", keep)
+	if err != nil {
+		return fmt.Errorf("query candidates: %w", err)
+	}
+	for _, id := range ids {
+		versions, err := pathVersionsTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("query versions: %w", err)
+		}
+		sortVersions(versions)
+		for _, v := range versions[:len(versions)-keep] {
+			if err := deleteVersion(ctx, tx, id, v, res); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Vacuum rebuilds the database file to return the space freed by Prune
+// to the file system.
+func (c *Client) Vacuum(ctx context.Context) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "VACUUM")
+	return err
+}
@@ -0,0 +1,57 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"golang.org/x/mod/module"
+)
+
+// PseudoOnly describes a path the index has seen with pseudo-versions
+// only, i.e. whose module never tagged a release.
+type PseudoOnly struct {
+	Path     string
+	Versions int     // number of pseudo-versions
+	Latest   Version // highest pseudo-version
+}
+
+// PseudoOnlyPaths returns those of paths that have only pseudo-versions
+// in the index, in the order of paths. Paths without versions are
+// skipped, since nothing is known about their tags.
+func (c *Client) PseudoOnlyPaths(ctx context.Context, paths []string) ([]PseudoOnly, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	var pseudoOnly []PseudoOnly
+	for _, path := range paths {
+		var id int64
+		err := db.QueryRowContext(ctx, "SELECT id FROM paths WHERE path = ?", path).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("select path %s: %w", path, err)
+		}
+		versions, err := PathVersions(ctx, db, id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(versions) == 0 {
+			continue
+		}
+		tagged := false
+		for _, v := range versions {
+			if !module.IsPseudoVersion(v.Version) {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			pseudoOnly = append(pseudoOnly, PseudoOnly{Path: path, Versions: len(versions), Latest: versions[len(versions)-1]})
+		}
+	}
+	return pseudoOnly, nil
+}
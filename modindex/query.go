@@ -0,0 +1,103 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// QueryResult holds the rows returned by Query.
+type QueryResult struct {
+	Columns []string
+	Rows    [][]any // NULL as nil, text and blobs as string
+}
+
+// Query runs an SQL query against the database and returns all rows. The
+// query runs on a separate connection that opens the database file
+// read-only, so statements that would change the database fail, even if
+// the client was opened for writing.
+func (c *Client) Query(ctx context.Context, query string, args ...any) (*QueryResult, error) {
+	if _, err := c.sqlite(); err != nil {
+		return nil, err
+	}
+	// Unlike PRAGMA query_only, which the query itself could turn off,
+	// mode=ro can't be undone on the connection.
+	db, err := sql.Open("sqlite", sqliteDSN(c.dbPath, c.busyTimeout, true))
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var res QueryResult
+	if res.Columns, err = rows.Columns(); err != nil {
+		return nil, fmt.Errorf("columns: %w", err)
+	}
+	for rows.Next() {
+		row := make([]any, len(res.Columns))
+		ptrs := make([]any, len(row))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	return &res, rows.Err()
+}
+
+// TableSchema describes a table of the database.
+type TableSchema struct {
+	Name    string
+	Columns []ColumnSchema
+}
+
+// ColumnSchema describes a column of a table.
+type ColumnSchema struct {
+	Name       string
+	Type       string
+	NotNull    bool
+	PrimaryKey bool
+}
+
+// Schema returns the tables of the database with their columns, ordered
+// by name.
+func (c *Client) Schema(ctx context.Context) ([]TableSchema, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT m.name, c.name, c.type, c."notnull", c.pk > 0
+		FROM sqlite_master AS m JOIN pragma_table_info(m.name) AS c
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+		ORDER BY m.name, c.cid`)
+	if err != nil {
+		return nil, fmt.Errorf("query schema: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tables []TableSchema
+	for rows.Next() {
+		var table string
+		var col ColumnSchema
+		if err := rows.Scan(&table, &col.Name, &col.Type, &col.NotNull, &col.PrimaryKey); err != nil {
+			return nil, fmt.Errorf("scan column: %w", err)
+		}
+		if n := len(tables); n == 0 || tables[n-1].Name != table {
+			tables = append(tables, TableSchema{Name: table})
+		}
+		tables[len(tables)-1].Columns = append(tables[len(tables)-1].Columns, col)
+	}
+	return tables, rows.Err()
+}
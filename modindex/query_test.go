@@ -0,0 +1,31 @@
+package modindex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ngrash/modhunt/modindex/indextest"
+)
+
+func TestQueryReadOnly(t *testing.T) {
+	ctx := context.Background()
+	srv := indextest.NewServer()
+	defer srv.Close()
+	c := openTestClient(t, srv)
+
+	for _, query := range []string{
+		"DELETE FROM schema_migrations",
+		"PRAGMA query_only = OFF; DELETE FROM schema_migrations",
+	} {
+		if _, err := c.Query(ctx, query); err == nil {
+			t.Errorf("Query(%q) succeeded", query)
+		}
+	}
+	res, err := c.Query(ctx, "SELECT COUNT(*) FROM schema_migrations")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if n := res.Rows[0][0].(int64); n == 0 {
+		t.Error("schema_migrations was emptied")
+	}
+}
@@ -0,0 +1,185 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The index only knows versions published since 2019, so the release
+// history of older modules is fetched from a module proxy (@v/list and
+// the .info of every version) and stored in the releases table, keyed by
+// module path rather than paths.id because curated modules need not be in
+// the index at all. release_cadence summarizes it for scoring and reports.
+
+// Release is a tagged version of a module and its publish time.
+type Release struct {
+	Version string
+	Time    time.Time
+}
+
+// Cadence summarizes the release history of a module.
+type Cadence struct {
+	Module    string
+	First     time.Time // first release
+	Last      time.Time // most recent release
+	Releases  int
+	PerYear   float64 // releases per year since First, counting at least one year
+	FetchedAt time.Time
+}
+
+// CadenceOf summarizes releases as of now.
+func CadenceOf(module string, releases []Release, now time.Time) Cadence {
+	cad := Cadence{Module: module, Releases: len(releases), FetchedAt: now}
+	for _, r := range releases {
+		if cad.First.IsZero() || r.Time.Before(cad.First) {
+			cad.First = r.Time
+		}
+		if r.Time.After(cad.Last) {
+			cad.Last = r.Time
+		}
+	}
+	if len(releases) > 0 {
+		years := max(now.Sub(cad.First).Hours()/24/365, 1)
+		cad.PerYear = float64(len(releases)) / years
+	}
+	return cad
+}
+
+// StoreReleases replaces the stored releases of module and updates its
+// cadence, which it returns.
+func (c *Client) StoreReleases(ctx context.Context, module string, releases []Release) (Cadence, error) {
+	cad := CadenceOf(module, releases, time.Now().UTC())
+	db, err := c.sqlite()
+	if err != nil {
+		return cad, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return cad, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM releases WHERE module = ?", module); err != nil {
+		return cad, fmt.Errorf("delete releases: %w", err)
+	}
+	for _, r := range releases {
+		_, err := tx.ExecContext(ctx, "INSERT INTO releases (module, version, time) VALUES (?, ?, ?)",
+			module, r.Version, r.Time.UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return cad, fmt.Errorf("insert release: %w", err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO release_cadence (module, first_release, last_release, releases, per_year, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (module) DO UPDATE SET
+			first_release = excluded.first_release,
+			last_release = excluded.last_release,
+			releases = excluded.releases,
+			per_year = excluded.per_year,
+			fetched_at = excluded.fetched_at`,
+		module, nullTime(cad.First), nullTime(cad.Last), cad.Releases, cad.PerYear, cad.FetchedAt.Format(time.RFC3339Nano))
+	if err != nil {
+		return cad, fmt.Errorf("upsert cadence: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return cad, fmt.Errorf("commit transaction: %w", err)
+	}
+	return cad, nil
+}
+
+// Releases returns the stored releases of module in the order of their
+// publish time.
+func (c *Client) Releases(ctx context.Context, module string) ([]Release, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT version, time FROM releases WHERE module = ? ORDER BY time, version", module)
+	if err != nil {
+		return nil, fmt.Errorf("query releases: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var releases []Release
+	for rows.Next() {
+		var r Release
+		var t string
+		if err := rows.Scan(&r.Version, &t); err != nil {
+			return nil, fmt.Errorf("scan release: %w", err)
+		}
+		if r.Time, err = time.Parse(time.RFC3339Nano, t); err != nil {
+			return nil, fmt.Errorf("parse time of %s@%s: %w", module, r.Version, err)
+		}
+		releases = append(releases, r)
+	}
+	return releases, rows.Err()
+}
+
+// ErrNoCadence is returned by Cadence for modules whose releases
+// were never stored.
+var ErrNoCadence = errors.New("no releases stored")
+
+// Cadence returns the stored cadence of module.
+func (c *Client) Cadence(ctx context.Context, module string) (Cadence, error) {
+	cads, err := c.cadences(ctx, "WHERE module = ?", module)
+	if err != nil {
+		return Cadence{}, err
+	}
+	if len(cads) == 0 {
+		return Cadence{}, fmt.Errorf("%s: %w", module, ErrNoCadence)
+	}
+	return cads[0], nil
+}
+
+// Cadences returns the stored cadences of all modules,
+// the most frequently released first.
+func (c *Client) Cadences(ctx context.Context) ([]Cadence, error) {
+	return c.cadences(ctx, "ORDER BY per_year DESC, module")
+}
+
+func (c *Client) cadences(ctx context.Context, clause string, args ...any) ([]Cadence, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT module, first_release, last_release, releases, per_year, fetched_at FROM release_cadence "+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query cadence: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var cads []Cadence
+	for rows.Next() {
+		var cad Cadence
+		var first, last sql.NullString
+		var fetched string
+		if err := rows.Scan(&cad.Module, &first, &last, &cad.Releases, &cad.PerYear, &fetched); err != nil {
+			return nil, fmt.Errorf("scan cadence: %w", err)
+		}
+		for _, f := range []struct {
+			s sql.NullString
+			t *time.Time
+		}{{first, &cad.First}, {last, &cad.Last}, {sql.NullString{String: fetched, Valid: true}, &cad.FetchedAt}} {
+			if !f.s.Valid {
+				continue
+			}
+			if *f.t, err = time.Parse(time.RFC3339Nano, f.s.String); err != nil {
+				return nil, fmt.Errorf("parse cadence of %s: %w", cad.Module, err)
+			}
+		}
+		cads = append(cads, cad)
+	}
+	return cads, rows.Err()
+}
+
+// nullTime formats t for the database, storing the zero time as NULL.
+func nullTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(time.RFC3339Nano), Valid: true}
+}
@@ -0,0 +1,173 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Repository metadata comes from the API of the forge hosting a module,
+// e.g. GitHub. Repositories are keyed by host and name rather than module
+// path because several modules can share a repository. Topics are kept
+// in their own table so that modules can be found by topic. The ETag of
+// the response is kept to refresh repositories with conditional requests.
+
+// Repo is the metadata of a source repository.
+type Repo struct {
+	Host        string // e.g. "github.com"
+	Name        string // e.g. "spf13/cobra"
+	Description string
+	Stars       int
+	Forks       int
+	Archived    bool
+	PushedAt    time.Time
+	License     string // SPDX identifier, empty if unknown
+	Topics      []string
+	ETag        string // of the API response, empty if unknown
+
+	// LatestRelease is the tag of the latest release published on the
+	// forge or, without releases, the latest tag. It need not be the latest
+	// version on the proxy.
+	LatestRelease   string
+	LatestReleaseAt time.Time
+
+	// BusFactor is the number of authors who made half of the recent
+	// commits, or 0 if unknown.
+	BusFactor int
+
+	FetchedAt time.Time
+}
+
+// StoreRepo replaces the stored metadata of the repository.
+func (c *Client) StoreRepo(ctx context.Context, r Repo) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO repos (host, name, description, stars, forks, archived, pushed_at, license, etag, latest_release, latest_release_at, bus_factor, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (host, name) DO UPDATE SET
+			description = excluded.description,
+			stars = excluded.stars,
+			forks = excluded.forks,
+			archived = excluded.archived,
+			pushed_at = excluded.pushed_at,
+			license = excluded.license,
+			etag = excluded.etag,
+			latest_release = excluded.latest_release,
+			latest_release_at = excluded.latest_release_at,
+			bus_factor = excluded.bus_factor,
+			fetched_at = excluded.fetched_at`,
+		r.Host, r.Name, r.Description, r.Stars, r.Forks, r.Archived, nullTime(r.PushedAt), nullString(r.License), nullString(r.ETag),
+		nullString(r.LatestRelease), nullTime(r.LatestReleaseAt), sql.NullInt64{Int64: int64(r.BusFactor), Valid: r.BusFactor > 0}, r.FetchedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("upsert repo: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM repo_topics WHERE host = ? AND name = ?", r.Host, r.Name); err != nil {
+		return fmt.Errorf("delete topics: %w", err)
+	}
+	for _, t := range r.Topics {
+		_, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO repo_topics (host, name, topic) VALUES (?, ?, ?)", r.Host, r.Name, t)
+		if err != nil {
+			return fmt.Errorf("insert topic: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// TouchRepo marks the stored metadata of the repository as fetched at t,
+// e.g. after the forge reported it unchanged.
+func (c *Client) TouchRepo(ctx context.Context, host, name string, t time.Time) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "UPDATE repos SET fetched_at = ? WHERE host = ? AND name = ?", t.UTC().Format(time.RFC3339Nano), host, name)
+	if err != nil {
+		return fmt.Errorf("touch repo: %w", err)
+	}
+	return nil
+}
+
+// ErrNoRepo is returned by Repo for repositories that were never stored.
+var ErrNoRepo = errors.New("no repository metadata stored")
+
+// Repo returns the stored metadata of the repository.
+func (c *Client) Repo(ctx context.Context, host, name string) (Repo, error) {
+	repos, err := c.repos(ctx, "WHERE r.host = ? AND r.name = ?", host, name)
+	if err != nil {
+		return Repo{}, err
+	}
+	if len(repos) == 0 {
+		return Repo{}, fmt.Errorf("%s/%s: %w", host, name, ErrNoRepo)
+	}
+	return repos[0], nil
+}
+
+// Repos returns the stored metadata of all repositories, ordered by
+// host and name.
+func (c *Client) Repos(ctx context.Context) ([]Repo, error) {
+	return c.repos(ctx, "")
+}
+
+func (c *Client) repos(ctx context.Context, where string, args ...any) ([]Repo, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT r.host, r.name, r.description, r.stars, r.forks, r.archived, r.pushed_at, r.license, r.etag, r.latest_release, r.latest_release_at, r.bus_factor, r.fetched_at,
+			COALESCE((SELECT GROUP_CONCAT(topic, ' ') FROM (SELECT topic FROM repo_topics AS t WHERE t.host = r.host AND t.name = r.name ORDER BY topic)), '')
+		FROM repos AS r `+where+`
+		ORDER BY r.host, r.name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query repos: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var repos []Repo
+	for rows.Next() {
+		var r Repo
+		var pushed, license, etag, release, releaseAt sql.NullString
+		var busFactor sql.NullInt64
+		var fetched, topics string
+		err := rows.Scan(&r.Host, &r.Name, &r.Description, &r.Stars, &r.Forks, &r.Archived, &pushed, &license, &etag,
+			&release, &releaseAt, &busFactor, &fetched, &topics)
+		if err != nil {
+			return nil, fmt.Errorf("scan repo: %w", err)
+		}
+		r.License, r.ETag, r.LatestRelease = license.String, etag.String, release.String
+		r.BusFactor = int(busFactor.Int64)
+		for _, f := range []struct {
+			s sql.NullString
+			t *time.Time
+		}{{pushed, &r.PushedAt}, {releaseAt, &r.LatestReleaseAt}} {
+			if !f.s.Valid {
+				continue
+			}
+			if *f.t, err = time.Parse(time.RFC3339Nano, f.s.String); err != nil {
+				return nil, fmt.Errorf("parse repo %s/%s: %w", r.Host, r.Name, err)
+			}
+		}
+		if r.FetchedAt, err = time.Parse(time.RFC3339Nano, fetched); err != nil {
+			return nil, fmt.Errorf("parse fetched_at of %s/%s: %w", r.Host, r.Name, err)
+		}
+		if topics != "" {
+			r.Topics = strings.Fields(topics)
+		}
+		repos = append(repos, r)
+	}
+	return repos, rows.Err()
+}
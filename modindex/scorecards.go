@@ -0,0 +1,138 @@
+package modindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// OpenSSF Scorecard results rate the supply-chain security practices of
+// a repository. Like repository metadata, they are keyed by host and name,
+// and the result of every check is kept in its own row.
+
+// Scorecard is the latest OpenSSF Scorecard result of a repository.
+type Scorecard struct {
+	Host      string
+	Name      string
+	Score     float64 // 0 to 10
+	Scanned   string  // date of the scan, e.g. "2024-05-13"
+	Commit    string  // scanned commit
+	Checks    []ScorecardCheck
+	FetchedAt time.Time
+}
+
+// ScorecardCheck is the result of one check of a Scorecard.
+type ScorecardCheck struct {
+	Name   string // e.g. "Branch-Protection"
+	Score  int    // 0 to 10, -1 if the check did not apply
+	Reason string
+}
+
+// StoreScorecard replaces the stored Scorecard result of the repository.
+func (c *Client) StoreScorecard(ctx context.Context, s Scorecard) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO scorecards (host, name, score, scanned, commit_sha, fetched_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (host, name) DO UPDATE SET
+			score = excluded.score,
+			scanned = excluded.scanned,
+			commit_sha = excluded.commit_sha,
+			fetched_at = excluded.fetched_at`,
+		s.Host, s.Name, s.Score, s.Scanned, s.Commit, s.FetchedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("upsert scorecard: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM scorecard_checks WHERE host = ? AND name = ?", s.Host, s.Name); err != nil {
+		return fmt.Errorf("delete checks: %w", err)
+	}
+	for _, ch := range s.Checks {
+		_, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO scorecard_checks (host, name, check_name, score, reason) VALUES (?, ?, ?, ?, ?)",
+			s.Host, s.Name, ch.Name, ch.Score, ch.Reason)
+		if err != nil {
+			return fmt.Errorf("insert check: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ErrNoScorecard is returned by Scorecard for repositories whose result
+// was never stored.
+var ErrNoScorecard = errors.New("no scorecard stored")
+
+// Scorecard returns the stored Scorecard result of the repository.
+func (c *Client) Scorecard(ctx context.Context, host, name string) (Scorecard, error) {
+	cards, err := c.scorecards(ctx, "WHERE host = ? AND name = ?", host, name)
+	if err != nil {
+		return Scorecard{}, err
+	}
+	if len(cards) == 0 {
+		return Scorecard{}, fmt.Errorf("%s/%s: %w", host, name, ErrNoScorecard)
+	}
+	return cards[0], nil
+}
+
+// Scorecards returns the stored Scorecard results of all repositories,
+// ordered by host and name.
+func (c *Client) Scorecards(ctx context.Context) ([]Scorecard, error) {
+	return c.scorecards(ctx, "")
+}
+
+func (c *Client) scorecards(ctx context.Context, where string, args ...any) ([]Scorecard, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT host, name, score, scanned, commit_sha, fetched_at FROM scorecards "+where+" ORDER BY host, name", args...)
+	if err != nil {
+		return nil, fmt.Errorf("query scorecards: %w", err)
+	}
+	var cards []Scorecard
+	index := make(map[[2]string]int)
+	for rows.Next() {
+		var s Scorecard
+		var fetched string
+		if err := rows.Scan(&s.Host, &s.Name, &s.Score, &s.Scanned, &s.Commit, &fetched); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan scorecard: %w", err)
+		}
+		if s.FetchedAt, err = time.Parse(time.RFC3339Nano, fetched); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("parse fetched_at of %s/%s: %w", s.Host, s.Name, err)
+		}
+		index[[2]string{s.Host, s.Name}] = len(cards)
+		cards = append(cards, s)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("close scorecards: %w", err)
+	}
+
+	rows, err = db.QueryContext(ctx, "SELECT host, name, check_name, score, reason FROM scorecard_checks "+where+" ORDER BY host, name, check_name", args...)
+	if err != nil {
+		return nil, fmt.Errorf("query checks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var host, name string
+		var ch ScorecardCheck
+		if err := rows.Scan(&host, &name, &ch.Name, &ch.Score, &ch.Reason); err != nil {
+			return nil, fmt.Errorf("scan check: %w", err)
+		}
+		if i, ok := index[[2]string{host, name}]; ok {
+			cards[i].Checks = append(cards[i].Checks, ch)
+		}
+	}
+	return cards, rows.Err()
+}
@@ -0,0 +1,171 @@
+package modindex
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The size of a module is measured on the zip file the module proxy
+// serves for one version, usually the latest. Like releases, sizes are
+// keyed by module path and replaced when a module is analyzed again.
+
+// Size describes the zip file of a module version.
+type Size struct {
+	Module       string
+	Version      string
+	ZipSize      int64 // compressed size of the zip file
+	Size         int64 // uncompressed size of all files
+	Files        int
+	VendorFiles  int   // files in vendor or third_party directories
+	VendorSize   int64 // their uncompressed size
+	BinaryFiles  int   // files that do not look like text
+	BinarySize   int64 // their uncompressed size
+	LargestFiles []FileSize
+	AnalyzedAt   time.Time
+}
+
+// FileSize is the uncompressed size of a file in a module zip.
+type FileSize struct {
+	Name   string `json:"name"` // relative to the module root
+	Size   int64  `json:"size"`
+	Binary bool   `json:"binary"`
+}
+
+// largestFiles is the number of files kept in Size.LargestFiles.
+const largestFiles = 5
+
+// vendorDirs are directory names conventionally holding copies of other
+// code. The go command leaves vendor directories out of module zips, but
+// older zips and the other conventions still show up.
+var vendorDirs = []string{"vendor", "third_party", "third-party", "thirdparty", "_vendor"}
+
+// AnalyzeZip measures the module zip of path at version.
+func AnalyzeZip(path, version string, data []byte) (Size, error) {
+	s := Size{Module: path, Version: version, ZipSize: int64(len(data)), AnalyzedAt: time.Now().UTC()}
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return s, fmt.Errorf("open zip: %w", err)
+	}
+	prefix := path + "@" + version + "/"
+	var files []FileSize
+	for _, f := range z.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		fs := FileSize{Name: strings.TrimPrefix(f.Name, prefix), Size: int64(f.UncompressedSize64)}
+		if fs.Binary, err = isBinary(f); err != nil {
+			return s, fmt.Errorf("read %s: %w", f.Name, err)
+		}
+		s.Files++
+		s.Size += fs.Size
+		if isVendored(fs.Name) {
+			s.VendorFiles++
+			s.VendorSize += fs.Size
+		}
+		if fs.Binary {
+			s.BinaryFiles++
+			s.BinarySize += fs.Size
+		}
+		files = append(files, fs)
+	}
+	slices.SortFunc(files, func(a, b FileSize) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Name, b.Name))
+	})
+	s.LargestFiles = files[:min(len(files), largestFiles)]
+	return s, nil
+}
+
+// isBinary reports whether f looks binary, using the heuristic of git:
+// a NUL byte in the first 8000 bytes.
+func isBinary(f *zip.File) (bool, error) {
+	r, err := f.Open()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = r.Close() }()
+	head, err := io.ReadAll(io.LimitReader(r, 8000))
+	if err != nil {
+		return false, err
+	}
+	return bytes.IndexByte(head, 0) >= 0, nil
+}
+
+func isVendored(name string) bool {
+	for _, dir := range strings.Split(path.Dir(name), "/") {
+		if slices.Contains(vendorDirs, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// StoreSize replaces the stored size of s.Module with s.
+func (c *Client) StoreSize(ctx context.Context, s Size) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	largest, err := json.Marshal(s.LargestFiles)
+	if err != nil {
+		return fmt.Errorf("encode largest files: %w", err)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO module_sizes (module, version, zip_size, size, files, vendor_files, vendor_size, binary_files, binary_size, largest_files, analyzed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (module) DO UPDATE SET
+			version = excluded.version,
+			zip_size = excluded.zip_size,
+			size = excluded.size,
+			files = excluded.files,
+			vendor_files = excluded.vendor_files,
+			vendor_size = excluded.vendor_size,
+			binary_files = excluded.binary_files,
+			binary_size = excluded.binary_size,
+			largest_files = excluded.largest_files,
+			analyzed_at = excluded.analyzed_at`,
+		s.Module, s.Version, s.ZipSize, s.Size, s.Files, s.VendorFiles, s.VendorSize, s.BinaryFiles, s.BinarySize,
+		string(largest), s.AnalyzedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("upsert size: %w", err)
+	}
+	return nil
+}
+
+// ErrNoSize is returned by Size for modules that were never analyzed.
+var ErrNoSize = errors.New("size not analyzed")
+
+// Size returns the stored size of module.
+func (c *Client) Size(ctx context.Context, module string) (Size, error) {
+	s := Size{Module: module}
+	db, err := c.sqlite()
+	if err != nil {
+		return s, err
+	}
+	var largest, analyzed string
+	err = db.QueryRowContext(ctx, `SELECT version, zip_size, size, files, vendor_files, vendor_size, binary_files, binary_size, largest_files, analyzed_at
+		FROM module_sizes WHERE module = ?`, module).
+		Scan(&s.Version, &s.ZipSize, &s.Size, &s.Files, &s.VendorFiles, &s.VendorSize, &s.BinaryFiles, &s.BinarySize, &largest, &analyzed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, fmt.Errorf("%s: %w", module, ErrNoSize)
+	}
+	if err != nil {
+		return s, fmt.Errorf("select size: %w", err)
+	}
+	if s.AnalyzedAt, err = time.Parse(time.RFC3339Nano, analyzed); err != nil {
+		return s, fmt.Errorf("parse size of %s: %w", module, err)
+	}
+	if err := json.Unmarshal([]byte(largest), &s.LargestFiles); err != nil {
+		return s, fmt.Errorf("decode largest files of %s: %w", module, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,107 @@
+package modindex
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A snapshot is a compacted copy of the database for publishing, e.g. as
+// a release artifact, so users can fetch the enriched dataset instead of
+// building it. It is an ordinary database at the schema version of the
+// modhunt that created it, which later versions migrate when opening it.
+// Caches and the state of local runs are left out, as are the yearly
+// archives, which live outside the database.
+
+// snapshotCleared are the tables emptied in snapshots: caches of answers
+// and failures and the progress of local runs.
+var snapshotCleared = []string{"proxy_lookups", "download_failures", "enrich_stages"}
+
+// SnapshotInfo describes a snapshot written by Snapshot.
+type SnapshotInfo struct {
+	SchemaVersion int
+	Size          int64
+	SHA256        string // hex-encoded checksum of the file
+	Versions      int64  // versions in the live table
+	Archives      int    // archived years left out
+}
+
+// Snapshot writes a compacted copy of the database to path, which must
+// not exist.
+func (c *Client) Snapshot(ctx context.Context, path string) (SnapshotInfo, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return SnapshotInfo{}, fmt.Errorf("%s exists", path)
+	}
+	var info SnapshotInfo
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM archives").Scan(&info.Archives); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("count archives: %w", err)
+	}
+
+	// VACUUM INTO writes a consistent copy while others may keep writing.
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", tmp); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("copy database: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp) }() // no-op after rename
+
+	if err := compactSnapshot(ctx, tmp, &info); err != nil {
+		return SnapshotInfo{}, err
+	}
+	if info.SHA256, info.Size, err = FileSHA256(tmp); err != nil {
+		return SnapshotInfo{}, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("rename snapshot: %w", err)
+	}
+	return info, nil
+}
+
+// compactSnapshot clears the tables left out of snapshots in the copy at
+// path and vacuums it.
+func compactSnapshot(ctx context.Context, path string, info *SnapshotInfo) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	if info.SchemaVersion, err = SchemaVersion(ctx, db); err != nil {
+		return err
+	}
+	for _, table := range append(snapshotCleared, "archives") {
+		if _, err := db.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			return fmt.Errorf("clear %s: %w", table, err)
+		}
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM versions").Scan(&info.Versions); err != nil {
+		return fmt.Errorf("count versions: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("vacuum snapshot: %w", err)
+	}
+	return db.Close()
+}
+
+// FileSHA256 returns the hex-encoded SHA-256 checksum and the size of the
+// file at path.
+func FileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
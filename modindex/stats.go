@@ -0,0 +1,176 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Stats summarizes the contents of the index database.
+type Stats struct {
+	Paths     int64
+	Versions  int64
+	Generated int64 // paths tagged by a ClassRule
+	Variants  int64 // paths linked to another spelling by DetectCaseVariants
+	Oldest    time.Time
+	Newest    time.Time
+	SizeBytes int64
+
+	// PerDay holds the number of versions published per day,
+	// for the most recent days, in ascending order.
+	PerDay []DayCount
+
+	// Top holds the paths with the most versions, in descending order.
+	Top []PathCount
+
+	// Hosts holds the hosts with the most paths, in descending order.
+	Hosts []HostCount
+}
+
+type DayCount struct {
+	Day   string // YYYY-MM-DD
+	Count int64
+}
+
+type PathCount struct {
+	Path  string
+	Count int64
+}
+
+// Stats computes Stats for the index database from its summary tables.
+// The histogram covers the given number of days before the newest
+// version and Top and Hosts are limited to top entries. Top excludes
+// generated paths, unless includeGenerated is set, and case variants,
+// whose versions are those of their canonical path.
+func (c *Client) Stats(ctx context.Context, days, top int, includeGenerated bool) (*Stats, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+
+	var s Stats
+
+	// COUNT(*) on paths uses the rowid b-tree; versions are counted per
+	// day in the summaries.
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM paths").Scan(&s.Paths); err != nil {
+		return nil, fmt.Errorf("count paths: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(SUM(releases), 0) FROM releases_per_day").Scan(&s.Versions); err != nil {
+		return nil, fmt.Errorf("count versions: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM paths WHERE class IS NOT NULL").Scan(&s.Generated); err != nil {
+		return nil, fmt.Errorf("count generated paths: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM paths WHERE case_of IS NOT NULL").Scan(&s.Variants); err != nil {
+		return nil, fmt.Errorf("count case variants: %w", err)
+	}
+
+	// MIN and MAX are answered directly from idx_versions_timestamp.
+	var oldest, newest sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT MIN(timestamp) FROM versions").Scan(&oldest); err != nil {
+		return nil, fmt.Errorf("select oldest: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT MAX(timestamp) FROM versions").Scan(&newest); err != nil {
+		return nil, fmt.Errorf("select newest: %w", err)
+	}
+	if oldest.Valid {
+		if s.Oldest, err = time.Parse(time.RFC3339Nano, oldest.String); err != nil {
+			return nil, fmt.Errorf("parse oldest timestamp: %w", err)
+		}
+	}
+	if newest.Valid {
+		if s.Newest, err = time.Parse(time.RFC3339Nano, newest.String); err != nil {
+			return nil, fmt.Errorf("parse newest timestamp: %w", err)
+		}
+	}
+
+	if !s.Newest.IsZero() && days > 0 {
+		from := s.Newest.AddDate(0, 0, -days+1).Format(time.DateOnly)
+		rows, err := db.QueryContext(ctx, "SELECT day, releases FROM releases_per_day WHERE day >= ? ORDER BY day", from)
+		if err != nil {
+			return nil, fmt.Errorf("query versions per day: %w", err)
+		}
+		for rows.Next() {
+			var d DayCount
+			if err := rows.Scan(&d.Day, &d.Count); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("scan versions per day: %w", err)
+			}
+			s.PerDay = append(s.PerDay, d)
+		}
+		if err := rows.Close(); err != nil {
+			return nil, fmt.Errorf("close versions per day: %w", err)
+		}
+	}
+
+	if top > 0 {
+		// The index on releases yields the counts in order, so only
+		// skipped paths are read beyond the top.
+		rows, err := db.QueryContext(ctx, `SELECT p.path, m.releases
+			FROM module_release_counts AS m JOIN paths AS p ON p.id = m.path_id
+			WHERE (? OR p.class IS NULL) AND p.case_of IS NULL
+			ORDER BY m.releases DESC LIMIT ?`, includeGenerated, top)
+		if err != nil {
+			return nil, fmt.Errorf("query top paths: %w", err)
+		}
+		for rows.Next() {
+			var p PathCount
+			if err := rows.Scan(&p.Path, &p.Count); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("scan top paths: %w", err)
+			}
+			s.Top = append(s.Top, p)
+		}
+		if err := rows.Close(); err != nil {
+			return nil, fmt.Errorf("close top paths: %w", err)
+		}
+		if s.Hosts, err = c.HostCounts(ctx, top); err != nil {
+			return nil, err
+		}
+	}
+
+	row := db.QueryRowContext(ctx, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()")
+	if err := row.Scan(&s.SizeBytes); err != nil {
+		return nil, fmt.Errorf("database size: %w", err)
+	}
+
+	return &s, nil
+}
+
+// NewPath is a module path with the time its first version appeared.
+type NewPath struct {
+	Path      string
+	FirstSeen time.Time
+	Class     string // see ClassRule
+}
+
+// NewPaths returns the paths whose first version appeared at or after since,
+// ordered by path. Generated paths are excluded unless includeGenerated is set.
+func (c *Client) NewPaths(ctx context.Context, since time.Time, includeGenerated bool) ([]NewPath, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT path, first_seen, COALESCE(class, '') FROM paths WHERE first_seen >= ? AND (? OR class IS NULL) ORDER BY path",
+		since.UTC().Format(time.RFC3339Nano), includeGenerated)
+	if err != nil {
+		return nil, fmt.Errorf("query new paths: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var paths []NewPath
+	for rows.Next() {
+		var p NewPath
+		var firstSeen string
+		if err := rows.Scan(&p.Path, &firstSeen, &p.Class); err != nil {
+			return nil, fmt.Errorf("scan new path: %w", err)
+		}
+		if p.FirstSeen, err = time.Parse(time.RFC3339Nano, firstSeen); err != nil {
+			return nil, fmt.Errorf("parse first seen of %s: %w", p.Path, err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
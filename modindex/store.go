@@ -0,0 +1,62 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ngrash/modhunt/modindex/index"
+)
+
+// Store is the storage used to synchronize the module index.
+// The default is the SQLite database, all queries work on it
+// exclusively. A Postgres store can be selected with WithPostgres
+// for synchronization of the full index with concurrent readers.
+type Store interface {
+	// LastVersion returns the most recent version in the store or the
+	// zero value if the store is empty.
+	LastVersion(ctx context.Context) (index.VersionInfo, error)
+
+	// InsertVersions stores versions and their paths, skipping versions
+	// that are already stored, and returns the number of versions inserted.
+	InsertVersions(ctx context.Context, versions []*index.VersionInfo) (int, error)
+
+	// Coverage returns the synchronized time ranges in ascending order.
+	Coverage(ctx context.Context) ([]Gap, error)
+
+	// AddCoverage marks [from, to] as synchronized.
+	AddCoverage(ctx context.Context, from, to time.Time) error
+
+	// RecordBatch logs a stored batch for lag reporting.
+	RecordBatch(ctx context.Context, b SyncBatch) error
+
+	Close() error
+}
+
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func (s *sqliteStore) LastVersion(ctx context.Context) (index.VersionInfo, error) {
+	return lastVersionInfo(ctx, s.db)
+}
+
+func (s *sqliteStore) InsertVersions(ctx context.Context, versions []*index.VersionInfo) (int, error) {
+	return insertVersions(ctx, s.db, versions)
+}
+
+func (s *sqliteStore) Coverage(ctx context.Context) ([]Gap, error) {
+	return Coverage(ctx, s.db)
+}
+
+func (s *sqliteStore) AddCoverage(ctx context.Context, from, to time.Time) error {
+	return addCoverage(ctx, s.db, from, to)
+}
+
+func (s *sqliteStore) RecordBatch(ctx context.Context, b SyncBatch) error {
+	return recordBatch(ctx, s.db, b)
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,143 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Counting the versions of the index means scanning tens of millions of
+// rows, so the counts are kept in summary tables: module_release_counts
+// per path, releases_per_day and host_counts per first path element.
+// Sync and Heal refresh them for the versions they inserted; commands
+// that delete versions rebuild them.
+
+// refreshSummaries recounts the versions published since the given time
+// and the paths and hosts they belong to. With the zero time, the
+// summaries are rebuilt. Clients without SQLite database have none.
+func (c *Client) refreshSummaries(ctx context.Context, since time.Time) error {
+	if c.db == nil {
+		return nil
+	}
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := summarize(ctx, tx, since); err != nil {
+		return fmt.Errorf("refresh summaries: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RefreshSummaries rebuilds the summary tables from all versions, e.g.
+// after they were changed without the methods of Client.
+func (c *Client) RefreshSummaries(ctx context.Context) error {
+	if _, err := c.sqlite(); err != nil {
+		return err
+	}
+	return c.refreshSummaries(ctx, time.Time{})
+}
+
+// hostExpr is the SQL expression of the first element of p.path.
+const hostExpr = "substr(p.path, 1, instr(p.path || '/', '/') - 1)"
+
+func summarize(ctx context.Context, tx *sql.Tx, since time.Time) error {
+	if since.IsZero() {
+		return execAll(
+			"DELETE FROM releases_per_day;",
+			"INSERT INTO releases_per_day (day, releases) SELECT substr(timestamp, 1, 10), COUNT(*) FROM versions GROUP BY 1;",
+			"DELETE FROM module_release_counts;",
+			"INSERT INTO module_release_counts (path_id, releases, latest) SELECT path_id, COUNT(*), MAX(timestamp) FROM versions GROUP BY path_id;",
+			"DELETE FROM host_counts;",
+			`INSERT INTO host_counts (host, paths, releases)
+				SELECT `+hostExpr+`, COUNT(*), COALESCE(SUM(m.releases), 0)
+				FROM paths AS p LEFT JOIN module_release_counts AS m ON m.path_id = p.id
+				GROUP BY 1;`,
+		)(ctx, tx)
+	}
+
+	// Days are recounted from their start, which compares lower than
+	// every timestamp of the day.
+	day := since.UTC().Format(time.DateOnly)
+	if _, err := tx.ExecContext(ctx, "DELETE FROM releases_per_day WHERE day >= ?", day); err != nil {
+		return fmt.Errorf("delete days: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO releases_per_day (day, releases)
+		SELECT substr(timestamp, 1, 10), COUNT(*) FROM versions WHERE timestamp >= ? GROUP BY 1`, day); err != nil {
+		return fmt.Errorf("count days: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT p.id, `+hostExpr+` FROM versions AS v JOIN paths AS p ON p.id = v.path_id
+		WHERE v.timestamp >= ?`, day)
+	if err != nil {
+		return fmt.Errorf("query released paths: %w", err)
+	}
+	var ids []int64
+	hosts := make(map[string]bool)
+	for rows.Next() {
+		var id int64
+		var host string
+		if err := rows.Scan(&id, &host); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("scan released path: %w", err)
+		}
+		ids = append(ids, id)
+		hosts[host] = true
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("close released paths: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO module_release_counts (path_id, releases, latest)
+			SELECT path_id, COUNT(*), MAX(timestamp) FROM versions WHERE path_id = ? GROUP BY path_id`, id); err != nil {
+			return fmt.Errorf("count releases: %w", err)
+		}
+	}
+	for host := range hosts {
+		// The paths of a host are a range of the unique index on path;
+		// "0" follows "/".
+		if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO host_counts (host, paths, releases)
+			SELECT ?, COUNT(*), COALESCE(SUM(m.releases), 0)
+			FROM paths AS p LEFT JOIN module_release_counts AS m ON m.path_id = p.id
+			WHERE p.path = ? OR (p.path >= ? AND p.path < ?)`, host, host, host+"/", host+"0"); err != nil {
+			return fmt.Errorf("count host %s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// HostCount is the number of paths and versions on a host.
+type HostCount struct {
+	Host     string
+	Paths    int64
+	Releases int64
+}
+
+// HostCounts returns the n hosts with the most paths, according to the
+// summary tables.
+func (c *Client) HostCounts(ctx context.Context, n int) ([]HostCount, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT host, paths, releases FROM host_counts ORDER BY paths DESC, host LIMIT ?", n)
+	if err != nil {
+		return nil, fmt.Errorf("query host counts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var counts []HostCount
+	for rows.Next() {
+		var h HostCount
+		if err := rows.Scan(&h.Host, &h.Paths, &h.Releases); err != nil {
+			return nil, fmt.Errorf("scan host count: %w", err)
+		}
+		counts = append(counts, h)
+	}
+	return counts, rows.Err()
+}
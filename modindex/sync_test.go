@@ -0,0 +1,196 @@
+package modindex
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ngrash/modhunt/modindex/index"
+	"github.com/ngrash/modhunt/modindex/indextest"
+)
+
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// openTestClient opens a new database synchronized from srv in pages of 100.
+func openTestClient(t *testing.T, srv *indextest.Server, opts ...Option) *Client {
+	t.Helper()
+	opts = append([]Option{
+		WithDBPath(filepath.Join(t.TempDir(), "index.db")),
+		WithMigrate(),
+		WithIndexURL(srv.IndexURL()),
+		WithBatchSize(100),
+	}, opts...)
+	c, err := Open(context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func countVersions(t *testing.T, c *Client) int {
+	t.Helper()
+	var n int
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM versions").Scan(&n); err != nil {
+		t.Fatalf("count versions: %v", err)
+	}
+	return n
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	srv := indextest.NewServer(indextest.Generate(250, testStart, time.Minute)...)
+	defer srv.Close()
+	c := openTestClient(t, srv)
+
+	res, err := c.Sync(ctx, SyncOptions{})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if res.Inserted != 250 {
+		t.Errorf("Sync inserted %d versions, want 250", res.Inserted)
+	}
+
+	// Versions published later are picked up by the next run.
+	later := indextest.Generate(50, testStart.Add(time.Hour*24), time.Minute)
+	for i := range later {
+		later[i].Version = "v1.1.0"
+	}
+	srv.Add(later...)
+	if res, err = c.Sync(ctx, SyncOptions{}); err != nil {
+		t.Fatalf("second Sync: %v", err)
+	}
+	if res.Inserted != 50 {
+		t.Errorf("second Sync inserted %d versions, want 50", res.Inserted)
+	}
+	if n := countVersions(t, c); n != 300 {
+		t.Errorf("database has %d versions, want 300", n)
+	}
+
+	want := []Gap{{From: testStart, To: testStart.Add(time.Hour*24 + 49*time.Minute)}}
+	if got, err := c.store.Coverage(ctx); err != nil {
+		t.Fatalf("Coverage: %v", err)
+	} else if !slices.Equal(got, want) {
+		t.Errorf("Coverage = %v, want %v", got, want)
+	}
+}
+
+func TestSyncResume(t *testing.T) {
+	ctx := context.Background()
+	srv := indextest.NewServer(indextest.Generate(250, testStart, time.Minute)...)
+	defer srv.Close()
+	c := openTestClient(t, srv, WithIndexOptions(index.WithRetries(0)))
+
+	// The request for the second page fails.
+	progress := func(p SyncProgress) {
+		if !p.Current.IsZero() && srv.Requests() == 1 {
+			srv.Fail(http.StatusInternalServerError)
+		}
+	}
+	res, err := c.Sync(ctx, SyncOptions{Progress: progress})
+	if err == nil {
+		t.Fatal("interrupted Sync succeeded")
+	}
+	if res.Inserted != 100 {
+		t.Errorf("interrupted Sync inserted %d versions, want 100", res.Inserted)
+	}
+
+	res, err = c.Sync(ctx, SyncOptions{})
+	if err != nil {
+		t.Fatalf("resumed Sync: %v", err)
+	}
+	if res.Inserted != 150 {
+		t.Errorf("resumed Sync inserted %d versions, want 150", res.Inserted)
+	}
+	if n := countVersions(t, c); n != 250 {
+		t.Errorf("database has %d versions, want 250", n)
+	}
+}
+
+func TestSyncHeal(t *testing.T) {
+	ctx := context.Background()
+	srv := indextest.NewServer(indextest.Generate(300, testStart, time.Minute)...)
+	defer srv.Close()
+	c := openTestClient(t, srv)
+
+	if _, err := c.Sync(ctx, SyncOptions{}); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	// Lose the versions between the 100th and 200th minute, as if they
+	// were never synchronized.
+	from, to := testStart.Add(100*time.Minute), testStart.Add(200*time.Minute)
+	_, err := c.db.Exec("DELETE FROM versions WHERE timestamp > ? AND timestamp < ?",
+		from.Format(time.RFC3339Nano), to.Format(time.RFC3339Nano))
+	if err != nil {
+		t.Fatalf("delete versions: %v", err)
+	}
+	if _, err := c.db.Exec("DELETE FROM coverage"); err != nil {
+		t.Fatalf("clear coverage: %v", err)
+	}
+	for _, r := range []Gap{{From: testStart, To: from}, {From: to, To: testStart.Add(299 * time.Minute)}} {
+		if err := addCoverage(ctx, c.db, r.From, r.To); err != nil {
+			t.Fatalf("addCoverage: %v", err)
+		}
+	}
+	if n := countVersions(t, c); n != 201 {
+		t.Fatalf("database has %d versions after deletion, want 201", n)
+	}
+
+	res, err := c.Sync(ctx, SyncOptions{Heal: true})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if want := []Gap{{From: from, To: to}}; !slices.Equal(res.Healed, want) {
+		t.Errorf("Sync healed %v, want %v", res.Healed, want)
+	}
+	if n := countVersions(t, c); n != 300 {
+		t.Errorf("database has %d versions after healing, want 300", n)
+	}
+	ranges, err := c.store.Coverage(ctx)
+	if err != nil {
+		t.Fatalf("Coverage: %v", err)
+	}
+	if len(ranges) != 1 {
+		t.Errorf("Coverage = %v after healing, want a single range", ranges)
+	}
+
+	// Nothing is left to heal.
+	if healed, err := c.Heal(ctx); err != nil {
+		t.Fatalf("Heal: %v", err)
+	} else if len(healed) != 0 {
+		t.Errorf("Heal filled %v, want nothing", healed)
+	}
+}
+
+func TestSyncPrunePseudoOnly(t *testing.T) {
+	ctx := context.Background()
+	srv := indextest.NewServer(
+		index.VersionInfo{Path: "example.com/released", Version: "v1.0.0", Timestamp: testStart},
+		index.VersionInfo{Path: "example.com/pseudo", Version: "v0.0.0-20240101000100-0123456789ab", Timestamp: testStart.Add(time.Minute)},
+		index.VersionInfo{Path: "example.com/pseudo", Version: "v0.0.0-20240101000200-0123456789ab", Timestamp: testStart.Add(2 * time.Minute)},
+	)
+	defer srv.Close()
+	c := openTestClient(t, srv)
+
+	if _, err := c.Sync(ctx, SyncOptions{}); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	res, err := c.Prune(ctx, PruneOptions{PseudoOnly: true})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if want := (PruneResult{Versions: 2, Paths: 1}); res != want {
+		t.Errorf("Prune = %+v, want %+v", res, want)
+	}
+	paths, err := c.Paths(ctx, true)
+	if err != nil {
+		t.Fatalf("Paths: %v", err)
+	}
+	if want := []string{"example.com/released"}; !slices.Equal(paths, want) {
+		t.Errorf("Paths = %q after pruning, want %q", paths, want)
+	}
+}
@@ -0,0 +1,124 @@
+package modindex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Topics are harvested from forges per repository (repo_topics) and
+// mapped to the modules of curated packages in module_topics, which is
+// replaced as a whole whenever repositories are synced. Topics are
+// lower-case on all forges that have them.
+
+// ReplaceModuleTopics replaces the topics of all modules with topics,
+// keyed by module path.
+func (c *Client) ReplaceModuleTopics(ctx context.Context, topics map[string][]string) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM module_topics"); err != nil {
+		return fmt.Errorf("delete module topics: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT OR IGNORE INTO module_topics (module, topic) VALUES (?, ?)")
+	if err != nil {
+		return fmt.Errorf("prepare insert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	for module, ts := range topics {
+		for _, t := range ts {
+			if _, err := stmt.ExecContext(ctx, module, strings.ToLower(t)); err != nil {
+				return fmt.Errorf("insert module topic: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ModuleTopics returns the topics of all modules, keyed by module path
+// and sorted.
+func (c *Client) ModuleTopics(ctx context.Context) (map[string][]string, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT module, topic FROM module_topics ORDER BY module, topic")
+	if err != nil {
+		return nil, fmt.Errorf("query module topics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	topics := make(map[string][]string)
+	for rows.Next() {
+		var module, topic string
+		if err := rows.Scan(&module, &topic); err != nil {
+			return nil, fmt.Errorf("scan module topic: %w", err)
+		}
+		topics[module] = append(topics[module], topic)
+	}
+	return topics, rows.Err()
+}
+
+// TopicCount is a topic and the number of modules having it.
+type TopicCount struct {
+	Topic   string
+	Modules int
+}
+
+// TopicCounts returns all topics of at least minModules modules, the most
+// common first.
+func (c *Client) TopicCounts(ctx context.Context, minModules int) ([]TopicCount, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT topic, COUNT(*) AS n FROM module_topics
+		GROUP BY topic HAVING n >= ? ORDER BY n DESC, topic`, minModules)
+	if err != nil {
+		return nil, fmt.Errorf("query topic counts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var counts []TopicCount
+	for rows.Next() {
+		var tc TopicCount
+		if err := rows.Scan(&tc.Topic, &tc.Modules); err != nil {
+			return nil, fmt.Errorf("scan topic count: %w", err)
+		}
+		counts = append(counts, tc)
+	}
+	return counts, rows.Err()
+}
+
+// ModulesWithTopic returns the sorted paths of the modules having topic.
+func (c *Client) ModulesWithTopic(ctx context.Context, topic string) ([]string, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT module FROM module_topics WHERE topic = ? ORDER BY module", strings.ToLower(topic))
+	if err != nil {
+		return nil, fmt.Errorf("query modules with topic: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var modules []string
+	for rows.Next() {
+		var m string
+		if err := rows.Scan(&m); err != nil {
+			return nil, fmt.Errorf("scan module: %w", err)
+		}
+		modules = append(modules, m)
+	}
+	return modules, rows.Err()
+}
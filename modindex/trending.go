@@ -0,0 +1,169 @@
+package modindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Trending modules are found by comparing how often a path was released
+// in a recent window with the year before, using the versions of the
+// index. How many packages depend on a module is snapshotted from
+// deps.dev into dependent_counts, keyed by module path, so that repeated
+// runs show which modules gain dependents.
+
+// Activity is how often a module path was released in a window and in
+// the year before it. Pseudo-versions do not count.
+type Activity struct {
+	Path      string
+	FirstSeen time.Time
+	Recent    int    // releases in the window
+	Before    int    // releases in the year before the window
+	Latest    string // highest version released in the window
+}
+
+// Newest returns the publish time of the most recent version in the
+// index, or the zero time if it is empty.
+func (c *Client) Newest(ctx context.Context) (time.Time, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return time.Time{}, err
+	}
+	last, err := lastVersionInfo(ctx, db)
+	return last.Timestamp, err
+}
+
+// Activity returns the paths released at least once in [start, end],
+// ordered by path. Generated paths and case variants are skipped.
+func (c *Client) Activity(ctx context.Context, start, end time.Time) ([]Activity, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	// Timestamps are stored as text, where a fraction of a second sorts
+	// before the full second, e.g. "10:00:00.5Z" before "10:00:00Z". The
+	// query widens the range by a second on both ends and the timestamps
+	// are compared exactly below.
+	before := start.AddDate(-1, 0, 0)
+	rows, err := db.QueryContext(ctx, `SELECT p.path, p.first_seen, v.version, v.timestamp
+		FROM versions AS v JOIN paths AS p ON p.id = v.path_id
+		WHERE v.timestamp >= ? AND v.timestamp <= ? AND p.class IS NULL AND p.case_of IS NULL
+		ORDER BY p.path`,
+		before.Add(-time.Second).UTC().Format(time.RFC3339Nano), end.Add(time.Second).UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, fmt.Errorf("query activity: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var activity []Activity
+	var a *Activity
+	for rows.Next() {
+		var path, firstSeen, version, timestamp string
+		if err := rows.Scan(&path, &firstSeen, &version, &timestamp); err != nil {
+			return nil, fmt.Errorf("scan activity: %w", err)
+		}
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("parse timestamp of %s %s: %w", path, version, err)
+		}
+		if t.Before(before) || t.After(end) {
+			continue
+		}
+		if a == nil || a.Path != path {
+			if a != nil && a.Recent > 0 {
+				activity = append(activity, *a)
+			}
+			a = &Activity{Path: path}
+			if a.FirstSeen, err = time.Parse(time.RFC3339Nano, firstSeen); err != nil {
+				return nil, fmt.Errorf("parse first seen of %s: %w", path, err)
+			}
+		}
+		if module.IsPseudoVersion(version) {
+			continue
+		}
+		if t.Before(start) {
+			a.Before++
+			continue
+		}
+		a.Recent++
+		if a.Latest == "" || semver.Compare(version, a.Latest) > 0 {
+			a.Latest = version
+		}
+	}
+	if a != nil && a.Recent > 0 {
+		activity = append(activity, *a)
+	}
+	return activity, rows.Err()
+}
+
+// DependentCount is the number of packages depending on a version of a
+// module according to deps.dev.
+type DependentCount struct {
+	Module    string
+	Version   string
+	Total     int
+	Direct    int
+	FetchedAt time.Time
+}
+
+// StoreDependentCount adds d to the dependent counts of d.Module.
+func (c *Client) StoreDependentCount(ctx context.Context, d DependentCount) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "INSERT OR REPLACE INTO dependent_counts (module, fetched_at, version, total, direct) VALUES (?, ?, ?, ?, ?)",
+		d.Module, d.FetchedAt.UTC().Format(time.RFC3339Nano), d.Version, d.Total, d.Direct)
+	if err != nil {
+		return fmt.Errorf("insert dependent count: %w", err)
+	}
+	return nil
+}
+
+// ErrNoDependentCount is returned by DependentGrowth for modules whose
+// dependents were never counted.
+var ErrNoDependentCount = errors.New("dependents not counted")
+
+// DependentGrowth returns the latest dependent count of module and the
+// latest one fetched at or before since, or the oldest one if none was.
+func (c *Client) DependentGrowth(ctx context.Context, module string, since time.Time) (from, to DependentCount, err error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return from, to, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT fetched_at, version, total, direct FROM dependent_counts WHERE module = ? ORDER BY fetched_at", module)
+	if err != nil {
+		return from, to, fmt.Errorf("query dependent counts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var counts []DependentCount
+	for rows.Next() {
+		d := DependentCount{Module: module}
+		var fetched string
+		if err := rows.Scan(&fetched, &d.Version, &d.Total, &d.Direct); err != nil {
+			return from, to, fmt.Errorf("scan dependent count: %w", err)
+		}
+		if d.FetchedAt, err = time.Parse(time.RFC3339Nano, fetched); err != nil {
+			return from, to, fmt.Errorf("parse dependent count of %s: %w", module, err)
+		}
+		counts = append(counts, d)
+	}
+	if err := rows.Err(); err != nil {
+		return from, to, err
+	}
+	if len(counts) == 0 {
+		return from, to, fmt.Errorf("%s: %w", module, ErrNoDependentCount)
+	}
+	from = counts[0]
+	for _, d := range counts {
+		if !d.FetchedAt.After(since) {
+			from = d
+		}
+	}
+	return from, counts[len(counts)-1], nil
+}
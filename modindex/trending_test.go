@@ -0,0 +1,49 @@
+package modindex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ngrash/modhunt/modindex/index"
+	"github.com/ngrash/modhunt/modindex/indextest"
+)
+
+func TestActivityFractionalSeconds(t *testing.T) {
+	ctx := context.Background()
+	start := testStart.AddDate(1, 0, 0)
+	end := start.Add(24*time.Hour + 500*time.Millisecond)
+	srv := indextest.NewServer(
+		// Before the year before the window.
+		index.VersionInfo{Path: "example.com/m", Version: "v0.9.0", Timestamp: testStart.Add(-500 * time.Millisecond)},
+		index.VersionInfo{Path: "example.com/m", Version: "v1.0.0", Timestamp: testStart.Add(500 * time.Millisecond)},
+		index.VersionInfo{Path: "example.com/m", Version: "v1.1.0", Timestamp: start.Add(-500 * time.Millisecond)},
+		index.VersionInfo{Path: "example.com/m", Version: "v1.2.0", Timestamp: start},
+		index.VersionInfo{Path: "example.com/m", Version: "v1.3.0", Timestamp: end.Add(-500 * time.Millisecond)},
+		index.VersionInfo{Path: "example.com/m", Version: "v1.4.0", Timestamp: end},
+	)
+	defer srv.Close()
+	c := openTestClient(t, srv)
+	if _, err := c.Sync(ctx, SyncOptions{}); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	newest, err := c.Newest(ctx)
+	if err != nil {
+		t.Fatalf("Newest: %v", err)
+	}
+	if !newest.Equal(end) {
+		t.Fatalf("Newest = %s, want %s", newest, end)
+	}
+	activity, err := c.Activity(ctx, start, newest)
+	if err != nil {
+		t.Fatalf("Activity: %v", err)
+	}
+	if len(activity) != 1 {
+		t.Fatalf("Activity = %+v, want one path", activity)
+	}
+	a := activity[0]
+	if a.Recent != 3 || a.Before != 2 || a.Latest != "v1.4.0" {
+		t.Errorf("Activity = %d recent, %d before, latest %s, want 3, 2, v1.4.0", a.Recent, a.Before, a.Latest)
+	}
+}
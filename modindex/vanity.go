@@ -0,0 +1,63 @@
+package modindex
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Curated links on hosts other than forges are mostly vanity import
+// paths, whose go-import meta tag names the repository they are served
+// from. The tag found for each path is kept in vanity_imports keyed by
+// path, so that the hosting landscape can be told without asking every
+// host again. Paths without a tag have no repository root.
+
+// VanityImport is the go-import meta tag served for a path.
+type VanityImport struct {
+	Path       string
+	Prefix     string // import path of the repository root
+	VCS        string // e.g. "git" or "mod"
+	RepoRoot   string // URL of the repository, empty if there is no tag
+	ResolvedAt time.Time
+}
+
+// StoreVanityImport replaces the stored tag of v.Path.
+func (c *Client) StoreVanityImport(ctx context.Context, v VanityImport) error {
+	db, err := c.sqlite()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "INSERT OR REPLACE INTO vanity_imports (path, prefix, vcs, repo_root, resolved_at) VALUES (?, ?, ?, ?, ?)",
+		v.Path, nullString(v.Prefix), nullString(v.VCS), nullString(v.RepoRoot), v.ResolvedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("insert vanity import: %w", err)
+	}
+	return nil
+}
+
+// VanityImports returns the stored tags of all paths, ordered by path.
+func (c *Client) VanityImports(ctx context.Context) ([]VanityImport, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT path, COALESCE(prefix, ''), COALESCE(vcs, ''), COALESCE(repo_root, ''), resolved_at FROM vanity_imports ORDER BY path")
+	if err != nil {
+		return nil, fmt.Errorf("query vanity imports: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var imports []VanityImport
+	for rows.Next() {
+		var v VanityImport
+		var resolved string
+		if err := rows.Scan(&v.Path, &v.Prefix, &v.VCS, &v.RepoRoot, &resolved); err != nil {
+			return nil, fmt.Errorf("scan vanity import: %w", err)
+		}
+		if v.ResolvedAt, err = time.Parse(time.RFC3339Nano, resolved); err != nil {
+			return nil, fmt.Errorf("parse resolution of %s: %w", v.Path, err)
+		}
+		imports = append(imports, v)
+	}
+	return imports, rows.Err()
+}
@@ -0,0 +1,178 @@
+package modindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ngrash/modhunt/modindex/index"
+)
+
+// VerifyReport lists the problems found by Verify.
+type VerifyReport struct {
+	// Orphans is the number of versions without a path.
+	Orphans int64
+
+	// BadTimestamps holds the number of unparsable timestamps
+	// and up to ten examples.
+	BadTimestamps        int64
+	BadTimestampExamples []string
+
+	// Gaps lists time windows between consecutive versions
+	// that are longer than the gap threshold.
+	Gaps []Gap
+
+	// Repaired holds a description of each repair performed.
+	Repaired []string
+}
+
+// Gap is a time window without any versions in the database.
+type Gap struct {
+	From, To time.Time
+}
+
+func (g Gap) String() string {
+	return fmt.Sprintf("%s - %s (%s)", g.From.Format(time.RFC3339), g.To.Format(time.RFC3339), g.To.Sub(g.From).Round(time.Second))
+}
+
+// OK reports whether no problems were found.
+func (r *VerifyReport) OK() bool {
+	return r.Orphans == 0 && r.BadTimestamps == 0 && len(r.Gaps) == 0
+}
+
+// Verify checks the invariants of the index database. Periods longer than
+// gap without any version are reported as gaps. If repair is true,
+// orphans are removed and gaps are backfilled from the index. Versions
+// can't be stored twice, the primary key of (path, version) prevents it.
+func (c *Client) Verify(ctx context.Context, gap time.Duration, repair bool) (*VerifyReport, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+
+	var r VerifyReport
+
+	row := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM versions WHERE path_id IS NULL OR path_id NOT IN (SELECT id FROM paths)")
+	if err := row.Scan(&r.Orphans); err != nil {
+		return nil, fmt.Errorf("count orphans: %w", err)
+	}
+
+	if err := scanTimestamps(ctx, db, gap, &r); err != nil {
+		return nil, err
+	}
+
+	if !repair {
+		return &r, nil
+	}
+
+	if r.Orphans > 0 {
+		res, err := db.ExecContext(ctx, "DELETE FROM versions WHERE path_id IS NULL OR path_id NOT IN (SELECT id FROM paths)")
+		if err != nil {
+			return nil, fmt.Errorf("delete orphans: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		r.Repaired = append(r.Repaired, fmt.Sprintf("deleted %d orphaned versions", n))
+	}
+
+	if len(r.Gaps) > 0 {
+		for _, g := range r.Gaps {
+			n, err := c.fillWindow(ctx, &sqliteStore{db: db}, g.From, g.To)
+			if err != nil {
+				return nil, fmt.Errorf("fill gap %s: %w", g, err)
+			}
+			r.Repaired = append(r.Repaired, fmt.Sprintf("inserted %d versions into gap %s", n, g))
+		}
+	}
+	if len(r.Repaired) > 0 {
+		if err := c.refreshSummaries(ctx, time.Time{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &r, nil
+}
+
+// scanTimestamps walks all timestamps in order to find unparsable ones and gaps.
+func scanTimestamps(ctx context.Context, db *sql.DB, gap time.Duration, r *VerifyReport) error {
+	rows, err := db.QueryContext(ctx, "SELECT timestamp FROM versions ORDER BY timestamp")
+	if err != nil {
+		return fmt.Errorf("query timestamps: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var prev time.Time
+	for rows.Next() {
+		var raw sql.NullString
+		if err := rows.Scan(&raw); err != nil {
+			return fmt.Errorf("scan timestamp: %w", err)
+		}
+		t, err := time.Parse(time.RFC3339Nano, raw.String)
+		if err != nil {
+			r.BadTimestamps++
+			if len(r.BadTimestampExamples) < 10 {
+				r.BadTimestampExamples = append(r.BadTimestampExamples, raw.String)
+			}
+			continue
+		}
+		if !prev.IsZero() && t.Sub(prev) > gap {
+			r.Gaps = append(r.Gaps, Gap{From: prev, To: t})
+		}
+		prev = t
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate timestamps: %w", err)
+	}
+	return nil
+}
+
+// fillWindow fetches all versions published in [from, to) from the index
+// and inserts the ones missing from the database. It returns the number
+// of versions inserted.
+func (c *Client) fillWindow(ctx context.Context, store Store, from, to time.Time) (int, error) {
+	inserted := 0
+	batch := make([]*index.VersionInfo, 0, c.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		batchesFetched.Inc()
+		n, err := store.InsertVersions(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("insert batch: %w", err)
+		}
+		inserted += n
+		batch = batch[:0]
+		return nil
+	}
+
+	var last time.Time
+	for v, err := range c.index.Stream(ctx, from) {
+		if errors.Is(err, index.ErrPageFull) && !last.IsZero() {
+			// We cannot page any further, keep what we have.
+			if err := flush(); err != nil {
+				return inserted, err
+			}
+			return inserted, store.AddCoverage(ctx, from, last)
+		}
+		if err != nil {
+			httpErrors.Inc()
+			return inserted, fmt.Errorf("get versions: %w", err)
+		}
+		if !v.Timestamp.Before(to) {
+			break
+		}
+		last = v.Timestamp
+		batch = append(batch, v)
+		if len(batch) == c.batchSize {
+			if err := flush(); err != nil {
+				return inserted, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return inserted, err
+	}
+	return inserted, store.AddCoverage(ctx, from, to)
+}
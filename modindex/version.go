@@ -0,0 +1,222 @@
+package modindex
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Version is a version of a module path as stored in the database.
+type Version struct {
+	Version      string
+	Timestamp    string
+	Incompatible bool
+	Retracted    bool
+}
+
+// CompareVersions orders versions by semantic versioning precedence.
+// Pseudo-versions are valid semantic versions and sort by their base
+// version and commit time. Invalid versions sort first.
+func CompareVersions(a, b string) int {
+	if c := semver.Compare(a, b); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func sortVersions(versions []string) {
+	slices.SortFunc(versions, CompareVersions)
+}
+
+const (
+	vtInvalid = iota
+	vtPseudo
+	vtPrerelease
+	vtIncompatible
+	vtStable
+)
+
+// classifyVersion ranks a version by how preferable it is as the latest version.
+func classifyVersion(v string) int {
+	if !semver.IsValid(v) {
+		return vtInvalid
+	}
+	if module.IsPseudoVersion(v) {
+		return vtPseudo
+	}
+	if semver.Prerelease(v) != "" {
+		return vtPrerelease
+	}
+	if strings.HasSuffix(v, "+incompatible") {
+		return vtIncompatible
+	}
+	return vtStable
+}
+
+// LatestVersion returns the version the go command would most likely
+// resolve as latest: the highest release, else the highest +incompatible
+// release, else the highest prerelease, else the highest pseudo-version.
+// Retracted versions are ignored. It returns the empty string if no
+// version qualifies.
+func LatestVersion(versions []Version) string {
+	var latest string
+	for _, v := range versions {
+		if v.Retracted {
+			continue
+		}
+		if latest == "" {
+			latest = v.Version
+			continue
+		}
+		if c := cmp.Compare(classifyVersion(v.Version), classifyVersion(latest)); c > 0 || c == 0 && CompareVersions(v.Version, latest) > 0 {
+			latest = v.Version
+		}
+	}
+	return latest
+}
+
+// PathVersions returns all versions of the path with the given ID,
+// ordered by CompareVersions.
+func PathVersions(ctx context.Context, db *sql.DB, pathID int64) ([]Version, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, timestamp, incompatible, retracted FROM versions WHERE path_id = ?", pathID)
+	if err != nil {
+		return nil, fmt.Errorf("query versions: %w", err)
+	}
+	var versions []Version
+	for rows.Next() {
+		var v Version
+		if err := rows.Scan(&v.Version, &v.Timestamp, &v.Incompatible, &v.Retracted); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("close versions: %w", err)
+	}
+	slices.SortFunc(versions, func(a, b Version) int {
+		return CompareVersions(a.Version, b.Version)
+	})
+	return versions, nil
+}
+
+// latestOf returns the version chosen by LatestVersion for the path with
+// the given ID or the zero Version if there is none.
+func latestOf(ctx context.Context, db *sql.DB, pathID int64) (Version, error) {
+	versions, err := PathVersions(ctx, db, pathID)
+	if err != nil {
+		return Version{}, err
+	}
+	latest := LatestVersion(versions)
+	for _, v := range versions {
+		if v.Version == latest {
+			return v, nil
+		}
+	}
+	return Version{}, nil
+}
+
+// MarkRetracted flags all versions of the path with the given ID that fall
+// into one of the retract intervals, which should come from the go.mod file
+// of the latest version. It returns the number of versions newly flagged.
+func MarkRetracted(ctx context.Context, db *sql.DB, pathID int64, retracts []*modfile.Retract) (int, error) {
+	if len(retracts) == 0 {
+		return 0, nil
+	}
+
+	versions, err := PathVersions(ctx, db, pathID)
+	if err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	for _, v := range versions {
+		if v.Retracted {
+			continue
+		}
+		for _, r := range retracts {
+			if semver.Compare(r.Low, v.Version) <= 0 && semver.Compare(v.Version, r.High) <= 0 {
+				_, err := db.ExecContext(ctx, "UPDATE versions SET retracted = 1, retract_rationale = ? WHERE path_id = ? AND version = ?", r.Rationale, pathID, v.Version)
+				if err != nil {
+					return marked, fmt.Errorf("mark %s retracted: %w", v.Version, err)
+				}
+				marked++
+				break
+			}
+		}
+	}
+	return marked, nil
+}
+
+// ErrPathNotFound is returned when a module path is not in the database.
+var ErrPathNotFound = errors.New("path not found")
+
+// Versions returns all versions of a module path ordered by CompareVersions.
+func (c *Client) Versions(ctx context.Context, path string) ([]Version, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return nil, err
+	}
+
+	pathID, err := lookupPathID(ctx, db, path)
+	if err != nil {
+		return nil, err
+	}
+	return PathVersions(ctx, db, pathID)
+}
+
+// Latest returns the version of a module path chosen by LatestVersion.
+// The Version is zero if no version qualifies.
+func (c *Client) Latest(ctx context.Context, path string) (Version, error) {
+	db, err := c.sqlite()
+	if err != nil {
+		return Version{}, err
+	}
+	pathID, err := lookupPathID(ctx, db, path)
+	if err != nil {
+		return Version{}, err
+	}
+	return latestOf(ctx, db, pathID)
+}
+
+// lookupPathID returns the ID of path. If the path is unknown, the error
+// wraps ErrPathNotFound and mentions paths differing only in case.
+func lookupPathID(ctx context.Context, db *sql.DB, path string) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, "SELECT id FROM paths WHERE path = ?", path).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("select path: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT path FROM paths WHERE lower(path) = lower(?)", path)
+	if err != nil {
+		return 0, fmt.Errorf("select similar paths: %w", err)
+	}
+	var similar []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("scan similar path: %w", err)
+		}
+		similar = append(similar, p)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("close similar paths: %w", err)
+	}
+	if len(similar) > 0 {
+		return 0, fmt.Errorf("%w: %s (did you mean %s?)", ErrPathNotFound, path, strings.Join(similar, ", "))
+	}
+	return 0, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+}
@@ -0,0 +1,188 @@
+// Package modscore rates the health of Go modules on a scale from 0 to
+// 100.
+//
+// A score combines factors like release recency, popularity, maintainer
+// responsiveness and known vulnerabilities. Every factor rates one signal from 0 to 1 and has a
+// fixed weight; the score is the weighted mean of the factors whose
+// signal is known, so a module is not punished for data that was never
+// collected. Archived repositories and deprecated modules are capped, no
+// matter how well they did before. Score.Factors explains every score.
+package modscore
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// Signals are the facts a score is computed from. Each group of fields
+// is only considered if its Has field is set.
+type Signals struct {
+	HasReleases     bool
+	LastRelease     time.Time // zero if never released
+	ReleasesPerYear float64
+
+	HasRepo   bool
+	Stars     int
+	Archived  bool
+	BusFactor int // 0 if unknown
+
+	HasGraph   bool // whether the dependency graph was loaded
+	ImportedBy int  // modules requiring the module
+	Deprecated bool
+
+	HasVulns bool
+	Vulns    int // advisories affecting the latest version
+
+	HasIssues      bool
+	IssuesSampled  int           // recent issues old enough to expect a response
+	IssuesAnswered int           // sampled issues that got a response
+	MedianResponse time.Duration // until the first response to an answered issue
+}
+
+// Factor is the contribution of one signal to a score.
+type Factor struct {
+	Name   string
+	Weight float64 // relative to the other factors
+	Known  bool    // whether the signal was known; unknown factors do not count
+	Value  float64 // from 0 to 1
+	Detail string  // the signal, e.g. "last release 2024-05-01"
+}
+
+// Score is the health of a module.
+type Score struct {
+	Total   int // from 0 to 100
+	Factors []Factor
+	// Cap explains why Total was capped, e.g. "archived", or is empty.
+	Cap string
+}
+
+// Known reports whether any factor was known. Scores without known
+// factors are 0 and meaningless.
+func (s Score) Known() bool {
+	for _, f := range s.Factors {
+		if f.Known {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the total, or "-" if no factor was known.
+func (s Score) String() string {
+	if !s.Known() {
+		return "-"
+	}
+	return fmt.Sprint(s.Total)
+}
+
+// maxCapped is the highest score of archived or deprecated modules.
+const maxCapped = 25
+
+// Compute scores the signals as of now.
+func Compute(s Signals, now time.Time) Score {
+	var score Score
+	add := func(name string, weight float64, known bool, value float64, detail string) {
+		score.Factors = append(score.Factors, Factor{
+			Name:   name,
+			Weight: weight,
+			Known:  known,
+			Value:  math.Max(0, math.Min(1, value)),
+			Detail: detail,
+		})
+	}
+
+	// A release within half a year is recent, three years without one
+	// is a sign of abandonment.
+	recency, detail := 0.0, "never released"
+	if !s.LastRelease.IsZero() {
+		age := now.Sub(s.LastRelease).Hours() / 24 / 365
+		recency = 1 - (age-0.5)/2.5
+		detail = "last release " + s.LastRelease.Format(time.DateOnly)
+	}
+	add("release recency", 20, s.HasReleases, recency, detail)
+	add("release cadence", 10, s.HasReleases, s.ReleasesPerYear/4, fmt.Sprintf("%.1f releases per year", s.ReleasesPerYear))
+
+	// Popularity is logarithmic: 10,000 stars and 1,000 importers max out.
+	add("stars", 15, s.HasRepo, math.Log10(float64(s.Stars)+1)/4, fmt.Sprintf("%d stars", s.Stars))
+	add("importers", 20, s.HasGraph, math.Log10(float64(s.ImportedBy)+1)/3, fmt.Sprintf("required by %d modules", s.ImportedBy))
+
+	add("vulnerabilities", 15, s.HasVulns, 1-0.5*float64(s.Vulns), fmt.Sprintf("%d in latest version", s.Vulns))
+	// Maintainers answering every issue within a day are responsive,
+	// a median response of a month or more is as bad as no answer.
+	responsiveness, detail := 0.0, "no recent issues"
+	if s.IssuesSampled > 0 {
+		answered := float64(s.IssuesAnswered) / float64(s.IssuesSampled)
+		speed := 0.0
+		if s.IssuesAnswered > 0 {
+			speed = 1 - (s.MedianResponse.Hours()/24-1)/29
+		}
+		responsiveness = (answered + math.Max(0, math.Min(1, speed))) / 2
+		detail = fmt.Sprintf("%d of %d recent issues answered", s.IssuesAnswered, s.IssuesSampled)
+		if s.IssuesAnswered > 0 {
+			detail += fmt.Sprintf(", median %.1f days", s.MedianResponse.Hours()/24)
+		}
+	}
+	add("issue responsiveness", 10, s.HasIssues && s.IssuesSampled > 0, responsiveness, detail)
+	add("bus factor", 10, s.HasRepo && s.BusFactor > 0, float64(s.BusFactor)/3, fmt.Sprintf("%d authors make half of the commits", s.BusFactor))
+	add("not archived", 5, s.HasRepo, boolValue(!s.Archived), describe(s.Archived, "archived", "active repository"))
+	add("not deprecated", 5, s.HasGraph, boolValue(!s.Deprecated), describe(s.Deprecated, "deprecated", "not deprecated"))
+
+	var sum, weights float64
+	for _, f := range score.Factors {
+		if f.Known {
+			sum += f.Weight * f.Value
+			weights += f.Weight
+		}
+	}
+	if weights > 0 {
+		score.Total = int(math.Round(100 * sum / weights))
+	}
+	switch {
+	case s.HasRepo && s.Archived:
+		score.Cap = "archived"
+	case s.HasGraph && s.Deprecated:
+		score.Cap = "deprecated"
+	}
+	if score.Cap != "" {
+		score.Total = min(score.Total, maxCapped)
+	}
+	return score
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func describe(b bool, yes, no string) string {
+	if b {
+		return yes
+	}
+	return no
+}
+
+// BusFactor returns the smallest number of authors who made at least
+// half of the commits, given the author of every commit of a sample.
+func BusFactor(authors []string) int {
+	if len(authors) == 0 {
+		return 0
+	}
+	counts := make(map[string]int)
+	for _, a := range authors {
+		counts[a]++
+	}
+	sorted := slices.SortedFunc(maps.Values(counts), func(a, b int) int { return b - a })
+	var sum int
+	for i, n := range sorted {
+		sum += n
+		if 2*sum >= len(authors) {
+			return i + 1
+		}
+	}
+	return len(sorted)
+}
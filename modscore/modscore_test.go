@@ -0,0 +1,83 @@
+package modscore
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func factor(t *testing.T, s Score, name string) Factor {
+	t.Helper()
+	for _, f := range s.Factors {
+		if f.Name == name {
+			return f
+		}
+	}
+	t.Fatalf("score has no factor %q", name)
+	return Factor{}
+}
+
+func TestComputeResponsiveness(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name   string
+		sig    Signals
+		known  bool
+		value  float64
+		detail string
+	}{
+		{"not sampled", Signals{}, false, 0, "no recent issues"},
+		{"no recent issues", Signals{HasIssues: true}, false, 0, "no recent issues"},
+		{"all answered within a day", Signals{HasIssues: true, IssuesSampled: 20, IssuesAnswered: 20, MedianResponse: 6 * time.Hour},
+			true, 1, "20 of 20 recent issues answered, median 0.2 days"},
+		{"none answered", Signals{HasIssues: true, IssuesSampled: 10},
+			true, 0, "0 of 10 recent issues answered"},
+		{"half answered in two weeks", Signals{HasIssues: true, IssuesSampled: 10, IssuesAnswered: 5, MedianResponse: 15*day + 12*time.Hour},
+			true, 0.5, "5 of 10 recent issues answered, median 15.5 days"},
+		{"all answered after months", Signals{HasIssues: true, IssuesSampled: 4, IssuesAnswered: 4, MedianResponse: 90 * day},
+			true, 0.5, "4 of 4 recent issues answered, median 90.0 days"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Compute(tt.sig, time.Now())
+			f := factor(t, s, "issue responsiveness")
+			if f.Known != tt.known || math.Abs(f.Value-tt.value) > 1e-9 || f.Detail != tt.detail {
+				t.Errorf("factor = %v, %v, %q, want %v, %v, %q", f.Known, f.Value, f.Detail, tt.known, tt.value, tt.detail)
+			}
+			if tt.known && s.Total != int(math.Round(100*tt.value)) {
+				t.Errorf("Total = %d, want %.0f as the only known factor", s.Total, 100*tt.value)
+			}
+		})
+	}
+}
+
+func TestComputeResponsivenessWeight(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sig := Signals{HasReleases: true, LastRelease: now.AddDate(0, -1, 0), ReleasesPerYear: 4}
+	if got := Compute(sig, now).Total; got != 100 {
+		t.Fatalf("Total = %d, want 100", got)
+	}
+
+	// Unanswered issues weigh 10 against the 30 of the release factors.
+	sig.HasIssues, sig.IssuesSampled = true, 20
+	if got := Compute(sig, now).Total; got != 75 {
+		t.Errorf("Total with unanswered issues = %d, want 75", got)
+	}
+}
+
+func TestBusFactor(t *testing.T) {
+	tests := []struct {
+		authors []string
+		want    int
+	}{
+		{nil, 0},
+		{[]string{"a", "a", "a", "b"}, 1},
+		{[]string{"a", "b", "c", "d"}, 2},
+		{[]string{"a", "a", "b", "b", "c", "c", "d"}, 2},
+	}
+	for _, tt := range tests {
+		if got := BusFactor(tt.authors); got != tt.want {
+			t.Errorf("BusFactor(%q) = %d, want %d", tt.authors, got, tt.want)
+		}
+	}
+}